@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	k8s2 "github.com/devtron-labs/common-lib/utils/k8s"
@@ -42,8 +43,16 @@ func (impl *ClusterUpgradeReadServiceImpl) GetClusterUpgradeSummaryValidationRes
 			return nil, err
 		}
 	}
-	cluster := pkg.NewClusterFromEnvOrConfig(restConfig)
-	results, err := kubedd.ValidateCluster(cluster, &pkg.Config{TargetKubernetesVersion: targetK8sVersion})
+	conf := &pkg.Config{TargetKubernetesVersion: targetK8sVersion}
+	cluster, err := pkg.NewClusterFromEnvOrConfig(restConfig, pkg.ClusterOptions{}, conf)
+	if err != nil {
+		impl.logger.Errorw("error in creating cluster client", "err", err)
+		return nil, err
+	}
+	results, summary, err := kubedd.ValidateCluster(context.Background(), cluster, conf)
+	if summary.Partial() {
+		impl.logger.Warnw("scan of cluster is partial", "skipped", summary.Skipped, "forbidden", summary.Forbidden)
+	}
 	if err != nil {
 		impl.logger.Errorw("error in ValidateCluster", "err", err)
 		if errors.Is(err, errors2.ErrOpenApiSpecNotFound) {
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2021 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/devtron-labs/silver-surfer/kubedd"
+	"github.com/devtron-labs/silver-surfer/pkg"
+	log2 "github.com/devtron-labs/silver-surfer/pkg/log"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+var checkNamespace = ""
+
+// checkCmd validates a single object of clusters
+var checkCmd = &cobra.Command{
+	Use:   "check <kind> <name>",
+	Short: "Validates a single object of the cluster",
+	Long:  `Validates a single object of the cluster without scanning the whole cluster, eg kubedd check deployment api -n payments. The kind may be given as in the kind filters, by its plural or by its short name, optionally qualified with its group eg deployments.apps.`,
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		logToStderrForDocument()
+		applyColorFlags()
+		if err := config.Validate(); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		if err := config.LoadSuppressions(); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		if err := config.LoadCustomRules(); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		if len(config.TargetVersions()) > 1 {
+			log2.Error(errors.New("a single object can only be validated against a single target version"))
+			os.Exit(1)
+		}
+		if !processCheck(args[0], args[1]) {
+			os.Exit(1)
+		}
+	},
+}
+
+func processCheck(kind, name string) bool {
+	outputManager := pkg.GetOutputManager(config.OutputFormat, noColor)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	selected, err := selectedContexts()
+	if err != nil {
+		log2.Error(err)
+		return false
+	}
+	success := true
+	for _, kubecontext := range selected {
+		contextSuccess, err := checkContext(ctx, kubecontext, kind, name, outputManager)
+		if err != nil {
+			log2.Error(err)
+		}
+		success = success && contextSuccess
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if err := outputManager.Flush(); err != nil {
+		log2.Error(err)
+		success = false
+	}
+	return success
+}
+
+// checkContext validates the object of the cluster of kubecontext and puts its result to outputManager, it returns
+// false when the object could not be validated or has findings which fail the run
+func checkContext(ctx context.Context, kubecontext, kind, name string, outputManager pkg.OutputManager) (bool, error) {
+	clusterConfig := *config
+	cluster, err := pkg.NewCluster(kubeconfig, kubecontext, &clusterConfig)
+	if err != nil {
+		return false, err
+	}
+	defer cluster.Close()
+	gvk, err := cluster.ResolveKind(kind)
+	if err != nil {
+		return false, err
+	}
+	autoTarget := len(clusterConfig.TargetVersions()) == 0
+	result, err := kubedd.ValidateClusterObject(ctx, cluster, gvk, checkNamespace, name, &clusterConfig)
+	if err != nil {
+		return false, err
+	}
+
+	clusterName := cluster.Name()
+	if len(kubecontext) > 0 {
+		clusterName = fmt.Sprintf("%s (context %s)", clusterName, kubecontext)
+	}
+	results := []pkg.ValidationResult{result}
+	if scanOutputManager, ok := outputManager.(pkg.ScanOutputManager); ok {
+		scan := pkg.ScanMetadata{Cluster: cluster.Name(), SourceVersion: cluster.Version(), TargetVersion: clusterConfig.TargetKubernetesVersion}
+		scanOutputManager.PutScan(scan, results, nil, &clusterConfig)
+		return !pkg.HasGatingFindings(results, &clusterConfig), nil
+	}
+	fmt.Println("")
+	fmt.Printf("Results for %s %s of cluster %s at version %s to %s%s%s\n", gvk.Kind, name, clusterName, cluster.Version(), clusterConfig.TargetKubernetesVersion, specNote(clusterConfig.TargetKubernetesVersion, results), autoTargetNote(autoTarget))
+	fmt.Println("-------------------------------------------")
+	outputManager.PutBulk(results)
+	return !pkg.HasGatingFindings(results, &clusterConfig), nil
+}
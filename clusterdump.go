@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2021 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"github.com/devtron-labs/silver-surfer/kubedd"
+	"github.com/devtron-labs/silver-surfer/pkg"
+	log2 "github.com/devtron-labs/silver-surfer/pkg/log"
+)
+
+// clusterDump is the path of a cluster dump to be validated instead of a live cluster
+var clusterDump = ""
+
+// processClusterDump validates the objects of clusterDump, it returns false when the dump could not be read or has
+// findings which fail the run
+func processClusterDump() bool {
+	outputManager := pkg.GetOutputManager(config.OutputFormat, noColor)
+	results, summary, err := kubedd.ValidateClusterDump(clusterDump, config)
+	if err != nil {
+		log2.Error(err)
+		return false
+	}
+
+	if scanOutputManager, ok := outputManager.(pkg.ScanOutputManager); ok {
+		scan := pkg.ScanMetadata{Cluster: clusterDump, SourceVersion: config.SourceKubernetesVersion, TargetVersion: config.TargetKubernetesVersion}
+		scanOutputManager.PutScan(scan, results, summary, config)
+	} else {
+		fmt.Println("")
+		fmt.Printf("Results for cluster dump %s to %s%s\n", clusterDump, config.TargetKubernetesVersion, specNote(config.TargetKubernetesVersion, results))
+		fmt.Println("-------------------------------------------")
+		outputManager.PutBulk(results)
+		if summary.Reportable() {
+			fmt.Println("")
+			fmt.Println(summary.String())
+		}
+	}
+	success := !hasErrors(results)
+	if summary.Partial() && config.FailOnFetchErrors {
+		success = false
+	}
+	if err := outputManager.Flush(); err != nil {
+		log2.Error(err)
+		success = false
+	}
+	return success
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2021 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+package main
+
+import (
+	"fmt"
+	"github.com/devtron-labs/silver-surfer/pkg"
+	log2 "github.com/devtron-labs/silver-surfer/pkg/log"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// downloadSpecsCmd fills the schema cache for air-gapped machines
+var downloadSpecsCmd = &cobra.Command{
+	Use:   "download-specs <version> [version...]",
+	Short: "Downloads the openapi specs of kubernetes versions into the schema cache",
+	Long:  `Downloads the openapi specs of kubernetes versions into the schema cache, replacing the cached ones. Run it on a connected machine and copy the schema cache dir to air-gapped machines, where kubedd --offline validates against the cached specs without any download.`,
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := pkg.DownloadSchemas(config, args); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		fmt.Printf("Specs of kubernetes %v downloaded to %s\n", args, config.SchemaCacheDir)
+	},
+}
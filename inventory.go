@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2021 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/devtron-labs/silver-surfer/pkg"
+	log2 "github.com/devtron-labs/silver-surfer/pkg/log"
+	"github.com/spf13/cobra"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+)
+
+// inventoryCmd counts the objects of clusters by kind and namespace without validating them
+var inventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Counts the objects of the cluster by kind and namespace without validating them",
+	Long:  `Counts the objects of the cluster by kind and namespace without validating them, only the metadata of objects is listed so that the inventory of large clusters completes quickly. The kind and namespace filters apply as for a scan.`,
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(objectName) > 0 {
+			config.FieldSelector = withNameSelector(config.FieldSelector, objectName)
+		}
+		if err := config.Validate(); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		if !processInventory() {
+			os.Exit(1)
+		}
+	},
+}
+
+// inventoryReport is the inventory of a cluster as printed with the json output
+type inventoryReport struct {
+	Cluster   string               `json:"cluster"`
+	Inventory []pkg.InventoryEntry `json:"inventory"`
+	Summary   *pkg.FetchSummary    `json:"summary"`
+}
+
+func processInventory() bool {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	selected, err := selectedContexts()
+	if err != nil {
+		log2.Error(err)
+		return false
+	}
+	success := true
+	for _, kubecontext := range selected {
+		if err := inventoryContext(ctx, kubecontext); err != nil {
+			log2.Error(err)
+			success = false
+			if ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	return success
+}
+
+// inventoryContext prints the inventory of the cluster of kubecontext
+func inventoryContext(ctx context.Context, kubecontext string) error {
+	clusterConfig := *config
+	cluster, err := pkg.NewCluster(kubeconfig, kubecontext, &clusterConfig)
+	if err != nil {
+		return err
+	}
+	defer cluster.Close()
+	gvks, err := cluster.DiscoverGVKs(ctx, &clusterConfig)
+	if err != nil {
+		return err
+	}
+	entries, summary, err := cluster.CountK8sObjects(ctx, gvks, &clusterConfig)
+	if err != nil {
+		return err
+	}
+	name := cluster.Name()
+	if len(kubecontext) > 0 {
+		name = fmt.Sprintf("%s (context %s)", name, kubecontext)
+	}
+	if clusterConfig.OutputFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "\t")
+		return encoder.Encode(inventoryReport{Cluster: name, Inventory: entries, Summary: summary})
+	}
+
+	fmt.Println("")
+	fmt.Printf("Inventory of cluster %s\n", name)
+	fmt.Println("-------------------------------------------")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tKIND\tAPIVERSION\tCOUNT")
+	total := 0
+	for _, entry := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", entry.Namespace, entry.Kind, entry.APIVersion(), entry.Count)
+		total += entry.Count
+	}
+	fmt.Fprintf(w, "\t\tTOTAL\t%d\n", total)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if summary.Reportable() {
+		fmt.Println("")
+		fmt.Println(summary.String())
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package kubedd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/devtron-labs/silver-surfer/pkg"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+const argoApplicationGroup = "argoproj.io"
+
+// ScanGitOpsRepo validates a GitOps repository app by app and returns the results keyed by app name.
+// Argo CD Application manifests found in the repo mark the directories referenced by their source path
+// as apps, every other top level directory is treated as an app named after the directory and manifests
+// lying at the root of the repo are reported under "."
+func ScanGitOpsRepo(root string, conf *pkg.Config) (map[string][]pkg.ValidationResult, error) {
+	apps, err := discoverGitOpsApps(root)
+	if err != nil {
+		return nil, err
+	}
+	reports := make(map[string][]pkg.ValidationResult, len(apps))
+	for app, files := range apps {
+		results := make([]pkg.ValidationResult, 0)
+		for _, file := range files {
+			contents, err := ioutil.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("could not open file %s: %w", file, err)
+			}
+			fileConf := *conf
+			if fileConf.FileName, err = filepath.Rel(root, file); err != nil {
+				fileConf.FileName = file
+			}
+			fileResults, err := Validate(contents, &fileConf)
+			if err != nil {
+				return nil, fmt.Errorf("could not validate file %s: %w", file, err)
+			}
+			results = append(results, fileResults...)
+		}
+		reports[app] = results
+	}
+	return reports, nil
+}
+
+// discoverGitOpsApps returns the manifest files of the repo grouped by app
+func discoverGitOpsApps(root string) (map[string][]string, error) {
+	var files []string
+	appDirs := map[string]string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(info.Name(), ".yaml") && !strings.HasSuffix(info.Name(), ".yml") {
+			return nil
+		}
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		applications := argoApplicationPaths(contents)
+		for name, sourcePath := range applications {
+			dir := filepath.Join(root, filepath.FromSlash(sourcePath))
+			if stat, err := os.Stat(dir); err == nil && stat.IsDir() {
+				appDirs[name] = dir
+			}
+		}
+		if len(applications) == 0 {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// nested app directories must win over their parents
+	appNames := make([]string, 0, len(appDirs))
+	for name := range appDirs {
+		appNames = append(appNames, name)
+	}
+	sort.Slice(appNames, func(i, j int) bool {
+		return len(appDirs[appNames[i]]) > len(appDirs[appNames[j]])
+	})
+
+	apps := map[string][]string{}
+	for _, file := range files {
+		app := ""
+		for _, name := range appNames {
+			if strings.HasPrefix(file, appDirs[name]+string(filepath.Separator)) {
+				app = name
+				break
+			}
+		}
+		if len(app) == 0 {
+			rel, err := filepath.Rel(root, file)
+			if err != nil {
+				return nil, err
+			}
+			app = "."
+			if parts := strings.SplitN(filepath.ToSlash(rel), "/", 2); len(parts) == 2 {
+				app = parts[0]
+			}
+		}
+		apps[app] = append(apps[app], file)
+	}
+	return apps, nil
+}
+
+// argoApplicationPaths returns the source path of every Argo CD Application in the manifest keyed by
+// application name, applications with multiple sources contribute the path of their first source
+func argoApplicationPaths(contents []byte) map[string]string {
+	paths := map[string]string{}
+	// the documents before one which cannot be read are still looked at
+	docs, _ := splitDocuments(contents)
+	for _, doc := range docs {
+		var obj unstructured.Unstructured
+		if err := yaml.Unmarshal(doc, &obj.Object); err != nil || obj.Object == nil {
+			continue
+		}
+		if obj.GetKind() != "Application" || !strings.HasPrefix(obj.GetAPIVersion(), argoApplicationGroup+"/") {
+			continue
+		}
+		sourcePath, _, _ := unstructured.NestedString(obj.Object, "spec", "source", "path")
+		if len(sourcePath) == 0 {
+			if sources, ok, _ := unstructured.NestedSlice(obj.Object, "spec", "sources"); ok {
+				for _, source := range sources {
+					if s, ok := source.(map[string]interface{}); ok {
+						if p, ok := s["path"].(string); ok && len(p) > 0 {
+							sourcePath = p
+							break
+						}
+					}
+				}
+			}
+		}
+		if len(sourcePath) > 0 {
+			paths[obj.GetName()] = sourcePath
+		}
+	}
+	return paths
+}
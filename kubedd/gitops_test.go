@@ -0,0 +1,64 @@
+package kubedd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiscoverGitOpsApps(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"apps/cart.yaml": `apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: cart
+spec:
+  source:
+    path: services/cart
+---
+apiVersion: argoproj.io/v1alpha1
+kind: Application
+metadata:
+  name: payments
+spec:
+  sources:
+  - chart: redis
+  - path: services/payments/overlays/prod
+`,
+		"services/cart/deployment.yaml":                    "kind: Deployment\n",
+		"services/cart/base/service.yml":                   "kind: Service\n",
+		"services/payments/overlays/prod/deployment.yaml":  "kind: Deployment\n",
+		"services/payments/overlays/stage/deployment.yaml": "kind: Deployment\n",
+		"monitoring/prometheus.yaml":                       "kind: StatefulSet\n",
+		"monitoring/README.md":                             "not a manifest",
+		"namespace.yaml":                                   "kind: Namespace\n",
+		".github/workflows/ci.yaml":                        "on: push\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+
+	apps, err := discoverGitOpsApps(root)
+	assert.NoError(t, err)
+	got := map[string][]string{}
+	for app, appFiles := range apps {
+		for _, file := range appFiles {
+			rel, _ := filepath.Rel(root, file)
+			got[app] = append(got[app], filepath.ToSlash(rel))
+		}
+		sort.Strings(got[app])
+	}
+	assert.Equal(t, map[string][]string{
+		"cart":       {"services/cart/base/service.yml", "services/cart/deployment.yaml"},
+		"payments":   {"services/payments/overlays/prod/deployment.yaml"},
+		"services":   {"services/payments/overlays/stage/deployment.yaml"},
+		"monitoring": {"monitoring/prometheus.yaml"},
+		".":          {"namespace.yaml"},
+	}, got)
+}
@@ -18,21 +18,41 @@
 package kubedd
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/devtron-labs/silver-surfer/pkg"
 	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	"io"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	"os"
-	"strings"
+	"sigs.k8s.io/yaml"
 )
 
-var yamlSeparator = []byte("\n---\n")
-
 // Validate a Kubernetes YAML file, parsing out individual resources
 // and validating them all according to the  relevant schemas
 func Validate(input []byte, conf *pkg.Config) ([]pkg.ValidationResult, error) {
-	kubeC := pkg.NewKubeCheckerImpl()
+	if len(conf.SourceKubernetesVersion) == 0 && len(conf.TargetKubernetesVersion) != 0 {
+		conf.SourceKubernetesVersion = conf.TargetKubernetesVersion
+	}
+	var downloaded []string
+	if len(conf.TargetSchemaLocation) == 0 {
+		downloaded = append(downloaded, conf.TargetKubernetesVersion)
+	}
+	if len(conf.SourceSchemaLocation) == 0 {
+		downloaded = append(downloaded, conf.SourceKubernetesVersion)
+	}
+	if err := conf.MissingSchemas(downloaded...); err != nil {
+		kLog.Error(err)
+		os.Exit(1)
+	}
+	kubeC := pkg.NewCachedKubeChecker(conf)
 	if len(conf.TargetSchemaLocation) > 0 {
 		err := kubeC.LoadFromPath(conf.TargetKubernetesVersion, conf.TargetSchemaLocation, false)
 		if err != nil {
@@ -59,91 +79,447 @@ func Validate(input []byte, conf *pkg.Config) ([]pkg.ValidationResult, error) {
 			os.Exit(1)
 		}
 	}
-	if len(conf.SourceKubernetesVersion) == 0 && len(conf.TargetKubernetesVersion) != 0 {
-		conf.SourceKubernetesVersion = conf.TargetKubernetesVersion
+	docs, err := splitDocuments(input)
+	if err != nil {
+		return nil, fmt.Errorf("unable to split the documents of %s: %w", conf.FileName, err)
 	}
-	splits := bytes.Split(input, yamlSeparator)
 	var validationResults []pkg.ValidationResult
 	//isVersionSupported := isVersionSupported()
-	for _, split := range splits {
-		validationResult, err := kubeC.ValidateYaml(string(split), conf.TargetKubernetesVersion)
+	for i, doc := range docs {
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+		var object map[string]interface{}
+		if err := yaml.Unmarshal(doc, &object); err == nil && (object["apiVersion"] == nil || object["kind"] == nil) {
+			kLog.Debug(fmt.Sprintf("skipping document %d of %s, it has no apiVersion or kind", i+1, conf.FileName))
+			continue
+		}
+		validationResult, err := kubeC.ValidateYaml(string(doc), conf.TargetKubernetesVersion)
 		if err != nil {
-			fmt.Printf("err: %v\n", err)
+			kLog.Error(err)
 			continue
 		}
+		validationResult.FileName = conf.FileName
+		validationResult.DocumentIndex = i + 1
+		if object != nil {
+			validationResult = pkg.CheckRemovedComponentFlags(validationResult, object, conf)
+			validationResult = pkg.CheckPodFieldDeprecations(validationResult, object)
+			validationResult = pkg.CheckCustomRules(validationResult, object, conf)
+		}
 		//validationResult = isVersionSupported(validationResult, kubeC, conf)
 		validationResult = pkg.FilterValidationResults(validationResult, conf)
+		if object != nil {
+			obj := &unstructured.Unstructured{Object: object}
+			validationResult = pkg.ApplySuppressions(validationResult, obj, conf)
+			validationResult = writeFixedManifest(validationResult, obj, conf)
+		}
+		validationResult = pkg.MarkAlreadyRemoved(validationResult, kubeC, conf)
+		validationResult.Severity = pkg.ResultSeverity(validationResult, conf)
 		validationResults = append(validationResults, validationResult)
 	}
+	pkg.SortResults(validationResults)
 
 	return validationResults, nil
 }
 
-func ValidateCluster(cluster *pkg.Cluster, conf *pkg.Config) ([]pkg.ValidationResult, error) {
-	kubeC := pkg.NewKubeCheckerImpl()
-	if len(conf.TargetSchemaLocation) > 0 {
-		err := kubeC.LoadFromPath(conf.TargetKubernetesVersion, conf.TargetSchemaLocation, false)
+// splitDocuments returns the documents of a multi-document yaml input or of concatenated json objects, including
+// empty ones so that the index of a document is its position in the input
+func splitDocuments(input []byte) ([][]byte, error) {
+	if bytes.HasPrefix(bytes.TrimSpace(input), []byte("{")) {
+		decoder := json.NewDecoder(bytes.NewReader(input))
+		var docs [][]byte
+		for {
+			var doc json.RawMessage
+			err := decoder.Decode(&doc)
+			if err == io.EOF {
+				return docs, nil
+			}
+			if err != nil {
+				return docs, err
+			}
+			docs = append(docs, doc)
+		}
+	}
+	reader := k8syaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(input)))
+	var docs [][]byte
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return docs, err
+		}
+		docs = append(docs, doc)
+	}
+}
+
+// ValidateCluster validates the objects in cluster against the target kubernetes version, once ctx is done the
+// objects fetched so far are validated and returned along with the error of ctx. The summary lists the resources
+// which could not be fetched, when it is partial so are the results. Only the results against the first target
+// version are returned when several are set, see ValidateClusterTargets
+func ValidateCluster(ctx context.Context, cluster *pkg.Cluster, conf *pkg.Config) ([]pkg.ValidationResult, *pkg.FetchSummary, error) {
+	targetResults, summary, err := ValidateClusterTargets(ctx, cluster, conf)
+	if len(targetResults) == 0 {
+		return make([]pkg.ValidationResult, 0), summary, err
+	}
+	return targetResults[0].Results, summary, err
+}
+
+// ValidateClusterTargets validates the objects in cluster like ValidateCluster against each of the target versions
+// of conf, see Config.TargetVersions. The objects are fetched once and the results are returned per target version
+// in the order the versions were given
+func ValidateClusterTargets(ctx context.Context, cluster *pkg.Cluster, conf *pkg.Config) ([]pkg.TargetResults, *pkg.FetchSummary, error) {
+	checkers, serverVersion, err := loadClusterCheckers(ctx, cluster, conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	targets := conf.TargetVersions()
+	resources, err := clusterKinds(checkers[0], serverVersion, conf.ForTarget(targets[0]))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to list the kinds of cluster %s at version %s: %w", cluster.Name(), serverVersion, err)
+	}
+	ctx, span := conf.StartSpan(ctx, pkg.SpanValidateCluster,
+		attribute.String("k8s.server_version", serverVersion), attribute.String("k8s.target_version", conf.TargetKubernetesVersion))
+	defer span.End()
+	crds, customKinds := customResources(ctx, cluster, conf)
+	objects, summary, fetchErr := cluster.FetchK8sObjects(ctx, append(resources, customKinds...), conf)
+	serverWarnings := cluster.ServerWarnings()
+	if conf.CheckDeprecatedAPIUsage {
+		summary.AddDeprecatedAPIUsage(ctx, cluster)
+	}
+	for _, risk := range pkg.CheckCRDConversion(objects) {
+		kLog.Warn(risk.Message)
+	}
+	var owners *pkg.OwnerIndex
+	if conf.AnnotateRootOwner {
+		owners = pkg.NewOwnerIndex(objects)
+	}
+	targetResults := make([]pkg.TargetResults, 0, len(targets))
+	count := 0
+	severities := map[string]int{}
+	for t, target := range targets {
+		// the source version of a live cluster is its own, the last applied configuration of its objects may be in
+		// api versions it no longer serves
+		targetConf := conf.ForTarget(target).ForSource(serverVersion)
+		if t > 0 {
+			// manifests are only fixed for the first target version, the one the results of ValidateCluster are for
+			targetConf.FixOutputDir = ""
+		}
+		var validationResults []pkg.ValidationResult
+		//isVersionSupported := isVersionSupported()
+		outcomes := validateObjects(objects, targetConf, func(obj *unstructured.Unstructured) (pkg.ValidationResult, []string, error) {
+			validationResult, suppressed, err := validateClusterObject(checkers[t], crds, cluster.Name(), obj, owners, targetConf)
+			if err == nil && t == 0 && conf.VerifyCluster != nil {
+				validated, _, _ := pkg.LastAppliedObject(obj, targetConf)
+				validationResult = pkg.VerifyDryRun(ctx, validationResult, validated, targetConf)
+				validationResult.Severity = pkg.ResultSeverity(validationResult, targetConf)
+			}
+			return validationResult, suppressed, err
+		})
+		for i, outcome := range outcomes {
+			validationResult, suppressed, err := outcome.result, outcome.suppressed, outcome.err
+			var missingCRD *pkg.MissingCRDError
+			if errors.As(err, &missingCRD) {
+				summary.AddMissingDefinition(missingCRD.Group)
+				continue
+			}
+			if err != nil {
+				kLog.Error(err)
+				continue
+			}
+			// the rules suppressed by annotations are the same against every target version
+			if t == 0 && len(suppressed) > 0 && summary != nil {
+				summary.Suppressed = append(summary.Suppressed, pkg.NewSuppressedObject(&objects[i], suppressed))
+			}
+			targetConf.EmitFinding(validationResult)
+			pkg.RecordFinding(span, validationResult)
+			validationResults = append(validationResults, validationResult)
+		}
+		pkg.AnalyzeRemovals(ctx, validationResults, objects, cluster, targetConf)
+		pkg.AttachServerWarnings(validationResults, serverWarnings)
+		pkg.SortResults(validationResults)
+		count += len(validationResults)
+		for severity, n := range pkg.SeverityCounts(validationResults) {
+			severities[severity] += n
+		}
+		targetResults = append(targetResults, pkg.TargetResults{TargetVersion: target, Results: validationResults})
+	}
+	summary.Sort()
+	conf.Emit(pkg.ScanEvent{Type: pkg.ScanEventScanComplete, Count: count, Severities: severities})
+
+	return targetResults, summary, fetchErr
+}
+
+// ValidateClusterObject fetches the object of gvk named name from cluster and validates it like ValidateCluster does
+// the objects of the cluster. See Cluster.GetObject for the namespace looked up and the errors returned for kinds
+// which are not served and missing objects
+func ValidateClusterObject(ctx context.Context, cluster *pkg.Cluster, gvk schema.GroupVersionKind, namespace, name string, conf *pkg.Config) (pkg.ValidationResult, error) {
+	obj, err := cluster.GetObject(ctx, gvk, namespace, name)
+	if err != nil {
+		return pkg.ValidationResult{}, err
+	}
+	kubeC, serverVersion, err := loadClusterChecker(ctx, cluster, conf)
+	if err != nil {
+		return pkg.ValidationResult{}, err
+	}
+	conf = conf.ForSource(serverVersion)
+	var crds *pkg.CRDIndex
+	if !pkg.IsBuiltinAPIGroup(gvk.Group) {
+		if crds, err = cluster.FetchCRDIndex(ctx, conf); err != nil {
+			return pkg.ValidationResult{}, err
+		}
+	}
+	var owners *pkg.OwnerIndex
+	if conf.AnnotateRootOwner {
+		// only the direct controller of the object is known without fetching the cluster
+		owners = pkg.NewOwnerIndex(nil)
+	}
+	validationResult, _, err := validateClusterObject(kubeC, crds, cluster.Name(), obj, owners, conf)
+	if err != nil {
+		return pkg.ValidationResult{}, err
+	}
+	conf.EmitFinding(validationResult)
+	return validationResult, nil
+}
+
+// ValidateClusterDump validates the objects of the cluster dump at path, see pkg.LoadClusterDump, against the target
+// kubernetes version like ValidateCluster does the objects of a live cluster. The documents of the dump which could
+// not be parsed are reported as skipped in the summary
+func ValidateClusterDump(path string, conf *pkg.Config) ([]pkg.ValidationResult, *pkg.FetchSummary, error) {
+	objects, summary, err := pkg.LoadClusterDump(path, conf)
+	if err != nil {
+		return make([]pkg.ValidationResult, 0), nil, err
+	}
+	kubeC, err := loadTargetChecker(conf)
+	if err != nil {
+		return make([]pkg.ValidationResult, 0), nil, err
+	}
+	for _, risk := range pkg.CheckCRDConversion(objects) {
+		kLog.Warn(risk.Message)
+	}
+	var owners *pkg.OwnerIndex
+	if conf.AnnotateRootOwner {
+		owners = pkg.NewOwnerIndex(objects)
+	}
+	var validationResults []pkg.ValidationResult
+	outcomes := validateObjects(objects, conf, func(obj *unstructured.Unstructured) (pkg.ValidationResult, []string, error) {
+		return validateClusterObject(kubeC, nil, path, obj, owners, conf)
+	})
+	for i, outcome := range outcomes {
+		validationResult, suppressed, err := outcome.result, outcome.suppressed, outcome.err
 		if err != nil {
 			kLog.Error(err)
-			os.Exit(1)
+			continue
 		}
-	} else {
-		err := kubeC.LoadFromUrl(conf.TargetKubernetesVersion, false)
+		if len(suppressed) > 0 {
+			summary.Suppressed = append(summary.Suppressed, pkg.NewSuppressedObject(&objects[i], suppressed))
+		}
+		conf.EmitFinding(validationResult)
+		validationResults = append(validationResults, validationResult)
+	}
+	pkg.AnalyzeRemovals(context.Background(), validationResults, objects, nil, conf)
+	pkg.SortResults(validationResults)
+	summary.Sort()
+	conf.Emit(pkg.ScanEvent{Type: pkg.ScanEventScanComplete, Count: len(validationResults), Severities: pkg.SeverityCounts(validationResults)})
+	return validationResults, summary, nil
+}
+
+// WatchCluster validates the objects created or updated in cluster until ctx is done, see Cluster.WatchK8sObjects, and
+// passes their results to report as they come. The objects which exist when it is called are validated by
+// ValidateCluster
+func WatchCluster(ctx context.Context, cluster *pkg.Cluster, conf *pkg.Config, report func(pkg.ValidationResult)) error {
+	kubeC, serverVersion, err := loadClusterChecker(ctx, cluster, conf)
+	if err != nil {
+		return err
+	}
+	resources, err := clusterKinds(kubeC, serverVersion, conf)
+	if err != nil {
+		return err
+	}
+	conf = conf.ForSource(serverVersion)
+	var owners *pkg.OwnerIndex
+	if conf.AnnotateRootOwner {
+		// only the direct controller of the object is known without fetching the cluster
+		owners = pkg.NewOwnerIndex(nil)
+	}
+	events, errc := cluster.WatchK8sObjects(ctx, resources, conf)
+	for event := range events {
+		validationResult, _, err := validateClusterObject(kubeC, nil, cluster.Name(), &event.Object, owners, conf)
 		if err != nil {
 			kLog.Error(err)
-			return make([]pkg.ValidationResult, 0), err
+			continue
 		}
+		conf.EmitFinding(validationResult)
+		report(validationResult)
 	}
-	serverVersion, err := cluster.ServerVersion()
+	return <-errc
+}
+
+// customResources returns the CustomResourceDefinitions of cluster along with the kinds of the custom resources to
+// be fetched, custom resources are not scanned when either cannot be listed
+func customResources(ctx context.Context, cluster *pkg.Cluster, conf *pkg.Config) (*pkg.CRDIndex, []schema.GroupVersionKind) {
+	if conf.IncludeCustomResources == pkg.CustomResourcesOnlyBuiltin {
+		return nil, nil
+	}
+	crds, err := cluster.FetchCRDIndex(ctx, conf)
 	if err != nil {
-		kLog.Error(err)
-		serverVersion = conf.TargetKubernetesVersion
+		kLog.Warn(fmt.Sprintf("custom resources are not validated: %v", err))
+		return nil, nil
+	}
+	kinds, err := cluster.CustomResourceKinds(ctx, conf)
+	if err != nil {
+		kLog.Warn(fmt.Sprintf("custom resources are not validated: %v", err))
+		return nil, nil
 	}
-	fmt.Println("current cluster server version:- ", serverVersion)
+	return crds, kinds
+}
+
+// clusterKinds returns the kinds to be fetched from a cluster at serverVersion, those of the target version are used
+// when the schemas of serverVersion are not known
+func clusterKinds(kubeC pkg.KubeChecker, serverVersion string, conf *pkg.Config) ([]schema.GroupVersionKind, error) {
 	resources, err := kubeC.GetKinds(serverVersion)
 	if err != nil {
 		kLog.Error(err)
-		//return make([]pkg.ValidationResult, 0), nil
 		resources, err = kubeC.GetKinds(conf.TargetKubernetesVersion)
 		if err != nil {
 			kLog.Error(err)
-			return make([]pkg.ValidationResult, 0), nil
+			return nil, err
 		}
 	}
-	objects := cluster.FetchK8sObjects(resources, conf)
-	var validationResults []pkg.ValidationResult
-	//isVersionSupported := isVersionSupported()
-	for _, obj := range objects {
-		annotations := obj.GetAnnotations()
-		k8sObj := ""
-		if val, ok := annotations["kubectl.kubernetes.io/last-applied-configuration"]; ok {
-			var uns unstructured.Unstructured
-			if err := uns.UnmarshalJSON([]byte(val)); err != nil {
-				if strings.EqualFold(uns.GetKind(), obj.GetKind()) {
-					k8sObj = val
-				}
-			}
+	return append(resources, pkg.OverlayKinds()...), nil
+}
+
+// loadClusterChecker loads the schemas of the target kubernetes version, which is resolved from the cluster when
+// unset, and returns them along with the version of the cluster
+func loadClusterChecker(ctx context.Context, cluster *pkg.Cluster, conf *pkg.Config) (pkg.KubeChecker, string, error) {
+	checkers, serverVersion, err := loadClusterCheckers(ctx, cluster, conf)
+	if err != nil {
+		return nil, "", err
+	}
+	return checkers[0], serverVersion, nil
+}
+
+// loadClusterCheckers loads the schemas of each of the target versions of conf, which are resolved from the cluster
+// when unset, and returns them in order along with the version of the cluster. The schemas of a version are loaded
+// once even if it is repeated
+func loadClusterCheckers(ctx context.Context, cluster *pkg.Cluster, conf *pkg.Config) ([]pkg.KubeChecker, string, error) {
+	if len(conf.TargetVersions()) == 0 {
+		targetVersion, err := pkg.ResolveTargetVersion(ctx, cluster, conf)
+		if err != nil {
+			kLog.Error(err)
+			return nil, "", err
 		}
-		if len(k8sObj) == 0 {
-			bt, err := obj.MarshalJSON()
-			if err != nil {
-				continue
-			}
-			k8sObj = string(bt)
+		conf.TargetKubernetesVersion = targetVersion
+		kLog.Info(fmt.Sprintf("target kubernetes version resolved to %s", targetVersion))
+	}
+	if len(conf.TargetSchemaLocation) == 0 {
+		if err := conf.MissingSchemas(conf.TargetVersions()...); err != nil {
+			return nil, "", err
 		}
-		if len(k8sObj) > 0 {
-			validationResult, err := kubeC.ValidateJson(k8sObj, conf.TargetKubernetesVersion)
-			if err != nil {
-				fmt.Printf("err: %v\n", err)
-				continue
-			}
-			//validationResult = isVersionSupported(validationResult, kubeC, conf)
-			validationResult = pkg.FilterValidationResults(validationResult, conf)
-			validationResults = append(validationResults, validationResult)
+	}
+	var checkers []pkg.KubeChecker
+	for _, target := range conf.TargetVersions() {
+		kubeC, err := loadTargetChecker(conf.ForTarget(target))
+		if err != nil {
+			return nil, "", err
 		}
+		checkers = append(checkers, kubeC)
+	}
+	serverVersion, err := cluster.ServerVersion(ctx)
+	if err != nil {
+		kLog.Error(err)
+		serverVersion = conf.TargetVersions()[0]
 	}
+	kLog.Info(fmt.Sprintf("current cluster server version %s", serverVersion))
+	return checkers, serverVersion, nil
+}
 
-	return validationResults, nil
+// loadTargetChecker loads the schemas of the target kubernetes version
+func loadTargetChecker(conf *pkg.Config) (pkg.KubeChecker, error) {
+	kubeC := pkg.NewCachedKubeChecker(conf)
+	if len(conf.TargetSchemaLocation) > 0 {
+		err := kubeC.LoadFromPath(conf.TargetKubernetesVersion, conf.TargetSchemaLocation, false)
+		if err != nil {
+			kLog.Error(err)
+			os.Exit(1)
+		}
+	} else {
+		err := kubeC.LoadFromUrl(conf.TargetKubernetesVersion, false)
+		if err != nil {
+			kLog.Error(err)
+			return nil, err
+		}
+	}
+	return kubeC, nil
+}
+
+// validationOutcome is what validating an object returned, see validateObjects
+type validationOutcome struct {
+	result     pkg.ValidationResult
+	suppressed []string
+	err        error
+}
+
+// validateObjects validates objects with validate from conf.ValidationWorkers() goroutines. validate must only read
+// the objects and the state it shares with the other calls. The outcomes are in the order of objects so that results
+// and the summary are built from them in the same order whatever the number of workers
+func validateObjects(objects []unstructured.Unstructured, conf *pkg.Config, validate func(obj *unstructured.Unstructured) (pkg.ValidationResult, []string, error)) []validationOutcome {
+	outcomes := make([]validationOutcome, len(objects))
+	pkg.ForEachConcurrently(len(objects), conf.ValidationWorkers(), func(i int) {
+		result, suppressed, err := validate(&objects[i])
+		outcomes[i] = validationOutcome{result: result, suppressed: suppressed, err: err}
+	})
+	return outcomes
+}
+
+// validateClusterObject validates obj fetched from the cluster named clusterName against the target kubernetes
+// version, or against its CustomResourceDefinition when it is a custom resource covered by crds. The rules suppressed
+// by the annotations of obj are returned along with the result. With Config.UseLastApplied the last applied
+// configuration of obj is validated instead, see pkg.LastAppliedObject
+func validateClusterObject(kubeC pkg.KubeChecker, crds *pkg.CRDIndex, clusterName string, obj *unstructured.Unstructured, owners *pkg.OwnerIndex, conf *pkg.Config) (pkg.ValidationResult, []string, error) {
+	validated, source, note := pkg.LastAppliedObject(obj, conf)
+	var validationResult pkg.ValidationResult
+	var err error
+	if crds.Covers(validated) {
+		validationResult, err = crds.ValidateCustomResource(validated)
+	} else {
+		var bt []byte
+		if bt, err = validated.MarshalJSON(); err != nil {
+			return pkg.ValidationResult{}, nil, err
+		}
+		validationResult, err = kubeC.ValidateJson(string(bt), conf.TargetKubernetesVersion)
+	}
+	if err != nil {
+		return pkg.ValidationResult{}, nil, err
+	}
+	validationResult.Cluster = clusterName
+	validationResult.ValidatedFrom = source
+	validationResult.ValidatedFromNote = note
+	if owners != nil {
+		validationResult.RootOwner = owners.RootOwner(obj)
+	}
+	validationResult = pkg.CheckRemovedComponentFlags(validationResult, validated.Object, conf)
+	validationResult = pkg.CheckPodFieldDeprecations(validationResult, validated.Object)
+	validationResult = pkg.CheckCustomRules(validationResult, validated.Object, conf)
+	//validationResult = isVersionSupported(validationResult, kubeC, conf)
+	validationResult = pkg.FilterValidationResults(validationResult, conf)
+	validationResult, suppressed := pkg.SuppressRules(validationResult, obj, conf)
+	validationResult = pkg.ApplySuppressions(validationResult, obj, conf)
+	validationResult = pkg.MarkAlreadyRemoved(validationResult, kubeC, conf)
+	validationResult.Severity = pkg.ResultSeverity(validationResult, conf)
+	validationResult = writeFixedManifest(validationResult, validated, conf)
+	return validationResult, suppressed, nil
+}
+
+// writeFixedManifest writes the fixed manifest of obj, see pkg.WriteFixedManifest, failures are only warned about as
+// the result is reported all the same
+func writeFixedManifest(validationResult pkg.ValidationResult, obj *unstructured.Unstructured, conf *pkg.Config) pkg.ValidationResult {
+	validationResult, err := pkg.WriteFixedManifest(validationResult, obj, conf)
+	if err != nil {
+		kLog.Warn(err.Error())
+	}
+	return validationResult
 }
 
 //func isVersionSupported() func(result pkg.ValidationResult, kubeC pkg.KubeChecker, conf *pkg.Config) pkg.ValidationResult {
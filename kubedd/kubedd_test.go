@@ -1,14 +1,31 @@
 package kubedd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"github.com/devtron-labs/silver-surfer/pkg"
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	"io"
+	"k8s.io/client-go/rest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"sigs.k8s.io/yaml"
+	"strings"
 	"testing"
 )
 
 func TestValidateCluster(t *testing.T) {
-	cluster := pkg.NewCluster("", "")
+	cluster, err := pkg.NewCluster("", "", nil)
+	if err != nil {
+		t.Skipf("no cluster available: %v", err)
+	}
 	config := pkg.NewDefaultConfig()
 	config.SelectKinds = []string{"ReplicaSet"}
 	//config.SelectNamespaces = []string{"esrgan2k"}
@@ -35,7 +52,7 @@ func TestValidateCluster(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := ValidateCluster(tt.args.cluster, tt.args.conf)
+			got, _, err := ValidateCluster(context.Background(), tt.args.cluster, tt.args.conf)
 			for _, r := range got {
 				if r.ResourceName == "keda-operator" {
 					fmt.Printf("%+v\n", r)
@@ -51,3 +68,339 @@ func TestValidateCluster(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitDocuments(t *testing.T) {
+	input := "---\napiVersion: v1\nkind: ConfigMap\ndata:\n  script: |\n    echo\n    ---\n---\n# nothing here\n---\napiVersion: v1\nkind: Secret\n"
+	docs, err := splitDocuments([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 3 {
+		t.Fatalf("splitDocuments() returned %d documents, want 3", len(docs))
+	}
+	if got := string(docs[2]); got != "apiVersion: v1\nkind: Secret\n" {
+		t.Errorf("splitDocuments() third document = %q", got)
+	}
+}
+
+func TestSplitDocuments_json(t *testing.T) {
+	input := `{"apiVersion": "v1", "kind": "ConfigMap", "data": {"separator": "\n---\n"}}
+{"apiVersion": "v1", "kind": "Secret"}`
+	docs, err := splitDocuments([]byte(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("splitDocuments() returned %d documents, want 2", len(docs))
+	}
+	if got := string(docs[1]); got != `{"apiVersion": "v1", "kind": "Secret"}` {
+		t.Errorf("splitDocuments() second document = %q", got)
+	}
+}
+
+// workersSwagger is a minimal openapi spec of a kubernetes release serving apps/v1 Deployments, the names of which
+// hold a pattern compiled on validation
+const workersSwagger = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.29.0"},
+  "paths": {
+    "/apis/apps/v1/namespaces/{namespace}/deployments": {
+      "parameters": [{"name": "namespace", "in": "path", "required": true, "type": "string"}],
+      "post": {
+        "responses": {"200": {"description": "OK"}},
+        "x-kubernetes-group-version-kind": {"group": "apps", "kind": "Deployment", "version": "v1"}
+      }
+    }
+  },
+  "definitions": {
+    "io.k8s.api.apps.v1.Deployment": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "spec": {"$ref": "#/definitions/io.k8s.api.apps.v1.DeploymentSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+    },
+    "io.k8s.api.apps.v1.DeploymentSpec": {
+      "type": "object",
+      "required": ["selector"],
+      "properties": {
+        "replicas": {"type": "integer", "format": "int32"},
+        "selector": {"type": "object"}
+      }
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "name": {"pattern": "^[a-z0-9-]+$"},
+        "namespace": {"type": "string"}
+      }
+    }
+  }
+}`
+
+// writeWorkersDump writes the spec of workersSwagger and a cluster dump of n Deployments, every third of which is
+// invalid, to a temporary directory and returns the config validating the dump against the spec
+func writeWorkersDump(tb testing.TB, n int) (string, *pkg.Config) {
+	dir := tb.TempDir()
+	var dump bytes.Buffer
+	for i := 0; i < n; i++ {
+		var replicas interface{} = i % 5
+		if i%3 == 0 {
+			replicas = "many"
+		}
+		name := fmt.Sprintf("cart-%d", i)
+		if i%7 == 0 {
+			name = fmt.Sprintf("Cart_%d", i)
+		}
+		doc, _ := json.Marshal(map[string]interface{}{
+			"apiVersion": "apps/v1", "kind": "Deployment",
+			"metadata": map[string]interface{}{"name": name, "namespace": fmt.Sprintf("shop-%d", i%10)},
+			"spec":     map[string]interface{}{"replicas": replicas, "selector": map[string]interface{}{}},
+		})
+		dump.WriteString("---\n")
+		dump.Write(doc)
+		dump.WriteString("\n")
+	}
+	dumpPath, specPath := filepath.Join(dir, "dump.yaml"), filepath.Join(dir, "swagger.json")
+	if err := os.WriteFile(dumpPath, dump.Bytes(), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	if err := os.WriteFile(specPath, []byte(workersSwagger), 0644); err != nil {
+		tb.Fatal(err)
+	}
+	conf := &pkg.Config{TargetKubernetesVersion: "1.29", TargetSchemaLocation: specPath}
+	return dumpPath, conf
+}
+
+// TestValidateClusterDump_validationConcurrency is meant to be run with -race, the results of many workers must be
+// those of a single one in the same order
+func TestValidateClusterDump_validationConcurrency(t *testing.T) {
+	// the workers only interleave, and their races show, with several threads
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+	dumpPath, conf := writeWorkersDump(t, 3000)
+	conf.ValidationConcurrency = 1
+	want, _, err := ValidateClusterDump(dumpPath, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 3000 {
+		t.Fatalf("ValidateClusterDump() got %d results, want 3000", len(want))
+	}
+	for _, workers := range []int{4, 16} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			conf.ValidationConcurrency = workers
+			got, _, err := ValidateClusterDump(dumpPath, conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("ValidateClusterDump() with %d workers differs from a single worker", workers)
+			}
+		})
+	}
+}
+
+func BenchmarkValidateClusterDump(b *testing.B) {
+	dumpPath, conf := writeWorkersDump(b, 3000)
+	for _, workers := range []int{1, runtime.GOMAXPROCS(0)} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			conf.ValidationConcurrency = workers
+			for i := 0; i < b.N; i++ {
+				if _, _, err := ValidateClusterDump(dumpPath, conf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// newDeploymentsCluster returns a cluster of kubernetes 1.28 serving apps/v1 Deployments, the upgrade plan ConfigMap
+// and everything else is not found
+func newDeploymentsCluster(t *testing.T, deployments ...map[string]interface{}) *pkg.Cluster {
+	groupVersion := map[string]interface{}{"groupVersion": "apps/v1", "version": "v1"}
+	responses := map[string]interface{}{
+		"/version": map[string]interface{}{"major": "1", "minor": "28", "gitVersion": "v1.28.4"},
+		"/api":     map[string]interface{}{"kind": "APIVersions", "versions": []string{"v1"}},
+		"/api/v1":  map[string]interface{}{"kind": "APIResourceList", "groupVersion": "v1", "resources": []interface{}{}},
+		"/apis": map[string]interface{}{"kind": "APIGroupList", "apiVersion": "v1", "groups": []interface{}{
+			map[string]interface{}{"name": "apps", "versions": []interface{}{groupVersion}, "preferredVersion": groupVersion},
+		}},
+		"/apis/apps/v1": map[string]interface{}{"kind": "APIResourceList", "groupVersion": "apps/v1", "resources": []interface{}{
+			map[string]interface{}{"name": "deployments", "namespaced": true, "kind": "Deployment", "verbs": []string{"get", "list"}},
+		}},
+		"/apis/apps/v1/deployments": map[string]interface{}{"kind": "DeploymentList", "apiVersion": "apps/v1",
+			"metadata": map[string]interface{}{}, "items": deployments},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			response = map[string]interface{}{"kind": "Status", "apiVersion": "v1", "status": "Failure",
+				"reason": "NotFound", "code": http.StatusNotFound, "message": fmt.Sprintf("%s not found", r.URL.Path)}
+		}
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	cluster, err := pkg.NewClusterFromEnvOrConfig(&rest.Config{Host: server.URL}, pkg.ClusterOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cluster
+}
+
+// scanToStdout scans cluster to the next minor version like the kubedd command does with the output format outFmt,
+// logging to stderr, and returns what was written to stdout
+func scanToStdout(t *testing.T, cluster *pkg.Cluster, outFmt string) []byte {
+	_, conf := writeWorkersDump(t, 0)
+	conf.TargetKubernetesVersion = ""
+	conf.SchemaCacheDir = t.TempDir()
+	conf.Offline = true
+	conf.SkipAccessReview = true
+	conf.IncludeCustomResources = pkg.CustomResourcesOnlyBuiltin
+	kLog.SetOutput(os.Stderr)
+	defer kLog.SetOutput(os.Stdout)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	written := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		written <- out
+	}()
+
+	outputManager := pkg.GetOutputManager(outFmt, true).(pkg.ScanOutputManager)
+	targetResults, summary, err := ValidateClusterTargets(context.Background(), cluster, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range targetResults {
+		scan := pkg.ScanMetadata{Cluster: cluster.Name(), SourceVersion: cluster.Version(), TargetVersion: target.TargetVersion}
+		if err := outputManager.PutScan(scan, target.Results, summary, conf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := outputManager.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	return <-written
+}
+
+// invalidDeployment is a Deployment of the cluster the replicas of which are not an integer
+var invalidDeployment = map[string]interface{}{
+	"apiVersion": "apps/v1", "kind": "Deployment",
+	"metadata": map[string]interface{}{"name": "cart", "namespace": "shop"},
+	"spec":     map[string]interface{}{"replicas": "many", "selector": map[string]interface{}{}},
+}
+
+// TestValidateClusterTargets_documentOutput asserts that a cluster scan writes nothing but the document of the output
+// format to stdout
+func TestValidateClusterTargets_documentOutput(t *testing.T) {
+	cluster := newDeploymentsCluster(t, invalidDeployment)
+	tests := []struct {
+		outFmt string
+		decode func(stdout []byte) (*pkg.Report, error)
+	}{
+		{
+			outFmt: "json",
+			decode: func(stdout []byte) (*pkg.Report, error) {
+				report := &pkg.Report{}
+				return report, json.Unmarshal(stdout, report)
+			},
+		},
+		{
+			outFmt: "yaml",
+			decode: func(stdout []byte) (*pkg.Report, error) {
+				report := &pkg.Report{}
+				return report, yaml.UnmarshalStrict(stdout, report)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.outFmt, func(t *testing.T) {
+			stdout := scanToStdout(t, cluster, tt.outFmt)
+			report, err := tt.decode(stdout)
+			if err != nil {
+				t.Fatalf("stdout is not a %s document: %v\n%s", tt.outFmt, err, stdout)
+			}
+			if len(report.Metadata.Scans) != 1 || report.Metadata.Scans[0].TargetVersion != "1.29" {
+				t.Errorf("scans = %+v, want a single one to 1.29", report.Metadata.Scans)
+			}
+			if len(report.Findings) == 0 {
+				t.Errorf("no findings of the invalid deployment in\n%s", stdout)
+			}
+		})
+	}
+}
+
+// TestValidateClusterTargets_junitOutput asserts that a cluster scan writes nothing but the junit document to stdout,
+// text in front of the document is skipped by xml.Unmarshal but not by every CI system
+func TestValidateClusterTargets_junitOutput(t *testing.T) {
+	cluster := newDeploymentsCluster(t, invalidDeployment)
+	stdout := scanToStdout(t, cluster, "junit")
+	if !bytes.HasPrefix(stdout, []byte(xml.Header)) {
+		t.Fatalf("stdout does not start with the xml header\n%s", stdout)
+	}
+	var doc struct {
+		XMLName  xml.Name `xml:"testsuites"`
+		Failures int      `xml:"failures,attr"`
+		Suites   []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(stdout, &doc); err != nil {
+		t.Fatalf("stdout is not a junit document: %v\n%s", err, stdout)
+	}
+	if len(doc.Suites) != 1 || doc.Failures == 0 {
+		t.Errorf("got %d test suites with %d failures, want one with the failures of the invalid deployment\n%s", len(doc.Suites), doc.Failures, stdout)
+	}
+}
+
+// TestValidateClusterTargets_alreadyRemoved asserts that the objects of a live cluster last applied in an api version
+// which neither the cluster nor the target version serve are reported as already removed
+func TestValidateClusterTargets_alreadyRemoved(t *testing.T) {
+	applied, _ := json.Marshal(map[string]interface{}{
+		"apiVersion": "extensions/v1beta1", "kind": "Deployment",
+		"metadata": map[string]interface{}{"name": "cart", "namespace": "shop"},
+		"spec":     map[string]interface{}{"replicas": 2},
+	})
+	cluster := newDeploymentsCluster(t, map[string]interface{}{
+		"apiVersion": "apps/v1", "kind": "Deployment",
+		"metadata": map[string]interface{}{"name": "cart", "namespace": "shop",
+			"annotations": map[string]interface{}{pkg.LastAppliedConfigAnnotation: string(applied)}},
+		"spec": map[string]interface{}{"replicas": 2, "selector": map[string]interface{}{}},
+	})
+	_, conf := writeWorkersDump(t, 0)
+	conf.SchemaCacheDir = t.TempDir()
+	conf.Offline = true
+	conf.SkipAccessReview = true
+	conf.IncludeCustomResources = pkg.CustomResourcesOnlyBuiltin
+	conf.UseLastApplied = true
+	// the spec of the cluster version serves apps/v1 Deployments only, like the one of the target version
+	sourceSpec := strings.Replace(workersSwagger, "v1.29.0", "v1.28.0", 1)
+	if err := os.WriteFile(filepath.Join(conf.SchemaCacheDir, "swagger-1.28.json"), []byte(sourceSpec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	targetResults, _, err := ValidateClusterTargets(context.Background(), cluster, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targetResults) != 1 || len(targetResults[0].Results) != 1 {
+		t.Fatalf("ValidateClusterTargets() got %+v, want a single result", targetResults)
+	}
+	result := targetResults[0].Results[0]
+	if !result.Deleted || !result.AlreadyRemoved {
+		t.Errorf("result of %s %s is Deleted=%v AlreadyRemoved=%v, want both", result.APIVersion, result.Kind, result.Deleted, result.AlreadyRemoved)
+	}
+	if result.Severity != pkg.SeverityError {
+		t.Errorf("severity = %q, want %q", result.Severity, pkg.SeverityError)
+	}
+}
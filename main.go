@@ -20,7 +20,9 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/devtron-labs/silver-surfer/kubedd"
 	"github.com/devtron-labs/silver-surfer/pkg"
@@ -29,12 +31,15 @@ import (
 	multierror "github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"syscall"
 )
 
 var (
@@ -43,8 +48,13 @@ var (
 	date                = "unknown"
 	directories         = make([]string, 0)
 	ignoredPathPatterns = make([]string, 0)
+	includeGlobs        = make([]string, 0)
+	excludeGlobs        = make([]string, 0)
 	kubeconfig          = ""
-	kubecontext         = ""
+	kubecontexts        = make([]string, 0)
+	verifyContext       = ""
+	allContexts         = false
+	objectName          = ""
 	noColor             = false
 	// forceColor tells kubedd to use colored output even if
 	// stdout is not a TTY
@@ -75,7 +85,17 @@ var RootCmd = &cobra.Command{
 	Short:   "Validates migration of Kubernetes YAML file to specific kubernetes version",
 	Long:    `Validates migration of Kubernetes YAML file to specific kubernetes version, It provides details of issues with the kubernetes object in case they are migrated to cluster with newer kubernetes version`,
 	Version: fmt.Sprintf("Version: %s\nCommit: %s\nDate: %s\n", version, commit, date),
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// the discovery of clusters is cached unless disabled with --no-discovery-cache
+		if len(config.DiscoveryCacheDir) == 0 {
+			config.DiscoveryCacheDir = pkg.DefaultDiscoveryCacheDir()
+		}
+		if len(config.SchemaCacheDir) == 0 {
+			config.SchemaCacheDir = pkg.DefaultSchemaCacheDir()
+		}
+	},
 	Run: func(cmd *cobra.Command, args []string) {
+		documentOutput := logToStderrForDocument()
 		if config.IgnoreMissingSchemas && !config.Quiet {
 			log2.Warn("Set to ignore missing schemas")
 		}
@@ -91,21 +111,56 @@ var RootCmd = &cobra.Command{
 		}
 
 		success := true
-
-		// Assert that colors will definitely be used if requested
-		if forceColor {
-			color.NoColor = false
-		} else if noColor {
-			color.NoColor = true
-		}
+		applyColorFlags()
 
 		//if len(args) < 1 && len(directories) < 1 && len(kubeconfig) < 1 {
 		//	log.Error(errors.New("at least one file or one directory or kubeconfig path should be passed as argument"))
 		//	os.Exit(1)
 		//}
+		if kubeconfig == pkg.KubeconfigStdin && readsStdin(args) {
+			log2.Error(errors.New("stdin cannot be read for both the kubeconfig and manifests"))
+			os.Exit(1)
+		}
+		if len(objectName) > 0 {
+			config.FieldSelector = withNameSelector(config.FieldSelector, objectName)
+		}
+		if err := config.Validate(); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		if err := config.LoadSuppressions(); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		if err := config.LoadCustomRules(); err != nil {
+			log2.Error(err)
+			os.Exit(1)
+		}
+		if clusterDump == "-" && (kubeconfig == pkg.KubeconfigStdin || readsStdin(args)) {
+			log2.Error(errors.New("stdin cannot be read for both the cluster dump and the kubeconfig or manifests"))
+			os.Exit(1)
+		}
+		if watchObjects && (len(args) > 0 || len(directories) > 0 || len(clusterDump) > 0) {
+			log2.Error(errors.New("only clusters can be watched, not manifests or cluster dumps"))
+			os.Exit(1)
+		}
+		if watchObjects && documentOutput {
+			log2.Error(fmt.Errorf("the %s output is a single document for the whole run, clusters cannot be watched with it", config.OutputFormat))
+			os.Exit(1)
+		}
+		if len(config.TargetVersions()) > 1 && (len(args) > 0 || len(directories) > 0 || len(clusterDump) > 0 || watchObjects) {
+			log2.Error(errors.New("several target versions can only be validated when scanning clusters"))
+			os.Exit(1)
+		}
+		if len(config.TargetVersions()) == 0 && (len(args) > 0 || len(directories) > 0 || len(clusterDump) > 0) {
+			log2.Error(errors.New("the target kubernetes version cannot be determined without a cluster, set it with --target-kubernetes-version"))
+			os.Exit(1)
+		}
 		if len(args) > 0 || len(directories) > 0 {
 			// code flow will enter here when --directories is provided in the command
 			success = processFiles(args)
+		} else if len(clusterDump) > 0 {
+			success = processClusterDump()
 		} else {
 			success = processCluster()
 		}
@@ -126,8 +181,14 @@ func processFiles(args []string) bool {
 
 	var aggResults []pkg.ValidationResult
 	for _, fileName := range files {
-		filePath, _ := filepath.Abs(fileName)
-		fileContents, err := ioutil.ReadFile(filePath)
+		var fileContents []byte
+		if fileName == "-" {
+			fileContents, err = io.ReadAll(os.Stdin)
+			fileName = "stdin"
+		} else {
+			filePath, _ := filepath.Abs(fileName)
+			fileContents, err = ioutil.ReadFile(filePath)
+		}
 		if err != nil {
 			log2.Error(fmt.Errorf("Could not open file %v", fileName))
 			earlyExit()
@@ -143,12 +204,19 @@ func processFiles(args []string) bool {
 			continue
 		}
 
+		aggResults = append(aggResults, results...)
+		if _, ok := outputManager.(pkg.ScanOutputManager); ok {
+			continue
+		}
 		fmt.Println("")
-		fmt.Printf("Results for file %s\n", fileName)
+		fmt.Printf("Results for file %s%s\n", fileName, specNote(config.TargetKubernetesVersion, results))
 		fmt.Println("-------------------------------------------")
 		outputManager.PutBulk(results)
-
-		aggResults = append(aggResults, results...)
+	}
+	// the files are a single scan of the document
+	if scanOutputManager, ok := outputManager.(pkg.ScanOutputManager); ok {
+		scan := pkg.ScanMetadata{SourceVersion: config.SourceKubernetesVersion, TargetVersion: config.TargetKubernetesVersion}
+		scanOutputManager.PutScan(scan, aggResults, nil, config)
 	}
 
 	// only use result of hasErrors check if `success` is currently truthy
@@ -164,47 +232,217 @@ func processFiles(args []string) bool {
 }
 
 func processCluster() bool {
-	success := true
 	outputManager := pkg.GetOutputManager(config.OutputFormat, noColor)
-	cluster := pkg.NewCluster(kubeconfig, kubecontext)
-	results, err := kubedd.ValidateCluster(cluster, config)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	selected, err := selectedContexts()
 	if err != nil {
 		log2.Error(err)
-		earlyExit()
-		success = false
-		return success
+		return false
 	}
 
-	serverVersion, _ := cluster.ServerVersion()
-	fmt.Println("")
-	fmt.Printf("Results for cluster at version %s to %s\n", serverVersion, config.TargetKubernetesVersion)
-	fmt.Println("-------------------------------------------")
-	outputManager.PutBulk(results)
+	if watchObjects && len(selected) > 1 {
+		log2.Error(errors.New("only a single context can be watched"))
+		return false
+	}
 
-	//aggResults = append(aggResults, results...)
-	success = success && !hasErrors(results)
-	err = outputManager.Flush()
-	if err != nil {
-		log2.Error(err)
-		success = false
+	success := true
+	failures := map[string]error{}
+	for _, kubecontext := range selected {
+		clusterSuccess, err := processContext(ctx, kubecontext, outputManager)
+		if err != nil {
+			log2.Error(err)
+			failures[kubecontext] = err
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		success = success && clusterSuccess
+	}
+	// results are flushed as they come while watching
+	if !watchObjects {
+		if err := outputManager.Flush(); err != nil {
+			log2.Error(err)
+			success = false
+		}
+	}
+	if _, ok := outputManager.(pkg.ScanOutputManager); len(selected) > 1 && len(failures) > 0 && !ok {
+		fmt.Println("")
+		fmt.Printf("Failed to scan %d of %d contexts\n", len(failures), len(selected))
+		fmt.Println("-------------------------------------------")
+		for _, kubecontext := range selected {
+			if err, ok := failures[kubecontext]; ok {
+				fmt.Printf("%s: %v\n", kubecontext, err)
+			}
+		}
 	}
 	return success
 }
 
-// hasErrors returns truthy if any of the provided results
-// contain errors.
-func hasErrors(res []pkg.ValidationResult) bool {
-	for _, r := range res {
-		if r.Deleted || r.Deprecated {
-			return true
+// selectedContexts returns the kubecontexts to be scanned, the empty string stands for the current context
+func selectedContexts() ([]string, error) {
+	selected := kubecontexts
+	if allContexts {
+		contexts, err := pkg.ListContexts(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		selected = nil
+		for _, kubeContext := range contexts {
+			selected = append(selected, kubeContext.Name)
+		}
+	}
+	if len(selected) == 0 {
+		// the current context
+		selected = []string{""}
+	}
+	return selected, nil
+}
+
+// autoTargetNote marks the target version in the header of results when it was not given but resolved from the cluster
+func autoTargetNote(auto bool) string {
+	if !auto {
+		return ""
+	}
+	return " (selected automatically, set --target-kubernetes-version to choose another)"
+}
+
+// specNote names the spec the results were validated against in the header of results, which is the latest spec of
+// the minor version of a patch target version when the release has none
+func specNote(target string, results []pkg.ValidationResult) string {
+	for _, result := range results {
+		if len(result.SpecVersion) == 0 {
+			continue
+		}
+		if result.SpecVersion == strings.TrimPrefix(strings.TrimSpace(target), "v") {
+			return fmt.Sprintf(" using the spec of kubernetes %s", result.SpecVersion)
+		}
+		return fmt.Sprintf(" using the latest spec of kubernetes %s as %s has none", result.SpecVersion, target)
+	}
+	return ""
+}
+
+// processContext validates the cluster of kubecontext and puts its results to outputManager, it returns false when the
+// cluster could not be scanned or has findings which fail the run
+func processContext(ctx context.Context, kubecontext string, outputManager pkg.OutputManager) (bool, error) {
+	// the target version may be resolved per cluster
+	clusterConfig := *config
+	clusterConfig.Progress = newProgressReporter(os.Stderr)
+	cluster, err := pkg.NewCluster(kubeconfig, kubecontext, &clusterConfig)
+	if err != nil {
+		return false, err
+	}
+	defer cluster.Close()
+	if len(verifyContext) > 0 {
+		verifyCluster, err := pkg.NewCluster(kubeconfig, verifyContext, &clusterConfig)
+		if err != nil {
+			return false, fmt.Errorf("unable to load the verify context %s: %w", verifyContext, err)
+		}
+		defer verifyCluster.Close()
+		clusterConfig.VerifyCluster = verifyCluster
+	}
+	if err := cluster.Preflight(ctx, &clusterConfig); err != nil {
+		var preflightErr *pkg.PreflightError
+		if errors.As(err, &preflightErr) {
+			log2.Warn(preflightErr.Hint())
+		}
+		return false, err
+	}
+	autoTarget := len(clusterConfig.TargetVersions()) == 0
+	targetResults, summary, err := kubedd.ValidateClusterTargets(ctx, cluster, &clusterConfig)
+	if err != nil {
+		earlyExit()
+		return false, err
+	}
+
+	name := cluster.Name()
+	if len(kubecontext) > 0 {
+		name = fmt.Sprintf("%s (context %s)", name, kubecontext)
+	}
+	success := true
+	scanOutputManager, documentOutput := outputManager.(pkg.ScanOutputManager)
+	for i, target := range targetResults {
+		success = success && !pkg.HasGatingFindings(target.Results, &clusterConfig)
+		if documentOutput {
+			scan := pkg.ScanMetadata{Cluster: cluster.Name(), SourceVersion: cluster.Version(), TargetVersion: target.TargetVersion}
+			// the summary of the cluster is the same for all target versions, it is put along with the first one
+			targetSummary := summary
+			if i > 0 {
+				targetSummary = nil
+			}
+			scanOutputManager.PutScan(scan, target.Results, targetSummary, &clusterConfig)
+			continue
 		}
-		if len(r.ErrorsForOriginal) > 0 || len(r.ErrorsForLatest) > 0 {
+		fmt.Println("")
+		fmt.Printf("Results for cluster %s at version %s to %s%s%s\n", name, cluster.Version(), target.TargetVersion, specNote(target.TargetVersion, target.Results), autoTargetNote(autoTarget))
+		fmt.Println("-------------------------------------------")
+		outputManager.PutBulk(target.Results)
+	}
+	if stdOutputManager, ok := outputManager.(*pkg.STDOutputManager); ok && len(targetResults) > 1 {
+		fmt.Println("")
+		fmt.Printf("Upgrade path of cluster %s at version %s through %s\n", name, cluster.Version(), strings.Join(clusterConfig.TargetVersions(), ", "))
+		fmt.Println("-------------------------------------------")
+		stdOutputManager.UpgradePathTableOutput(targetResults)
+	}
+	if summary.Reportable() && !documentOutput {
+		fmt.Println("")
+		fmt.Println(summary.String())
+	}
+	if summary.Partial() && clusterConfig.FailOnFetchErrors {
+		success = false
+	}
+	if watchObjects {
+		watchSuccess, err := watchContext(ctx, cluster, name, &clusterConfig, outputManager)
+		return success && watchSuccess, err
+	}
+	return success, nil
+}
+
+// logToStderrForDocument writes the logs to stderr when the output is a single document, for it to be the only thing
+// written to stdout, and returns true if it is
+func logToStderrForDocument() bool {
+	_, ok := pkg.GetOutputManager(config.OutputFormat, noColor).(pkg.ScanOutputManager)
+	if ok {
+		log2.SetOutput(os.Stderr)
+	}
+	return ok
+}
+
+// applyColorFlags asserts that colors will definitely be used if requested
+func applyColorFlags() {
+	if forceColor {
+		color.NoColor = false
+	} else if noColor {
+		color.NoColor = true
+	}
+}
+
+// withNameSelector adds the requirement on the name of objects to fieldSelector
+func withNameSelector(fieldSelector, name string) string {
+	nameSelector := "metadata.name=" + name
+	if len(fieldSelector) == 0 {
+		return nameSelector
+	}
+	return fieldSelector + "," + nameSelector
+}
+
+// readsStdin returns true if manifests are read from stdin
+func readsStdin(args []string) bool {
+	for _, arg := range args {
+		if arg == "-" {
 			return true
 		}
 	}
 	return false
 }
 
+// hasErrors returns truthy if any of the provided results
+// contain errors which are not downgraded to info.
+func hasErrors(res []pkg.ValidationResult) bool {
+	return pkg.HasGatingFindings(res, config)
+}
+
 // isIgnored returns whether the specified filename should be ignored.
 func isIgnored(path string) (bool, error) {
 	for _, p := range ignoredPathPatterns {
@@ -219,28 +457,18 @@ func isIgnored(path string) (bool, error) {
 	return false, nil
 }
 
+// aggregateFiles returns the files among args and the manifests of the directories among args and directories,
+// see pkg.FindManifestFiles
 func aggregateFiles(args []string) ([]string, error) {
-	files := make([]string, len(args))
-	copy(files, args)
-
+	opts := pkg.ManifestFileOptions{Include: includeGlobs, Exclude: excludeGlobs, Ignored: isIgnored}
+	var files []string
 	var allErrors *multierror.Error
-	for _, directory := range directories {
-		err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
-			ignored, err := isIgnored(path)
-			if err != nil {
-				return err
-			}
-			if !info.IsDir() && (strings.HasSuffix(info.Name(), ".yaml") || strings.HasSuffix(info.Name(), ".yml")) && !ignored {
-				files = append(files, path)
-			}
-			return nil
-		})
+	for _, p := range append(append([]string{}, args...), directories...) {
+		found, err := pkg.FindManifestFiles([]string{p}, opts)
 		if err != nil {
 			allErrors = multierror.Append(allErrors, err)
 		}
+		files = append(files, found...)
 	}
 
 	return files, allErrors.ErrorOrNil()
@@ -267,6 +495,7 @@ func init() {
 		rootCmdName = strings.Replace(rootCmdName, "-", " ", 1)
 	}
 	RootCmd.Use = fmt.Sprintf("%s <file> [file...]", rootCmdName)
+	pkg.BuildVersion = version
 	pkg.AddKubeaddFlags(RootCmd, config)
 	RootCmd.Flags().BoolVarP(&forceColor, "force-color", "", false, "Force colored output even if stdout is not a TTY")
 	RootCmd.Flags().BoolVarP(&noColor, "no-color", "", false, "Display results without color")
@@ -274,8 +503,27 @@ func init() {
 	RootCmd.Flags().StringSliceVarP(&directories, "directories", "d", []string{}, "A comma-separated list of directories to recursively search for YAML documents")
 	RootCmd.Flags().StringSliceVarP(&ignoredPathPatterns, "ignored-path-patterns", "i", []string{}, "A comma-separated list of regular expressions specifying paths to ignore")
 	RootCmd.Flags().StringSliceVarP(&ignoredPathPatterns, "ignored-filename-patterns", "", []string{}, "An alias for ignored-path-patterns")
-	RootCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "", "", "Path of kubeconfig file of cluster to be scanned")
-	RootCmd.Flags().StringVarP(&kubecontext, "kubecontext", "", "", "Kubecontext to be selected")
+	RootCmd.Flags().StringSliceVarP(&includeGlobs, "include", "", []string{}, "Globs of the files of directories to be validated, relative to the directory, ** matches any number of directories (default [**/*.yaml,**/*.yml])")
+	RootCmd.Flags().StringSliceVarP(&excludeGlobs, "exclude", "", []string{}, "Globs of the files and directories of directories never to be validated eg **/charts/**, takes precedence over include")
+	RootCmd.Flags().StringVarP(&kubeconfig, "kubeconfig", "", "", "Path of kubeconfig file of cluster to be scanned, - reads the kubeconfig from stdin")
+	RootCmd.Flags().StringVarP(&verifyContext, "verify-context", "", "", "Kubecontext of a second cluster eg a staging cluster at the target version, objects passing schema validation are applied to it with a server-side dry-run and its rejections reported")
+	RootCmd.Flags().StringSliceVarP(&kubecontexts, "kubecontext", "", []string{}, "Kubecontext to be selected, can be repeated or comma-separated to scan multiple clusters")
+	RootCmd.Flags().StringVarP(&clusterDump, "cluster-dump", "", "", "Path of a dump of the objects of a cluster eg kubectl get -o yaml output to be validated instead of a live cluster, - reads the dump from stdin")
+	RootCmd.Flags().BoolVarP(&allContexts, "all-contexts", "", false, "Scan the clusters of all contexts of the kubeconfig")
+	RootCmd.Flags().BoolVarP(&watchObjects, "watch", "", false, "Keep validating the objects created or updated in the cluster after it is scanned, until interrupted")
+	RootCmd.Flags().StringVarP(&objectName, "name", "", "", "Name of the objects to be scanned, short for --field-selector metadata.name=<name>")
+	// manifests are passed as arguments to the root command
+	RootCmd.Args = cobra.ArbitraryArgs
+	RootCmd.CompletionOptions.DisableDefaultCmd = true
+	inventoryCmd.Flags().AddFlagSet(RootCmd.Flags())
+	RootCmd.AddCommand(inventoryCmd)
+	checkCmd.Flags().StringVarP(&checkNamespace, "namespace", "n", "", "Namespace of the object, the default namespace is used when unset")
+	checkCmd.Flags().AddFlagSet(RootCmd.Flags())
+	RootCmd.AddCommand(checkCmd)
+	downloadSpecsCmd.Flags().StringVarP(&config.SchemaCacheDir, "schema-cache-dir", "", "", "Directory the openapi specs are downloaded to, defaults to ~/.cache/silver-surfer/schemas")
+	downloadSpecsCmd.Flags().StringVarP(&config.SchemaBaseURL, "schema-base-url", "", pkg.DefaultSchemaBaseURL, "Base url of a mirror of the kubernetes repository the openapi specs are downloaded from")
+	downloadSpecsCmd.Flags().StringToStringVarP(&config.SchemaChecksums, "schema-sha256", "", map[string]string{}, "Recorded sha256 the downloaded openapi specs must match by kubernetes version eg 1.29=<sha256>, can be repeated")
+	RootCmd.AddCommand(downloadSpecsCmd)
 
 	viper.SetEnvPrefix("KUBEADD")
 	viper.AutomaticEnv()
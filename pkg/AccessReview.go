@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var selfSubjectAccessReviews = authorizationv1.SchemeGroupVersion.WithResource("selfsubjectaccessreviews")
+
+// reviewAccess asks the api server whether the user may list each target and returns the targets which may be
+// listed, the others are recorded as forbidden in summary. Targets whose access could not be reviewed, eg as the
+// authorization api is not served, are kept and fail while listing if they are forbidden
+func (c *Cluster) reviewAccess(ctx context.Context, targets []fetchTarget, conf *Config, summary *FetchSummary) []fetchTarget {
+	denials := make([]string, len(targets))
+	tokens := make(chan struct{}, conf.concurrency())
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int, target fetchTarget) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			denials[i] = c.listDenial(ctx, target, conf)
+		}(i, target)
+	}
+	wg.Wait()
+
+	var allowed []fetchTarget
+	for i, target := range targets {
+		if len(denials[i]) == 0 {
+			allowed = append(allowed, target)
+			continue
+		}
+		conf.Emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: target.resource.String(), Reason: "list not allowed"})
+		summary.Forbidden = append(summary.Forbidden, SkippedResource{Resource: target.resource.String(), Failure: FetchForbidden, Message: denials[i]})
+	}
+	return allowed
+}
+
+// listDenial returns why the user may not list target, or an empty string if it may. Namespaced resources listed
+// in each of the selected namespaces are reviewed in each of them
+func (c *Cluster) listDenial(ctx context.Context, target fetchTarget, conf *Config) string {
+	reviewed := []string{""}
+	if selected, ok := conf.listedNamespaces(); target.namespaced && ok {
+		reviewed = selected
+	}
+	var denied []string
+	for _, namespace := range reviewed {
+		status, err := c.accessReview(ctx, target.resource, namespace)
+		if err != nil || status.Allowed {
+			continue
+		}
+		scope := "across the cluster"
+		if len(namespace) > 0 {
+			scope = fmt.Sprintf("in namespace %s", namespace)
+		}
+		if len(status.Reason) > 0 {
+			scope = fmt.Sprintf("%s: %s", scope, status.Reason)
+		}
+		denied = append(denied, scope)
+	}
+	if len(denied) == 0 {
+		return ""
+	}
+	sort.Strings(denied)
+	return fmt.Sprintf("not allowed to list %s %s", target.resource.Resource, strings.Join(denied, ", "))
+}
+
+// accessReview reviews whether the user may list resource in namespace, or across the cluster when it is empty
+func (c *Cluster) accessReview(ctx context.Context, resource schema.GroupVersionResource, namespace string) (authorizationv1.SubjectAccessReviewStatus, error) {
+	review := &authorizationv1.SelfSubjectAccessReview{
+		TypeMeta: v1.TypeMeta{APIVersion: authorizationv1.SchemeGroupVersion.String(), Kind: "SelfSubjectAccessReview"},
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Group:     resource.Group,
+				Version:   resource.Version,
+				Resource:  resource.Resource,
+			},
+		},
+	}
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(review)
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, err
+	}
+	result, err := c.dynamicClient().Resource(selfSubjectAccessReviews).Create(ctx, &unstructured.Unstructured{Object: obj}, v1.CreateOptions{})
+	if err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, fmt.Errorf("failed to review access to %s: %w", resource, err)
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(result.Object, review); err != nil {
+		return authorizationv1.SubjectAccessReviewStatus{}, err
+	}
+	return review.Status, nil
+}
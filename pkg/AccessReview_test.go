@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_accessReview(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secretGvk := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	secrets := server.addResource(secretGvk, "secrets", true)
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", "cart"))
+	server.addObject(secrets, newFakeObject("v1", "Secret", "shop", "token"))
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "billing", "invoices"))
+	server.deny(secrets, "")
+	server.deny(secrets, "shop")
+	server.deny(secrets, "billing")
+	server.deny(deployments, "billing")
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{configMapGvk, secretGvk, deploymentGvk}
+
+	tests := []struct {
+		name          string
+		conf          *Config
+		wantObjects   int
+		wantForbidden []string
+		wantRequests  []string
+	}{
+		{
+			name:          "resources listed across the cluster",
+			conf:          &Config{IncludeTerminalObjects: true, Concurrency: 1},
+			wantObjects:   3,
+			wantForbidden: []string{"/v1, Resource=secrets|not allowed to list secrets across the cluster: no rbac policy matched"},
+			wantRequests:  []string{"/api/v1/configmaps", "/apis/apps/v1/deployments"},
+		},
+		{
+			name:        "resources listed in each selected namespace",
+			conf:        &Config{IncludeTerminalObjects: true, Concurrency: 1, SelectNamespaces: []string{"shop", "billing"}},
+			wantObjects: 1,
+			wantForbidden: []string{
+				"/v1, Resource=secrets|not allowed to list secrets in namespace billing: no rbac policy matched, in namespace shop: no rbac policy matched",
+				"apps/v1, Resource=deployments|not allowed to list deployments in namespace billing: no rbac policy matched",
+			},
+			wantRequests: []string{"/api/v1/namespaces/billing/configmaps", "/api/v1/namespaces/shop/configmaps"},
+		},
+		{
+			name:         "access review skipped",
+			conf:         &Config{IncludeTerminalObjects: true, Concurrency: 1, SkipAccessReview: true},
+			wantObjects:  4,
+			wantRequests: []string{"/api/v1/configmaps", "/api/v1/secrets", "/apis/apps/v1/deployments"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(server.resourceRequests())
+			objs, summary, err := cluster.FetchK8sObjects(context.Background(), gvks, tt.conf)
+			assert.NoError(t, err)
+			assert.Len(t, objs, tt.wantObjects)
+			assert.Empty(t, summary.Skipped)
+			var forbidden []string
+			for _, resource := range summary.Forbidden {
+				assert.Equal(t, FetchForbidden, resource.Failure)
+				forbidden = append(forbidden, resource.Resource+"|"+resource.Message)
+			}
+			assert.Equal(t, tt.wantForbidden, forbidden)
+			assert.Equal(t, len(tt.wantForbidden) > 0, summary.Partial())
+			assert.Equal(t, tt.wantRequests, server.resourceRequests()[before:])
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_accessReviewUnavailable(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	server.addObject(server.addResource(configMapGvk, "configmaps", true), newFakeObject("v1", "ConfigMap", "shop", "cart"))
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == selfSubjectAccessReviewsPath {
+			writeStatus(w, http.StatusNotFound, "NotFound", "the server could not find the requested resource")
+			return true
+		}
+		return false
+	}
+	cluster := server.cluster(t)
+
+	// resources whose access could not be reviewed are listed
+	objs, summary, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, &Config{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.False(t, summary.Partial())
+}
+
+func TestFetchSummary_String_forbidden(t *testing.T) {
+	summary := &FetchSummary{
+		Listed:    2,
+		Skipped:   []SkippedResource{{Resource: "batch/v1, Resource=jobs", Failure: FetchTimeout, Message: "request timed out"}},
+		Forbidden: []SkippedResource{{Resource: "/v1, Resource=secrets", Failure: FetchForbidden, Message: "not allowed to list secrets across the cluster"}},
+	}
+	assert.Equal(t, "2 of 4 resources could not be fetched, results are partial\n"+
+		"batch/v1, Resource=jobs: timeout: request timed out\n"+
+		"skipped due to permissions:\n"+
+		"/v1, Resource=secrets: not allowed to list secrets across the cluster", summary.String())
+}
@@ -0,0 +1,96 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// ageNewerThan is reported for objects left out by NewerThan, ie objects which are older
+	ageNewerThan = "newer-than"
+	// ageOlderThan is reported for objects left out by OlderThan, ie objects which are newer
+	ageOlderThan = "older-than"
+)
+
+// ParseAge parses a Go duration eg 2160h or a number of days eg 90d
+func ParseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid age %q: %w", s, err)
+		}
+		return time.Duration(n * float64(24*time.Hour)), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q: %w", s, err)
+	}
+	return d, nil
+}
+
+// ageValue is a flag holding a duration parsed by ParseAge
+type ageValue struct {
+	d *time.Duration
+}
+
+func (v ageValue) String() string {
+	if v.d == nil || *v.d == 0 {
+		return ""
+	}
+	return v.d.String()
+}
+
+func (v ageValue) Set(s string) error {
+	d, err := ParseAge(s)
+	if err != nil {
+		return err
+	}
+	*v.d = d
+	return nil
+}
+
+func (v ageValue) Type() string {
+	return "duration"
+}
+
+// validateAgeFilters returns an error if NewerThan or OlderThan is negative or if together they leave no object
+func (c *Config) validateAgeFilters() error {
+	if c.NewerThan < 0 || c.OlderThan < 0 {
+		return fmt.Errorf("invalid age filters: newer-than %s and older-than %s must not be negative", c.NewerThan, c.OlderThan)
+	}
+	if c.NewerThan > 0 && c.OlderThan > 0 && c.NewerThan <= c.OlderThan {
+		return fmt.Errorf("invalid age filters: no object is both newer than %s and older than %s", c.NewerThan, c.OlderThan)
+	}
+	return nil
+}
+
+// ageExcluded returns the age filter which leaves obj out of a scan started at now, ageNewerThan or ageOlderThan, or
+// the empty string if obj passes both. Objects without a creation timestamp are not filtered by age
+func (c *Config) ageExcluded(obj v1.Object, now time.Time) string {
+	created := obj.GetCreationTimestamp()
+	if created.IsZero() {
+		return ""
+	}
+	age := now.Sub(created.Time)
+	switch {
+	case c.NewerThan > 0 && age > c.NewerThan:
+		return ageNewerThan
+	case c.OlderThan > 0 && age < c.OlderThan:
+		return ageOlderThan
+	}
+	return ""
+}
+
+// countAgeExcluded counts obj as left out by the age filter in the summary
+func (s *FetchSummary) countAgeExcluded(filter string) {
+	switch filter {
+	case ageNewerThan:
+		s.NewerThanExcluded++
+	case ageOlderThan:
+		s.OlderThanExcluded++
+	}
+}
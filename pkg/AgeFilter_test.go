@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{age: "90d", want: 90 * 24 * time.Hour},
+		{age: "1.5d", want: 36 * time.Hour},
+		{age: "2160h", want: 2160 * time.Hour},
+		{age: "30m", want: 30 * time.Minute},
+		{age: "d", wantErr: true},
+		{age: "ninety days", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.age, func(t *testing.T) {
+			got, err := ParseAge(tt.age)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_validateAgeFilters(t *testing.T) {
+	assert.NoError(t, (&Config{NewerThan: 90 * 24 * time.Hour, OlderThan: 30 * 24 * time.Hour}).Validate())
+	assert.Error(t, (&Config{NewerThan: 30 * 24 * time.Hour, OlderThan: 90 * 24 * time.Hour}).Validate())
+	assert.Error(t, (&Config{NewerThan: -time.Hour}).Validate())
+}
+
+func TestCluster_FetchK8sObjects_ageFilters(t *testing.T) {
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	server := newFakeApiServer(t)
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"week": 7 * 24 * time.Hour, "quarter": 60 * 24 * time.Hour, "year": 365 * 24 * time.Hour} {
+		obj := newFakeObject("v1", "ConfigMap", "shop", name)
+		obj["metadata"].(map[string]interface{})["creationTimestamp"] = now.Add(-age).UTC().Format(time.RFC3339)
+		server.addObject(configMaps, obj)
+	}
+	// objects without a creation timestamp pass through
+	server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", "undated"))
+	cluster := server.cluster(t)
+
+	tests := []struct {
+		name      string
+		conf      *Config
+		want      []string
+		wantNewer int
+		wantOlder int
+	}{
+		{
+			name: "no age filter",
+			conf: &Config{},
+			want: []string{"quarter", "undated", "week", "year"},
+		},
+		{
+			name:      "newer than",
+			conf:      &Config{NewerThan: 90 * 24 * time.Hour},
+			want:      []string{"quarter", "undated", "week"},
+			wantNewer: 1,
+		},
+		{
+			name:      "window",
+			conf:      &Config{NewerThan: 90 * 24 * time.Hour, OlderThan: 30 * 24 * time.Hour},
+			want:      []string{"quarter", "undated"},
+			wantNewer: 1,
+			wantOlder: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, summary, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, tt.conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetName())
+			}
+			assert.ElementsMatch(t, tt.want, got)
+			assert.Equal(t, tt.wantNewer, summary.NewerThanExcluded)
+			assert.Equal(t, tt.wantOlder, summary.OlderThanExcluded)
+			assert.Equal(t, tt.wantNewer+tt.wantOlder > 0, summary.Reportable())
+			if tt.wantOlder > 0 {
+				assert.Contains(t, summary.String(), "1 objects newer than older-than skipped")
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// VersionLifecycle is the state of one version of a kind served outside the kubernetes openapi spec
+type VersionLifecycle struct {
+	Version    string
+	Deprecated bool
+	Removed    bool
+}
+
+// KindLifecycle lists the versions in which a kind is served, ordered from oldest to newest
+type KindLifecycle struct {
+	Kind     string
+	Versions []VersionLifecycle
+}
+
+// ApiOverlay carries version lifecycle data of an api group whose kinds are not part of the
+// kubernetes openapi spec, eg Gateway API, and is consulted before the spec while validating
+type ApiOverlay struct {
+	Name  string
+	Group string
+	Kinds []KindLifecycle
+}
+
+var (
+	apiOverlaysLock sync.RWMutex
+	apiOverlays     = map[string]ApiOverlay{}
+)
+
+func init() {
+	RegisterApiOverlay(GatewayApiOverlay)
+}
+
+// RegisterApiOverlay adds an overlay, replacing any overlay previously registered for the same group
+func RegisterApiOverlay(overlay ApiOverlay) {
+	apiOverlaysLock.Lock()
+	defer apiOverlaysLock.Unlock()
+	apiOverlays[overlay.Group] = overlay
+}
+
+func lookupKindLifecycle(apiVersion, kind string) (KindLifecycle, string, bool) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil || len(gv.Group) == 0 {
+		return KindLifecycle{}, "", false
+	}
+	apiOverlaysLock.RLock()
+	defer apiOverlaysLock.RUnlock()
+	overlay, ok := apiOverlays[gv.Group]
+	if !ok {
+		return KindLifecycle{}, "", false
+	}
+	for _, kl := range overlay.Kinds {
+		if strings.EqualFold(kl.Kind, kind) {
+			return kl, gv.Version, true
+		}
+	}
+	return KindLifecycle{}, "", false
+}
+
+// OverlayKinds returns the latest served version of every kind known to the registered overlays
+func OverlayKinds() []schema.GroupVersionKind {
+	apiOverlaysLock.RLock()
+	defer apiOverlaysLock.RUnlock()
+	var gvks []schema.GroupVersionKind
+	for group, overlay := range apiOverlays {
+		for _, kl := range overlay.Kinds {
+			if latest, ok := kl.latest(); ok {
+				gvks = append(gvks, schema.GroupVersionKind{Group: group, Version: latest.Version, Kind: kl.Kind})
+			}
+		}
+	}
+	return gvks
+}
+
+func (kl KindLifecycle) latest() (VersionLifecycle, bool) {
+	for i := len(kl.Versions) - 1; i >= 0; i-- {
+		if !kl.Versions[i].Removed {
+			return kl.Versions[i], true
+		}
+	}
+	return VersionLifecycle{}, false
+}
+
+// applyKindLifecycle fills the result of an object whose kind is tracked by an overlay
+func applyKindLifecycle(result ValidationResult, kl KindLifecycle, version string) ValidationResult {
+	result.ValidatedAgainstSchema = false
+	gv, _ := schema.ParseGroupVersion(result.APIVersion)
+	latest, hasLatest := kl.latest()
+	if hasLatest && latest.Version != version {
+		result.LatestAPIVersion = fmt.Sprintf(gvFormat, gv.Group, latest.Version)
+	}
+	for _, vl := range kl.Versions {
+		if vl.Version != version {
+			continue
+		}
+		if vl.Removed {
+			result.Deleted = true
+			result.IsVersionSupported = 2
+		}
+		result.Deprecated = vl.Deprecated
+		return result
+	}
+	// version is not known to have ever been served
+	result.Deleted = true
+	result.IsVersionSupported = 2
+	return result
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGatewayApiOverlay(t *testing.T) {
+	ks := newKubeSpec(&openapi3.T{})
+	tests := []struct {
+		name       string
+		apiVersion string
+		kind       string
+		deprecated bool
+		deleted    bool
+		latest     string
+	}{
+		{
+			name:       "superseded HTTPRoute",
+			apiVersion: "gateway.networking.k8s.io/v1beta1",
+			kind:       "HTTPRoute",
+			deprecated: true,
+			latest:     "gateway.networking.k8s.io/v1",
+		},
+		{
+			name:       "current Gateway",
+			apiVersion: "gateway.networking.k8s.io/v1",
+			kind:       "Gateway",
+		},
+		{
+			name:       "experimental TCPRoute",
+			apiVersion: "gateway.networking.k8s.io/v1alpha2",
+			kind:       "TCPRoute",
+		},
+		{
+			name:       "unknown version",
+			apiVersion: "gateway.networking.k8s.io/v1alpha1",
+			kind:       "Gateway",
+			deleted:    true,
+			latest:     "gateway.networking.k8s.io/v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ks.ValidateObject(map[string]interface{}{
+				"apiVersion": tt.apiVersion,
+				"kind":       tt.kind,
+				"metadata":   map[string]interface{}{"name": "example", "namespace": "default"},
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, tt.deprecated, got.Deprecated)
+			assert.Equal(t, tt.deleted, got.Deleted)
+			assert.Equal(t, tt.latest, got.LatestAPIVersion)
+		})
+	}
+}
+
+func TestRegisterApiOverlay(t *testing.T) {
+	RegisterApiOverlay(ApiOverlay{
+		Name:  "example",
+		Group: "example.com",
+		Kinds: []KindLifecycle{{Kind: "Widget", Versions: []VersionLifecycle{{Version: "v1", Removed: true}, {Version: "v2"}}}},
+	})
+	got, err := newKubeSpec(&openapi3.T{}).ValidateObject(map[string]interface{}{
+		"apiVersion": "example.com/v1",
+		"kind":       "Widget",
+		"metadata":   map[string]interface{}{"name": "example"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, got.Deleted)
+	assert.Equal(t, "example.com/v2", got.LatestAPIVersion)
+	assert.Contains(t, OverlayKinds(), schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"})
+}
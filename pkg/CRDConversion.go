@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	crdConversionNone    = "None"
+	crdConversionWebhook = "Webhook"
+)
+
+// CRDConversionRisk describes a CustomResourceDefinition serving several versions without a conversion webhook,
+// objects stored in one version are then served as is in every other version and fields not shared by the
+// versions are lost once the stored version is removed
+type CRDConversionRisk struct {
+	Name           string
+	Strategy       string
+	ServedVersions []string
+	StorageVersion string
+	Message        string
+}
+
+// CheckCRDConversion inspects the CustomResourceDefinitions among objs and returns the ones which serve
+// multiple versions with conversion strategy None, other objects are ignored
+func CheckCRDConversion(objs []unstructured.Unstructured) []CRDConversionRisk {
+	var risks []CRDConversionRisk
+	for _, obj := range objs {
+		if obj.GetKind() != "CustomResourceDefinition" || !strings.HasPrefix(obj.GetAPIVersion(), "apiextensions.k8s.io/") {
+			continue
+		}
+		strategy, _, _ := unstructured.NestedString(obj.Object, "spec", "conversion", "strategy")
+		if len(strategy) == 0 {
+			strategy = crdConversionNone
+		}
+		if strategy == crdConversionWebhook {
+			continue
+		}
+		versions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "versions")
+		var served []string
+		storage := ""
+		for _, v := range versions {
+			version, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := version["name"].(string)
+			if isServed, _ := version["served"].(bool); isServed {
+				served = append(served, name)
+			}
+			if isStorage, _ := version["storage"].(bool); isStorage {
+				storage = name
+			}
+		}
+		if len(served) < 2 {
+			continue
+		}
+		risks = append(risks, CRDConversionRisk{
+			Name:           obj.GetName(),
+			Strategy:       strategy,
+			ServedVersions: served,
+			StorageVersion: storage,
+			Message: fmt.Sprintf("crd %s serves versions %s with conversion strategy %s, objects stored as %s lose fields not present in other versions when %s is removed",
+				obj.GetName(), strings.Join(served, ", "), strategy, storage, storage),
+		})
+	}
+	return risks
+}
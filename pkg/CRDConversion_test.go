@@ -0,0 +1,44 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func newFakeCRD(name, strategy string, versions ...map[string]interface{}) unstructured.Unstructured {
+	obj := newFakeObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", name)
+	var vs []interface{}
+	for _, v := range versions {
+		vs = append(vs, v)
+	}
+	spec := map[string]interface{}{"versions": vs}
+	if len(strategy) > 0 {
+		spec["conversion"] = map[string]interface{}{"strategy": strategy}
+	}
+	obj["spec"] = spec
+	return unstructured.Unstructured{Object: obj}
+}
+
+func TestCheckCRDConversion(t *testing.T) {
+	v1alpha1 := map[string]interface{}{"name": "v1alpha1", "served": true, "storage": false}
+	v1alpha1Unserved := map[string]interface{}{"name": "v1alpha1", "served": false, "storage": false}
+	v1 := map[string]interface{}{"name": "v1", "served": true, "storage": true}
+	objs := []unstructured.Unstructured{
+		newFakeCRD("widgets.example.com", "", v1alpha1, v1),
+		newFakeCRD("gadgets.example.com", "None", v1alpha1, v1),
+		newFakeCRD("webhooks.example.com", "Webhook", v1alpha1, v1),
+		newFakeCRD("single.example.com", "None", v1alpha1Unserved, v1),
+		{Object: newFakeObject("apps/v1", "Deployment", "shop", "cart")},
+	}
+	risks := CheckCRDConversion(objs)
+	var names []string
+	for _, risk := range risks {
+		names = append(names, risk.Name)
+		assert.Equal(t, "None", risk.Strategy)
+		assert.Equal(t, []string{"v1alpha1", "v1"}, risk.ServedVersions)
+		assert.Equal(t, "v1", risk.StorageVersion)
+	}
+	assert.Equal(t, []string{"widgets.example.com", "gadgets.example.com"}, names)
+}
@@ -0,0 +1,247 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// customResourceDefinitionResource is listed to index the definitions of custom resources regardless of the filters
+var customResourceDefinitionResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// crdVersion is a version of a CustomResourceDefinition, schema is nil when the version has none
+type crdVersion struct {
+	served     bool
+	deprecated bool
+	schema     *openapi3.Schema
+}
+
+type crdDefinition struct {
+	storage  string
+	versions map[string]*crdVersion
+	// storedVersions are the versions objects may still be stored in, until they are migrated
+	storedVersions []string
+	// preserveUnknownFields is set for definitions whose objects may hold fields their schema does not define
+	preserveUnknownFields bool
+}
+
+// CRDIndex holds the CustomResourceDefinitions of a cluster by group and kind, so that custom resources are validated
+// against the schemas of their definition rather than the specs of kubernetes
+type CRDIndex struct {
+	definitions map[schema.GroupKind]*crdDefinition
+	// strict reports the fields of custom resources their structural schema does not define, see Config.Strict
+	strict bool
+}
+
+// MissingCRDError is returned for custom resources whose CustomResourceDefinition is not known
+type MissingCRDError struct {
+	Group string
+	Kind  string
+}
+
+func (e *MissingCRDError) Error() string {
+	return fmt.Sprintf("no CustomResourceDefinition found for %s.%s", e.Kind, e.Group)
+}
+
+// NewCRDIndex indexes the CustomResourceDefinitions among objs, other objects are ignored. Versions whose schema
+// cannot be parsed are indexed without one
+func NewCRDIndex(objs []unstructured.Unstructured) *CRDIndex {
+	index := &CRDIndex{definitions: map[schema.GroupKind]*crdDefinition{}}
+	for _, obj := range objs {
+		if obj.GroupVersionKind().GroupKind() != customResourceDefinitionKind.GroupKind() {
+			continue
+		}
+		group, _, _ := unstructured.NestedString(obj.Object, "spec", "group")
+		kind, _, _ := unstructured.NestedString(obj.Object, "spec", "names", "kind")
+		// v1beta1 definitions may share one schema across their versions
+		shared, _, _ := unstructured.NestedMap(obj.Object, "spec", "validation", "openAPIV3Schema")
+		storedVersions, _, _ := unstructured.NestedStringSlice(obj.Object, "status", "storedVersions")
+		definition := &crdDefinition{versions: map[string]*crdVersion{}, storedVersions: storedVersions}
+		// unknown fields are preserved by default by v1beta1 definitions only
+		preserve, found, _ := unstructured.NestedBool(obj.Object, "spec", "preserveUnknownFields")
+		definition.preserveUnknownFields = preserve || !found && obj.GroupVersionKind().Version == "v1beta1"
+		versions, _, _ := unstructured.NestedSlice(obj.Object, "spec", "versions")
+		for _, v := range versions {
+			version, ok := v.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := version["name"].(string)
+			served, _ := version["served"].(bool)
+			deprecated, _ := version["deprecated"].(bool)
+			if isStorage, _ := version["storage"].(bool); isStorage {
+				definition.storage = name
+			}
+			openAPIV3Schema, ok, _ := unstructured.NestedMap(version, "schema", "openAPIV3Schema")
+			if !ok {
+				openAPIV3Schema = shared
+			}
+			definition.versions[name] = &crdVersion{served: served, deprecated: deprecated, schema: parseCRDSchema(openAPIV3Schema)}
+		}
+		index.definitions[schema.GroupKind{Group: group, Kind: kind}] = definition
+	}
+	return index
+}
+
+func parseCRDSchema(openAPIV3Schema map[string]interface{}) *openapi3.Schema {
+	if len(openAPIV3Schema) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(openAPIV3Schema)
+	if err != nil {
+		return nil
+	}
+	scm := &openapi3.Schema{}
+	if err := scm.UnmarshalJSON(data); err != nil {
+		return nil
+	}
+	precompilePatterns(scm, map[*openapi3.Schema]bool{})
+	return scm
+}
+
+// FetchCRDIndex lists the CustomResourceDefinitions of the cluster into a CRDIndex, the filters of conf do not apply
+func (c *Cluster) FetchCRDIndex(ctx context.Context, conf *Config) (*CRDIndex, error) {
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	unfiltered := *conf
+	unfiltered.LabelSelector, unfiltered.FieldSelector = "", ""
+	definitions, err := c.listAll(ctx, customResourceDefinitionResource, "", 0, &unfiltered)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the CustomResourceDefinitions of cluster %s: %w", c.name, err)
+	}
+	index := NewCRDIndex(definitions)
+	index.strict = conf.Strict
+	return index, nil
+}
+
+// CustomResourceKinds returns the kinds of custom resources served by the cluster which pass the filters of conf, see
+// DiscoverGVKs, or none when IncludeCustomResources is CustomResourcesOnlyBuiltin
+func (c *Cluster) CustomResourceKinds(ctx context.Context, conf *Config) ([]schema.GroupVersionKind, error) {
+	if conf.IncludeCustomResources == CustomResourcesOnlyBuiltin {
+		return nil, nil
+	}
+	gvks, err := c.DiscoverGVKs(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	var custom []schema.GroupVersionKind
+	for _, gvk := range gvks {
+		if !IsBuiltinAPIGroup(gvk.Group) {
+			custom = append(custom, gvk)
+		}
+	}
+	return custom, nil
+}
+
+// Covers returns true if obj is a custom resource to be validated with ValidateCustomResource
+func (i *CRDIndex) Covers(obj *unstructured.Unstructured) bool {
+	return i != nil && !IsBuiltinAPIGroup(obj.GroupVersionKind().Group)
+}
+
+// ValidateCustomResource validates obj against the schema of its version in its CustomResourceDefinition, schema
+// errors are reported as ErrorsForOriginal. When strict, the fields the schema does not define are reported as
+// UnknownFields unless the definition preserves unknown fields. Objects which may be stored in a version which is not served or no longer
+// listed by the definition, according to the storedVersions of the definition or the managedFields of the object when
+// they are kept, are reported as Deleted in that version along with their errors against the storage version. A
+// MissingCRDError is returned when the definition is not known
+func (i *CRDIndex) ValidateCustomResource(obj *unstructured.Unstructured) (ValidationResult, error) {
+	gvk := obj.GroupVersionKind()
+	definition, ok := i.definitions[gvk.GroupKind()]
+	if !ok {
+		return ValidationResult{}, &MissingCRDError{Group: gvk.Group, Kind: gvk.Kind}
+	}
+	// the object is validated the way it is serialized, as the validation of built-in kinds does
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return ValidationResult{}, err
+	}
+	var value map[string]interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return ValidationResult{}, err
+	}
+	result := ValidationResult{Kind: gvk.Kind, APIVersion: gvk.GroupVersion().String(), ResourceNamespace: obj.GetNamespace(), ResourceName: obj.GetName()}
+	if len(result.ResourceNamespace) == 0 {
+		result.ResourceNamespace = "undefined"
+	}
+	if version, ok := definition.versions[gvk.Version]; ok && version.schema != nil {
+		result.ValidatedAgainstSchema = true
+		result.ErrorsForOriginal = visitCRDSchema(version.schema, value)
+		if i.strict && !definition.preserveUnknownFields {
+			result.UnknownFields = unknownFields(withoutTypeMeta(value), version.schema)
+		}
+	}
+	versions := append([]string{gvk.Version}, definition.storedVersions...)
+	for _, used := range append(versions, writtenVersions(obj, gvk.Group)...) {
+		version, ok := definition.versions[used]
+		if ok && version.served {
+			if version.deprecated && used == gvk.Version {
+				result.Deprecated = true
+			}
+			continue
+		}
+		result.Deleted = true
+		result.IsVersionSupported = 2
+		result.APIVersion = schema.GroupVersion{Group: gvk.Group, Version: used}.String()
+		break
+	}
+	if (result.Deleted || result.Deprecated) && len(definition.storage) > 0 && definition.storage != gvk.Version {
+		result.LatestAPIVersion = schema.GroupVersion{Group: gvk.Group, Version: definition.storage}.String()
+		if storage := definition.versions[definition.storage]; storage.schema != nil {
+			result.ErrorsForLatest = visitCRDSchema(storage.schema, value)
+			result.FieldMigrations = fieldMigrations(withoutTypeMeta(value), storage.schema)
+		}
+	}
+	return result, nil
+}
+
+// withoutTypeMeta returns value without the apiVersion, kind and metadata, which the schemas of custom resources
+// need not define
+func withoutTypeMeta(value map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(value))
+	for key, field := range value {
+		if key != "apiVersion" && key != "kind" && key != "metadata" {
+			fields[key] = field
+		}
+	}
+	return fields
+}
+
+// writtenVersions returns the versions of group the fields of obj were written with, according to its managedFields
+func writtenVersions(obj *unstructured.Unstructured, group string) []string {
+	var versions []string
+	for _, entry := range obj.GetManagedFields() {
+		gv, err := schema.ParseGroupVersion(entry.APIVersion)
+		if err != nil || gv.Group != group {
+			continue
+		}
+		versions = append(versions, gv.Version)
+	}
+	return versions
+}
+
+func visitCRDSchema(scm *openapi3.Schema, value map[string]interface{}) []*openapi3.SchemaError {
+	err := scm.VisitJSON(value, openapi3.MultiErrors())
+	if err == nil {
+		return nil
+	}
+	return flattenSchemaErrors(err)
+}
+
+func flattenSchemaErrors(err error) []*openapi3.SchemaError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var schemaErrs []*openapi3.SchemaError
+		for _, nested := range e {
+			schemaErrs = append(schemaErrs, flattenSchemaErrors(nested)...)
+		}
+		return schemaErrs
+	case *openapi3.SchemaError:
+		return []*openapi3.SchemaError{e}
+	}
+	return []*openapi3.SchemaError{{Reason: err.Error(), Origin: err}}
+}
@@ -0,0 +1,147 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newFakeSchemaCRD(group, kind, plural string, storedVersions []interface{}, versions ...interface{}) map[string]interface{} {
+	crd := newFakeObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", plural+"."+group)
+	crd["spec"] = map[string]interface{}{
+		"group":    group,
+		"names":    map[string]interface{}{"kind": kind, "plural": plural},
+		"versions": versions,
+	}
+	crd["status"] = map[string]interface{}{"storedVersions": storedVersions}
+	return crd
+}
+
+func crdVersionSpec(name string, served, storage, deprecated bool) map[string]interface{} {
+	return map[string]interface{}{
+		"name": name, "served": served, "storage": storage, "deprecated": deprecated,
+		"schema": map[string]interface{}{"openAPIV3Schema": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"spec": map[string]interface{}{
+					"type":       "object",
+					"properties": map[string]interface{}{"replicas": map[string]interface{}{"type": "integer"}},
+				},
+			},
+		}},
+	}
+}
+
+func newFakeCustomResource(apiVersion, kind, name string, replicas interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: newFakeObject(apiVersion, kind, "shop", name)}
+	obj.Object["spec"] = map[string]interface{}{"replicas": replicas}
+	return obj
+}
+
+func TestCRDIndex_ValidateCustomResource(t *testing.T) {
+	crds := NewCRDIndex([]unstructured.Unstructured{
+		{Object: newFakeSchemaCRD("example.com", "Widget", "widgets", []interface{}{"v1"},
+			crdVersionSpec("v1alpha1", false, false, false), crdVersionSpec("v1beta1", true, false, true), crdVersionSpec("v1", true, true, false))},
+		// objects may still be stored in v1alpha1, which is no longer listed
+		{Object: newFakeSchemaCRD("example.com", "Gadget", "gadgets", []interface{}{"v1alpha1", "v1"}, crdVersionSpec("v1", true, true, false))},
+		{Object: newFakeObject("v1", "ConfigMap", "shop", "settings")},
+	})
+	writtenInAlpha := newFakeCustomResource("example.com/v1", "Widget", "written", int64(3))
+	writtenInAlpha.Object["metadata"].(map[string]interface{})["managedFields"] = []interface{}{
+		map[string]interface{}{"manager": "kubectl", "operation": "Update", "apiVersion": "example.com/v1alpha1"},
+	}
+
+	tests := []struct {
+		name           string
+		obj            *unstructured.Unstructured
+		wantAPIVersion string
+		wantLatest     string
+		wantErrors     int
+		wantDeleted    bool
+		wantDeprecated bool
+	}{
+		{name: "valid", obj: newFakeCustomResource("example.com/v1", "Widget", "valid", int64(3)), wantAPIVersion: "example.com/v1"},
+		{name: "invalid against its own schema", obj: newFakeCustomResource("example.com/v1", "Widget", "invalid", "three"), wantAPIVersion: "example.com/v1", wantErrors: 1},
+		{name: "deprecated version", obj: newFakeCustomResource("example.com/v1beta1", "Widget", "beta", int64(3)), wantAPIVersion: "example.com/v1beta1", wantLatest: "example.com/v1", wantDeprecated: true},
+		{name: "written in a version which is not served", obj: writtenInAlpha, wantAPIVersion: "example.com/v1alpha1", wantDeleted: true},
+		{name: "stored in a version which was dropped", obj: newFakeCustomResource("example.com/v1", "Gadget", "stored", int64(3)), wantAPIVersion: "example.com/v1alpha1", wantDeleted: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, crds.Covers(tt.obj))
+			got, err := crds.ValidateCustomResource(tt.obj)
+			assert.NoError(t, err)
+			assert.True(t, got.ValidatedAgainstSchema)
+			assert.Equal(t, tt.wantAPIVersion, got.APIVersion)
+			assert.Equal(t, tt.wantLatest, got.LatestAPIVersion)
+			assert.Len(t, got.ErrorsForOriginal, tt.wantErrors)
+			assert.Equal(t, tt.wantDeleted, got.Deleted)
+			assert.Equal(t, tt.wantDeprecated, got.Deprecated)
+		})
+	}
+
+	_, err := crds.ValidateCustomResource(newFakeCustomResource("other.io/v1", "Sprocket", "unknown", int64(1)))
+	var missing *MissingCRDError
+	assert.True(t, errors.As(err, &missing))
+	assert.Equal(t, "other.io", missing.Group)
+	assert.False(t, crds.Covers(&unstructured.Unstructured{Object: newFakeObject("apps/v1", "Deployment", "shop", "web")}))
+	assert.False(t, (*CRDIndex)(nil).Covers(newFakeCustomResource("example.com/v1", "Widget", "valid", int64(3))))
+}
+
+func TestCRDIndex_ValidateCustomResource_strict(t *testing.T) {
+	preserving := newFakeSchemaCRD("example.com", "Gadget", "gadgets", []interface{}{"v1"}, crdVersionSpec("v1", true, true, false))
+	unstructured.SetNestedField(preserving, true, "spec", "preserveUnknownFields")
+	crds := NewCRDIndex([]unstructured.Unstructured{
+		{Object: newFakeSchemaCRD("example.com", "Widget", "widgets", []interface{}{"v1"}, crdVersionSpec("v1", true, true, false))},
+		{Object: preserving},
+	})
+	crds.strict = true
+
+	widget := newFakeCustomResource("example.com/v1", "Widget", "typo", int64(3))
+	widget.Object["spec"].(map[string]interface{})["replica"] = int64(3)
+	got, err := crds.ValidateCustomResource(widget)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spec.replica"}, got.UnknownFields)
+
+	gadget := newFakeCustomResource("example.com/v1", "Gadget", "typo", int64(3))
+	gadget.Object["spec"].(map[string]interface{})["replica"] = int64(3)
+	got, err = crds.ValidateCustomResource(gadget)
+	assert.NoError(t, err)
+	assert.Empty(t, got.UnknownFields)
+}
+
+func TestCluster_FetchCRDIndex(t *testing.T) {
+	server := newFakeApiServer(t)
+	definitions := server.addResource(customResourceDefinitionKind, "customresourcedefinitions", false)
+	server.addObject(definitions, newFakeSchemaCRD("example.com", "Widget", "widgets", []interface{}{"v1"}, crdVersionSpec("v1", true, true, false)))
+	server.addResource(schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}, "widgets", true)
+	server.addResource(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "deployments", true)
+	cluster := server.cluster(t)
+
+	// the definitions are listed regardless of the selectors
+	crds, err := cluster.FetchCRDIndex(context.Background(), &Config{LabelSelector: "app=web"})
+	assert.NoError(t, err)
+	_, err = crds.ValidateCustomResource(newFakeCustomResource("example.com/v1", "Widget", "valid", int64(3)))
+	assert.NoError(t, err)
+
+	kinds, err := cluster.CustomResourceKinds(context.Background(), &Config{})
+	assert.NoError(t, err)
+	assert.Equal(t, []schema.GroupVersionKind{{Group: "example.com", Version: "v1", Kind: "Widget"}}, kinds)
+	kinds, err = cluster.CustomResourceKinds(context.Background(), &Config{IncludeCustomResources: CustomResourcesOnlyBuiltin})
+	assert.NoError(t, err)
+	assert.Empty(t, kinds)
+}
+
+func TestFetchSummary_AddMissingDefinition(t *testing.T) {
+	summary := &FetchSummary{}
+	summary.AddMissingDefinition("example.com")
+	summary.AddMissingDefinition("other.io")
+	summary.AddMissingDefinition("example.com")
+	assert.Equal(t, []string{"example.com", "other.io"}, summary.MissingDefinitions)
+	assert.True(t, summary.Reportable())
+	assert.Contains(t, summary.String(), "2 api groups without CustomResourceDefinition, their objects were not validated: example.com, other.io")
+}
@@ -1,159 +1,713 @@
 package pkg
 
 import (
+	"bytes"
 	"context"
-	"flag"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
-	"os/user"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	"go.opentelemetry.io/otel/attribute"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/discovery/cached/memory"
 	"k8s.io/client-go/dynamic"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 )
 
+// Cluster talks to the api server of a kubernetes cluster, its clients are only built once the cluster is first used
 type Cluster struct {
 	resources         []schema.GroupVersionResource
 	disco             discovery.DiscoveryInterface
 	restConfig        *rest.Config
 	kubernetesVersion string
 	clientset         dynamic.Interface
-	Name              string
-	Version           string
+	restMapper        meta.RESTMapper
+	name              string
+	httpClient        *http.Client
+	clientMu          sync.RWMutex
+	clientsOnce       sync.Once
+	clientsErr        error
+	versionOnce       sync.Once
+	// discoveryCacheDir is the directory discovery responses are cached in, they are not cached when empty
+	discoveryCacheDir string
+	discoveryCacheTTL time.Duration
+	// warnings collects the warnings returned by the api server, see ServerWarnings
+	warnings *serverWarnings
 }
 
-func NewCluster(kubeconfig string, kubecontext string) *Cluster {
-	cluster := Cluster{}
-	pathOptions := clientcmd.NewDefaultPathOptions()
-	if len(kubeconfig) != 0 {
-		pathOptions.GlobalFile = kubeconfig
+// serverVersionTimeout bounds looking up the version of the api server for Cluster.Version
+var serverVersionTimeout = 10 * time.Second
+
+// NewCluster builds a Cluster from kubeconfig files loaded the same way as kubectl does: an explicit kubeconfig
+// wins, otherwise the files listed in KUBECONFIG are merged and the default kubeconfig is used when it is unset.
+// The kubeconfig is read from stdin when kubeconfig is KubeconfigStdin. The current context is used when kubecontext
+// is empty. Client options configured in conf are applied, conf may be nil.
+func NewCluster(kubeconfig string, kubecontext string, conf *Config) (*Cluster, error) {
+	if kubeconfig == KubeconfigStdin {
+		data, err := kubeconfigFromStdin.read()
+		if err != nil {
+			return nil, err
+		}
+		return NewClusterFromKubeconfigBytes(data, kubecontext, conf)
 	}
-	config, err := pathOptions.GetStartingConfig()
+	restConfig, clusterName, err := loadKubeconfig(kubeconfig, kubecontext)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
+	return newClusterFromRestConfig(restConfig, clusterName, conf)
+}
+
+// loadKubeconfig returns the rest config and the cluster name of kubecontext, or of the current context when it is
+// empty, from the kubeconfig files loaded by kubeconfigLoadingRules
+func loadKubeconfig(kubeconfig string, kubecontext string) (*rest.Config, string, error) {
+	loadingRules := kubeconfigLoadingRules(kubeconfig)
 
 	configOverrides := clientcmd.ConfigOverrides{}
 	if kubecontext != "" {
 		configOverrides.CurrentContext = kubecontext
 	}
 
-	clientConfig := clientcmd.NewDefaultClientConfig(*config, &configOverrides)
-	cluster.restConfig, err = clientConfig.ClientConfig()
-	cluster.restConfig.WarningHandler = rest.NoWarnings{}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &configOverrides)
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to load kubeconfig %s: %w", strings.Join(loadingRules.GetLoadingPrecedence(), string(filepath.ListSeparator)), err)
+	}
+	rawConfig, err := clientConfig.RawConfig()
 	if err != nil {
-		panic(err)
+		return nil, "", fmt.Errorf("failed to load kubeconfig %s: %w", strings.Join(loadingRules.GetLoadingPrecedence(), string(filepath.ListSeparator)), err)
+	}
+	return restConfig, contextClusterName(rawConfig, kubecontext), nil
+}
+
+// contextClusterName returns the name of the cluster of kubecontext, or of the current context when it is empty
+func contextClusterName(config clientcmdapi.Config, kubecontext string) string {
+	if len(kubecontext) == 0 {
+		kubecontext = config.CurrentContext
+	}
+	if kubeContext, ok := config.Contexts[kubecontext]; ok {
+		return kubeContext.Cluster
 	}
+	return ""
+}
 
-	if cluster.disco, err = discovery.NewDiscoveryClientForConfig(cluster.restConfig); err != nil {
-		panic(err)
+// kubeconfigLoadingRules returns the rules kubectl loads kubeconfig files with, kubeconfig overrides the KUBECONFIG
+// environment variable and the default kubeconfig when it is not empty
+func kubeconfigLoadingRules(kubeconfig string) *clientcmd.ClientConfigLoadingRules {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if len(kubeconfig) != 0 {
+		loadingRules.ExplicitPath = kubeconfig
 	}
+	return loadingRules
+}
 
-	cluster.clientset, err = dynamic.NewForConfig(cluster.restConfig)
+// NewClusterFromKubeconfigBytes builds a Cluster from the contents of a kubeconfig held in memory, the current context
+// is used when contextName is empty. Client options configured in conf are applied, conf may be nil.
+func NewClusterFromKubeconfigBytes(kubeconfig []byte, contextName string, conf *Config) (*Cluster, error) {
+	if len(bytes.TrimSpace(kubeconfig)) == 0 {
+		return nil, fmt.Errorf("kubeconfig is empty")
+	}
+	clientConfig, err := clientcmd.NewClientConfigFromBytes(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	rawConfig, err := clientConfig.RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	configOverrides := clientcmd.ConfigOverrides{CurrentContext: contextName}
+	restConfig, err := clientcmd.NewNonInteractiveClientConfig(rawConfig, contextName, &configOverrides, nil).ClientConfig()
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
 	}
+	return newClusterFromRestConfig(restConfig, contextClusterName(rawConfig, contextName), conf)
+}
+
+// ClusterOptions configures how NewClusterFromEnvOrConfig finds the cluster
+type ClusterOptions struct {
+	// KubeconfigPath is the kubeconfig used when USE_LOCAL_DEV_MODE is true, the files listed in KUBECONFIG or
+	// $HOME/.kube/config are used when it is empty
+	KubeconfigPath string
+
+	// TokenFile is the service account token used in cluster, it is re-read as the token rotates. It defaults to the
+	// SERVICE_ACCOUNT_TOKEN_FILE environment variable and to the default service account token mount when unset.
+	TokenFile string
 
-	return &cluster
+	// RootCAFile is the CA bundle the api server certificate is verified with in cluster, it defaults to the
+	// SERVICE_ACCOUNT_ROOT_CA_FILE environment variable and to the default service account CA mount when unset
+	RootCAFile string
 }
 
-func NewClusterFromEnvOrConfig(restConfig *rest.Config) *Cluster {
-	cluster := Cluster{}
-	defaultRestConfig := &rest.Config{}
+const (
+	serviceAccountTokenFile  = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	serviceAccountRootCAFile = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+)
+
+// NewClusterFromEnvOrConfig builds a Cluster from the local kubeconfig when USE_LOCAL_DEV_MODE is true, from
+// restConfig when it is provided and from the in-cluster service account otherwise. Client options configured in conf
+// are applied, conf may be nil.
+func NewClusterFromEnvOrConfig(restConfig *rest.Config, opts ClusterOptions, conf *Config) (*Cluster, error) {
 	var err error
+	var clusterName string
 	useLocalDevMode := os.Getenv("USE_LOCAL_DEV_MODE")
 	if useLocalDevMode == "true" {
-		usr, err := user.Current()
+		restConfig, clusterName, err = loadKubeconfig(opts.KubeconfigPath, "")
+		if err != nil {
+			return nil, err
+		}
+	} else if restConfig == nil {
+		restConfig, err = inClusterConfig(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get rest config via InClusterConfig: %w", err)
+		}
+	}
+	return newClusterFromRestConfig(restConfig, clusterName, conf)
+}
+
+// inClusterConfig is rest.InClusterConfig reading the service account token and CA from the paths of opts,
+// which allows projected tokens mounted at non default paths
+func inClusterConfig(opts ClusterOptions) (*rest.Config, error) {
+	tokenFile, rootCAFile := opts.TokenFile, opts.RootCAFile
+	if len(tokenFile) == 0 {
+		tokenFile = os.Getenv("SERVICE_ACCOUNT_TOKEN_FILE")
+	}
+	if len(rootCAFile) == 0 {
+		rootCAFile = os.Getenv("SERVICE_ACCOUNT_ROOT_CA_FILE")
+	}
+	if len(tokenFile) == 0 && len(rootCAFile) == 0 {
+		return rest.InClusterConfig()
+	}
+	if len(tokenFile) == 0 {
+		tokenFile = serviceAccountTokenFile
+	}
+	if len(rootCAFile) == 0 {
+		rootCAFile = serviceAccountRootCAFile
+	}
+
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if len(host) == 0 || len(port) == 0 {
+		return nil, rest.ErrNotInCluster
+	}
+	if _, err := os.Stat(tokenFile); err != nil {
+		return nil, fmt.Errorf("failed to read service account token: %w", err)
+	}
+	caData, err := os.ReadFile(rootCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account root CA: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caData) {
+		return nil, fmt.Errorf("service account root CA %s does not contain any PEM encoded certificate", rootCAFile)
+	}
+	// the token is only set as file so that rotated tokens are picked up
+	return &rest.Config{
+		Host:            "https://" + net.JoinHostPort(host, port),
+		TLSClientConfig: rest.TLSClientConfig{CAFile: rootCAFile},
+		BearerTokenFile: tokenFile,
+	}, nil
+}
+
+// NewClusterFromToken builds a Cluster talking to the api server at host with a bearer token, the server
+// certificate is verified with the PEM encoded caData unless insecure is set
+func NewClusterFromToken(host, token string, caData []byte, insecure bool) (*Cluster, error) {
+	if len(host) == 0 {
+		return nil, fmt.Errorf("api server host is required")
+	}
+	if len(caData) == 0 && !insecure {
+		return nil, fmt.Errorf("either ca data or insecure is required to connect to %s", host)
+	}
+	restConfig := &rest.Config{
+		Host:        host,
+		BearerToken: token,
+		TLSClientConfig: rest.TLSClientConfig{
+			Insecure: insecure,
+		},
+	}
+	if !insecure {
+		restConfig.TLSClientConfig.CAData = caData
+	}
+	return newClusterFromRestConfig(restConfig, "", nil)
+}
+
+// newClusterFromRestConfig returns a Cluster named name, or after the api server host when name is empty, the api
+// server is not contacted until the cluster is used
+func newClusterFromRestConfig(restConfig *rest.Config, name string, conf *Config) (*Cluster, error) {
+	if len(name) == 0 {
+		name = restConfig.Host
+	}
+	cluster := &Cluster{restConfig: restConfig, name: name, discoveryCacheDir: conf.discoveryCacheDir(restConfig.Host), discoveryCacheTTL: conf.discoveryCacheTTL(), warnings: newServerWarnings(conf)}
+	cluster.warnings.collect(cluster.restConfig)
+	if err := conf.applyClientOptions(cluster.restConfig); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// initClients builds the discovery and dynamic clients on first use, a client set with SetDynamicClient is kept.
+// Both clients share one http client whose transport is reused by client-go for equal rest configs.
+func (c *Cluster) initClients() error {
+	c.clientsOnce.Do(func() {
+		httpClient, err := rest.HTTPClientFor(c.restConfig)
 		if err != nil {
-			panic(err)
+			c.clientsErr = fmt.Errorf("failed to create http client for %s: %w", c.restConfig.Host, err)
+			return
 		}
-		kubeconfig := flag.String("kubeconfig", filepath.Join(usr.HomeDir, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-		//flag.Parse()
-		defaultRestConfig, err = clientcmd.BuildConfigFromFlags("", *kubeconfig)
+		c.httpClient = httpClient
+		disco, err := discovery.NewDiscoveryClientForConfigAndClient(c.restConfig, httpClient)
 		if err != nil {
-			panic(err)
+			c.clientsErr = fmt.Errorf("failed to create discovery client for %s: %w", c.restConfig.Host, err)
+			return
 		}
-	} else if restConfig != nil {
-		defaultRestConfig = restConfig
-	} else {
-		defaultRestConfig, err = rest.InClusterConfig()
+		c.disco = disco
+		if len(c.discoveryCacheDir) > 0 {
+			c.disco = newDiskCachedDiscovery(disco, c.discoveryCacheDir, c.discoveryCacheTTL)
+		}
+		if c.dynamicClient() != nil {
+			return
+		}
+		client, err := dynamic.NewForConfigAndClient(c.restConfig, httpClient)
 		if err != nil {
-			fmt.Println("error in getting rest config via InClusterConfig")
-			panic(err)
+			c.clientsErr = fmt.Errorf("failed to create dynamic client for %s: %w", c.restConfig.Host, err)
+			return
 		}
+		c.SetDynamicClient(client)
+	})
+	return c.clientsErr
+}
+
+// Close releases the idle connections to the api server. The cluster may be discarded after Close, using it again
+// opens new connections. As transports are shared between clusters with equal rest configs, idle connections of
+// those clusters are closed as well.
+func (c *Cluster) Close() {
+	if c.httpClient != nil {
+		c.httpClient.CloseIdleConnections()
 	}
+}
 
-	cluster.restConfig = defaultRestConfig
-	cluster.restConfig.WarningHandler = rest.NoWarnings{}
+// Name returns the name of the cluster in the kubeconfig, or the api server host when it was not built from a kubeconfig
+func (c *Cluster) Name() string {
+	return c.name
+}
 
-	if cluster.disco, err = discovery.NewDiscoveryClientForConfig(cluster.restConfig); err != nil {
-		panic(err)
+// Version returns the major.minor version of the api server, it is looked up on first call and is empty when the
+// version could not be determined
+func (c *Cluster) Version() string {
+	c.versionOnce.Do(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), serverVersionTimeout)
+		defer cancel()
+		var err error
+		if c.kubernetesVersion, err = c.ServerVersion(ctx); err != nil {
+			kLog.Warn(fmt.Sprintf("unable to determine the version of cluster %s: %v", c.name, err))
+		}
+	})
+	return c.kubernetesVersion
+}
+
+// applyClientOptions applies the user agent, rate limits, impersonation, TLS options and proxy configured in c to restConfig, the default rate
+// limits are used when c is nil or leaves them unset. The proxy of c wins over the proxy-url of the kubeconfig, which
+// wins over the HTTPS_PROXY and HTTP_PROXY environment variables.
+func (c *Config) applyClientOptions(restConfig *rest.Config) error {
+	restConfig.QPS, restConfig.Burst = DefaultClientQPS, DefaultClientBurst
+	if c == nil {
+		restConfig.UserAgent = userAgent("")
+		return nil
+	}
+	restConfig.UserAgent = userAgent(c.UserAgentSuffix)
+	if c.QPS > 0 {
+		restConfig.QPS = c.QPS
+	}
+	if c.Burst > 0 {
+		restConfig.Burst = c.Burst
+	}
+	if c.RateLimiter != nil {
+		restConfig.RateLimiter = c.RateLimiter
+	}
+	if len(c.ImpersonateUser) > 0 {
+		restConfig.Impersonate = rest.ImpersonationConfig{
+			UserName: c.ImpersonateUser,
+			UID:      c.ImpersonateUID,
+			Groups:   c.ImpersonateGroups,
+		}
+	}
+	if err := c.applyTLSOptions(restConfig); err != nil {
+		return err
 	}
+	if len(c.ProxyURL) > 0 {
+		proxyURL, err := url.Parse(c.ProxyURL)
+		if err != nil || len(proxyURL.Host) == 0 {
+			return fmt.Errorf("invalid proxy url %q, expected a url like http://proxy.example.com:3128", c.ProxyURL)
+		}
+		restConfig.Proxy = http.ProxyURL(proxyURL)
+	}
+	return nil
+}
 
-	cluster.clientset, err = dynamic.NewForConfig(cluster.restConfig)
+// applyTLSOptions overrides the verification of the api server certificate configured in restConfig with
+// InsecureSkipTLSVerify or CertificateAuthorityFile of c
+func (c *Config) applyTLSOptions(restConfig *rest.Config) error {
+	if c.InsecureSkipTLSVerify && len(c.CertificateAuthorityFile) > 0 {
+		return fmt.Errorf("insecure-skip-tls-verify and certificate-authority cannot be used together")
+	}
+	if c.InsecureSkipTLSVerify {
+		restConfig.TLSClientConfig.Insecure = true
+		restConfig.TLSClientConfig.CAFile, restConfig.TLSClientConfig.CAData = "", nil
+		return nil
+	}
+	if len(c.CertificateAuthorityFile) == 0 {
+		return nil
+	}
+	caData, err := os.ReadFile(c.CertificateAuthorityFile)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to read certificate authority: %w", err)
+	}
+	if !x509.NewCertPool().AppendCertsFromPEM(caData) {
+		return fmt.Errorf("certificate authority %s does not contain any PEM encoded certificate", c.CertificateAuthorityFile)
 	}
+	restConfig.TLSClientConfig.Insecure = false
+	restConfig.TLSClientConfig.CAFile, restConfig.TLSClientConfig.CAData = "", caData
+	return nil
+}
+
+// SetRESTMapper makes the cluster resolve kinds to resources using mapper instead of live discovery
+func (c *Cluster) SetRESTMapper(mapper meta.RESTMapper) {
+	c.restMapper = mapper
+}
 
-	return &cluster
+// SetDynamicClient replaces the dynamic client used to fetch objects from the cluster
+func (c *Cluster) SetDynamicClient(client dynamic.Interface) {
+	c.clientMu.Lock()
+	defer c.clientMu.Unlock()
+	c.clientset = client
 }
 
-func (c *Cluster) ServerVersion() (string, error) {
-	info, err := c.disco.ServerVersion()
+// dynamicClient returns the dynamic client, which may be replaced while resources are being listed concurrently
+func (c *Cluster) dynamicClient() dynamic.Interface {
+	c.clientMu.RLock()
+	defer c.clientMu.RUnlock()
+	return c.clientset
+}
+
+func (c *Cluster) mapper(cached discovery.CachedDiscoveryInterface) meta.RESTMapper {
+	if c.restMapper != nil {
+		return c.restMapper
+	}
+	return restmapper.NewDeferredDiscoveryRESTMapper(cached)
+}
+
+// ServerVersion returns the major.minor version of the api server
+func (c *Cluster) ServerVersion(ctx context.Context) (string, error) {
+	if err := c.initClients(); err != nil {
+		return "", err
+	}
+	body, err := c.disco.RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
 	if err != nil {
 		return "", err
 	}
+	var info version.Info
+	if err := json.Unmarshal(body, &info); err != nil {
+		return "", fmt.Errorf("unable to parse the server version: %w", err)
+	}
 	return fmt.Sprintf("%s.%s", info.Major, strings.Trim(info.Minor, "+")), nil
 }
-func (c *Cluster) FetchK8sObjects(gvks []schema.GroupVersionKind, conf *Config) []unstructured.Unstructured {
-	var resources []schema.GroupVersionResource
-	mapper := restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(c.disco))
+
+// PlannedUpgradeVersion reads the kubernetes version the cluster is planned to be upgraded to from a ConfigMap
+// referenced as namespace/name. The key is looked up in the ConfigMap data first and then in its annotations,
+// an empty version is returned when either the ConfigMap or the key is absent.
+func (c *Cluster) PlannedUpgradeVersion(ctx context.Context, configMap, key string) (string, error) {
+	parts := strings.SplitN(configMap, "/", 2)
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+		return "", fmt.Errorf("invalid upgrade plan configmap %q, expected namespace/name", configMap)
+	}
+	if err := c.initClients(); err != nil {
+		return "", err
+	}
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	cm, err := c.dynamicClient().Resource(gvr).Namespace(parts[0]).Get(ctx, parts[1], v1.GetOptions{})
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if version, ok, _ := unstructured.NestedString(cm.Object, "data", key); ok && len(version) > 0 {
+		return strings.TrimSpace(version), nil
+	}
+	if version, ok := cm.GetAnnotations()[key]; ok {
+		return strings.TrimSpace(version), nil
+	}
+	return "", nil
+}
+
+// FetchK8sObjects lists the objects of gvks served by the cluster, the returned summary lists the resources which
+// could not be fetched, spans of every listed resource are children of ctx.
+// Resources which fail to list are skipped, once ctx is done no further resources are listed and the objects fetched
+// so far are returned along with the error of ctx. Objects served in several versions are returned once, in the
+// preferred version of the cluster.
+func (c *Cluster) FetchK8sObjects(ctx context.Context, gvks []schema.GroupVersionKind, conf *Config) ([]unstructured.Unstructured, *FetchSummary, error) {
+	// every object fetched is collected, even once ctx is done
+	objc, errc, summary := c.streamK8sObjects(ctx, context.Background(), gvks, conf)
 	var objs []unstructured.Unstructured
-	for _, gvk := range gvks {
-		if Contains(gvk.Kind, conf.IgnoreKinds) {
+	for obj := range objc {
+		objs = append(objs, obj)
+	}
+	return objs, summary, <-errc
+}
+
+// fetchTarget is a resource to be listed by FetchK8sObjects
+type fetchTarget struct {
+	resource   schema.GroupVersionResource
+	kind       schema.GroupVersionKind
+	namespaced bool
+	options    KindFetchOptions
+	// preferred is false if the resource is listed in another version than the preferred one of the cluster
+	preferred bool
+}
+
+// fetchTargets applies the filters of conf to gvks and maps them to the resources to be listed, mapping failures are
+// added to summary. Resources in their preferred version are listed first, so that the copies of objects served in
+// several versions which are kept are the ones in the preferred version
+func (c *Cluster) fetchTargets(gvks []schema.GroupVersionKind, conf *Config, summary *FetchSummary) ([]fetchTarget, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	var targets []fetchTarget
+	seen := map[schema.GroupVersionResource]bool{}
+	cached := c.cachedDiscovery()
+	mapper := c.mapper(cached)
+	// groups of broken aggregated apis, eg of a metrics-server which is down, are reported and the others are listed
+	var discoveryErr *discovery.ErrGroupDiscoveryFailed
+	if _, _, err := cached.ServerGroupsAndResources(); errors.As(err, &discoveryErr) {
+		var failed []schema.GroupVersion
+		for gv, err := range discoveryErr.Groups {
+			// group versions without resources are reported as failed by discovery but are not broken
+			if !strings.Contains(err.Error(), "received empty response") {
+				failed = append(failed, gv)
+			}
+		}
+		sort.Slice(failed, func(i, j int) bool { return failed[i].String() < failed[j].String() })
+		for _, gv := range failed {
+			err := discoveryErr.Groups[gv]
+			conf.Emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: gv.String(), Reason: fmt.Sprintf("discovery failed: %v", err)})
+			summary.Skipped = append(summary.Skipped, SkippedResource{Resource: gv.String(), Failure: FetchDiscoveryFailure, Message: err.Error()})
+		}
+		if conf.FailOnDiscoveryError && len(failed) > 0 {
+			return nil, fmt.Errorf("discovery of cluster %s failed: %w", c.name, err)
+		}
+	}
+	for _, gvk := range conf.withCustomResourceDefinitions(gvks) {
+		if reason := conf.kindSkipReason(gvk); len(reason) > 0 {
+			conf.Emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: gvk.String(), Reason: reason})
 			continue
 		}
-		if len(conf.SelectKinds) > 0 && !Contains(gvk.Kind, conf.SelectKinds) {
+		kindOptions := conf.kindFetchOptions(gvk.Kind)
+		if kindOptions.Skip {
+			conf.Emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: gvk.String(), Reason: "kind skipped by per kind options"})
 			continue
 		}
-		gvr, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		version := conf.preferredVersion(mapper, gvk)
+		gvr, err := mapper.RESTMapping(gvk.GroupKind(), version)
 		if err != nil {
+			conf.Emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: gvk.String(), Reason: "not served by cluster"})
+			if !meta.IsNoMatchError(err) {
+				summary.Skipped = append(summary.Skipped, SkippedResource{Resource: gvk.String(), Failure: FetchMappingFailure, Message: err.Error()})
+			}
 			continue
 		}
-		resources = append(resources, gvr.Resource)
-	}
-	for _, resource := range resources {
-		if strings.Contains(resource.Resource, "lists") || strings.Contains(resource.Resource, "reviews") || strings.EqualFold(resource.Resource, "bindings") {
+		if seen[gvr.Resource] {
 			continue
 		}
-		resInf := c.clientset.Resource(resource)
-		objList, err := resInf.List(context.Background(), v1.ListOptions{})
-		if err != nil {
-			fmt.Printf("err while fetching resource %v error %v\n", resource, err)
+		seen[gvr.Resource] = true
+		if !listable(cached, gvr.Resource, conf) {
+			conf.Emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: gvr.Resource.String(), Reason: "not listable"})
 			continue
 		}
-		for _, obj := range objList.Items {
-			namespace := obj.GetNamespace()
-			if len(obj.GetNamespace()) == 0 {
-				namespace = "default"
+		preferred := true
+		if version == gvk.Version {
+			// versions overridden by PreferredVersionOverrides are preferred over the one of the cluster
+			if preferredMapping, err := mapper.RESTMapping(gvk.GroupKind()); err == nil {
+				preferred = preferredMapping.Resource == gvr.Resource
 			}
-			if Contains(namespace, conf.IgnoreNamespaces) {
-				continue
+		}
+		c.warnings.setKind(gvr.Resource, gvr.GroupVersionKind)
+		targets = append(targets, fetchTarget{
+			resource:   gvr.Resource,
+			kind:       gvr.GroupVersionKind,
+			namespaced: gvr.Scope.Name() == meta.RESTScopeNameNamespace,
+			options:    kindOptions,
+			preferred:  preferred,
+		})
+	}
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].preferred && !targets[j].preferred
+	})
+	return targets, nil
+}
+
+// fetchResource lists the objects of resource, a resource which failed to list is skipped and returned as such.
+// A resource whose objects were capped by its limit, see Config.MaxObjectsPerResource, is returned as truncated.
+// An error is only returned when the scan should stop, ie when ctx is done or authentication failed.
+func (c *Cluster) fetchResource(ctx context.Context, target fetchTarget, conf *Config, emit func(ScanEvent)) ([]unstructured.Unstructured, *TruncatedResource, *SkippedResource, error) {
+	resource, namespaced, kindOptions := target.resource, target.namespaced, target.options
+	if ctx.Err() != nil {
+		return nil, nil, nil, ctx.Err()
+	}
+	emit(ScanEvent{Type: ScanEventResourceStarted, Resource: resource.String()})
+	spanCtx, span := conf.StartSpan(ctx, SpanFetchResource, attribute.String("k8s.resource", resource.String()))
+	limit := conf.objectLimit(kindOptions)
+	items, unlisted, err := c.listSelectedNamespaces(spanCtx, resource, namespaced, limit, conf)
+	if k8sErrors.IsUnauthorized(err) && ctx.Err() == nil {
+		// credentials issued by exec plugins may have expired during a long scan
+		if err = c.refreshCredentials(); err == nil {
+			items, unlisted, err = c.listSelectedNamespaces(spanCtx, resource, namespaced, limit, conf)
+		}
+		if k8sErrors.IsUnauthorized(err) {
+			err = fmt.Errorf("authentication to cluster %s failed while listing %s: %w", c.name, resource, err)
+			emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: resource.String(), Reason: err.Error()})
+			endSpan(span, err)
+			return nil, nil, &SkippedResource{Resource: resource.String(), Failure: FetchUnauthorized, Message: err.Error()}, err
+		}
+	}
+	if err != nil && ctx.Err() != nil {
+		endSpan(span, err)
+		return nil, nil, nil, ctx.Err()
+	}
+	if err != nil {
+		failure := fetchFailureOf(err)
+		err = c.describeListError(err)
+		emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: resource.String(), Reason: err.Error()})
+		endSpan(span, err)
+		return nil, nil, &SkippedResource{Resource: resource.String(), Failure: failure, Message: err.Error()}, nil
+	}
+	var truncated *TruncatedResource
+	if unlisted != 0 {
+		truncated = &TruncatedResource{Resource: resource.String(), Fetched: len(items)}
+		if unlisted > 0 {
+			truncated.Total = int64(len(items)) + unlisted
+		}
+	}
+	var objs []unstructured.Unstructured
+	for _, obj := range items {
+		if !conf.namespaceSelected(obj.GetNamespace(), namespaced) {
+			continue
+		}
+		if conf.IgnoreOwnedObjects && ownedByController(&obj) {
+			continue
+		}
+		if kindOptions.MetadataOnly {
+			obj = metadataOnly(obj)
+		}
+		objs = append(objs, obj)
+	}
+	emit(ScanEvent{Type: ScanEventResourceFinished, Resource: resource.String(), Count: len(objs)})
+	span.SetAttributes(attribute.Int("k8s.object_count", len(objs)))
+	endSpan(span, nil)
+	return objs, truncated, nil, nil
+}
+
+// listSelectedNamespaces lists namespaced resources in each of the namespaces selected by name or label rather than
+// across the cluster, so that only permissions on those namespaces are required. Namespaces selected by patterns can
+// only be listed across the cluster. At most limit objects are listed unless it is zero, the approximate number of
+// objects left unlisted is returned as by listLimited
+func (c *Cluster) listSelectedNamespaces(ctx context.Context, resource schema.GroupVersionResource, namespaced bool, limit int64, conf *Config) ([]unstructured.Unstructured, int64, error) {
+	selected, ok := conf.listedNamespaces()
+	if !namespaced || !ok {
+		return c.listLimited(ctx, resource, "", limit, conf)
+	}
+	var items []unstructured.Unstructured
+	for i, namespace := range selected {
+		remaining := int64(0)
+		if limit > 0 {
+			remaining = limit - int64(len(items))
+		}
+		namespaceItems, unlisted, err := c.listLimited(ctx, resource, namespace, remaining, conf)
+		if err != nil {
+			return nil, 0, err
+		}
+		for i := range namespaceItems {
+			// objects are in the namespace they were listed in even if the api server leaves it out
+			if len(namespaceItems[i].GetNamespace()) == 0 {
+				namespaceItems[i].SetNamespace(namespace)
 			}
-			if len(conf.SelectNamespaces) > 0 && !Contains(namespace, conf.SelectNamespaces) {
-				continue
+		}
+		items = append(items, namespaceItems...)
+		if limit > 0 && int64(len(items)) >= limit {
+			if i < len(selected)-1 {
+				// the objects of the namespaces left are not even counted
+				unlisted = -1
 			}
-			objs = append(objs, obj)
+			return items, unlisted, nil
 		}
 	}
-	return objs
+	return items, 0, nil
+}
+
+// listable returns true if the resource is not a subresource and advertises the list verb in discovery, resources
+// matched by ExcludeResources are never listed and those matched by IncludeResources always are. Resources are
+// assumed to be listable when discovery is not available
+func listable(cached discovery.CachedDiscoveryInterface, resource schema.GroupVersionResource, conf *Config) bool {
+	if matchesResourceFilters(resource, conf.ExcludeResources) {
+		return false
+	}
+	if matchesResourceFilters(resource, conf.IncludeResources) {
+		return true
+	}
+	if strings.Contains(resource.Resource, "/") {
+		return false
+	}
+	resourceList, err := cached.ServerResourcesForGroupVersion(resource.GroupVersion().String())
+	if err != nil {
+		return true
+	}
+	for _, apiResource := range resourceList.APIResources {
+		if apiResource.Name == resource.Resource {
+			return sets.New(apiResource.Verbs...).Has("list")
+		}
+	}
+	return true
+}
+
+// describeListError names the impersonated user in errors caused by missing permissions, as the permissions
+// of that user rather than of the kubeconfig user are checked
+func (c *Cluster) describeListError(err error) error {
+	if k8sErrors.IsForbidden(err) && len(c.restConfig.Impersonate.UserName) > 0 {
+		return fmt.Errorf("forbidden as impersonated user %s: %w", c.restConfig.Impersonate.UserName, err)
+	}
+	return err
+}
+
+// namespaceSelected applies the namespace filters to objects of namespaced resources, objects of cluster scoped
+// resources are not in any namespace and always selected
+func (c *Config) namespaceSelected(namespace string, namespaced bool) bool {
+	if !namespaced {
+		return true
+	}
+	if Contains(namespace, c.IgnoreNamespaces) {
+		return false
+	}
+	if len(c.SelectNamespaces) > 0 && !Contains(namespace, c.SelectNamespaces) {
+		return false
+	}
+	return c.labeledNamespaces == nil || c.labeledNamespaces.Has(namespace)
 }
@@ -0,0 +1,205 @@
+package pkg
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+)
+
+// DumpDocumentError is a document of a cluster dump which could not be parsed, documents are counted from 1
+type DumpDocumentError struct {
+	Index int
+	Err   error
+}
+
+func (e *DumpDocumentError) Error() string {
+	return fmt.Sprintf("document %d: %v", e.Index, e.Err)
+}
+
+func (e *DumpDocumentError) Unwrap() error {
+	return e.Err
+}
+
+// ReadClusterDump parses the objects of a cluster dump, eg the output of kubectl get -o yaml or -o json, from r. The
+// documents of the dump may be objects or lists whose items are unwrapped. Documents which cannot be parsed are
+// returned as DumpDocumentError and do not stop the others from being parsed, an error is only returned if r fails
+func ReadClusterDump(r io.Reader) ([]unstructured.Unstructured, []*DumpDocumentError, error) {
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(r))
+	var objs []unstructured.Unstructured
+	var docErrs []*DumpDocumentError
+	for index := 1; ; index++ {
+		doc, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return objs, docErrs, nil
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		docObjs, err := parseDumpDocument(doc)
+		if err != nil {
+			docErrs = append(docErrs, &DumpDocumentError{Index: index, Err: err})
+			continue
+		}
+		objs = append(objs, docObjs...)
+	}
+}
+
+// parseDumpDocument returns the object of a yaml or json document, or the items of the list it holds
+func parseDumpDocument(doc []byte) ([]unstructured.Unstructured, error) {
+	var content map[string]interface{}
+	if err := yaml.Unmarshal(doc, &content); err != nil {
+		return nil, err
+	}
+	if len(content) == 0 {
+		// empty documents, eg between two separators or holding only comments, have no objects
+		return nil, nil
+	}
+	obj := unstructured.Unstructured{Object: content}
+	if !obj.IsList() {
+		if len(obj.GetKind()) == 0 || len(obj.GetAPIVersion()) == 0 {
+			return nil, errors.New("object has no apiVersion or kind")
+		}
+		return []unstructured.Unstructured{obj}, nil
+	}
+	list, err := obj.ToList()
+	if err != nil {
+		return nil, err
+	}
+	for i, item := range list.Items {
+		if len(item.GetKind()) == 0 || len(item.GetAPIVersion()) == 0 {
+			return nil, fmt.Errorf("item %d of %s has no apiVersion or kind", i, obj.GetKind())
+		}
+	}
+	return list.Items, nil
+}
+
+// LoadClusterDump reads the cluster dump at path, - reads it from stdin, and applies the filters of conf to its
+// objects, see FilterObjects. The documents which could not be parsed are reported as skipped in the summary
+func LoadClusterDump(path string, conf *Config) ([]unstructured.Unstructured, *FetchSummary, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not open cluster dump: %w", err)
+		}
+		defer file.Close()
+		r = file
+	}
+	objs, docErrs, err := ReadClusterDump(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read cluster dump %s: %w", path, err)
+	}
+	objs, summary, err := FilterObjects(objs, conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, docErr := range docErrs {
+		summary.Skipped = append(summary.Skipped, SkippedResource{
+			Resource: fmt.Sprintf("%s document %d", path, docErr.Index),
+			Failure:  FetchUnparseable,
+			Message:  docErr.Err.Error(),
+		})
+	}
+	return objs, summary, nil
+}
+
+// FilterObjects applies the filters of conf to objects which were not fetched from a live cluster, eg read from a
+// cluster dump, like FetchK8sObjects does to the objects it lists. Objects without a namespace are taken to be cluster
+// scoped and namespaces are selected by label from the Namespace objects among objs
+func FilterObjects(objs []unstructured.Unstructured, conf *Config) ([]unstructured.Unstructured, *FetchSummary, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, nil, err
+	}
+	labelSelector, _ := labels.Parse(conf.LabelSelector)
+	fieldSelector, _ := fields.ParseSelector(conf.FieldSelector)
+	conf = conf.withObjectNamespaceLabels(objs).withTerminatingNamespaces(objs)
+	summary := &FetchSummary{}
+	now := time.Now()
+	kinds := sets.New[string]()
+	// objects exported in several versions or groups are kept once
+	kept := map[types.UID]bool{}
+	var filtered []unstructured.Unstructured
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if len(conf.kindSkipReason(gvk)) > 0 || conf.kindFetchOptions(gvk.Kind).Skip {
+			continue
+		}
+		namespaced := len(obj.GetNamespace()) > 0
+		if !conf.namespaceSelected(obj.GetNamespace(), namespaced) {
+			continue
+		}
+		if !labelSelector.Matches(labels.Set(obj.GetLabels())) || !matchesFieldSelector(fieldSelector, obj) {
+			continue
+		}
+		if conf.IgnoreOwnedObjects && ownedByController(&obj) {
+			continue
+		}
+		if uid := obj.GetUID(); len(uid) > 0 {
+			if kept[uid] {
+				continue
+			}
+			kept[uid] = true
+		}
+		kinds.Insert(gvk.String())
+		if filter := conf.ageExcluded(&obj, now); len(filter) > 0 {
+			summary.countAgeExcluded(filter)
+			continue
+		}
+		if conf.systemNamespaceExcluded(obj.GetNamespace()) {
+			summary.SystemNamespaceObjects++
+			continue
+		}
+		if conf.terminalObject(&obj) {
+			summary.TerminalObjects++
+			continue
+		}
+		if conf.ignoredByAnnotation(&obj) {
+			summary.Suppressed = append(summary.Suppressed, NewSuppressedObject(&obj, nil))
+			continue
+		}
+		obj = *obj.DeepCopy()
+		if !conf.KeepManagedFields {
+			pruneMetadata(&obj, conf.UseLastApplied)
+		}
+		if conf.kindFetchOptions(gvk.Kind).MetadataOnly {
+			obj = metadataOnly(obj)
+		}
+		filtered = append(filtered, obj)
+	}
+	summary.Listed = kinds.Len()
+	return filtered, summary, nil
+}
+
+// withObjectNamespaceLabels returns conf with the namespaces selected by NamespaceLabelSelector resolved from the
+// Namespace objects among objs
+func (c *Config) withObjectNamespaceLabels(objs []unstructured.Unstructured) *Config {
+	if len(c.NamespaceLabelSelector) == 0 {
+		return c
+	}
+	selector, _ := labels.Parse(c.NamespaceLabelSelector)
+	resolved := *c
+	resolved.labeledNamespaces = sets.New[string]()
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if gvk.Group == "" && gvk.Kind == "Namespace" && selector.Matches(labels.Set(obj.GetLabels())) {
+			resolved.labeledNamespaces.Insert(obj.GetName())
+		}
+	}
+	if resolved.labeledNamespaces.Len() == 0 {
+		kLog.Warn(fmt.Sprintf("no Namespace objects matching namespace selector %q, namespaced objects are skipped", c.NamespaceLabelSelector))
+	}
+	return &resolved
+}
@@ -0,0 +1,143 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const clusterDumpYaml = `---
+apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: shop
+    labels:
+      environment: prod
+- apiVersion: v1
+  kind: Namespace
+  metadata:
+    name: sandbox
+- apiVersion: extensions/v1beta1
+  kind: Ingress
+  metadata:
+    name: web
+    namespace: shop
+    uid: ingress-shop-web
+    labels:
+      app: web
+---
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata: {name: web, namespace: shop, uid: ingress-shop-web, labels: {app: web}}
+---
+this: [is not
+---
+{"apiVersion": "v1", "kind": "ConfigMapList", "items": [
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings", "namespace": "sandbox"}},
+  {"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "settings", "namespace": "shop", "labels": {"app": "web"}}}
+]}
+---
+metadata:
+  name: orphan
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: viewer
+`
+
+func describeObjects(objs []unstructured.Unstructured) []string {
+	var got []string
+	for _, obj := range objs {
+		got = append(got, obj.GetAPIVersion()+" "+obj.GetKind()+" "+obj.GetNamespace()+"/"+obj.GetName())
+	}
+	return got
+}
+
+func TestReadClusterDump(t *testing.T) {
+	objs, docErrs, err := ReadClusterDump(strings.NewReader(clusterDumpYaml))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"v1 Namespace /shop",
+		"v1 Namespace /sandbox",
+		"extensions/v1beta1 Ingress shop/web",
+		"networking.k8s.io/v1 Ingress shop/web",
+		"v1 ConfigMap sandbox/settings",
+		"v1 ConfigMap shop/settings",
+		"rbac.authorization.k8s.io/v1 ClusterRole /viewer",
+	}, describeObjects(objs))
+	var indexes []int
+	for _, docErr := range docErrs {
+		indexes = append(indexes, docErr.Index)
+	}
+	assert.Equal(t, []int{3, 5}, indexes)
+	assert.ErrorContains(t, docErrs[1], "document 5: object has no apiVersion or kind")
+}
+
+func TestFilterObjects(t *testing.T) {
+	objs, _, err := ReadClusterDump(strings.NewReader(clusterDumpYaml))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name string
+		conf *Config
+		want []string
+	}{
+		{
+			name: "objects exported in several versions are kept once",
+			conf: &Config{},
+			want: []string{"v1 Namespace /shop", "v1 Namespace /sandbox", "extensions/v1beta1 Ingress shop/web", "v1 ConfigMap sandbox/settings", "v1 ConfigMap shop/settings", "rbac.authorization.k8s.io/v1 ClusterRole /viewer"},
+		},
+		{
+			name: "kind and api group filters",
+			conf: &Config{SelectKinds: []string{"ConfigMap", "Ingress"}, IgnoreAPIGroups: []string{"extensions"}},
+			want: []string{"networking.k8s.io/v1 Ingress shop/web", "v1 ConfigMap sandbox/settings", "v1 ConfigMap shop/settings"},
+		},
+		{
+			name: "namespace filters keep cluster scoped objects",
+			conf: &Config{IgnoreNamespaces: []string{"shop"}},
+			want: []string{"v1 Namespace /shop", "v1 Namespace /sandbox", "v1 ConfigMap sandbox/settings", "rbac.authorization.k8s.io/v1 ClusterRole /viewer"},
+		},
+		{
+			name: "label selector",
+			conf: &Config{LabelSelector: "app=web"},
+			want: []string{"extensions/v1beta1 Ingress shop/web", "v1 ConfigMap shop/settings"},
+		},
+		{
+			name: "namespaces selected by the labels of the namespace objects",
+			conf: &Config{NamespaceLabelSelector: "environment=prod", SelectKinds: []string{"ConfigMap"}},
+			want: []string{"v1 ConfigMap shop/settings"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, summary, err := FilterObjects(objs, tt.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, describeObjects(filtered))
+			assert.False(t, summary.Partial())
+		})
+	}
+}
+
+func TestLoadClusterDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(clusterDumpYaml), 0o600))
+
+	objs, summary, err := LoadClusterDump(path, &Config{SelectKinds: []string{"ClusterRole"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rbac.authorization.k8s.io/v1 ClusterRole /viewer"}, describeObjects(objs))
+	assert.True(t, summary.Partial())
+	assert.Equal(t, path+" document 3", summary.Skipped[0].Resource)
+	assert.Equal(t, FetchUnparseable, summary.Skipped[0].Failure)
+	assert.Equal(t, path+" document 5", summary.Skipped[1].Resource)
+
+	_, _, err = LoadClusterDump(filepath.Join(t.TempDir(), "missing.yaml"), &Config{})
+	assert.ErrorContains(t, err, "could not open cluster dump")
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_clusterScoped(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	clusterRoleGvk := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	volumeGvk := schema.GroupVersionKind{Version: "v1", Kind: "PersistentVolume"}
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "default", "settings"))
+	server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", "settings"))
+	server.addObject(server.addResource(clusterRoleGvk, "clusterroles", false), newFakeObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "viewer"))
+	server.addObject(server.addResource(volumeGvk, "persistentvolumes", false), newFakeObject("v1", "PersistentVolume", "", "data"))
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{configMapGvk, clusterRoleGvk, volumeGvk}
+
+	tests := []struct {
+		name string
+		conf *Config
+		want []string
+	}{
+		{
+			name: "ignoring default keeps cluster scoped objects",
+			conf: &Config{IgnoreNamespaces: []string{"default"}},
+			want: []string{"ConfigMap shop/settings", "ClusterRole /viewer", "PersistentVolume /data"},
+		},
+		{
+			name: "selecting default does not select cluster scoped objects by their empty namespace",
+			conf: &Config{SelectNamespaces: []string{"default"}},
+			want: []string{"ConfigMap default/settings", "ClusterRole /viewer", "PersistentVolume /data"},
+		},
+		{
+			name: "selecting a pattern",
+			conf: &Config{SelectNamespaces: []string{"sh*"}},
+			want: []string{"ConfigMap shop/settings", "ClusterRole /viewer", "PersistentVolume /data"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Concurrency = 1
+			objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, tt.conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind()+" "+obj.GetNamespace()+"/"+obj.GetName())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_namespaceSelected_clusterScoped(t *testing.T) {
+	conf := &Config{SelectNamespaces: []string{"shop"}, IgnoreNamespaces: []string{"default"}}
+	assert.True(t, conf.namespaceSelected("", false))
+	assert.False(t, conf.namespaceSelected("", true))
+	assert.False(t, conf.namespaceSelected("default", true))
+	assert.True(t, conf.namespaceSelected("shop", true))
+}
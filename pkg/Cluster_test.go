@@ -1,7 +1,31 @@
 package pkg
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
 )
 
 func TestCluster_ServerVersion(t *testing.T) {
@@ -11,18 +35,21 @@ func TestCluster_ServerVersion(t *testing.T) {
 		wantErr bool
 	}{
 		{
-			name: "cluster version",
+			name:    "cluster version",
 			wantErr: false,
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			c := NewCluster("", "")
+			c, err := NewCluster("", "", nil)
+			if err != nil {
+				t.Skipf("no cluster available: %v", err)
+			}
 			config := NewDefaultConfig()
 			config.SelectKinds = []string{"deployment"}
 			config.TargetKubernetesVersion = "1.16"
 			config.SelectNamespaces = []string{"prod"}
-			got, err := c.ServerVersion()
+			got, err := c.ServerVersion(context.Background())
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ServerVersion() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -32,4 +59,699 @@ func TestCluster_ServerVersion(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// writeKubeconfig writes a kubeconfig with a single context named after the cluster pointing to server
+func writeKubeconfig(t *testing.T, path, name, server string) {
+	contents := `apiVersion: v1
+kind: Config
+clusters:
+- name: ` + name + `
+  cluster:
+    server: ` + server + `
+contexts:
+- name: ` + name + `
+  context:
+    cluster: ` + name + `
+current-context: ` + name + `
+`
+	assert.NoError(t, os.WriteFile(path, []byte(contents), 0600))
+}
+
+func TestNewCluster_errors(t *testing.T) {
+	server := newFakeApiServer(t)
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	writeKubeconfig(t, kubeconfig, "fake", server.URL)
+
+	cluster, err := NewCluster(kubeconfig, "", nil)
+	if assert.NoError(t, err) {
+		version, err := cluster.ServerVersion(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, "1.27", version)
+	}
+
+	_, err = NewCluster(kubeconfig, "missing", nil)
+	assert.ErrorContains(t, err, kubeconfig)
+
+	_, err = NewCluster(filepath.Join(t.TempDir(), "missing"), "", nil)
+	assert.Error(t, err)
+}
+
+func TestNewClusterFromToken(t *testing.T) {
+	server := newFakeApiServer(t)
+	tlsServer := httptest.NewTLSServer(server)
+	t.Cleanup(tlsServer.Close)
+	caData := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw})
+
+	tests := []struct {
+		name     string
+		host     string
+		caData   []byte
+		insecure bool
+		wantErr  bool
+	}{
+		{name: "ca data", host: tlsServer.URL, caData: caData},
+		{name: "insecure", host: tlsServer.URL, insecure: true},
+		{name: "missing host", caData: caData, wantErr: true},
+		{name: "neither ca data nor insecure", host: tlsServer.URL, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := NewClusterFromToken(tt.host, "s3cr3t", tt.caData, tt.insecure)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			version, err := cluster.ServerVersion(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "1.27", version)
+			requests := server.recorded()
+			assert.Equal(t, "Bearer s3cr3t", requests[len(requests)-1].Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestNewCluster_kubeconfigList(t *testing.T) {
+	production := newFakeApiServer(t)
+	staging := newFakeApiServer(t)
+	staging.version = "1.28"
+	dir := t.TempDir()
+	first := filepath.Join(dir, "config")
+	second := filepath.Join(dir, "staging")
+	writeKubeconfig(t, first, "production", production.URL)
+	writeKubeconfig(t, second, "staging", staging.URL)
+	t.Setenv("KUBECONFIG", first+string(filepath.ListSeparator)+second)
+
+	tests := []struct {
+		name        string
+		kubeconfig  string
+		kubecontext string
+		want        string
+		wantErr     bool
+	}{
+		{name: "current context of first file", want: "1.27"},
+		{name: "context of second file", kubecontext: "staging", want: "1.28"},
+		{name: "explicit kubeconfig wins", kubeconfig: second, want: "1.28"},
+		{name: "context missing in explicit kubeconfig", kubeconfig: second, kubecontext: "production", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := NewCluster(tt.kubeconfig, tt.kubecontext, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			if assert.NoError(t, err) {
+				version, err := cluster.ServerVersion(context.Background())
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, version)
+			}
+		})
+	}
+}
+
+func TestNewCluster_impersonation(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	server.addResource(deploymentGvk, "deployments", true)
+	var users, groups []string
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/apis/apps/v1/deployments" {
+			return false
+		}
+		users = append(users, r.Header.Get("Impersonate-User"))
+		groups = append(groups, r.Header.Values("Impersonate-Group")...)
+		writeStatus(w, http.StatusForbidden, "Forbidden", "deployments.apps is forbidden")
+		return true
+	}
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	writeKubeconfig(t, kubeconfig, "fake", server.URL)
+
+	var reasons []string
+	conf := &Config{
+		ImpersonateUser:   "ci-bot",
+		ImpersonateGroups: []string{"auditors", "viewers"},
+		EventSink: func(event ScanEvent) {
+			if event.Type == ScanEventResourceSkipped {
+				reasons = append(reasons, event.Reason)
+			}
+		},
+	}
+	cluster, err := NewCluster(kubeconfig, "", conf)
+	if !assert.NoError(t, err) {
+		return
+	}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{deploymentGvk}, conf)
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+	assert.Equal(t, []string{"ci-bot"}, users)
+	assert.Equal(t, []string{"auditors", "viewers"}, groups)
+	if assert.Len(t, reasons, 1) {
+		assert.Contains(t, reasons[0], "forbidden as impersonated user ci-bot")
+	}
+}
+
+func TestNewClusterFromEnvOrConfig_clientOptions(t *testing.T) {
+	server := newFakeApiServer(t)
+	limiter := flowcontrol.NewFakeAlwaysRateLimiter()
+	tests := []struct {
+		name            string
+		conf            *Config
+		wantQPS         float32
+		wantBurst       int
+		wantRateLimiter flowcontrol.RateLimiter
+	}{
+		{name: "nil config", wantQPS: DefaultClientQPS, wantBurst: DefaultClientBurst},
+		{name: "unset limits", conf: &Config{}, wantQPS: DefaultClientQPS, wantBurst: DefaultClientBurst},
+		{name: "configured limits", conf: &Config{QPS: 20, Burst: 40}, wantQPS: 20, wantBurst: 40},
+		{name: "custom rate limiter", conf: &Config{RateLimiter: limiter}, wantQPS: DefaultClientQPS, wantBurst: DefaultClientBurst, wantRateLimiter: limiter},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := NewClusterFromEnvOrConfig(server.restConfig(), ClusterOptions{}, tt.conf)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tt.wantQPS, cluster.restConfig.QPS)
+			assert.Equal(t, tt.wantBurst, cluster.restConfig.Burst)
+			assert.Equal(t, tt.wantRateLimiter, cluster.restConfig.RateLimiter)
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_cancelled(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	server.addObject(server.addResource(configMapGvk, "configmaps", true), newFakeObject("v1", "ConfigMap", "shop", "cart"))
+	cluster := server.cluster(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf := &Config{
+		IncludeTerminalObjects: true,
+		Concurrency:            1,
+		// the scan is cancelled as soon as the first resource has been listed
+		EventSink: func(event ScanEvent) {
+			if event.Type == ScanEventResourceFinished {
+				cancel()
+			}
+		},
+	}
+	objs, _, err := cluster.FetchK8sObjects(ctx, []schema.GroupVersionKind{deploymentGvk, configMapGvk}, conf)
+	assert.ErrorIs(t, err, context.Canceled)
+	if assert.Len(t, objs, 1) {
+		assert.Equal(t, "Deployment", objs[0].GetKind())
+	}
+	assert.Equal(t, []string{"/apis/apps/v1/deployments"}, server.resourceRequests())
+
+	_, err = cluster.ServerVersion(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// newConnectProxy starts a proxy tunnelling every CONNECT request to target regardless of the requested host,
+// so that clients can only reach target through the proxy, and returns the number of tunnels opened
+func newConnectProxy(t *testing.T, target string) (*httptest.Server, *int32) {
+	var connects int32
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "only CONNECT is supported", http.StatusMethodNotAllowed)
+			return
+		}
+		atomic.AddInt32(&connects, 1)
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			_ = upstream.Close()
+			return
+		}
+		go func() {
+			_, _ = io.Copy(upstream, conn)
+			_ = upstream.Close()
+		}()
+		_, _ = io.Copy(conn, upstream)
+		_ = conn.Close()
+	}))
+	t.Cleanup(proxy.Close)
+	return proxy, &connects
+}
+
+func TestNewCluster_proxy(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	tlsServer := httptest.NewTLSServer(server)
+	t.Cleanup(tlsServer.Close)
+	kubeconfigProxy, kubeconfigConnects := newConnectProxy(t, tlsServer.Listener.Addr().String())
+	flagProxy, flagConnects := newConnectProxy(t, tlsServer.Listener.Addr().String())
+
+	// the api server host does not resolve, it is only reachable through the proxies
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	contents := `apiVersion: v1
+kind: Config
+clusters:
+- name: corp
+  cluster:
+    server: https://kube.corp.invalid
+    insecure-skip-tls-verify: true
+    proxy-url: ` + kubeconfigProxy.URL + `
+contexts:
+- name: corp
+  context:
+    cluster: corp
+current-context: corp
+`
+	assert.NoError(t, os.WriteFile(kubeconfig, []byte(contents), 0600))
+
+	tests := []struct {
+		name     string
+		conf     *Config
+		connects *int32
+		unused   *int32
+	}{
+		{name: "kubeconfig proxy-url", conf: &Config{}, connects: kubeconfigConnects, unused: flagConnects},
+		{name: "proxy url overrides kubeconfig", conf: &Config{ProxyURL: flagProxy.URL}, connects: flagConnects, unused: kubeconfigConnects},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(kubeconfigConnects, 0)
+			atomic.StoreInt32(flagConnects, 0)
+			cluster, err := NewCluster(kubeconfig, "", tt.conf)
+			if !assert.NoError(t, err) {
+				return
+			}
+			version, err := cluster.ServerVersion(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "1.27", version)
+			objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{deploymentGvk}, tt.conf)
+			assert.NoError(t, err)
+			assert.Len(t, objs, 1)
+			assert.NotZero(t, atomic.LoadInt32(tt.connects))
+			assert.Zero(t, atomic.LoadInt32(tt.unused))
+		})
+	}
+
+	_, err := NewCluster(kubeconfig, "", &Config{ProxyURL: "proxy.corp:3128"})
+	assert.ErrorContains(t, err, "invalid proxy url")
+}
+
+// newSelfSignedCertificate returns a PEM encoded self signed CA certificate unrelated to the test servers
+func newSelfSignedCertificate(t *testing.T) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestNewCluster_tlsOptions(t *testing.T) {
+	server := newFakeApiServer(t)
+	tlsServer := httptest.NewTLSServer(server)
+	t.Cleanup(tlsServer.Close)
+
+	dir := t.TempDir()
+	caFile := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw}), 0600))
+	otherCaFile := filepath.Join(dir, "other-ca.crt")
+	assert.NoError(t, os.WriteFile(otherCaFile, newSelfSignedCertificate(t), 0600))
+	malformedCaFile := filepath.Join(dir, "malformed-ca.crt")
+	assert.NoError(t, os.WriteFile(malformedCaFile, []byte("not a certificate"), 0600))
+
+	// the kubeconfig does not know the self signed certificate of the server
+	kubeconfig := filepath.Join(dir, "config")
+	writeKubeconfig(t, kubeconfig, "kind", tlsServer.URL)
+	insecureKubeconfig := filepath.Join(dir, "insecure-config")
+	writeKubeconfig(t, insecureKubeconfig, "kind", tlsServer.URL)
+	contents, err := os.ReadFile(insecureKubeconfig)
+	assert.NoError(t, err)
+	contents = []byte(strings.Replace(string(contents), "    server:", "    insecure-skip-tls-verify: true\n    server:", 1))
+	assert.NoError(t, os.WriteFile(insecureKubeconfig, contents, 0600))
+
+	tests := []struct {
+		name          string
+		kubeconfig    string
+		conf          *Config
+		wantErr       string
+		wantVerifyErr bool
+	}{
+		{name: "unknown certificate", kubeconfig: kubeconfig, conf: &Config{}, wantVerifyErr: true},
+		{name: "insecure skip tls verify", kubeconfig: kubeconfig, conf: &Config{InsecureSkipTLSVerify: true}},
+		{name: "certificate authority file", kubeconfig: kubeconfig, conf: &Config{CertificateAuthorityFile: caFile}},
+		{name: "certificate authority file overrides insecure kubeconfig", kubeconfig: insecureKubeconfig, conf: &Config{CertificateAuthorityFile: otherCaFile}, wantVerifyErr: true},
+		{name: "malformed certificate authority file", kubeconfig: kubeconfig, conf: &Config{CertificateAuthorityFile: malformedCaFile}, wantErr: "does not contain any PEM encoded certificate"},
+		{name: "missing certificate authority file", kubeconfig: kubeconfig, conf: &Config{CertificateAuthorityFile: filepath.Join(dir, "missing.crt")}, wantErr: "failed to read certificate authority"},
+		{name: "both options", kubeconfig: kubeconfig, conf: &Config{InsecureSkipTLSVerify: true, CertificateAuthorityFile: caFile}, wantErr: "cannot be used together"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := NewCluster(tt.kubeconfig, "", tt.conf)
+			if len(tt.wantErr) > 0 {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			_, err = cluster.ServerVersion(context.Background())
+			if tt.wantVerifyErr {
+				assert.ErrorContains(t, err, "certificate")
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	cluster, err := NewClusterFromEnvOrConfig(&rest.Config{Host: tlsServer.URL}, ClusterOptions{}, &Config{CertificateAuthorityFile: caFile})
+	if assert.NoError(t, err) {
+		_, err = cluster.ServerVersion(context.Background())
+		assert.NoError(t, err)
+	}
+}
+
+func TestNewClusterFromKubeconfigBytes(t *testing.T) {
+	server := newFakeApiServer(t)
+	path := filepath.Join(t.TempDir(), "config")
+	writeKubeconfig(t, path, "fake", server.URL)
+	kubeconfig, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name        string
+		kubeconfig  []byte
+		contextName string
+		wantErr     string
+	}{
+		{name: "current context", kubeconfig: kubeconfig},
+		{name: "explicit context", kubeconfig: kubeconfig, contextName: "fake"},
+		{name: "unknown context", kubeconfig: kubeconfig, contextName: "missing", wantErr: "missing"},
+		{name: "empty kubeconfig", kubeconfig: []byte("\n"), wantErr: "kubeconfig is empty"},
+		{name: "invalid kubeconfig", kubeconfig: []byte("clusters: {"), wantErr: "failed to parse kubeconfig"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cluster, err := NewClusterFromKubeconfigBytes(tt.kubeconfig, tt.contextName, &Config{QPS: 10})
+			if len(tt.wantErr) > 0 {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, float32(10), cluster.restConfig.QPS)
+			version, err := cluster.ServerVersion(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, "1.27", version)
+		})
+	}
+}
+
+func TestNewClusterFromEnvOrConfig(t *testing.T) {
+	localServer := newFakeApiServer(t)
+	localServer.version = "1.26"
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	writeKubeconfig(t, kubeconfig, "local", localServer.URL)
+	server := newFakeApiServer(t)
+
+	tests := []struct {
+		name         string
+		localDevMode string
+		kubeconfig   string
+		opts         ClusterOptions
+		restConfig   *rest.Config
+		want         string
+		wantErr      string
+	}{
+		{name: "local dev mode with KUBECONFIG", localDevMode: "true", kubeconfig: kubeconfig, restConfig: server.restConfig(), want: "1.26"},
+		{name: "local dev mode with kubeconfig path", localDevMode: "true", opts: ClusterOptions{KubeconfigPath: kubeconfig}, want: "1.26"},
+		{name: "local dev mode without kubeconfig", localDevMode: "true", opts: ClusterOptions{KubeconfigPath: filepath.Join(t.TempDir(), "missing")}, wantErr: "failed to load kubeconfig"},
+		{name: "explicit rest config", kubeconfig: kubeconfig, restConfig: server.restConfig(), want: "1.27"},
+		{name: "in cluster fallback", kubeconfig: kubeconfig, wantErr: "InClusterConfig"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("USE_LOCAL_DEV_MODE", tt.localDevMode)
+			t.Setenv("KUBECONFIG", tt.kubeconfig)
+			t.Setenv("KUBERNETES_SERVICE_HOST", "")
+			cluster, err := NewClusterFromEnvOrConfig(tt.restConfig, tt.opts, nil)
+			if len(tt.wantErr) > 0 {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			version, err := cluster.ServerVersion(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, version)
+		})
+	}
+}
+
+func TestCluster_NameAndVersion(t *testing.T) {
+	server := newFakeApiServer(t)
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	writeKubeconfig(t, kubeconfig, "staging", server.URL)
+	unreachable := newFakeApiServer(t)
+	unreachable.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/version" {
+			return false
+		}
+		writeStatus(w, http.StatusServiceUnavailable, "ServiceUnavailable", "version unavailable")
+		return true
+	}
+
+	cluster, err := NewCluster(kubeconfig, "", nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "staging", cluster.Name())
+		assert.Equal(t, "1.27", cluster.Version())
+	}
+	cluster = server.cluster(t)
+	assert.Equal(t, server.URL, cluster.Name())
+	assert.Equal(t, "1.27", cluster.Version())
+	cluster = unreachable.cluster(t)
+	assert.Equal(t, unreachable.URL, cluster.Name())
+	assert.Empty(t, cluster.Version())
+}
+
+func TestNewClusterFromEnvOrConfig_serviceAccountFiles(t *testing.T) {
+	server := newFakeApiServer(t)
+	tlsServer := httptest.NewTLSServer(server)
+	t.Cleanup(tlsServer.Close)
+	host, port, err := net.SplitHostPort(tlsServer.Listener.Addr().String())
+	assert.NoError(t, err)
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("bound-token"), 0600))
+	rootCAFile := filepath.Join(dir, "ca.crt")
+	assert.NoError(t, os.WriteFile(rootCAFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: tlsServer.Certificate().Raw}), 0600))
+
+	tests := []struct {
+		name    string
+		opts    ClusterOptions
+		env     map[string]string
+		wantErr string
+	}{
+		{name: "cluster options", opts: ClusterOptions{TokenFile: tokenFile, RootCAFile: rootCAFile}},
+		{name: "environment", env: map[string]string{"SERVICE_ACCOUNT_TOKEN_FILE": tokenFile, "SERVICE_ACCOUNT_ROOT_CA_FILE": rootCAFile}},
+		{name: "missing token", opts: ClusterOptions{TokenFile: filepath.Join(dir, "missing"), RootCAFile: rootCAFile}, wantErr: "failed to read service account token"},
+		{name: "not in cluster", opts: ClusterOptions{TokenFile: tokenFile, RootCAFile: rootCAFile}, env: map[string]string{"KUBERNETES_SERVICE_HOST": ""}, wantErr: rest.ErrNotInCluster.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("USE_LOCAL_DEV_MODE", "")
+			t.Setenv("KUBERNETES_SERVICE_HOST", host)
+			t.Setenv("KUBERNETES_SERVICE_PORT", port)
+			t.Setenv("SERVICE_ACCOUNT_TOKEN_FILE", "")
+			t.Setenv("SERVICE_ACCOUNT_ROOT_CA_FILE", "")
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			cluster, err := NewClusterFromEnvOrConfig(nil, tt.opts, nil)
+			if len(tt.wantErr) > 0 {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, tokenFile, cluster.restConfig.BearerTokenFile)
+			assert.Empty(t, cluster.restConfig.BearerToken)
+			_, err = cluster.ServerVersion(context.Background())
+			assert.NoError(t, err)
+			requests := server.recorded()
+			assert.Equal(t, "Bearer bound-token", requests[len(requests)-1].Header.Get("Authorization"))
+		})
+	}
+}
+
+func TestNewCluster_lazyClients(t *testing.T) {
+	server := newFakeApiServer(t)
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	writeKubeconfig(t, kubeconfig, "fake", server.URL)
+
+	cluster, err := NewCluster(kubeconfig, "", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Empty(t, server.recorded(), "building a cluster must not contact the api server")
+	assert.Equal(t, "1.27", cluster.Version())
+	assert.NotEmpty(t, server.recorded())
+}
+
+// openFileCount returns the number of open file descriptors of the process, -1 when it cannot be determined
+func openFileCount() int {
+	fds, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(fds)
+}
+
+func TestCluster_Close(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	scan := func() {
+		cluster, err := NewClusterFromEnvOrConfig(server.restConfig(), ClusterOptions{}, nil)
+		if !assert.NoError(t, err) {
+			return
+		}
+		objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{deploymentGvk}, &Config{})
+		assert.NoError(t, err)
+		assert.Len(t, objs, 1)
+		cluster.Close()
+	}
+	// warm up the transport cache
+	scan()
+	goroutines, fds := runtime.NumGoroutine(), openFileCount()
+
+	for i := 0; i < 200; i++ {
+		scan()
+	}
+	// connections are torn down asynchronously
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= goroutines+5 && openFileCount() <= fds+5
+	}, 5*time.Second, 50*time.Millisecond, "goroutines %d -> %d, fds %d -> %d", goroutines, runtime.NumGoroutine(), fds, openFileCount())
+
+	// closing a cluster which was never used is a no-op
+	cluster, err := NewClusterFromEnvOrConfig(server.restConfig(), ClusterOptions{}, nil)
+	if assert.NoError(t, err) {
+		cluster.Close()
+	}
+}
+
+func newConcurrencyFakeApiServer(t testing.TB, latency time.Duration) (*fakeApiServer, []schema.GroupVersionKind) {
+	server := newFakeApiServer(t)
+	var gvks []schema.GroupVersionKind
+	for i := 0; i < 20; i++ {
+		gvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: fmt.Sprintf("Widget%02d", i)}
+		gvr := server.addResource(gvk, fmt.Sprintf("widget%02ds", i), true)
+		server.addObject(gvr, newFakeObject("example.com/v1", gvk.Kind, "shop", "cart"))
+		gvks = append(gvks, gvk)
+	}
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if strings.HasPrefix(r.URL.Path, "/apis/example.com/v1/") {
+			time.Sleep(latency)
+		}
+		return false
+	}
+	return server, gvks
+}
+
+func TestCluster_FetchK8sObjects_concurrency(t *testing.T) {
+	server, gvks := newConcurrencyFakeApiServer(t, 10*time.Millisecond)
+	var want []string
+	for _, gvk := range gvks {
+		want = append(want, gvk.Kind)
+	}
+	for _, concurrency := range []int{1, 4, 20} {
+		t.Run(fmt.Sprint(concurrency), func(t *testing.T) {
+			var finished int32
+			conf := &Config{
+				Concurrency: concurrency,
+				EventSink: func(event ScanEvent) {
+					if event.Type == ScanEventResourceFinished {
+						finished++
+					}
+				},
+			}
+			objs, _, err := server.cluster(t).FetchK8sObjects(context.Background(), gvks, conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind())
+			}
+			// objects are returned in the order of the requested kinds whatever the concurrency
+			assert.Equal(t, want, got)
+			assert.Equal(t, int32(len(gvks)), finished)
+		})
+	}
+}
+
+func BenchmarkCluster_FetchK8sObjects(b *testing.B) {
+	server, gvks := newConcurrencyFakeApiServer(b, 5*time.Millisecond)
+	for _, concurrency := range []int{1, DefaultConcurrency} {
+		b.Run(fmt.Sprintf("concurrency=%d", concurrency), func(b *testing.B) {
+			conf := &Config{Concurrency: concurrency}
+			for i := 0; i < b.N; i++ {
+				if _, _, err := server.cluster(b).FetchK8sObjects(context.Background(), gvks, conf); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_selectNamespaces(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	clusterRoleGvk := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	server.addResource(clusterRoleGvk, "clusterroles", false)
+	for _, ns := range []string{"shop", "web", "kube-system"} {
+		server.addObject(configMaps, newFakeObject("v1", "ConfigMap", ns, "settings"))
+	}
+	// listing across the cluster is forbidden
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/api/v1/configmaps" {
+			writeStatus(w, http.StatusForbidden, "Forbidden", `configmaps is forbidden: User "shop-admin" cannot list resource "configmaps" at the cluster scope`)
+			return true
+		}
+		return false
+	}
+	cluster := server.cluster(t)
+
+	conf := &Config{IncludeTerminalObjects: true, SelectNamespaces: []string{"web", "shop", "web"}, Concurrency: 1}
+	objs, summary, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk, clusterRoleGvk}, conf)
+	assert.NoError(t, err)
+	assert.False(t, summary.Partial())
+	var got []string
+	for _, obj := range objs {
+		got = append(got, obj.GetNamespace())
+	}
+	assert.Equal(t, []string{"shop", "web"}, got)
+	assert.Equal(t, []string{
+		"/api/v1/namespaces/shop/configmaps",
+		"/api/v1/namespaces/web/configmaps",
+		"/apis/rbac.authorization.k8s.io/v1/clusterroles",
+	}, server.resourceRequests())
+}
@@ -22,6 +22,18 @@ package pkg
 import (
 	"fmt"
 	"github.com/spf13/cobra"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/flowcontrol"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultClientQPS is the number of queries per second allowed to the api server when QPS is unset
+	DefaultClientQPS float32 = 50
+	// DefaultClientBurst is the burst allowed above DefaultClientQPS when Burst is unset
+	DefaultClientBurst = 100
 )
 
 // A Config object contains various configuration data for kubedd
@@ -54,7 +66,9 @@ type Config struct {
 	AdditionalSchemaLocations []string
 
 	// Strict tells kubedd whether to prohibit properties not in
-	// the schema. The API allows them, but kubectl does not
+	// the schema. The API allows them, but kubectl does not. They are
+	// reported as UnknownFields, custom resources are only strict when
+	// their CustomResourceDefinition does not preserve unknown fields
 	Strict bool
 
 	// IgnoreMissingSchemas tells kubedd whether to skip validation
@@ -77,29 +91,278 @@ type Config struct {
 	Quiet bool
 
 	// InsecureSkipTLSVerify controls whether to skip TLS certificate validation
-	// when retrieving schema content over HTTPS
+	// when retrieving schema content over HTTPS and connecting to the api server
 	InsecureSkipTLSVerify bool
 
+	// CertificateAuthorityFile is the PEM encoded CA bundle the api server certificate is verified with,
+	// it overrides the CA of the kubeconfig and cannot be combined with InsecureSkipTLSVerify
+	CertificateAuthorityFile string
+
 	// IgnoreKeysFromDeprecation is the list of keys to be skipped for depreciation check
 	IgnoreKeysFromDeprecation []string
 
 	// IgnoreKeysFromValidation is the list of keys to be skipped for validation check
 	IgnoreKeysFromValidation []string
 
-	// SelectNamespaces is the list of namespaces to be validated, by default all namespaces are validated
+	// SelectNamespaces is the list of namespaces to be validated, by default all namespaces are validated.
+	// Objects of cluster scoped resources are not in any namespace and are validated regardless
 	SelectNamespaces []string
 
-	// IgnoreNamespaces is the list of namespaces to be skipped for validation, by default none are skipped
+	// IgnoreNamespaces is the list of namespaces to be skipped for validation, by default none are skipped,
+	// cluster scoped objects are never skipped by it
 	IgnoreNamespaces []string
 
-	// SelectKinds is the list of kinds to be validated, by default all kinds are validated
+	// IncludeCustomResources is one of CustomResourcesAll, CustomResourcesOnlyBuiltin or CustomResourcesOnlyCRDs, by
+	// default all resources are scanned. With CustomResourcesOnlyCRDs the CustomResourceDefinitions are fetched as well
+	IncludeCustomResources string
+
+	// NamespaceLabelSelector restricts the namespaces scanned to those whose labels match it, eg environment=prod.
+	// The namespaces are resolved before the scan and filtered further by SelectNamespaces and IgnoreNamespaces, the
+	// name based filters alone apply when the user may not list namespaces
+	NamespaceLabelSelector string
+
+	// AllServedVersions makes Cluster.DiscoverGVKs return kinds in every version served by the cluster rather than
+	// in the preferred one only
+	AllServedVersions bool
+
+	// IncludeSystemNamespaces scans the namespaces of DefaultSystemNamespaces, which are skipped by default
+	IncludeSystemNamespaces bool
+
+	// IncludeTerminalObjects scans Pods which succeeded or failed, Jobs which completed or failed, and namespaces
+	// being deleted along with their objects, which are skipped by default
+	IncludeTerminalObjects bool
+
+	// SelectAPIGroups is the list of api groups to be validated, by default all groups are validated. The core group
+	// is selected by core or the empty string and groups may be patterns, eg *.istio.io. Resources of groups which
+	// are not selected, or ignored by IgnoreAPIGroups, are not listed at all; the kind filters, then the namespace
+	// filters, apply to the resources of the remaining groups
+	SelectAPIGroups []string
+
+	// IgnoreAPIGroups is the list of api groups to be skipped, it takes precedence over SelectAPIGroups
+	IgnoreAPIGroups []string
+
+	// SelectKinds is the list of kinds to be validated, by default all kinds are validated. Kinds may be qualified
+	// with their group, see KindFilter
 	SelectKinds []string
 
-	// IgnoreKinds is the list of kinds to be skipped for validation, by default none are skipped
+	// IncludeResources is the list of resources to be listed even if discovery does not advertise the list verb for
+	// them, entries are plural resource names optionally qualified with their group, eg allowlists.example.com
+	IncludeResources []string
+
+	// ExcludeResources is the list of resources never to be listed, it takes precedence over IncludeResources
+	ExcludeResources []string
+
+	// IgnoreOwnedObjects drops objects owned by a controller, eg the Pods of a ReplicaSet, so that only top-level
+	// objects are validated. Objects are dropped even if the kind of their controller is unknown
+	IgnoreOwnedObjects bool
+
+	// AnnotateRootOwner sets the RootOwner of the results of objects owned by a controller to their top-level
+	// controller, so that the findings of owned objects can be grouped under the objects they come from
+	AnnotateRootOwner bool
+
+	// KeepManagedFields keeps the managedFields and the last applied configuration annotation of objects fetched from
+	// the cluster, by default they are dropped to save memory as validation does not need them
+	KeepManagedFields bool
+
+	// UseLastApplied validates the last applied configuration annotation of objects fetched from the cluster rather
+	// than the live objects, whose fields defaulted or mutated by admission webhooks were never written by anyone.
+	// Objects without a usable annotation are validated live with a note in their result
+	UseLastApplied bool
+
+	// ShowServerWarnings prints the warnings returned by the api server while scanning as they come, they are
+	// collected and reported with the results of their objects either way, see Cluster.ServerWarnings
+	ShowServerWarnings bool
+
+	// CheckDeprecatedAPIUsage reads the deprecated apis clients requested from the metrics of the api server, so that
+	// clients which break on upgrade are found even if no object is stored in those apis
+	CheckDeprecatedAPIUsage bool
+
+	// VerifyCluster is the cluster the objects which pass schema validation are dry-run applied to, eg a staging
+	// cluster at the target version, so that admission webhooks and feature gates are accounted for. Nothing is ever
+	// persisted to it, see VerifyDryRun
+	VerifyCluster *Cluster
+
+	// NoAnnotations disables the opt-outs of objects with the IgnoreAnnotation and IgnoreRulesAnnotation annotations
+	NoAnnotations bool
+
+	// LabelSelector restricts the objects fetched from the cluster to those matching it, eg team=payments. The
+	// selector is evaluated by the api server
+	LabelSelector string
+
+	// FieldSelector restricts the objects fetched from the cluster to those matching it, eg status.phase!=Succeeded.
+	// The selector is evaluated client side for resources which do not support its fields
+	FieldSelector string
+
+	// IgnoreKinds is the list of kinds to be skipped for validation, by default none are skipped. Kinds may be
+	// qualified with their group, see KindFilter
 	IgnoreKinds []string
 
 	// IgnoreNullErrors is the flag to ignore null value errors
 	IgnoreNullErrors bool
+
+	// UpgradePlanConfigMap is the namespace/name of the ConfigMap in which the planned kubernetes version
+	// of the cluster is recorded, it is read when TargetKubernetesVersion is not set
+	UpgradePlanConfigMap string
+
+	// UpgradePlanKey is the data key or annotation of UpgradePlanConfigMap holding the planned kubernetes version
+	UpgradePlanKey string
+
+	// SkipMinorVersion defaults the target version to two minor versions after the version of the cluster rather than
+	// the next one, for upgrades skipping a minor version
+	SkipMinorVersion bool
+
+	// DowngradeToInfo is the list of kinds whose findings are reported as info only and never fail the run
+	DowngradeToInfo []string
+
+	// SeverityOverrides maps the rules of findings, eg deprecated-api, to the severity they are reported with instead
+	// of their default one, see ResultSeverity
+	SeverityOverrides map[string]string
+
+	// FailThreshold is the severity from which findings fail the run, DefaultFailThreshold is used when empty
+	FailThreshold string
+
+	// FailOnFetchErrors fails the run when objects of some resources could not be fetched from the cluster
+	FailOnFetchErrors bool
+
+	// FailOnDiscoveryError stops the scan when the resources of some api groups could not be discovered, by default
+	// those groups are reported as skipped and the other groups are scanned
+	FailOnDiscoveryError bool
+
+	// SkipAccessReview lists every resource without first reviewing whether the user may list it, resources the user
+	// may not list are then reported with the error returned by the api server
+	SkipAccessReview bool
+
+	// TransientErrorRetries is the number of times listing a resource is retried when the api server throttles the
+	// request, fails with a server error or times out, or when the connection to it is dropped, before the resource is
+	// skipped. DefaultListRetries is used when zero and listing is never retried when negative
+	TransientErrorRetries int
+
+	// PerKindOptions overrides how objects of a kind are fetched from the cluster, keyed by kind (case-insensitive).
+	// Options take precedence over the global defaults: Skip drops the kind even if it is in SelectKinds, Limit
+	// caps the number of objects listed which are otherwise unlimited and MetadataOnly keeps only the metadata
+	// of objects which are otherwise kept whole. IgnoreKinds still applies to kinds with options.
+	PerKindOptions map[string]KindFetchOptions
+
+	// RemovedComponentFlags is the list of kubernetes component flags looked up in container args and commands
+	// of workloads, DefaultRemovedComponentFlags is used when nil
+	RemovedComponentFlags []RemovedComponentFlag
+
+	// PreferredVersionOverrides maps an api group to the version in which its kinds are listed from the cluster,
+	// overriding the version picked by default, the core group is keyed as "core" or ""
+	PreferredVersionOverrides map[string]string
+
+	// ImpersonateUser is the user the cluster is scanned as, ImpersonateGroups and ImpersonateUID are only
+	// applied along with it
+	ImpersonateUser string
+
+	// ImpersonateGroups are the groups the cluster is scanned as
+	ImpersonateGroups []string
+
+	// ImpersonateUID is the uid the cluster is scanned as
+	ImpersonateUID string
+
+	// QPS is the number of queries per second allowed to the api server, DefaultClientQPS is used when unset
+	QPS float32
+
+	// Burst is the number of queries allowed above QPS in a burst, DefaultClientBurst is used when unset
+	Burst int
+
+	// RateLimiter throttles requests to the api server instead of QPS and Burst when set
+	RateLimiter flowcontrol.RateLimiter
+
+	// ProxyURL is the proxy the api server is connected through, it overrides the proxy-url of the kubeconfig
+	// and the proxy environment variables
+	ProxyURL string
+
+	// DiscoveryCacheDir is the directory the groups and resources discovered from clusters are cached in, one
+	// directory per api server, see DefaultDiscoveryCacheDir. Discovery is not cached when it is empty
+	DiscoveryCacheDir string
+
+	// NoDiscoveryCache disables the discovery cache even if DiscoveryCacheDir is set
+	NoDiscoveryCache bool
+
+	// SchemaCacheDir is the directory the openapi specs of kubernetes releases are cached in once downloaded and read
+	// from before any download, see DefaultSchemaCacheDir. Specs are not cached when it is empty
+	SchemaCacheDir string
+
+	// Offline forbids downloading specs, the specs of target and source versions must be cached in SchemaCacheDir
+	// or given with TargetSchemaLocation and SourceSchemaLocation
+	Offline bool
+
+	// SchemaBaseURL is a mirror of the kubernetes repository the specs are downloaded from, laid out like
+	// raw.githubusercontent.com, DefaultSchemaBaseURL is used when it is empty
+	SchemaBaseURL string
+
+	// SchemaChecksums are the sha256 the downloaded specs of kubernetes versions must match, by version
+	SchemaChecksums map[string]string
+
+	// SuppressionsFile is a YAML file of suppressions accepting the findings of some objects, see Suppression. Its
+	// entries are read into Suppressions by LoadSuppressions
+	SuppressionsFile string
+
+	// Suppressions are the suppressions in effect, see ApplySuppressions
+	Suppressions []Suppression
+
+	// RulesFile is a YAML file of house rules checked on every object, see CustomRule. Its rules are read into
+	// CustomRules by LoadCustomRules
+	RulesFile string
+
+	// CustomRules are the house rules in effect, see CheckCustomRules
+	CustomRules []CustomRule
+
+	// DisableAnalyzers are the removal analyzers not to run, see AnalyzeRemovals and RemovalAnalyzerNames
+	DisableAnalyzers []string
+
+	// FixOutputDir is the directory the manifests of objects which can be migrated with just an apiVersion change
+	// are written to with their latest api version, see WriteFixedManifest. Nothing is written when it is empty
+	FixOutputDir string
+
+	// DiscoveryCacheTTL is how long cached discovery responses are used, DefaultDiscoveryCacheTTL is used when unset
+	DiscoveryCacheTTL time.Duration
+
+	// Concurrency is the number of resources listed at the same time, DefaultConcurrency is used when unset
+	Concurrency int
+
+	// ValidationConcurrency is the number of objects validated at the same time, the number of CPUs is used when unset
+	ValidationConcurrency int
+
+	// PageSize is the number of objects listed per request, DefaultPageSize is used when unset
+	PageSize int64
+
+	// NewerThan leaves out the objects created longer ago than it before the scan started, zero keeps all objects
+	NewerThan time.Duration
+
+	// OlderThan leaves out the objects created more recently than it before the scan started, along with NewerThan it
+	// selects the objects created within a window. Zero keeps all objects
+	OlderThan time.Duration
+
+	// UseAPIServerCache lists objects from the watch cache of the api server rather than with quorum reads from etcd, to
+	// reduce the load of a scan on etcd. Objects may then be marginally stale and are not paginated
+	UseAPIServerCache bool
+
+	// MaxObjectsPerResource is the maximum number of objects listed per resource, larger resources are sampled and
+	// reported as truncated in the FetchSummary. Zero lists all objects
+	MaxObjectsPerResource int64
+
+	// WatchDebounce is how long the updates of a watched object are coalesced, DefaultWatchDebounce is used when unset
+	WatchDebounce time.Duration
+
+	// UserAgentSuffix is appended to the user agent of api requests, eg to tell the tenants of a controller apart
+	UserAgentSuffix string
+
+	// EventSink receives progress events while a cluster is being scanned, see NewJSONLinesEventSink
+	EventSink func(ScanEvent)
+
+	// Progress is told about the progress of fetching the objects of a cluster, progress is not reported when nil
+	Progress ProgressReporter
+
+	// Tracer records a span per fetched resource and an event per finding, tracing is disabled when nil
+	Tracer trace.Tracer
+
+	// labeledNamespaces are the namespaces matching NamespaceLabelSelector once resolved, nil when not resolved
+	labeledNamespaces sets.Set[string]
+	// terminatingNamespaces are the namespaces being deleted once resolved to be skipped, see IncludeTerminalObjects
+	terminatingNamespaces sets.Set[string]
 }
 
 // NewDefaultConfig creates a Config with default values
@@ -116,18 +379,119 @@ func AddKubeaddFlags(cmd *cobra.Command, config *Config) *cobra.Command {
 	//cmd.Flags().StringVarP(&config.FileName, "filename", "f", "stdin", "Filename to be displayed when testing manifests read from stdin")
 	cmd.Flags().StringVarP(&config.TargetSchemaLocation, "target-schema-location", "", "", "TargetSchemaLocation is the file path of kubernetes version of the target cluster for these manifests. Use this in air-gapped environment where it internet access is unavailable.")
 	cmd.Flags().StringVarP(&config.SourceSchemaLocation, "source-schema-location", "", "", "SourceSchemaLocation is the file path of kubernetes versions of the cluster on which manifests are deployed. Use this in air-gapped environment where it internet access is unavailable.")
-	cmd.Flags().StringVarP(&config.TargetKubernetesVersion, "target-kubernetes-version", "", "1.22", "Version of Kubernetes to migrate to eg 1.22, 1.21, 1.12")
+	cmd.Flags().StringVarP(&config.TargetKubernetesVersion, "target-kubernetes-version", "", "", "Version of Kubernetes to migrate to eg 1.22, 1.21, 1.12, or an exact release eg 1.28.9 validated against its own spec when published and else the latest spec of 1.28, or a comma-separated list of versions eg 1.27,1.29 to validate each step of an upgrade against. Defaults to the planned version of the upgrade plan ConfigMap or else the next minor version of the cluster, required for manifests and cluster dumps")
 	cmd.Flags().StringVarP(&config.SourceKubernetesVersion, "source-kubernetes-version", "", "", "Version of Kubernetes of the cluster on which kubernetes objects are deployed currently, ignored in case cluster is provided. In case of directory defaults to same as target-kubernetes-version.")
-	cmd.Flags().StringVarP(&config.OutputFormat, "output", "o", "", fmt.Sprintf("The format of the output of this script. Options are: %v", "(stdOut | json)"))
+	cmd.Flags().StringVarP(&config.OutputFormat, "output", "o", "", fmt.Sprintf("The format of the output of this script. Options are: %v", "(stdOut | json | yaml | backstage | runbook | junit)"))
 	//cmd.Flags().BoolVar(&config.Quiet, "quiet", false, "Silences any output aside from the direct results")
 	cmd.Flags().BoolVar(&config.InsecureSkipTLSVerify, "insecure-skip-tls-verify", false, "If true, the server's certificate will not be checked for validity. This will make your HTTPS connections insecure")
-	cmd.Flags().StringSliceVarP(&config.SelectNamespaces, "select-namespaces", "", []string{}, "A comma-separated list of namespaces to be selected, if left empty all namespaces are selected")
-	cmd.Flags().StringSliceVarP(&config.IgnoreNamespaces, "ignore-namespaces", "", []string{"kube-system"}, "A comma-separated list of namespaces to be skipped")
-	cmd.Flags().StringSliceVarP(&config.IgnoreKinds, "ignore-kinds", "", []string{"event", "CustomResourceDefinition"}, "A comma-separated list of kinds to be skipped")
-	cmd.Flags().StringSliceVarP(&config.SelectKinds, "select-kinds", "", []string{}, "A comma-separated list of kinds to be selected, if left empty all kinds are selected")
+	cmd.Flags().StringVarP(&config.CertificateAuthorityFile, "certificate-authority", "", "", "Path to a PEM encoded CA bundle to verify the api server certificate with, overrides the certificate authority of the kubeconfig")
+	cmd.Flags().StringVarP(&config.FieldSelector, "field-selector", "", "", "Field selector to filter the objects fetched from the cluster on, eg status.phase!=Succeeded")
+	cmd.Flags().StringVarP(&config.LabelSelector, "selector", "l", "", "Label selector to filter the objects fetched from the cluster on, eg team=payments")
+	cmd.Flags().StringSliceVarP(&config.SelectNamespaces, "select-namespaces", "", []string{}, "A comma-separated list of namespaces or patterns to be selected eg pr-* or ~^pr-[0-9]+$, if left empty all namespaces are selected, cluster scoped objects are selected regardless")
+	cmd.Flags().StringSliceVarP(&config.IgnoreNamespaces, "ignore-namespaces", "", []string{"kube-system"}, "A comma-separated list of namespaces or patterns to be skipped eg pr-* or ~^pr-[0-9]+$, takes precedence over select-namespaces")
+	cmd.Flags().StringVarP(&config.NamespaceLabelSelector, "namespace-selector", "", "", "Label selector of the namespaces to be scanned eg environment=prod, combined with select-namespaces and ignore-namespaces")
+	cmd.Flags().BoolVarP(&config.IncludeTerminalObjects, "include-terminal", "", false, "Scan Pods which succeeded or failed, Jobs which completed or failed and the objects of namespaces being deleted, which are skipped by default")
+	cmd.Flags().BoolVarP(&config.IncludeSystemNamespaces, "include-system-namespaces", "", false, "Scan the namespaces of the control plane and of add-ons managed by cloud providers eg kube-public, which are skipped unless selected with select-namespaces")
+	cmd.Flags().StringSliceVarP(&config.SelectAPIGroups, "select-api-groups", "", []string{}, "A comma-separated list of api groups or patterns to be selected eg core,apps,*.k8s.io, if left empty all groups are selected")
+	cmd.Flags().StringSliceVarP(&config.IgnoreAPIGroups, "ignore-api-groups", "", []string{}, "A comma-separated list of api groups or patterns to be skipped eg *.istio.io, takes precedence over select-api-groups")
+	cmd.Flags().StringVarP(&config.IncludeCustomResources, "include-custom-resources", "", CustomResourcesAll, fmt.Sprintf("Objects to be scanned by api group, %s, %s for the core, legacy and *.k8s.io groups or %s for the other groups along with the CustomResourceDefinitions", CustomResourcesAll, CustomResourcesOnlyBuiltin, CustomResourcesOnlyCRDs))
+	cmd.Flags().StringSliceVarP(&config.IncludeResources, "include-resources", "", []string{}, "A comma-separated list of resources to be listed even if the api server does not advertise list for them eg podmetrics.metrics.k8s.io")
+	cmd.Flags().StringSliceVarP(&config.ExcludeResources, "exclude-resources", "", []string{}, "A comma-separated list of resources never to be listed eg allowlists.example.com, takes precedence over include-resources")
+	cmd.Flags().BoolVarP(&config.KeepManagedFields, "keep-managed-fields", "", false, "Keep managedFields and the last-applied-configuration annotation of objects fetched from the cluster, they are dropped by default to save memory")
+	cmd.Flags().BoolVarP(&config.CheckDeprecatedAPIUsage, "check-deprecated-api-usage", "", false, "Report the deprecated apis requested from the cluster since the api server started, read from its /metrics")
+	cmd.Flags().BoolVarP(&config.ShowServerWarnings, "show-server-warnings", "", false, "Print the warnings returned by the api server while scanning the cluster, eg of deprecated resources, as they come")
+	cmd.Flags().BoolVarP(&config.UseLastApplied, "use-last-applied", "", false, "Validate the last-applied-configuration annotation of objects fetched from the cluster instead of the live objects, falling back to the live objects without it")
+	cmd.Flags().BoolVarP(&config.NoAnnotations, "no-annotations", "", false, "Scan objects opted out with the kubedd.io/ignore or kubedd.io/ignore-rules annotations, eg for compliance runs")
+	cmd.Flags().BoolVarP(&config.IgnoreOwnedObjects, "ignore-owned-objects", "", false, "Skip objects owned by a controller eg Pods of a ReplicaSet, only top-level objects are validated")
+	cmd.Flags().BoolVarP(&config.AnnotateRootOwner, "annotate-root-owner", "", false, "Report the top-level controller of objects owned by a controller eg the Deployment of a Pod")
+	cmd.Flags().StringSliceVarP(&config.IgnoreKinds, "ignore-kinds", "", []string{"event", "CustomResourceDefinition"}, "A comma-separated list of kinds or patterns to be skipped, optionally qualified with their group eg Ingress.extensions, takes precedence over select-kinds")
+	cmd.Flags().StringSliceVarP(&config.SelectKinds, "select-kinds", "", []string{}, "A comma-separated list of kinds or patterns to be selected, optionally qualified with their group eg Deployment.apps, if left empty all kinds are selected")
 	cmd.Flags().StringSliceVarP(&config.IgnoreKeysFromDeprecation, "ignore-keys-for-deprecation", "", []string{"metadata*", "status*"}, "A comma-separated list of keys to be ignored for depreciation check")
 	cmd.Flags().StringSliceVarP(&config.IgnoreKeysFromValidation, "ignore-keys-for-validation", "", []string{"status*", "metadata*"}, "A comma-separated list of keys to be ignored for validation check")
 	cmd.Flags().BoolVar(&config.IgnoreNullErrors, "ignore-null-errors", true, "Ignore null value errors")
+	cmd.Flags().StringToStringVarP(&config.SeverityOverrides, "severity", "", map[string]string{}, "Severity of the findings of rules overriding their default one eg deprecated-api=error, rules are removed-api, already-removed-api, deprecated-api, schema-error, deprecated-field, unknown-field, dry-run-rejected and deprecated-pod-field")
+	cmd.Flags().StringVarP(&config.FailThreshold, "fail-threshold", "", DefaultFailThreshold, "Severity from which findings fail the run, info, warning or error")
+	cmd.Flags().StringSliceVarP(&config.DowngradeToInfo, "downgrade-to-info", "", []string{}, "A comma-separated list of kinds whose findings are reported but never fail the run eg Job,CronJob")
+	cmd.Flags().BoolVarP(&config.FailOnDiscoveryError, "fail-on-discovery-error", "", false, "Stop the scan when the resources of some api groups could not be discovered, eg as an aggregated api server is down")
+	cmd.Flags().BoolVarP(&config.SkipAccessReview, "skip-access-review", "", false, "List every resource without first reviewing whether the user is allowed to list it")
+	cmd.Flags().BoolVarP(&config.FailOnFetchErrors, "fail-on-fetch-errors", "", false, "Fail the run when objects of some resources could not be fetched from the cluster")
+	cmd.Flags().IntVarP(&config.TransientErrorRetries, "transient-error-retries", "", DefaultListRetries, "Number of retries with backoff when listing a resource is throttled, fails with a server error, times out or the connection to the api server is dropped, negative to disable retries")
+	cmd.Flags().StringVarP(&config.ImpersonateUser, "as", "", "", "Username to impersonate while scanning the cluster")
+	cmd.Flags().StringSliceVarP(&config.ImpersonateGroups, "as-group", "", []string{}, "Group to impersonate while scanning the cluster, can be repeated to specify multiple groups")
+	cmd.Flags().StringVarP(&config.ImpersonateUID, "as-uid", "", "", "UID to impersonate while scanning the cluster")
+	cmd.Flags().Float32VarP(&config.QPS, "client-qps", "", DefaultClientQPS, "Number of queries per second allowed to the api server while scanning the cluster")
+	cmd.Flags().IntVarP(&config.Burst, "client-burst", "", DefaultClientBurst, "Number of queries allowed above client-qps in a burst while scanning the cluster")
+	cmd.Flags().StringVarP(&config.DiscoveryCacheDir, "cache-dir", "", "", "Directory the api groups and resources discovered from clusters are cached in for 10 minutes, defaults to ~/.kube/cache/silver-surfer")
+	cmd.Flags().StringVarP(&config.SchemaCacheDir, "schema-cache-dir", "", "", "Directory the openapi specs of kubernetes versions are cached in once downloaded and read from before downloading them, defaults to ~/.cache/silver-surfer/schemas")
+	cmd.Flags().StringVarP(&config.SchemaBaseURL, "schema-base-url", "", DefaultSchemaBaseURL, "Base url of a mirror of the kubernetes repository the openapi specs are downloaded from, as <base-url>/release-<version>/api/openapi-spec/swagger.json")
+	cmd.Flags().StringToStringVarP(&config.SchemaChecksums, "schema-sha256", "", map[string]string{}, "Recorded sha256 the downloaded openapi specs must match by kubernetes version eg 1.29=<sha256>, can be repeated")
+	cmd.Flags().BoolVarP(&config.Offline, "offline", "", false, "Never download openapi specs, they must be cached in schema-cache-dir eg with the download-specs command or given with the schema location flags")
+	cmd.Flags().BoolVarP(&config.NoDiscoveryCache, "no-discovery-cache", "", false, "Discover the api groups and resources of clusters on every run instead of caching them")
+	cmd.Flags().IntVarP(&config.Concurrency, "concurrency", "", DefaultConcurrency, "Number of resources listed from the api server at the same time")
+	cmd.Flags().IntVarP(&config.ValidationConcurrency, "validation-concurrency", "", 0, "Number of objects validated at the same time, defaults to the number of CPUs")
+	cmd.Flags().Int64VarP(&config.PageSize, "page-size", "", DefaultPageSize, "Number of objects listed per request to the api server")
+	cmd.Flags().VarP(ageValue{&config.NewerThan}, "newer-than", "", "Scan only the objects created within this duration before the scan eg 2160h or 90d")
+	cmd.Flags().VarP(ageValue{&config.OlderThan}, "older-than", "", "Scan only the objects created longer than this duration before the scan eg 720h or 30d, along with newer-than selects a window")
+	cmd.Flags().BoolVarP(&config.UseAPIServerCache, "use-apiserver-cache", "", false, "List objects from the watch cache of the api server instead of etcd to reduce the load of the scan, objects may be marginally stale and are listed without pagination")
+	cmd.Flags().Int64VarP(&config.MaxObjectsPerResource, "max-objects-per-resource", "", 0, "Maximum number of objects fetched per resource, larger resources are sampled and reported as truncated, 0 fetches all objects")
+	cmd.Flags().DurationVarP(&config.WatchDebounce, "watch-debounce", "", DefaultWatchDebounce, "How long the updates of an object are coalesced before it is validated again in watch mode")
+	cmd.Flags().StringVarP(&config.ProxyURL, "proxy-url", "", "", "URL of the proxy to connect to the api server through, overrides the proxy-url of the kubeconfig and the HTTPS_PROXY environment variable")
+	cmd.Flags().StringVarP(&config.UpgradePlanConfigMap, "upgrade-plan-configmap", "", "", fmt.Sprintf("namespace/name of the ConfigMap holding the planned kubernetes version of the cluster, used when target-kubernetes-version is not set eg %s", DefaultUpgradePlanConfigMap))
+	cmd.Flags().StringVarP(&config.UpgradePlanKey, "upgrade-plan-key", "", DefaultUpgradePlanKey, "Key in the upgrade plan ConfigMap data or annotations holding the planned kubernetes version")
+	cmd.Flags().StringVarP(&config.FixOutputDir, "fix-output-dir", "", "", "Directory the manifests of objects which only need their apiVersion changed are written to with their latest api version, as <dir>/<namespace>/<kind>-<name>.yaml")
+	cmd.Flags().StringSliceVarP(&config.DisableAnalyzers, "disable-analyzers", "", []string{}, fmt.Sprintf("A comma-separated list of removal analyzers not to run among %s, they add the next steps to the results of removed kinds with a known migration", strings.Join(RemovalAnalyzerNames(), ", ")))
+	cmd.Flags().StringVarP(&config.RulesFile, "rules", "", "", "YAML file of custom rules checking a field path of the objects matched by kind and group with an operator among exists, absent, equals, matches-regex and deprecated-on-version")
+	cmd.Flags().StringVarP(&config.SuppressionsFile, "suppress", "", "", "YAML file of suppressions accepting the findings of objects matched by kind, group, namespace and name, optionally until an expiry date")
+	cmd.Flags().BoolVarP(&config.Strict, "strict", "", false, "Report fields which are not defined by the schema of the api version of objects eg typos as errors, failing the run")
+	cmd.Flags().BoolVarP(&config.SkipMinorVersion, "skip-minor-version", "", false, "Default the target kubernetes version to two minor versions after the version of the cluster rather than the next one")
 
 	return cmd
 }
+
+// Validate returns an error if the selectors, the patterns of the namespace and kind filters, IncludeCustomResources,
+// the age filters or the severities are invalid
+func (c *Config) Validate() error {
+	if err := c.ValidateSelectors(); err != nil {
+		return err
+	}
+	if err := validateKubernetesVersions(c.TargetVersions()...); err != nil {
+		return err
+	}
+	if len(c.SourceKubernetesVersion) > 0 {
+		if err := validateKubernetesVersions(c.SourceKubernetesVersion); err != nil {
+			return err
+		}
+	}
+	if err := validateIncludeCustomResources(c.IncludeCustomResources); err != nil {
+		return err
+	}
+	if err := c.validateAgeFilters(); err != nil {
+		return err
+	}
+	if err := c.validateSeverities(); err != nil {
+		return err
+	}
+	if err := validateDisableAnalyzers(c.DisableAnalyzers); err != nil {
+		return err
+	}
+	filters := []struct {
+		name     string
+		patterns []string
+	}{
+		{name: "SelectNamespaces", patterns: c.SelectNamespaces},
+		{name: "IgnoreNamespaces", patterns: c.IgnoreNamespaces},
+		{name: "SelectAPIGroups", patterns: c.SelectAPIGroups},
+		{name: "IgnoreAPIGroups", patterns: c.IgnoreAPIGroups},
+		{name: "SelectKinds", patterns: c.SelectKinds},
+		{name: "IgnoreKinds", patterns: c.IgnoreKinds},
+		{name: "DowngradeToInfo", patterns: c.DowngradeToInfo},
+		{name: "IncludeResources", patterns: c.IncludeResources},
+		{name: "ExcludeResources", patterns: c.ExcludeResources},
+	}
+	for _, filter := range filters {
+		if err := validatePatterns(filter.name, filter.patterns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
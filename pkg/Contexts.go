@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// ContextInfo describes a context of the kubeconfig
+type ContextInfo struct {
+	Name      string
+	Cluster   string
+	Server    string
+	Namespace string
+	Current   bool
+}
+
+// ListContexts returns the contexts of the kubeconfig sorted by name, the kubeconfig files are loaded and merged
+// the same way as NewCluster does so every listed context can be passed to it
+func ListContexts(kubeconfig string) ([]ContextInfo, error) {
+	var config *clientcmdapi.Config
+	if kubeconfig == KubeconfigStdin {
+		data, err := kubeconfigFromStdin.read()
+		if err != nil {
+			return nil, err
+		}
+		if config, err = clientcmd.Load(data); err != nil {
+			return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+		}
+	} else {
+		loadingRules := kubeconfigLoadingRules(kubeconfig)
+		var err error
+		if config, err = loadingRules.Load(); err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig %s: %w", strings.Join(loadingRules.GetLoadingPrecedence(), string(filepath.ListSeparator)), err)
+		}
+	}
+	contexts := make([]ContextInfo, 0, len(config.Contexts))
+	for name, context := range config.Contexts {
+		info := ContextInfo{
+			Name:      name,
+			Cluster:   context.Cluster,
+			Namespace: context.Namespace,
+			Current:   name == config.CurrentContext,
+		}
+		if cluster, ok := config.Clusters[context.Cluster]; ok {
+			info.Server = cluster.Server
+		}
+		contexts = append(contexts, info)
+	}
+	sort.Slice(contexts, func(i, j int) bool {
+		return contexts[i].Name < contexts[j].Name
+	})
+	return contexts, nil
+}
@@ -0,0 +1,50 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListContexts(t *testing.T) {
+	dir := t.TempDir()
+	staging := filepath.Join(dir, "staging")
+	writeKubeconfig(t, staging, "staging", "https://staging.example.com")
+	prod := filepath.Join(dir, "prod")
+	contents := `apiVersion: v1
+kind: Config
+clusters:
+- name: prod
+  cluster:
+    server: https://prod.example.com
+contexts:
+- name: prod-shop
+  context:
+    cluster: prod
+    namespace: shop
+- name: dangling
+  context:
+    cluster: missing
+`
+	assert.NoError(t, os.WriteFile(prod, []byte(contents), 0600))
+	t.Setenv("KUBECONFIG", staging+string(filepath.ListSeparator)+prod)
+
+	want := []ContextInfo{
+		{Name: "dangling", Cluster: "missing"},
+		{Name: "prod-shop", Cluster: "prod", Server: "https://prod.example.com", Namespace: "shop"},
+		{Name: "staging", Cluster: "staging", Server: "https://staging.example.com", Current: true},
+	}
+	contexts, err := ListContexts("")
+	assert.NoError(t, err)
+	assert.Equal(t, want, contexts)
+
+	// an explicit kubeconfig is used instead of KUBECONFIG
+	contexts, err = ListContexts(prod)
+	assert.NoError(t, err)
+	assert.Equal(t, []ContextInfo{want[0], want[1]}, contexts)
+
+	_, err = ListContexts(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Values of Config.IncludeCustomResources
+const (
+	CustomResourcesAll         = "all"
+	CustomResourcesOnlyBuiltin = "only-builtin"
+	CustomResourcesOnlyCRDs    = "only-crds"
+)
+
+// customResourceDefinitionKind is fetched along with custom resources when only those are selected, so that the
+// served and storage versions of their definitions are known
+var customResourceDefinitionKind = schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+
+// IsBuiltinAPIGroup returns true if group is served by kubernetes itself rather than defined by a
+// CustomResourceDefinition, ie the core group, the legacy groups without a domain eg apps and the k8s.io groups.
+// Custom resources of groups approved under k8s.io eg gateway.networking.k8s.io count as builtin
+func IsBuiltinAPIGroup(group string) bool {
+	return !strings.Contains(group, ".") || group == "k8s.io" || strings.HasSuffix(group, ".k8s.io")
+}
+
+func validateIncludeCustomResources(value string) error {
+	switch value {
+	case "", CustomResourcesAll, CustomResourcesOnlyBuiltin, CustomResourcesOnlyCRDs:
+		return nil
+	}
+	return fmt.Errorf("invalid IncludeCustomResources %q, expected one of %s, %s or %s", value, CustomResourcesAll, CustomResourcesOnlyBuiltin, CustomResourcesOnlyCRDs)
+}
+
+// customResourcesSelected applies IncludeCustomResources to the api group of gvk
+func (c *Config) customResourcesSelected(gvk schema.GroupVersionKind) bool {
+	switch c.IncludeCustomResources {
+	case CustomResourcesOnlyBuiltin:
+		return IsBuiltinAPIGroup(gvk.Group)
+	case CustomResourcesOnlyCRDs:
+		return !IsBuiltinAPIGroup(gvk.Group)
+	}
+	return true
+}
+
+// withCustomResourceDefinitions adds the kind of CustomResourceDefinitions to gvks when only custom resources are
+// selected and the definitions are not already part of gvks
+func (c *Config) withCustomResourceDefinitions(gvks []schema.GroupVersionKind) []schema.GroupVersionKind {
+	if c.IncludeCustomResources != CustomResourcesOnlyCRDs {
+		return gvks
+	}
+	for _, gvk := range gvks {
+		if gvk.GroupKind() == customResourceDefinitionKind.GroupKind() {
+			return gvks
+		}
+	}
+	return append(append([]schema.GroupVersionKind(nil), gvks...), customResourceDefinitionKind)
+}
+
+// fetchesDefinitions returns true if gvk are the CustomResourceDefinitions fetched along with custom resources, which
+// are fetched regardless of the group and kind filters
+func (c *Config) fetchesDefinitions(gvk schema.GroupVersionKind) bool {
+	return c.IncludeCustomResources == CustomResourcesOnlyCRDs && gvk.GroupKind() == customResourceDefinitionKind.GroupKind()
+}
@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsBuiltinAPIGroup(t *testing.T) {
+	tests := []struct {
+		group string
+		want  bool
+	}{
+		{group: "", want: true},
+		{group: "apps", want: true},
+		{group: "batch", want: true},
+		{group: "apiextensions.k8s.io", want: true},
+		{group: "apiregistration.k8s.io", want: true},
+		{group: "rbac.authorization.k8s.io", want: true},
+		{group: "k8s.io", want: true},
+		{group: "cluster.x-k8s.io", want: false},
+		{group: "k8s.io.example.com", want: false},
+		{group: "cert-manager.io", want: false},
+		{group: "networking.istio.io", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.group, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsBuiltinAPIGroup(tt.group))
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_includeCustomResources(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	certificateGvk := schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+	crdGvk := schema.GroupVersionKind{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	server.addObject(server.addResource(certificateGvk, "certificates", true), newFakeObject("cert-manager.io/v1", "Certificate", "shop", "cart"))
+	server.addObject(server.addResource(crdGvk, "customresourcedefinitions", false), newFakeObject("apiextensions.k8s.io/v1", "CustomResourceDefinition", "", "certificates.cert-manager.io"))
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{deploymentGvk, certificateGvk}
+
+	tests := []struct {
+		name string
+		conf *Config
+		want []string
+	}{
+		{
+			name: "all by default",
+			conf: &Config{},
+			want: []string{"Deployment", "Certificate"},
+		},
+		{
+			name: "only builtin",
+			conf: &Config{IncludeCustomResources: CustomResourcesOnlyBuiltin},
+			want: []string{"Deployment"},
+		},
+		{
+			name: "only crds fetches the definitions even if their kind is ignored",
+			conf: &Config{IncludeCustomResources: CustomResourcesOnlyCRDs, IgnoreKinds: []string{"CustomResourceDefinition"}},
+			want: []string{"Certificate", "CustomResourceDefinition"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Concurrency = 1
+			objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, tt.conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeCustomResources: "crds"})
+	assert.ErrorContains(t, err, `invalid IncludeCustomResources "crds"`)
+}
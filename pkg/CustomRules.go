@@ -0,0 +1,283 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Operators of custom rules, a rule reports a finding for the objects it matches on which its operator holds
+const (
+	// OperatorExists holds when the path is set
+	OperatorExists = "exists"
+	// OperatorAbsent holds when the path is not set
+	OperatorAbsent = "absent"
+	// OperatorEquals holds when a value of the path equals the value of the rule
+	OperatorEquals = "equals"
+	// OperatorMatchesRegex holds when a value of the path matches the regular expression of the rule
+	OperatorMatchesRegex = "matches-regex"
+	// OperatorDeprecatedOnVersion holds when the path is set and the target version is at least the version of the rule
+	OperatorDeprecatedOnVersion = "deprecated-on-version"
+)
+
+// CustomRule is an entry of a rules file, a house rule checked on every object of the kind and group it matches.
+// Kind and Group are case-insensitive names or patterns like the fields of Suppression, empty ones match any object.
+// Path is a field path like spec.rules[*].host or metadata.annotations[kubernetes.io/ingress.class], which may also be
+// given as the JSONPath {.spec.rules[*].host}
+type CustomRule struct {
+	ID       string `json:"id"`
+	Kind     string `json:"kind,omitempty"`
+	Group    string `json:"group,omitempty"`
+	Path     string `json:"path"`
+	Operator string `json:"operator"`
+	// Value is the value compared by OperatorEquals or the regular expression of OperatorMatchesRegex
+	Value string `json:"value,omitempty"`
+	// Version is the kubernetes version from which OperatorDeprecatedOnVersion holds
+	Version string `json:"version,omitempty"`
+	// Severity of the findings of the rule, SeverityWarning when empty
+	Severity string `json:"severity,omitempty"`
+	Message  string `json:"message"`
+
+	segments []string
+	regex    *regexp.Regexp
+}
+
+// CustomRuleFinding is a finding of a CustomRule on an object
+type CustomRuleFinding struct {
+	Rule     string
+	Severity string
+	Path     string
+	Message  string
+}
+
+// customRulesFile is the format of Config.RulesFile
+type customRulesFile struct {
+	Rules []CustomRule `json:"rules"`
+}
+
+// LoadCustomRules reads the rules of RulesFile into CustomRules
+func (c *Config) LoadCustomRules() error {
+	if len(c.RulesFile) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(c.RulesFile)
+	if err != nil {
+		return fmt.Errorf("unable to read the rules file: %w", err)
+	}
+	var file customRulesFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return fmt.Errorf("unable to parse the rules file %s: %w", c.RulesFile, err)
+	}
+	rules, err := compileCustomRules(file.Rules)
+	if err != nil {
+		return fmt.Errorf("invalid rules file %s: %w", c.RulesFile, err)
+	}
+	c.CustomRules = rules
+	return nil
+}
+
+// compileCustomRules validates rules and parses their paths and regular expressions
+func compileCustomRules(rules []CustomRule) ([]CustomRule, error) {
+	ids := map[string]bool{}
+	for i := range rules {
+		r := &rules[i]
+		name := fmt.Sprintf("rules[%d]", i)
+		r.ID = strings.ToLower(strings.TrimSpace(r.ID))
+		switch {
+		case len(r.ID) == 0:
+			return nil, fmt.Errorf("missing id in %s", name)
+		case ids[r.ID]:
+			return nil, fmt.Errorf("duplicate id %q in %s", r.ID, name)
+		case suppressibleRules.Has(r.ID) || len(defaultRuleSeverities[r.ID]) > 0:
+			return nil, fmt.Errorf("id %q in %s is a built-in rule", r.ID, name)
+		case len(r.Message) == 0:
+			return nil, fmt.Errorf("missing message in %s", name)
+		}
+		ids[r.ID] = true
+		if err := validatePatterns(name, []string{r.Kind, r.Group}); err != nil {
+			return nil, err
+		}
+		segments, err := parseFieldPath(r.Path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q in %s: %w", r.Path, name, err)
+		}
+		r.segments = segments
+		switch r.Operator {
+		case OperatorExists, OperatorAbsent, OperatorEquals:
+		case OperatorMatchesRegex:
+			if r.regex, err = regexp.Compile(r.Value); err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q in %s: %w", r.Value, name, err)
+			}
+		case OperatorDeprecatedOnVersion:
+			if _, _, err := parseMajorMinor(r.Version); err != nil {
+				return nil, fmt.Errorf("invalid version %q in %s: %w", r.Version, name, err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown operator %q in %s, expected one of %s, %s, %s, %s or %s", r.Operator, name,
+				OperatorExists, OperatorAbsent, OperatorEquals, OperatorMatchesRegex, OperatorDeprecatedOnVersion)
+		}
+		if len(r.Severity) == 0 {
+			r.Severity = SeverityWarning
+		}
+		r.Severity = strings.ToLower(r.Severity)
+		if _, ok := severityRanks[r.Severity]; !ok {
+			return nil, fmt.Errorf("invalid severity %q in %s, expected one of %s, %s or %s", r.Severity, name, SeverityInfo, SeverityWarning, SeverityError)
+		}
+	}
+	return rules, nil
+}
+
+// parseFieldPath splits a field path into its fields, list indexes and * for every item of a list or map
+func parseFieldPath(path string) ([]string, error) {
+	path = strings.TrimSpace(path)
+	if strings.HasPrefix(path, "{") && strings.HasSuffix(path, "}") {
+		path = path[1 : len(path)-1]
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	var segments []string
+	for len(path) > 0 {
+		switch path[0] {
+		case '.':
+			path = path[1:]
+			continue
+		case '[':
+			end := strings.Index(path, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated [")
+			}
+			key := strings.Trim(path[1:end], `'"`)
+			if len(key) == 0 {
+				return nil, fmt.Errorf("empty []")
+			}
+			segments = append(segments, key)
+			path = path[end+1:]
+			continue
+		}
+		end := strings.IndexAny(path, ".[")
+		if end < 0 {
+			end = len(path)
+		}
+		segments = append(segments, path[:end])
+		path = path[end:]
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+	return segments, nil
+}
+
+// fieldValue is a value found at a concrete path of an object
+type fieldValue struct {
+	path  string
+	value interface{}
+}
+
+// lookupFieldPath returns the values of object at the path of segments, wildcards expand to every item
+func lookupFieldPath(value interface{}, segments []string, path string) []fieldValue {
+	if len(segments) == 0 {
+		return []fieldValue{{path: path, value: value}}
+	}
+	segment, rest := segments[0], segments[1:]
+	var values []fieldValue
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if segment == "*" {
+			for _, key := range sortedKeys(v) {
+				values = append(values, lookupFieldPath(v[key], rest, fieldPathKey(path, key))...)
+			}
+		} else if item, ok := v[segment]; ok {
+			values = lookupFieldPath(item, rest, fieldPathKey(path, segment))
+		}
+	case []interface{}:
+		if segment == "*" {
+			for i, item := range v {
+				values = append(values, lookupFieldPath(item, rest, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		} else if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(v) {
+			values = lookupFieldPath(v[i], rest, fmt.Sprintf("%s[%d]", path, i))
+		}
+	}
+	return values
+}
+
+// fieldPathKey appends key to path, as [key] when it is not a plain field name
+func fieldPathKey(path, key string) string {
+	if strings.ContainsAny(key, "./") {
+		return path + "[" + key + "]"
+	}
+	if len(path) == 0 {
+		return key
+	}
+	return path + "." + key
+}
+
+// matches returns true if the rule applies to objects of gvk
+func (r CustomRule) matches(gvk schema.GroupVersionKind) bool {
+	groupMatches := matchesEntry(gvk.Group, r.Group) || strings.EqualFold(r.Group, "core") && len(gvk.Group) == 0
+	return groupMatches && matchesEntry(gvk.Kind, r.Kind)
+}
+
+// evaluate returns the findings of the rule on object for the target version
+func (r CustomRule) evaluate(object map[string]interface{}, targetVersion string) []CustomRuleFinding {
+	values := lookupFieldPath(object, r.segments, "")
+	var paths []string
+	switch r.Operator {
+	case OperatorExists:
+		for _, v := range values {
+			paths = append(paths, v.path)
+		}
+	case OperatorAbsent:
+		if len(values) == 0 {
+			paths = append(paths, strings.Join(r.segments, "."))
+		}
+	case OperatorEquals, OperatorMatchesRegex:
+		for _, v := range values {
+			s := fmt.Sprint(v.value)
+			if r.Operator == OperatorEquals && s == r.Value || r.Operator == OperatorMatchesRegex && r.regex.MatchString(s) {
+				paths = append(paths, v.path)
+			}
+		}
+	case OperatorDeprecatedOnVersion:
+		if ok, err := isVersionAtLeast(targetVersion, r.Version); err == nil && ok {
+			for _, v := range values {
+				paths = append(paths, v.path)
+			}
+		}
+	}
+	findings := make([]CustomRuleFinding, 0, len(paths))
+	for _, path := range paths {
+		findings = append(findings, CustomRuleFinding{Rule: r.ID, Severity: r.Severity, Path: path, Message: r.Message})
+	}
+	return findings
+}
+
+// CheckCustomRules adds the findings of the CustomRules of conf matching object to result.CustomRuleFindings
+func CheckCustomRules(result ValidationResult, object map[string]interface{}, conf *Config) ValidationResult {
+	if len(conf.CustomRules) == 0 {
+		return result
+	}
+	apiVersion, _ := object["apiVersion"].(string)
+	kind, _ := object["kind"].(string)
+	gvk := schema.FromAPIVersionAndKind(apiVersion, kind)
+	for _, r := range conf.CustomRules {
+		if r.matches(gvk) {
+			result.CustomRuleFindings = append(result.CustomRuleFindings, r.evaluate(object, conf.TargetKubernetesVersion)...)
+		}
+	}
+	return result
+}
+
+// hasCustomRule returns true if id is the id of one of the CustomRules
+func (c *Config) hasCustomRule(id string) bool {
+	for _, r := range c.CustomRules {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestConfig_LoadCustomRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "custom.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`rules:
+- id: No-Extensions
+  group: extensions
+  path: apiVersion
+  operator: exists
+  severity: Error
+  message: extensions/* apis are not allowed
+- id: ingress-class
+  kind: Ingress
+  path: "{.spec.ingressClassName}"
+  operator: absent
+  message: Ingresses must declare ingressClassName
+`), 0o600))
+	conf := &Config{RulesFile: path}
+	assert.NoError(t, conf.LoadCustomRules())
+	assert.Len(t, conf.CustomRules, 2)
+	assert.Equal(t, "no-extensions", conf.CustomRules[0].ID)
+	assert.Equal(t, SeverityError, conf.CustomRules[0].Severity)
+	assert.Equal(t, SeverityWarning, conf.CustomRules[1].Severity)
+
+	for content, want := range map[string]string{
+		"rules:\n- id: a\n  path: spec\n  operator: near\n  message: m\n":                                                            `unknown operator "near" in rules[0]`,
+		"rules:\n- id: a\n  path: spec\n  operator: exists\n":                                                                        "missing message in rules[0]",
+		"rules:\n- id: removed-api\n  path: spec\n  operator: exists\n  message: m\n":                                                `id "removed-api" in rules[0] is a built-in rule`,
+		"rules:\n- id: a\n  path: spec[0\n  operator: exists\n  message: m\n":                                                        `invalid path "spec[0" in rules[0]`,
+		"rules:\n- id: a\n  path: spec\n  operator: matches-regex\n  value: (\n  message: m\n":                                       `invalid regular expression "(" in rules[0]`,
+		"rules:\n- id: a\n  path: spec\n  operator: exists\n  message: m\n- id: A\n  path: spec\n  operator: absent\n  message: m\n": `duplicate id "a" in rules[1]`,
+		"rules:\n- ids: a\n": "unable to parse",
+	} {
+		assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		assert.ErrorContains(t, (&Config{RulesFile: path}).LoadCustomRules(), want)
+	}
+}
+
+func TestCheckCustomRules(t *testing.T) {
+	rules, err := compileCustomRules([]CustomRule{
+		{ID: "no-extensions", Group: "extensions", Path: "apiVersion", Operator: OperatorExists, Severity: SeverityError, Message: "not allowed"},
+		{ID: "ingress-class", Kind: "Ingress", Path: "spec.ingressClassName", Operator: OperatorAbsent, Message: "declare ingressClassName"},
+		{ID: "legacy-class", Kind: "Ingress", Path: "metadata.annotations[kubernetes.io/ingress.class]", Operator: OperatorEquals, Value: "nginx", Message: "use ingressClassName"},
+		{ID: "wildcard-host", Kind: "Ingress", Path: "$.spec.rules[*].host", Operator: OperatorMatchesRegex, Value: `^\*\.`, Severity: SeverityInfo, Message: "no wildcard hosts"},
+		{ID: "backend-service-name", Kind: "Ingress", Path: "spec.rules[*].http.paths[*].backend.serviceName", Operator: OperatorDeprecatedOnVersion, Version: "1.22", Message: "use backend.service.name"},
+	})
+	assert.NoError(t, err)
+	ingress := newFakeObject("extensions/v1beta1", "Ingress", "default", "web")
+	withAnnotation(ingress, "kubernetes.io/ingress.class", "nginx")
+	ingress["spec"] = map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"host": "*.example.com", "http": map[string]interface{}{"paths": []interface{}{
+				map[string]interface{}{"backend": map[string]interface{}{"serviceName": "web"}},
+			}}},
+			map[string]interface{}{"host": "example.com"},
+		},
+	}
+
+	conf := &Config{CustomRules: rules, TargetKubernetesVersion: "1.22"}
+	result := CheckCustomRules(ValidationResult{}, ingress, conf)
+	assert.Equal(t, []CustomRuleFinding{
+		{Rule: "no-extensions", Severity: SeverityError, Path: "apiVersion", Message: "not allowed"},
+		{Rule: "ingress-class", Severity: SeverityWarning, Path: "spec.ingressClassName", Message: "declare ingressClassName"},
+		{Rule: "legacy-class", Severity: SeverityWarning, Path: "metadata.annotations[kubernetes.io/ingress.class]", Message: "use ingressClassName"},
+		{Rule: "wildcard-host", Severity: SeverityInfo, Path: "spec.rules[0].host", Message: "no wildcard hosts"},
+		{Rule: "backend-service-name", Severity: SeverityWarning, Path: "spec.rules[0].http.paths[0].backend.serviceName", Message: "use backend.service.name"},
+	}, result.CustomRuleFindings)
+	assert.Equal(t, SeverityError, ResultSeverity(result, conf))
+
+	conf.TargetKubernetesVersion = "1.21"
+	assert.Len(t, CheckCustomRules(ValidationResult{}, ingress, conf).CustomRuleFindings, 4)
+	assert.Empty(t, CheckCustomRules(ValidationResult{}, newFakeObject("v1", "ConfigMap", "default", "web"), conf).CustomRuleFindings)
+
+	withAnnotation(ingress, IgnoreRulesAnnotation, "no-extensions, legacy-class")
+	suppressed, rulesSuppressed := SuppressRules(result, &unstructured.Unstructured{Object: ingress}, conf)
+	assert.Equal(t, []string{"legacy-class", "no-extensions"}, rulesSuppressed)
+	assert.Len(t, suppressed.CustomRuleFindings, 3)
+}
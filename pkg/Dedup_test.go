@@ -0,0 +1,39 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_deduplicate(t *testing.T) {
+	server := newFakeApiServer(t)
+	hpaV2 := schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
+	hpaV1 := schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "HorizontalPodAutoscaler"}
+	// the first version added is the preferred one
+	hpaV2Resource := server.addResource(hpaV2, "horizontalpodautoscalers", true)
+	server.addObject(hpaV2Resource, newFakeObject("autoscaling/v2", "HorizontalPodAutoscaler", "shop", "web"))
+	hpaV1Resource := server.addResource(hpaV1, "horizontalpodautoscalers", true)
+	// the same objects, with the same uid, are served in every version
+	server.addObject(hpaV1Resource, newFakeObject("autoscaling/v1", "HorizontalPodAutoscaler", "shop", "web"))
+	// objects sharing a name in other namespaces are distinct
+	for _, gvr := range []schema.GroupVersionResource{hpaV2Resource, hpaV1Resource} {
+		server.addObject(gvr, newFakeObject(gvr.GroupVersion().String(), "HorizontalPodAutoscaler", "web", "web"))
+	}
+	cluster := server.cluster(t)
+
+	gvks := []schema.GroupVersionKind{hpaV1, hpaV2}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true, Concurrency: 1})
+	assert.NoError(t, err)
+	var got []string
+	for _, obj := range objs {
+		got = append(got, obj.GetAPIVersion()+"/"+obj.GetKind()+" "+obj.GetNamespace()+"/"+obj.GetName())
+	}
+	assert.Equal(t, []string{
+		"autoscaling/v2/HorizontalPodAutoscaler shop/web",
+		"autoscaling/v2/HorizontalPodAutoscaler web/web",
+	}, got)
+	assert.Equal(t, []string{"/apis/autoscaling/v2/horizontalpodautoscalers", "/apis/autoscaling/v1/horizontalpodautoscalers"}, server.resourceRequests())
+}
@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const (
+	// deprecatedApisMetric is set by the api server for every deprecated api requested since it started
+	deprecatedApisMetric = "apiserver_requested_deprecated_apis"
+	// requestsMetric counts the requests served by the api server by group, version and resource
+	requestsMetric = "apiserver_request_total"
+)
+
+// DeprecatedAPIUsage is a deprecated api which clients of the cluster requested since the api server started, even if
+// no object is stored in it those clients break once it is removed
+type DeprecatedAPIUsage struct {
+	Group       string `json:"group,omitempty"`
+	Version     string `json:"version"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	// RemovedRelease is the kubernetes version the api is removed in, empty if its removal is not planned
+	RemovedRelease string `json:"removedRelease,omitempty"`
+	// Requests is the number of requests to the api counted by the api server
+	Requests float64 `json:"requests,omitempty"`
+}
+
+func (u DeprecatedAPIUsage) String() string {
+	s := u.Version + "/" + u.Resource
+	if len(u.Group) > 0 {
+		s = u.Group + "/" + s
+	}
+	if len(u.Subresource) > 0 {
+		s += "/" + u.Subresource
+	}
+	s = "deprecated API usage observed: " + s
+	if len(u.RemovedRelease) > 0 {
+		s += ", removed in " + u.RemovedRelease
+	}
+	if u.Requests > 0 {
+		s += fmt.Sprintf(" (%.0f requests)", u.Requests)
+	}
+	return s
+}
+
+// DeprecatedAPIUsage reads the deprecated apis requested from the cluster off the metrics of the api server, which
+// needs get on the /metrics non-resource url. Only the api server instance answering is accounted for
+func (c *Cluster) DeprecatedAPIUsage(ctx context.Context) ([]DeprecatedAPIUsage, error) {
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	body, err := c.disco.RESTClient().Get().AbsPath("/metrics").Do(ctx).Raw()
+	if err != nil {
+		return nil, err
+	}
+	return parseDeprecatedAPIUsage(body)
+}
+
+// parseDeprecatedAPIUsage returns the deprecated apis of the metrics in text format along with the number of
+// requests made to them
+func parseDeprecatedAPIUsage(metrics []byte) ([]DeprecatedAPIUsage, error) {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bytes.NewReader(metrics))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the metrics of the api server: %w", err)
+	}
+	var usages []DeprecatedAPIUsage
+	index := map[DeprecatedAPIUsage]int{}
+	for _, m := range families[deprecatedApisMetric].GetMetric() {
+		if m.GetGauge().GetValue() == 0 {
+			continue
+		}
+		labels := metricLabels(m)
+		key := DeprecatedAPIUsage{Group: labels["group"], Version: labels["version"], Resource: labels["resource"], Subresource: labels["subresource"]}
+		if _, ok := index[key]; ok {
+			continue
+		}
+		usage := key
+		usage.RemovedRelease = labels["removed_release"]
+		index[key] = len(usages)
+		usages = append(usages, usage)
+	}
+	for _, m := range families[requestsMetric].GetMetric() {
+		labels := metricLabels(m)
+		key := DeprecatedAPIUsage{Group: labels["group"], Version: labels["version"], Resource: labels["resource"], Subresource: labels["subresource"]}
+		if i, ok := index[key]; ok {
+			usages[i].Requests += m.GetCounter().GetValue()
+		}
+	}
+	sort.SliceStable(usages, func(i, j int) bool { return usages[i].String() < usages[j].String() })
+	return usages, nil
+}
+
+func metricLabels(m *dto.Metric) map[string]string {
+	labels := map[string]string{}
+	for _, pair := range m.GetLabel() {
+		labels[pair.GetName()] = pair.GetValue()
+	}
+	return labels
+}
+
+// deprecatedAPIUsageNote explains why the deprecated apis requested from the cluster could not be read, see
+// Cluster.DeprecatedAPIUsage
+func deprecatedAPIUsageNote(err error) string {
+	if k8sErrors.IsForbidden(err) || k8sErrors.IsUnauthorized(err) {
+		return "deprecated API usage not checked, reading /metrics of the api server is not allowed"
+	}
+	return fmt.Sprintf("deprecated API usage not checked: %v", err)
+}
+
+// AddDeprecatedAPIUsage records the deprecated apis requested from cluster in the summary, or why they could not be
+// read, the scan goes on either way
+func (s *FetchSummary) AddDeprecatedAPIUsage(ctx context.Context, cluster *Cluster) {
+	if s == nil {
+		return
+	}
+	usages, err := cluster.DeprecatedAPIUsage(ctx)
+	if err != nil {
+		s.DeprecatedAPIUsageNote = deprecatedAPIUsageNote(err)
+		return
+	}
+	s.DeprecatedAPIUsage = usages
+}
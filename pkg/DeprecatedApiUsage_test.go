@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const deprecatedApiMetrics = `# HELP apiserver_requested_deprecated_apis [STABLE] Gauge of deprecated APIs that have been requested, broken out by API group, version, resource, subresource, and removed_release.
+# TYPE apiserver_requested_deprecated_apis gauge
+apiserver_requested_deprecated_apis{group="policy",removed_release="1.25",resource="podsecuritypolicies",subresource="",version="v1beta1"} 1
+apiserver_requested_deprecated_apis{group="batch",removed_release="1.25",resource="cronjobs",subresource="",version="v1beta1"} 0
+# HELP apiserver_request_total [STABLE] Counter of apiserver requests broken out for each verb, dry run value, group, version, resource, scope, component, and HTTP response code.
+# TYPE apiserver_request_total counter
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="policy",resource="podsecuritypolicies",scope="cluster",subresource="",verb="LIST",version="v1beta1"} 12
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="policy",resource="podsecuritypolicies",scope="resource",subresource="",verb="GET",version="v1beta1"} 3
+apiserver_request_total{code="200",component="apiserver",dry_run="",group="apps",resource="deployments",scope="cluster",subresource="",verb="LIST",version="v1"} 40
+`
+
+func TestCluster_DeprecatedAPIUsage(t *testing.T) {
+	server := newFakeApiServer(t)
+	forbidden := false
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/metrics" {
+			return false
+		}
+		if forbidden {
+			writeStatus(w, http.StatusForbidden, "Forbidden", `forbidden: User "ci" cannot get path "/metrics"`)
+			return true
+		}
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_, _ = w.Write([]byte(deprecatedApiMetrics))
+		return true
+	}
+	cluster := server.cluster(t)
+
+	usages, err := cluster.DeprecatedAPIUsage(context.Background())
+	assert.NoError(t, err)
+	// apis which are no longer requested are left out
+	assert.Equal(t, []DeprecatedAPIUsage{{Group: "policy", Version: "v1beta1", Resource: "podsecuritypolicies", RemovedRelease: "1.25", Requests: 15}}, usages)
+
+	summary := &FetchSummary{}
+	summary.AddDeprecatedAPIUsage(context.Background(), cluster)
+	assert.True(t, summary.Reportable())
+	assert.Contains(t, summary.String(), "deprecated API usage observed: policy/v1beta1/podsecuritypolicies, removed in 1.25 (15 requests)")
+
+	// missing permissions are noted without failing the scan
+	forbidden = true
+	summary = &FetchSummary{}
+	summary.AddDeprecatedAPIUsage(context.Background(), cluster)
+	assert.Empty(t, summary.DeprecatedAPIUsage)
+	assert.Equal(t, "deprecated API usage not checked, reading /metrics of the api server is not allowed", summary.DeprecatedAPIUsageNote)
+}
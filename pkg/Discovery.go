@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/discovery"
+)
+
+// DiscoverGVKs returns the listable kinds served by the cluster which pass the group and kind filters of conf, in the
+// preferred version of the cluster or in every served version with AllServedVersions. Kinds of api groups whose
+// discovery failed are left out, FetchK8sObjects reports those groups as skipped
+func (c *Cluster) DiscoverGVKs(ctx context.Context, conf *Config) ([]schema.GroupVersionKind, error) {
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	var resourceLists []*v1.APIResourceList
+	var err error
+	if conf.AllServedVersions {
+		_, resourceLists, err = c.disco.ServerGroupsAndResources()
+	} else {
+		resourceLists, err = c.disco.ServerPreferredResources()
+	}
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, fmt.Errorf("discovery of cluster %s failed: %w", c.name, err)
+	}
+	var gvks []schema.GroupVersionKind
+	for _, resourceList := range resourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+		for _, resource := range resourceList.APIResources {
+			// subresources like deployments/scale are not objects of their own
+			if strings.Contains(resource.Name, "/") {
+				continue
+			}
+			gvr := gv.WithResource(resource.Name)
+			if matchesResourceFilters(gvr, conf.ExcludeResources) ||
+				!sets.New(resource.Verbs...).Has("list") && !matchesResourceFilters(gvr, conf.IncludeResources) {
+				continue
+			}
+			gvk := gv.WithKind(resource.Kind)
+			if !conf.customResourcesSelected(gvk) || !conf.apiGroupSelected(gvk.Group) || matchesKindFilters(gvk, conf.IgnoreKinds) ||
+				len(conf.SelectKinds) > 0 && !matchesKindFilters(gvk, conf.SelectKinds) || conf.kindFetchOptions(gvk.Kind).Skip {
+				continue
+			}
+			gvks = append(gvks, gvk)
+		}
+	}
+	return gvks, nil
+}
+
+// FetchAllObjects lists the objects of every kind discovered by DiscoverGVKs like FetchK8sObjects does
+func (c *Cluster) FetchAllObjects(ctx context.Context, conf *Config) ([]unstructured.Unstructured, *FetchSummary, error) {
+	gvks, err := c.DiscoverGVKs(ctx, conf)
+	if err != nil {
+		return nil, &FetchSummary{}, err
+	}
+	return c.FetchK8sObjects(ctx, gvks, conf)
+}
@@ -0,0 +1,202 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	openapi_v2 "github.com/google/gnostic-models/openapiv2"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/openapi"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/util/homedir"
+)
+
+// DefaultDiscoveryCacheTTL is how long discovery responses cached on disk are used when DiscoveryCacheTTL is unset
+const DefaultDiscoveryCacheTTL = 10 * time.Minute
+
+// DefaultDiscoveryCacheDir returns the directory discovery responses are cached in by the kubedd command, next to
+// the discovery cache of kubectl
+func DefaultDiscoveryCacheDir() string {
+	return filepath.Join(homedir.HomeDir(), ".kube", "cache", "silver-surfer")
+}
+
+// discoveryCacheDir returns the directory the discovery responses of the api server at host are cached in, or an
+// empty string when they are not cached
+func (c *Config) discoveryCacheDir(host string) string {
+	if c == nil || c.NoDiscoveryCache || len(c.DiscoveryCacheDir) == 0 {
+		return ""
+	}
+	hash := sha256.Sum256([]byte(host))
+	return filepath.Join(c.DiscoveryCacheDir, hex.EncodeToString(hash[:8]))
+}
+
+func (c *Config) discoveryCacheTTL() time.Duration {
+	if c == nil || c.DiscoveryCacheTTL <= 0 {
+		return DefaultDiscoveryCacheTTL
+	}
+	return c.DiscoveryCacheTTL
+}
+
+// diskCachedDiscovery caches the groups and resources discovered by delegate as json files in dir for ttl, like the
+// discovery cache of kubectl. Failures to write the cache are ignored as the responses are served all the same
+type diskCachedDiscovery struct {
+	delegate discovery.DiscoveryInterface
+	dir      string
+	ttl      time.Duration
+
+	mu sync.Mutex
+	// invalidated is the time before which cached files are not used
+	invalidated time.Time
+	// fresh is false once a response was served from the cache
+	fresh bool
+}
+
+var _ discovery.CachedDiscoveryInterface = &diskCachedDiscovery{}
+
+func newDiskCachedDiscovery(delegate discovery.DiscoveryInterface, dir string, ttl time.Duration) *diskCachedDiscovery {
+	return &diskCachedDiscovery{delegate: delegate, dir: dir, ttl: ttl, fresh: true}
+}
+
+func (d *diskCachedDiscovery) ServerGroups() (*v1.APIGroupList, error) {
+	file := filepath.Join(d.dir, "servergroups.json")
+	groups := &v1.APIGroupList{}
+	if d.read(file, groups) {
+		return groups, nil
+	}
+	groups, err := d.delegate.ServerGroups()
+	if err != nil {
+		return groups, err
+	}
+	d.write(file, groups)
+	return groups, nil
+}
+
+func (d *diskCachedDiscovery) ServerResourcesForGroupVersion(groupVersion string) (*v1.APIResourceList, error) {
+	file := filepath.Join(d.dir, filepath.FromSlash(groupVersion), "serverresources.json")
+	resources := &v1.APIResourceList{}
+	if d.read(file, resources) {
+		return resources, nil
+	}
+	resources, err := d.delegate.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		return resources, err
+	}
+	d.write(file, resources)
+	return resources, nil
+}
+
+func (d *diskCachedDiscovery) ServerGroupsAndResources() ([]*v1.APIGroup, []*v1.APIResourceList, error) {
+	return discovery.ServerGroupsAndResources(d)
+}
+
+func (d *diskCachedDiscovery) ServerPreferredResources() ([]*v1.APIResourceList, error) {
+	return discovery.ServerPreferredResources(d)
+}
+
+func (d *diskCachedDiscovery) ServerPreferredNamespacedResources() ([]*v1.APIResourceList, error) {
+	return discovery.ServerPreferredNamespacedResources(d)
+}
+
+func (d *diskCachedDiscovery) RESTClient() restclient.Interface {
+	return d.delegate.RESTClient()
+}
+
+func (d *diskCachedDiscovery) ServerVersion() (*version.Info, error) {
+	return d.delegate.ServerVersion()
+}
+
+func (d *diskCachedDiscovery) OpenAPISchema() (*openapi_v2.Document, error) {
+	return d.delegate.OpenAPISchema()
+}
+
+func (d *diskCachedDiscovery) OpenAPIV3() openapi.Client {
+	return d.delegate.OpenAPIV3()
+}
+
+// WithLegacy returns d, groups and resources are always discovered one group version at a time
+func (d *diskCachedDiscovery) WithLegacy() discovery.DiscoveryInterface {
+	return d
+}
+
+// Fresh returns false once a response was served from the cache, until the cache is invalidated
+func (d *diskCachedDiscovery) Fresh() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.fresh
+}
+
+// Invalidate ignores the files cached so far, they are replaced as the groups and resources are discovered again
+func (d *diskCachedDiscovery) Invalidate() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.invalidated = time.Now()
+	d.fresh = true
+}
+
+// read decodes file into obj if it was cached within the ttl and after the cache was last invalidated
+func (d *diskCachedDiscovery) read(file string, obj interface{}) bool {
+	info, err := os.Stat(file)
+	if err != nil || time.Since(info.ModTime()) > d.ttl {
+		return false
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if info.ModTime().Before(d.invalidated) {
+		return false
+	}
+	data, err := os.ReadFile(file)
+	if err != nil || json.Unmarshal(data, obj) != nil {
+		return false
+	}
+	d.fresh = false
+	return true
+}
+
+// write caches obj as file, the file is replaced atomically so that concurrent runs never read partial files
+func (d *diskCachedDiscovery) write(file string, obj interface{}) {
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0750); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(file), filepath.Base(file)+".*")
+	if err != nil {
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err != nil || closeErr != nil {
+		return
+	}
+	_ = os.Rename(tmp.Name(), file)
+}
+
+// cachedDiscovery is a memory cache of the discovery of the cluster which is only fresh as long as the disk cache
+// below it is, so that mappers reset both caches when a kind is missing from a cache filled from disk
+func (c *Cluster) cachedDiscovery() discovery.CachedDiscoveryInterface {
+	cached := memory.NewMemCacheClient(c.disco)
+	disk, ok := c.disco.(discovery.CachedDiscoveryInterface)
+	if !ok {
+		return cached
+	}
+	return layeredDiscoveryCache{CachedDiscoveryInterface: cached, disk: disk}
+}
+
+type layeredDiscoveryCache struct {
+	discovery.CachedDiscoveryInterface
+	disk discovery.CachedDiscoveryInterface
+}
+
+func (c layeredDiscoveryCache) Fresh() bool {
+	return c.CachedDiscoveryInterface.Fresh() && c.disk.Fresh()
+}
@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// discoveryRequests returns the paths of the discovery requests received by server
+func discoveryRequests(server *fakeApiServer) []string {
+	var paths []string
+	for _, r := range server.recorded() {
+		if r.Path == "/api" || r.Path == "/apis" || r.Path == "/api/v1" || r.Path == "/apis/apps/v1" {
+			paths = append(paths, r.Path)
+		}
+	}
+	return paths
+}
+
+func TestCluster_discoveryCache(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	server.addObject(server.addResource(configMapGvk, "configmaps", true), newFakeObject("v1", "ConfigMap", "shop", "settings"))
+	conf := &Config{DiscoveryCacheDir: t.TempDir(), SkipAccessReview: true}
+	newCluster := func() *Cluster {
+		cluster, err := NewClusterFromEnvOrConfig(server.restConfig(), ClusterOptions{}, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return cluster
+	}
+	dir := conf.discoveryCacheDir(server.URL)
+
+	objs, _, err := newCluster().FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, conf)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.FileExists(t, filepath.Join(dir, "servergroups.json"))
+	assert.FileExists(t, filepath.Join(dir, "v1", "serverresources.json"))
+	discovered := len(discoveryRequests(server))
+	assert.NotZero(t, discovered)
+
+	// another run is served from the cache
+	objs, _, err = newCluster().FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, conf)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Len(t, discoveryRequests(server), discovered)
+
+	// kinds missing from the cache are discovered again
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	objs, _, err = newCluster().FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk, deploymentGvk}, conf)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 2)
+	assert.FileExists(t, filepath.Join(dir, "apps", "v1", "serverresources.json"))
+	assert.Contains(t, discoveryRequests(server)[discovered:], "/apis/apps/v1")
+
+	// expired files are replaced
+	discovered = len(discoveryRequests(server))
+	expired := time.Now().Add(-DefaultDiscoveryCacheTTL - time.Minute)
+	assert.NoError(t, os.Chtimes(filepath.Join(dir, "servergroups.json"), expired, expired))
+	_, _, err = newCluster().FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, conf)
+	assert.NoError(t, err)
+	assert.Contains(t, discoveryRequests(server)[discovered:], "/apis")
+}
+
+func TestConfig_discoveryCacheDir(t *testing.T) {
+	conf := &Config{DiscoveryCacheDir: "/cache"}
+	a, b := conf.discoveryCacheDir("https://a.example.com"), conf.discoveryCacheDir("https://b.example.com")
+	assert.Equal(t, "/cache", filepath.Dir(a))
+	assert.NotEqual(t, a, b)
+	assert.Empty(t, (&Config{DiscoveryCacheDir: "/cache", NoDiscoveryCache: true}).discoveryCacheDir("https://a.example.com"))
+	assert.Empty(t, (&Config{}).discoveryCacheDir("https://a.example.com"))
+	assert.Empty(t, (*Config)(nil).discoveryCacheDir("https://a.example.com"))
+}
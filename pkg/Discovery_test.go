@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_DiscoverGVKs(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	hpaV2 := schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
+	hpaV1 := schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "HorizontalPodAutoscaler"}
+	widgetV1 := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	widgetV1beta1 := schema.GroupVersionKind{Group: "example.com", Version: "v1beta1", Kind: "Widget"}
+	bindingGvk := schema.GroupVersionKind{Version: "v1", Kind: "Binding"}
+	server.addResource(configMapGvk, "configmaps", true)
+	server.addResource(deploymentGvk, "deployments", true)
+	server.addResource(hpaV2, "horizontalpodautoscalers", true)
+	server.addResource(hpaV1, "horizontalpodautoscalers", true)
+	server.addResource(widgetV1, "widgets", true)
+	server.addResource(widgetV1beta1, "widgets", true)
+	server.setVerbs(server.addResource(bindingGvk, "bindings", true), "create")
+	server.addObject(schema.GroupVersionResource{Group: "example.com", Version: "v1", Resource: "widgets"}, newFakeObject("example.com/v1", "Widget", "shop", "gear"))
+	cluster := server.cluster(t)
+
+	tests := []struct {
+		name string
+		conf *Config
+		want []schema.GroupVersionKind
+	}{
+		{
+			name: "preferred versions",
+			conf: &Config{},
+			want: []schema.GroupVersionKind{configMapGvk, deploymentGvk, hpaV2, widgetV1},
+		},
+		{
+			name: "all served versions",
+			conf: &Config{AllServedVersions: true},
+			want: []schema.GroupVersionKind{configMapGvk, deploymentGvk, hpaV2, hpaV1, widgetV1, widgetV1beta1},
+		},
+		{
+			name: "group and kind filters",
+			conf: &Config{AllServedVersions: true, IgnoreAPIGroups: []string{"apps"}, IgnoreKinds: []string{"ConfigMap"}},
+			want: []schema.GroupVersionKind{hpaV2, hpaV1, widgetV1, widgetV1beta1},
+		},
+		{
+			name: "custom resources only",
+			conf: &Config{IncludeCustomResources: CustomResourcesOnlyCRDs},
+			want: []schema.GroupVersionKind{widgetV1},
+		},
+		{
+			name: "resources which cannot be listed unless included",
+			conf: &Config{SelectKinds: []string{"Binding", "ConfigMap"}, IncludeResources: []string{"bindings"}},
+			want: []schema.GroupVersionKind{configMapGvk, bindingGvk},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gvks, err := cluster.DiscoverGVKs(context.Background(), tt.conf)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, gvks)
+		})
+	}
+
+	_, err := cluster.DiscoverGVKs(context.Background(), &Config{SelectKinds: []string{"~("}})
+	assert.Error(t, err)
+}
+
+func TestCluster_FetchAllObjects(t *testing.T) {
+	server := newFakeApiServer(t)
+	widgetV1 := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	widgetV1beta1 := schema.GroupVersionKind{Group: "example.com", Version: "v1beta1", Kind: "Widget"}
+	server.addObject(server.addResource(widgetV1, "widgets", true), newFakeObject("example.com/v1", "Widget", "shop", "gear"))
+	server.addObject(server.addResource(widgetV1beta1, "widgets", true), newFakeObject("example.com/v1beta1", "Widget", "shop", "gear"))
+	cluster := server.cluster(t)
+
+	// objects served in several versions are returned once, in the preferred version
+	objs, summary, err := cluster.FetchAllObjects(context.Background(), &Config{AllServedVersions: true})
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 1) {
+		assert.Equal(t, "example.com/v1", objs[0].GetAPIVersion())
+	}
+	assert.False(t, summary.Partial())
+}
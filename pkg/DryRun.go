@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// RuleDryRunRejected is the rule of the objects the verify cluster rejects on a dry-run apply, see Config.VerifyCluster
+const RuleDryRunRejected = "dry-run-rejected"
+
+// dryRunFieldManager is the field manager of the dry-run applies to verify clusters
+const dryRunFieldManager = "kubedd"
+
+// DryRunApply applies obj server-side to the cluster with dryRun=All, so that it goes through admission and the
+// validation of the api server without being persisted. Kinds which the cluster does not serve are reported as a
+// NoKindMatchError
+func (c *Cluster) DryRunApply(ctx context.Context, obj *unstructured.Unstructured) error {
+	if err := c.initClients(); err != nil {
+		return err
+	}
+	gvk := obj.GroupVersionKind()
+	mapping, err := c.mapper(c.cachedDiscovery()).RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return err
+	}
+	options := v1.ApplyOptions{DryRun: []string{v1.DryRunAll}, FieldManager: dryRunFieldManager, Force: true}
+	resource := c.dynamicClient().Resource(mapping.Resource)
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		_, err = resource.Namespace(obj.GetNamespace()).Apply(ctx, obj.GetName(), obj, options)
+	} else {
+		_, err = resource.Apply(ctx, obj.GetName(), obj, options)
+	}
+	return err
+}
+
+// passesSchemaValidation returns true if the object of vr validates against the schema it is migrated to
+func passesSchemaValidation(vr ValidationResult) bool {
+	if len(vr.LatestAPIVersion) > 0 {
+		return len(vr.ErrorsForLatest) == 0
+	}
+	return !vr.Deleted && len(vr.ErrorsForOriginal) == 0
+}
+
+// VerifyDryRun dry-run applies obj, in the latest api version of vr, to the VerifyCluster of conf when it passes
+// schema validation. A rejection is recorded in vr.DryRunRejection with the message of the api server, objects which
+// could not be verified, eg as their namespace does not exist on the verify cluster, in vr.DryRunNote
+func VerifyDryRun(ctx context.Context, vr ValidationResult, obj *unstructured.Unstructured, conf *Config) ValidationResult {
+	if conf.VerifyCluster == nil || !passesSchemaValidation(vr) {
+		return vr
+	}
+	apiVersion := obj.GetAPIVersion()
+	if len(vr.LatestAPIVersion) > 0 {
+		apiVersion = vr.LatestAPIVersion
+	}
+	err := conf.VerifyCluster.DryRunApply(ctx, migratedObject(obj, apiVersion))
+	var statusErr k8sErrors.APIStatus
+	switch {
+	case err == nil:
+	case meta.IsNoMatchError(err):
+		vr.DryRunRejection = fmt.Sprintf("%s %s is not served by the verify cluster", apiVersion, obj.GetKind())
+	case k8sErrors.IsNotFound(err) && isNamespaceNotFound(err):
+		vr.DryRunNote = fmt.Sprintf("namespace %s does not exist on the verify cluster, not verified", obj.GetNamespace())
+	case k8sErrors.IsForbidden(err) || k8sErrors.IsUnauthorized(err):
+		vr.DryRunNote = fmt.Sprintf("not allowed to verify on the verify cluster: %v", err)
+	case errors.As(err, &statusErr) && statusErr.Status().Code < 500:
+		vr.DryRunRejection = statusErr.Status().Message
+	default:
+		vr.DryRunNote = fmt.Sprintf("not verified: %v", err)
+	}
+	return vr
+}
+
+// isNamespaceNotFound returns true if the not found error is about the namespace of the object
+func isNamespaceNotFound(err error) bool {
+	var statusErr k8sErrors.APIStatus
+	return errors.As(err, &statusErr) && statusErr.Status().Details != nil && statusErr.Status().Details.Kind == "namespaces"
+}
@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestVerifyDryRun(t *testing.T) {
+	server := newFakeApiServer(t)
+	server.addResource(schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}, "ingresses", true)
+	var applied []string
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.Method != http.MethodPatch {
+			return false
+		}
+		// nothing may ever be persisted to the verify cluster
+		if r.URL.Query().Get("dryRun") != "All" {
+			writeStatus(w, http.StatusBadRequest, "BadRequest", "not a dry-run")
+			return true
+		}
+		body, _ := io.ReadAll(r.Body)
+		applied = append(applied, r.URL.Path)
+		switch r.URL.Path {
+		case "/apis/networking.k8s.io/v1/namespaces/shop/ingresses/web":
+			writeStatus(w, http.StatusBadRequest, "BadRequest", `admission webhook "policy.example.com" denied the request: ingress class is required`)
+		case "/apis/networking.k8s.io/v1/namespaces/legacy/ingresses/web":
+			writeJson(w, http.StatusNotFound, map[string]interface{}{
+				"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "NotFound", "code": 404,
+				"message": `namespaces "legacy" not found`, "details": map[string]interface{}{"name": "legacy", "kind": "namespaces"},
+			})
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write(body)
+		}
+		return true
+	}
+	conf := &Config{VerifyCluster: server.cluster(t)}
+	migratable := ValidationResult{Kind: "Ingress", APIVersion: "networking.k8s.io/v1beta1", LatestAPIVersion: "networking.k8s.io/v1", Deleted: true}
+	ingress := func(namespace string) *unstructured.Unstructured {
+		return &unstructured.Unstructured{Object: newFakeObject("networking.k8s.io/v1beta1", "Ingress", namespace, "web")}
+	}
+
+	got := VerifyDryRun(context.Background(), migratable, ingress("shop"), conf)
+	assert.Equal(t, `admission webhook "policy.example.com" denied the request: ingress class is required`, got.DryRunRejection)
+	assert.Equal(t, SeverityError, ResultSeverity(got, conf))
+
+	got = VerifyDryRun(context.Background(), migratable, ingress("legacy"), conf)
+	assert.Empty(t, got.DryRunRejection)
+	assert.Equal(t, "namespace legacy does not exist on the verify cluster, not verified", got.DryRunNote)
+
+	got = VerifyDryRun(context.Background(), migratable, ingress("cart"), conf)
+	assert.Empty(t, got.DryRunRejection)
+	assert.Empty(t, got.DryRunNote)
+	// the object is applied in its latest api version
+	assert.Equal(t, "/apis/networking.k8s.io/v1/namespaces/cart/ingresses/web", applied[len(applied)-1])
+
+	policy := &unstructured.Unstructured{Object: newFakeObject("policy/v1beta1", "PodSecurityPolicy", "", "restricted")}
+	got = VerifyDryRun(context.Background(), ValidationResult{Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1"}, policy, conf)
+	assert.Equal(t, "policy/v1beta1 PodSecurityPolicy is not served by the verify cluster", got.DryRunRejection)
+
+	// objects failing schema validation are not applied
+	applied = nil
+	failing := migratable
+	failing.ErrorsForLatest = []*openapi3.SchemaError{{Reason: "invalid"}}
+	got = VerifyDryRun(context.Background(), failing, ingress("shop"), conf)
+	assert.Empty(t, applied)
+	assert.Empty(t, got.DryRunRejection)
+}
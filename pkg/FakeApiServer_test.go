@@ -0,0 +1,423 @@
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+// fakeResource describes an api resource served by fakeApiServer
+type fakeResource struct {
+	gvk        schema.GroupVersionKind
+	resource   string
+	namespaced bool
+	verbs      []string
+	shortNames []string
+}
+
+func (r fakeResource) gvr() schema.GroupVersionResource {
+	return r.gvk.GroupVersion().WithResource(r.resource)
+}
+
+// fakeRequest is a copy of a request received by fakeApiServer
+type fakeRequest struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+}
+
+// fakeApiServer is a minimal kubernetes api server serving version, discovery, list and get
+// requests from in-memory objects, used to exercise the real discovery and dynamic clients
+type fakeApiServer struct {
+	*httptest.Server
+	version   string
+	mu        sync.Mutex
+	resources []fakeResource
+	objects   map[schema.GroupVersionResource][]*unstructured.Unstructured
+	requests  []fakeRequest
+	// denied holds the resources the user may not list as group/resource/namespace, see deny
+	denied map[string]bool
+	// intercept is called before the default handling, returning true marks the request as handled
+	intercept func(w http.ResponseWriter, r *http.Request) bool
+}
+
+func newFakeApiServer(t testing.TB) *fakeApiServer {
+	s := &fakeApiServer{
+		version: "1.27",
+		objects: map[schema.GroupVersionResource][]*unstructured.Unstructured{},
+		denied:  map[string]bool{},
+	}
+	s.Server = httptest.NewServer(s)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *fakeApiServer) restConfig() *rest.Config {
+	return &rest.Config{Host: s.URL}
+}
+
+// cluster returns a Cluster talking to the fake server
+func (s *fakeApiServer) cluster(t testing.TB) *Cluster {
+	t.Helper()
+	cluster, err := NewClusterFromEnvOrConfig(s.restConfig(), ClusterOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cluster
+}
+
+func (s *fakeApiServer) addResource(gvk schema.GroupVersionKind, resource string, namespaced bool) schema.GroupVersionResource {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := fakeResource{
+		gvk:        gvk,
+		resource:   resource,
+		namespaced: namespaced,
+		verbs:      []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+	}
+	s.resources = append(s.resources, r)
+	return r.gvr()
+}
+
+// setVerbs replaces the verbs advertised in discovery for the resource
+func (s *fakeApiServer) setVerbs(gvr schema.GroupVersionResource, verbs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.resources {
+		if s.resources[i].gvr() == gvr {
+			s.resources[i].verbs = verbs
+		}
+	}
+}
+
+// setShortNames sets the short names advertised in discovery for the resource, eg deploy for deployments
+func (s *fakeApiServer) setShortNames(gvr schema.GroupVersionResource, shortNames ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.resources {
+		if s.resources[i].gvr() == gvr {
+			s.resources[i].shortNames = shortNames
+		}
+	}
+}
+
+// deny answers self subject access reviews for listing the resource in namespace, or across the cluster when it is
+// empty, as not allowed
+func (s *fakeApiServer) deny(gvr schema.GroupVersionResource, namespace string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.denied[gvr.Group+"/"+gvr.Resource+"/"+namespace] = true
+}
+
+func (s *fakeApiServer) addObject(gvr schema.GroupVersionResource, obj map[string]interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[gvr] = append(s.objects[gvr], &unstructured.Unstructured{Object: obj})
+}
+
+func (s *fakeApiServer) recorded() []fakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeRequest(nil), s.requests...)
+}
+
+// resourceRequests returns the paths of all requests made against resources, skipping version and discovery calls
+func (s *fakeApiServer) resourceRequests() []string {
+	var paths []string
+	for _, r := range s.recorded() {
+		if r.Path == "/version" || r.Path == "/api" || r.Path == "/apis" || r.Path == "/api/v1" || strings.Count(r.Path, "/") == 3 && strings.HasPrefix(r.Path, "/apis/") || r.Path == selfSubjectAccessReviewsPath {
+			continue
+		}
+		paths = append(paths, r.Path)
+	}
+	return paths
+}
+
+func newFakeObject(apiVersion, kind, namespace, name string) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"name": name,
+		"uid":  fmt.Sprintf("%s-%s-%s", strings.ToLower(kind), namespace, name),
+	}
+	if len(namespace) > 0 {
+		metadata["namespace"] = namespace
+	}
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata":   metadata,
+	}
+}
+
+func (s *fakeApiServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, fakeRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.Query(), Header: r.Header.Clone()})
+	intercept := s.intercept
+	s.mu.Unlock()
+	if intercept != nil && intercept(w, r) {
+		return
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case path == selfSubjectAccessReviewsPath && r.Method == http.MethodPost:
+		s.serveAccessReview(w, r)
+	case path == "/version":
+		parts := strings.SplitN(s.version, ".", 2)
+		writeJson(w, http.StatusOK, map[string]interface{}{"major": parts[0], "minor": parts[1], "gitVersion": "v" + s.version + ".0"})
+	case path == "/api":
+		writeJson(w, http.StatusOK, map[string]interface{}{"kind": "APIVersions", "versions": []string{"v1"}})
+	case path == "/apis":
+		writeJson(w, http.StatusOK, s.groupList())
+	case path == "/api/v1":
+		writeJson(w, http.StatusOK, s.resourceList(schema.GroupVersion{Version: "v1"}))
+	case strings.HasPrefix(path, "/api/v1/"):
+		s.serveResource(w, r, schema.GroupVersion{Version: "v1"}, strings.Split(strings.TrimPrefix(path, "/api/v1/"), "/"))
+	case strings.HasPrefix(path, "/apis/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/apis/"), "/")
+		if len(parts) < 2 {
+			writeStatus(w, http.StatusNotFound, "NotFound", "not found")
+			return
+		}
+		gv := schema.GroupVersion{Group: parts[0], Version: parts[1]}
+		if len(parts) == 2 {
+			resourceList := s.resourceList(gv)
+			if len(resourceList["resources"].([]interface{})) == 0 {
+				writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("the server could not find the requested resource %s", gv.String()))
+				return
+			}
+			writeJson(w, http.StatusOK, resourceList)
+			return
+		}
+		s.serveResource(w, r, gv, parts[2:])
+	default:
+		writeStatus(w, http.StatusNotFound, "NotFound", "not found")
+	}
+}
+
+const selfSubjectAccessReviewsPath = "/apis/authorization.k8s.io/v1/selfsubjectaccessreviews"
+
+// serveAccessReview allows every request except those denied with deny
+func (s *fakeApiServer) serveAccessReview(w http.ResponseWriter, r *http.Request) {
+	var review map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	attributes, _, _ := unstructured.NestedStringMap(review, "spec", "resourceAttributes")
+	s.mu.Lock()
+	denied := s.denied[attributes["group"]+"/"+attributes["resource"]+"/"+attributes["namespace"]]
+	s.mu.Unlock()
+	status := map[string]interface{}{"allowed": !denied}
+	if denied {
+		status["reason"] = "no rbac policy matched"
+	}
+	review["status"] = status
+	writeJson(w, http.StatusCreated, review)
+}
+
+func (s *fakeApiServer) groupList() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	versions := map[string][]string{}
+	var groups []string
+	for _, r := range s.resources {
+		if len(r.gvk.Group) == 0 {
+			continue
+		}
+		if _, ok := versions[r.gvk.Group]; !ok {
+			groups = append(groups, r.gvk.Group)
+		}
+		if !containsString(versions[r.gvk.Group], r.gvk.Version) {
+			versions[r.gvk.Group] = append(versions[r.gvk.Group], r.gvk.Version)
+		}
+	}
+	var items []interface{}
+	for _, g := range groups {
+		var gvs []interface{}
+		for _, v := range versions[g] {
+			gvs = append(gvs, map[string]interface{}{"groupVersion": g + "/" + v, "version": v})
+		}
+		items = append(items, map[string]interface{}{
+			"name":             g,
+			"versions":         gvs,
+			"preferredVersion": gvs[0],
+		})
+	}
+	return map[string]interface{}{"kind": "APIGroupList", "apiVersion": "v1", "groups": items}
+}
+
+func (s *fakeApiServer) resourceList(gv schema.GroupVersion) map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]interface{}, 0)
+	for _, r := range s.resources {
+		if r.gvk.GroupVersion() != gv {
+			continue
+		}
+		items = append(items, map[string]interface{}{
+			"name":       r.resource,
+			"namespaced": r.namespaced,
+			"kind":       r.gvk.Kind,
+			"verbs":      r.verbs,
+			"shortNames": r.shortNames,
+		})
+	}
+	return map[string]interface{}{"kind": "APIResourceList", "apiVersion": "v1", "groupVersion": gv.String(), "resources": items}
+}
+
+func (s *fakeApiServer) lookup(gv schema.GroupVersion, resource string) (fakeResource, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.resources {
+		if r.gvk.GroupVersion() == gv && r.resource == resource {
+			return r, true
+		}
+	}
+	return fakeResource{}, false
+}
+
+func (s *fakeApiServer) serveResource(w http.ResponseWriter, r *http.Request, gv schema.GroupVersion, segments []string) {
+	namespace := ""
+	if len(segments) >= 3 && segments[0] == "namespaces" {
+		namespace = segments[1]
+		segments = segments[2:]
+	}
+	res, ok := s.lookup(gv, segments[0])
+	if !ok {
+		writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("the server could not find the requested resource %s", segments[0]))
+		return
+	}
+	if len(segments) == 2 {
+		s.serveGet(w, res, namespace, segments[1])
+		return
+	}
+	s.serveList(w, r, res, namespace)
+}
+
+func (s *fakeApiServer) serveGet(w http.ResponseWriter, res fakeResource, namespace, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, obj := range s.objects[res.gvr()] {
+		if obj.GetName() == name && obj.GetNamespace() == namespace {
+			writeJson(w, http.StatusOK, obj.Object)
+			return
+		}
+	}
+	writeStatus(w, http.StatusNotFound, "NotFound", fmt.Sprintf("%s %q not found", res.resource, name))
+}
+
+func (s *fakeApiServer) serveList(w http.ResponseWriter, r *http.Request, res fakeResource, namespace string) {
+	query := r.URL.Query()
+	selector, err := labels.Parse(query.Get("labelSelector"))
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, "BadRequest", err.Error())
+		return
+	}
+	fieldSelector := map[string]string{}
+	if fs := query.Get("fieldSelector"); len(fs) > 0 {
+		for _, term := range strings.Split(fs, ",") {
+			kv := strings.SplitN(term, "=", 2)
+			if len(kv) != 2 || (kv[0] != "metadata.name" && kv[0] != "metadata.namespace") {
+				writeStatus(w, http.StatusBadRequest, "BadRequest", fmt.Sprintf("field label not supported: %s", kv[0]))
+				return
+			}
+			fieldSelector[kv[0]] = kv[1]
+		}
+	}
+
+	s.mu.Lock()
+	var matched []interface{}
+	for _, obj := range s.objects[res.gvr()] {
+		if len(namespace) > 0 && obj.GetNamespace() != namespace {
+			continue
+		}
+		if !selector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		if name, ok := fieldSelector["metadata.name"]; ok && obj.GetName() != name {
+			continue
+		}
+		if ns, ok := fieldSelector["metadata.namespace"]; ok && obj.GetNamespace() != ns {
+			continue
+		}
+		matched = append(matched, obj.DeepCopy().Object)
+	}
+	s.mu.Unlock()
+
+	start := 0
+	if c := query.Get("continue"); len(c) > 0 {
+		start, _ = strconv.Atoi(c)
+	}
+	if start > len(matched) {
+		start = len(matched)
+	}
+	end := len(matched)
+	metadata := map[string]interface{}{"resourceVersion": "1"}
+	if limit, _ := strconv.Atoi(query.Get("limit")); limit > 0 && start+limit < len(matched) {
+		end = start + limit
+		metadata["continue"] = strconv.Itoa(end)
+		metadata["remainingItemCount"] = int64(len(matched) - end)
+	}
+	items := make([]interface{}, 0)
+	items = append(items, matched[start:end]...)
+	if strings.Contains(r.Header.Get("Accept"), "as=PartialObjectMetadataList") {
+		for i, item := range items {
+			items[i] = map[string]interface{}{
+				"apiVersion": "meta.k8s.io/v1",
+				"kind":       "PartialObjectMetadata",
+				"metadata":   item.(map[string]interface{})["metadata"],
+			}
+		}
+		writeJson(w, http.StatusOK, map[string]interface{}{
+			"apiVersion": "meta.k8s.io/v1",
+			"kind":       "PartialObjectMetadataList",
+			"metadata":   metadata,
+			"items":      items,
+		})
+		return
+	}
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"apiVersion": res.gvk.GroupVersion().String(),
+		"kind":       res.gvk.Kind + "List",
+		"metadata":   metadata,
+		"items":      items,
+	})
+}
+
+func writeJson(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeStatus(w http.ResponseWriter, code int, reason, message string) {
+	writeJson(w, code, map[string]interface{}{
+		"kind":       "Status",
+		"apiVersion": "v1",
+		"status":     "Failure",
+		"reason":     reason,
+		"message":    message,
+		"code":       code,
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
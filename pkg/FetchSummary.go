@@ -0,0 +1,191 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// FetchFailure is the reason a resource could not be fetched from the cluster
+type FetchFailure string
+
+const (
+	FetchForbidden      FetchFailure = "forbidden"
+	FetchUnauthorized   FetchFailure = "unauthorized"
+	FetchNotFound       FetchFailure = "not found"
+	FetchTimeout        FetchFailure = "timeout"
+	FetchMappingFailure FetchFailure = "mapping failure"
+	// FetchDiscoveryFailure is reported for group versions whose resources could not be discovered
+	FetchDiscoveryFailure FetchFailure = "discovery failure"
+	// FetchUnparseable is reported for the documents of a cluster dump which could not be parsed
+	FetchUnparseable FetchFailure = "unparseable"
+	FetchError       FetchFailure = "error"
+)
+
+// SkippedResource is a resource whose objects are missing from the scan
+type SkippedResource struct {
+	Resource string       `json:"resource"`
+	Failure  FetchFailure `json:"failure"`
+	Message  string       `json:"message"`
+	// Retries is the number of times listing the resource was retried before it was skipped
+	Retries int `json:"retries,omitempty"`
+}
+
+// TruncatedResource is a resource of which only the first objects were fetched, see Config.MaxObjectsPerResource
+type TruncatedResource struct {
+	Resource string `json:"resource"`
+	Fetched  int    `json:"fetched"`
+	// Total is the approximate number of objects of the resource, zero when the api server did not count them
+	Total int64 `json:"total,omitempty"`
+}
+
+func (t TruncatedResource) String() string {
+	if t.Total > 0 {
+		return fmt.Sprintf("%s: fetched %d of about %d objects", t.Resource, t.Fetched, t.Total)
+	}
+	return fmt.Sprintf("%s: fetched %d objects, more were not counted", t.Resource, t.Fetched)
+}
+
+// FetchSummary is returned by Cluster.FetchK8sObjects, resources skipped on purpose, ie ignored kinds or kinds not
+// served by the cluster, are not reported as skipped
+type FetchSummary struct {
+	// Listed is the number of resources which were listed successfully
+	Listed int `json:"listed"`
+	// Retries is the number of list requests retried after throttling, server errors or dropped connections
+	Retries int               `json:"retries,omitempty"`
+	Skipped []SkippedResource `json:"skipped,omitempty"`
+	// Forbidden are the resources the user may not list, they are skipped before listing
+	Forbidden []SkippedResource `json:"forbidden,omitempty"`
+	// Truncated are the resources whose objects were capped, their results are a sample and not partial
+	Truncated []TruncatedResource `json:"truncated,omitempty"`
+	// Suppressed are the objects opted out of the scan by their annotations, they do not make the results partial
+	Suppressed []SuppressedObject `json:"suppressed,omitempty"`
+	// SystemNamespaceObjects is the number of objects skipped as they are in one of DefaultSystemNamespaces
+	SystemNamespaceObjects int `json:"systemNamespaceObjects,omitempty"`
+	// TerminalObjects is the number of objects skipped unless Config.IncludeTerminalObjects is set, eg completed Pods
+	// and Jobs
+	TerminalObjects int `json:"terminalObjects,omitempty"`
+	// NewerThanExcluded is the number of objects left out by Config.NewerThan as they are older
+	NewerThanExcluded int `json:"newerThanExcluded,omitempty"`
+	// OlderThanExcluded is the number of objects left out by Config.OlderThan as they are newer
+	OlderThanExcluded int `json:"olderThanExcluded,omitempty"`
+	// MissingDefinitions are the api groups of custom resources without CustomResourceDefinition, their objects
+	// are not validated
+	MissingDefinitions []string `json:"missingDefinitions,omitempty"`
+	// DeprecatedAPIUsage are the deprecated apis clients requested from the cluster, see Config.CheckDeprecatedAPIUsage
+	DeprecatedAPIUsage []DeprecatedAPIUsage `json:"deprecatedApiUsage,omitempty"`
+	// DeprecatedAPIUsageNote says why DeprecatedAPIUsage could not be read
+	DeprecatedAPIUsageNote string `json:"deprecatedApiUsageNote,omitempty"`
+}
+
+// Partial returns true if objects of some resources could not be fetched
+func (s *FetchSummary) Partial() bool {
+	return s != nil && len(s.Skipped)+len(s.Forbidden) > 0
+}
+
+// HasSuppressed returns true if objects were opted out of the scan by their annotations
+func (s *FetchSummary) HasSuppressed() bool {
+	return s != nil && len(s.Suppressed) > 0
+}
+
+// HasTruncated returns true if only some objects of some resources were fetched
+func (s *FetchSummary) HasTruncated() bool {
+	return s != nil && len(s.Truncated) > 0
+}
+
+// AddMissingDefinition records that the CustomResourceDefinitions of group are missing, once per group
+func (s *FetchSummary) AddMissingDefinition(group string) {
+	if s != nil && !slices.Contains(s.MissingDefinitions, group) {
+		s.MissingDefinitions = append(s.MissingDefinitions, group)
+	}
+}
+
+// Reportable returns true if the summary has anything to report besides the number of resources listed
+func (s *FetchSummary) Reportable() bool {
+	return s.Partial() || s.HasTruncated() || s.HasSuppressed() || s != nil && s.SystemNamespaceObjects+s.TerminalObjects+s.NewerThanExcluded+s.OlderThanExcluded+len(s.MissingDefinitions)+len(s.DeprecatedAPIUsage)+len(s.DeprecatedAPIUsageNote) > 0
+}
+
+func (s *FetchSummary) String() string {
+	var sb strings.Builder
+	if s.Partial() {
+		s.writeSkipped(&sb)
+	} else {
+		sb.WriteString("all resources fetched")
+	}
+	if s.HasTruncated() {
+		fmt.Fprintf(&sb, "\n%d resources truncated, results are sampled:", len(s.Truncated))
+		for _, truncated := range s.Truncated {
+			fmt.Fprintf(&sb, "\n%s", truncated)
+		}
+	}
+	if s != nil && s.SystemNamespaceObjects > 0 {
+		fmt.Fprintf(&sb, "\n%d objects in system namespaces skipped by default", s.SystemNamespaceObjects)
+	}
+	if s != nil && s.TerminalObjects > 0 {
+		fmt.Fprintf(&sb, "\n%d terminal objects skipped, eg completed Pods and Jobs or objects of terminating namespaces, use --include-terminal to scan them", s.TerminalObjects)
+	}
+	if s != nil && s.NewerThanExcluded > 0 {
+		fmt.Fprintf(&sb, "\n%d objects older than newer-than skipped", s.NewerThanExcluded)
+	}
+	if s != nil && s.OlderThanExcluded > 0 {
+		fmt.Fprintf(&sb, "\n%d objects newer than older-than skipped", s.OlderThanExcluded)
+	}
+	if s != nil && len(s.MissingDefinitions) > 0 {
+		fmt.Fprintf(&sb, "\n%d api groups without CustomResourceDefinition, their objects were not validated: %s", len(s.MissingDefinitions), strings.Join(s.MissingDefinitions, ", "))
+	}
+	if s != nil {
+		for _, usage := range s.DeprecatedAPIUsage {
+			fmt.Fprintf(&sb, "\n%s", usage)
+		}
+		if len(s.DeprecatedAPIUsageNote) > 0 {
+			fmt.Fprintf(&sb, "\n%s", s.DeprecatedAPIUsageNote)
+		}
+	}
+	if s.HasSuppressed() {
+		fmt.Fprintf(&sb, "\n%d objects suppressed by annotations:", len(s.Suppressed))
+		for _, suppressed := range s.Suppressed {
+			fmt.Fprintf(&sb, "\n%s", suppressed)
+		}
+	}
+	return sb.String()
+}
+
+func (s *FetchSummary) writeSkipped(sb *strings.Builder) {
+	missing := len(s.Skipped) + len(s.Forbidden)
+	fmt.Fprintf(sb, "%d of %d resources could not be fetched, results are partial", missing, missing+s.Listed)
+	for _, skipped := range s.Skipped {
+		fmt.Fprintf(sb, "\n%s: %s: %s", skipped.Resource, skipped.Failure, skipped.Message)
+		if skipped.Retries > 0 {
+			fmt.Fprintf(sb, " (after %d retries)", skipped.Retries)
+		}
+	}
+	if len(s.Forbidden) > 0 {
+		sb.WriteString("\nskipped due to permissions:")
+		for _, forbidden := range s.Forbidden {
+			fmt.Fprintf(sb, "\n%s: %s", forbidden.Resource, forbidden.Message)
+		}
+	}
+}
+
+// fetchFailureOf classifies the error returned while listing a resource
+func fetchFailureOf(err error) FetchFailure {
+	var netErr net.Error
+	switch {
+	case k8sErrors.IsForbidden(err):
+		return FetchForbidden
+	case k8sErrors.IsUnauthorized(err):
+		return FetchUnauthorized
+	case k8sErrors.IsNotFound(err):
+		return FetchNotFound
+	case k8sErrors.IsTimeout(err), k8sErrors.IsServerTimeout(err), errors.Is(err, context.DeadlineExceeded):
+		return FetchTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return FetchTimeout
+	}
+	return FetchError
+}
@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_summary(t *testing.T) {
+	defer func(backoff time.Duration) { listRetryBackoff = backoff }(listRetryBackoff)
+	listRetryBackoff = time.Millisecond
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secretGvk := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	widgetGvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	jobGvk := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "Job"}
+	server.addObject(server.addResource(configMapGvk, "configmaps", true), newFakeObject("v1", "ConfigMap", "shop", "cart"))
+	server.addResource(secretGvk, "secrets", true)
+	server.addResource(widgetGvk, "widgets", true)
+	server.addResource(jobGvk, "jobs", true)
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		switch r.URL.Path {
+		case "/api/v1/secrets":
+			writeStatus(w, http.StatusForbidden, "Forbidden", `secrets is forbidden: User "viewer" cannot list resource "secrets"`)
+		case "/apis/example.com/v1/widgets":
+			writeStatus(w, http.StatusNotFound, "NotFound", "the server could not find the requested resource")
+		case "/apis/batch/v1/jobs":
+			writeStatus(w, http.StatusGatewayTimeout, "Timeout", "request timed out")
+		default:
+			return false
+		}
+		return true
+	}
+	cluster := server.cluster(t)
+
+	gvks := []schema.GroupVersionKind{configMapGvk, secretGvk, widgetGvk, jobGvk, {Group: "batch", Version: "v1beta1", Kind: "CronJob"}}
+	objs, summary, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.True(t, summary.Partial())
+	assert.Equal(t, 1, summary.Listed)
+	var got []string
+	for _, skipped := range summary.Skipped {
+		got = append(got, skipped.Resource+"|"+string(skipped.Failure))
+	}
+	// kinds not served by the cluster are not reported
+	assert.Equal(t, []string{
+		"/v1, Resource=secrets|forbidden",
+		"example.com/v1, Resource=widgets|not found",
+		"batch/v1, Resource=jobs|timeout",
+	}, got)
+	assert.Contains(t, summary.String(), "3 of 4 resources could not be fetched")
+	// only the timed out resource is retried
+	assert.Equal(t, 2, summary.Retries)
+	assert.Equal(t, 2, summary.Skipped[2].Retries)
+	assert.Contains(t, summary.String(), "jobs: timeout: request timed out (after 2 retries)")
+
+	var complete *FetchSummary
+	assert.False(t, complete.Partial())
+	assert.Equal(t, "all resources fetched", complete.String())
+}
+
+func TestCluster_FetchK8sObjects_discoveryFailure(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	podMetricsGvk := schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	server.addResource(podMetricsGvk, "pods", true)
+	server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "configmaps", true)
+	// the aggregated api server of metrics.k8s.io is down
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/apis/metrics.k8s.io/v1beta1" {
+			writeStatus(w, http.StatusServiceUnavailable, "ServiceUnavailable", "the server is currently unable to handle the request")
+			return true
+		}
+		return false
+	}
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{deploymentGvk, podMetricsGvk}
+
+	objs, summary, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	if assert.Len(t, summary.Skipped, 1) {
+		assert.Equal(t, "metrics.k8s.io/v1beta1", summary.Skipped[0].Resource)
+		assert.Equal(t, FetchDiscoveryFailure, summary.Skipped[0].Failure)
+		assert.Contains(t, summary.Skipped[0].Message, "unable to handle the request")
+	}
+	assert.Equal(t, []string{"/apis/apps/v1/deployments"}, server.resourceRequests())
+
+	_, _, err = cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true, FailOnDiscoveryError: true})
+	assert.ErrorContains(t, err, "discovery of cluster")
+	assert.Equal(t, []string{"/apis/apps/v1/deployments"}, server.resourceRequests())
+}
@@ -0,0 +1,156 @@
+package pkg
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// FieldMigration is a field of an object which is not part of the schema of its latest api version, along with the
+// field of the latest version which replaces it when one can be told apart
+type FieldMigration struct {
+	Path        string
+	Replacement string `json:",omitempty"`
+	Reason      string
+}
+
+// fieldMigrations compares object against the schema of its latest api version and returns the fields which have to
+// change to move the object to that version
+func fieldMigrations(object map[string]interface{}, latest *openapi3.Schema) []FieldMigration {
+	var migrations []FieldMigration
+	walkUnknownFields(object, latest, func(path, key string, parent *openapi3.Schema) {
+		migration := FieldMigration{Path: path, Reason: fmt.Sprintf("field %s does not exist in the latest api version", key)}
+		if replacement := replacementField(key, parent); len(replacement) > 0 {
+			migration.Replacement = strings.TrimSuffix(path, key) + replacement
+			migration.Reason = fmt.Sprintf("field %s is replaced by %s in the latest api version", key, replacement)
+		}
+		migrations = append(migrations, migration)
+	})
+	return migrations
+}
+
+// unknownFields returns the paths of the fields of object which are not defined by scm, see walkUnknownFields
+func unknownFields(object map[string]interface{}, scm *openapi3.Schema) []string {
+	var paths []string
+	walkUnknownFields(object, scm, func(path, _ string, _ *openapi3.Schema) {
+		paths = append(paths, path)
+	})
+	return paths
+}
+
+// walkUnknownFields calls unknown with the path and key of the fields of object which are not defined by scm along
+// with the schema of their parent, the fields below them are not walked. The status of the object is ignored as it is
+// not written by users
+func walkUnknownFields(object map[string]interface{}, scm *openapi3.Schema, unknown func(path, key string, parent *openapi3.Schema)) {
+	if scm == nil {
+		return
+	}
+	for _, key := range sortedKeys(object) {
+		if key == "status" {
+			continue
+		}
+		walkField(key, key, object[key], scm, unknown)
+	}
+}
+
+// walkField walks the field key of a parent object at path against the schema of the parent
+func walkField(path, key string, value interface{}, parent *openapi3.Schema, unknown func(path, key string, parent *openapi3.Schema)) {
+	if property := schemaValue(parent.Properties[key]); property != nil {
+		walkValue(path, value, property, unknown)
+		return
+	}
+	if additional := schemaValue(parent.AdditionalProperties); additional != nil {
+		walkValue(path, value, additional, unknown)
+		return
+	}
+	// objects without properties are free-form
+	if len(parent.Properties) == 0 || preservesUnknownFields(parent) {
+		return
+	}
+	unknown(path, key, parent)
+}
+
+func walkValue(path string, value interface{}, scm *openapi3.Schema, unknown func(path, key string, parent *openapi3.Schema)) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			walkField(path+"."+key, key, v[key], scm, unknown)
+		}
+	case []interface{}:
+		items := schemaValue(scm.Items)
+		if items == nil {
+			return
+		}
+		for i, item := range v {
+			walkValue(fmt.Sprintf("%s[%d]", path, i), item, items, unknown)
+		}
+	}
+}
+
+// replacementField guesses the property of parent which replaces the removed field key: either a property whose name
+// contains key, as backend is replaced by defaultBackend, or a nested property when key is made of the name of a
+// property and of one of its fields, as serviceName is replaced by service.name
+func replacementField(key string, parent *openapi3.Schema) string {
+	var candidates []string
+	for name := range parent.Properties {
+		if strings.Contains(strings.ToLower(name), strings.ToLower(key)) {
+			candidates = append(candidates, name)
+		}
+	}
+	if len(candidates) > 0 {
+		sort.Slice(candidates, func(i, j int) bool {
+			if len(candidates[i]) != len(candidates[j]) {
+				return len(candidates[i]) < len(candidates[j])
+			}
+			return candidates[i] < candidates[j]
+		})
+		return candidates[0]
+	}
+	for i, r := range key {
+		if i == 0 || !unicode.IsUpper(r) {
+			continue
+		}
+		property := schemaValue(parent.Properties[key[:i]])
+		if property == nil {
+			continue
+		}
+		field := string(unicode.ToLower(r)) + key[i+len(string(r)):]
+		if schemaValue(property.Properties[field]) != nil {
+			return key[:i] + "." + field
+		}
+	}
+	return ""
+}
+
+// schemaValue returns the schema of ref, looking through the allOf wrapping a single schema the specs put around
+// references with a description
+func schemaValue(ref *openapi3.SchemaRef) *openapi3.Schema {
+	if ref == nil || ref.Value == nil {
+		return nil
+	}
+	if scm := ref.Value; len(scm.Properties) == 0 && len(scm.AllOf) == 1 {
+		return schemaValue(scm.AllOf[0])
+	}
+	return ref.Value
+}
+
+// preservesUnknownFields returns true if scm accepts fields it does not declare
+func preservesUnknownFields(scm *openapi3.Schema) bool {
+	if scm.AdditionalPropertiesAllowed != nil && *scm.AdditionalPropertiesAllowed {
+		return true
+	}
+	_, ok := scm.Extensions["x-kubernetes-preserve-unknown-fields"]
+	return ok
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+// ingressV1Schema is the part of the schema of networking.k8s.io/v1 Ingress the v1beta1 objects differ from
+func ingressV1Schema() *openapi3.Schema {
+	service := openapi3.NewObjectSchema().
+		WithProperty("name", openapi3.NewStringSchema()).
+		WithProperty("port", openapi3.NewObjectSchema().WithProperty("number", openapi3.NewIntegerSchema()))
+	backend := openapi3.NewObjectSchema().WithPropertyRef("service", &openapi3.SchemaRef{Value: service})
+	path := openapi3.NewObjectSchema().
+		WithProperty("path", openapi3.NewStringSchema()).
+		WithProperty("pathType", openapi3.NewStringSchema()).
+		WithPropertyRef("backend", &openapi3.SchemaRef{Value: backend})
+	rule := openapi3.NewObjectSchema().
+		WithProperty("host", openapi3.NewStringSchema()).
+		WithProperty("http", openapi3.NewObjectSchema().WithProperty("paths", openapi3.NewArraySchema().WithItems(path)))
+	spec := openapi3.NewObjectSchema().
+		WithPropertyRef("defaultBackend", &openapi3.SchemaRef{Value: backend}).
+		WithProperty("rules", openapi3.NewArraySchema().WithItems(rule))
+	labels := openapi3.NewObjectSchema().WithAdditionalProperties(openapi3.NewStringSchema())
+	return openapi3.NewObjectSchema().
+		WithProperty("apiVersion", openapi3.NewStringSchema()).
+		WithProperty("kind", openapi3.NewStringSchema()).
+		WithProperty("metadata", openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema()).WithPropertyRef("labels", &openapi3.SchemaRef{Value: labels})).
+		WithProperty("spec", spec)
+}
+
+func TestFieldMigrations(t *testing.T) {
+	var ingress map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(`{
+		"apiVersion": "networking.k8s.io/v1beta1", "kind": "Ingress",
+		"metadata": {"name": "web", "labels": {"app": "web"}},
+		"spec": {
+			"backend": {"serviceName": "default", "servicePort": 80},
+			"rules": [{"host": "shop.example.com", "http": {"paths": [{"path": "/", "backend": {"serviceName": "web", "servicePort": 8080}}]}}]
+		},
+		"status": {"loadBalancer": {}}
+	}`), &ingress))
+
+	assert.Equal(t, []FieldMigration{
+		{Path: "spec.backend", Replacement: "spec.defaultBackend", Reason: "field backend is replaced by defaultBackend in the latest api version"},
+		{Path: "spec.rules[0].http.paths[0].backend.serviceName", Replacement: "spec.rules[0].http.paths[0].backend.service.name", Reason: "field serviceName is replaced by service.name in the latest api version"},
+		{Path: "spec.rules[0].http.paths[0].backend.servicePort", Replacement: "spec.rules[0].http.paths[0].backend.service.port", Reason: "field servicePort is replaced by service.port in the latest api version"},
+	}, fieldMigrations(ingress, ingressV1Schema()))
+
+	ingress["spec"].(map[string]interface{})["tls"] = []interface{}{}
+	migrations := fieldMigrations(ingress, ingressV1Schema())
+	assert.Equal(t, FieldMigration{Path: "spec.tls", Reason: "field tls does not exist in the latest api version"}, migrations[len(migrations)-1])
+
+	preserved := openapi3.NewObjectSchema().WithProperty("spec", openapi3.NewObjectSchema().WithProperty("replicas", openapi3.NewIntegerSchema()))
+	preserved.Properties["spec"].Value.Extensions = map[string]interface{}{"x-kubernetes-preserve-unknown-fields": true}
+	assert.Empty(t, fieldMigrations(map[string]interface{}{"spec": map[string]interface{}{"replicas": 1, "paused": true}}, preserved))
+	assert.Empty(t, fieldMigrations(ingress, nil))
+}
+
+func TestUnknownFields(t *testing.T) {
+	container := openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema()).WithProperty("image", openapi3.NewStringSchema())
+	deployment := openapi3.NewObjectSchema().
+		WithProperty("metadata", openapi3.NewObjectSchema().WithProperty("name", openapi3.NewStringSchema())).
+		WithProperty("spec", openapi3.NewObjectSchema().
+			WithProperty("replicas", openapi3.NewIntegerSchema()).
+			WithProperty("containers", openapi3.NewArraySchema().WithItems(container)))
+	object := map[string]interface{}{
+		"metadata": map[string]interface{}{"name": "web", "creationTimestamp": "2026-10-16T00:00:00Z"},
+		"spec": map[string]interface{}{
+			"replica":    int64(2),
+			"containers": []interface{}{map[string]interface{}{"name": "web", "imag": "nginx"}},
+		},
+		"status": map[string]interface{}{"replicas": int64(2)},
+	}
+
+	paths := unknownFields(object, deployment)
+	assert.Equal(t, []string{"metadata.creationTimestamp", "spec.containers[0].imag", "spec.replica"}, paths)
+
+	// fields of metadata are left out by the default keys ignored for validation
+	result := removeIgnoredKeys(ValidationResult{UnknownFields: paths}, &Config{IgnoreKeysFromValidation: []string{"status*", "metadata*"}})
+	assert.Equal(t, []string{"spec.containers[0].imag", "spec.replica"}, result.UnknownFields)
+	assert.Equal(t, "spec/containers/0/imag", fieldPointer("spec.containers[0].imag"))
+	assert.True(t, HasGatingFindings([]ValidationResult{result}, &Config{Strict: true}))
+}
@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// fieldSelectorNotSupported returns true if the api server rejected a list request as the resource does not
+// support one of the fields of its field selector
+func fieldSelectorNotSupported(err error) bool {
+	return k8sErrors.IsBadRequest(err) && strings.Contains(err.Error(), "field label not supported")
+}
+
+// matchesFieldSelector evaluates selector against obj client side, fields missing from obj have an empty value
+func matchesFieldSelector(selector fields.Selector, obj unstructured.Unstructured) bool {
+	set := fields.Set{}
+	for _, requirement := range selector.Requirements() {
+		value, found, _ := unstructured.NestedFieldNoCopy(obj.Object, strings.Split(requirement.Field, ".")...)
+		if found && value != nil {
+			set[requirement.Field] = fmt.Sprint(value)
+		}
+	}
+	return selector.Matches(set)
+}
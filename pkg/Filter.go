@@ -65,9 +65,24 @@ func removeIgnoredKeys(result ValidationResult, conf *Config) ValidationResult {
 		}
 		result.ErrorsForLatest = valErr
 	}
+	if len(result.UnknownFields) > 0 {
+		var unknown []string
+		for _, path := range result.UnknownFields {
+			if !Contains(fieldPointer(path), conf.IgnoreKeysFromValidation) {
+				unknown = append(unknown, path)
+			}
+		}
+		result.UnknownFields = unknown
+	}
 	return result
 }
 
+// fieldPointer turns a field path as spec.containers[0].name into the form of the keys of schema errors,
+// spec/containers/0/name, for it to be matched with the same patterns
+func fieldPointer(path string) string {
+	return strings.NewReplacer(".", "/", "[", "/", "]", "").Replace(path)
+}
+
 func excludeCPUMemoryNumberError(schemaError *openapi3.SchemaError) bool {
 	penultimateValue := len(schemaError.JSONPointer()) - 2
 	requestOrLimit := len(schemaError.JSONPointer()) > 1 && (schemaError.JSONPointer()[penultimateValue] == "requests" || schemaError.JSONPointer()[penultimateValue] == "limits")
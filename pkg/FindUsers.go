@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// ObjectRef identifies an object in the cluster
+type ObjectRef struct {
+	APIVersion string
+	Kind       string
+	Namespace  string
+	Name       string
+}
+
+// FindUsers lists the objects of every kind served in the group version and returns their references, eg to find
+// everything still reachable as extensions/v1beta1 before it is removed. The api server serves every object of a
+// kind in all versions of it, so the result is the objects which would be affected by the removal of gv.
+// Namespace and kind filters of conf are applied, including NamespaceLabelSelector.
+func (c *Cluster) FindUsers(ctx context.Context, gv schema.GroupVersion, conf *Config) ([]ObjectRef, error) {
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	conf, err := c.resolveNamespaceLabelSelector(ctx, conf)
+	if err != nil {
+		return nil, err
+	}
+	resourceList, err := c.disco.ServerResourcesForGroupVersion(gv.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to discover resources of %s: %w", gv.String(), err)
+	}
+	var refs []ObjectRef
+	for _, resource := range resourceList.APIResources {
+		// subresources like deployments/scale are not objects of their own
+		if strings.Contains(resource.Name, "/") || !sets.New(resource.Verbs...).Has("list") {
+			continue
+		}
+		gvk := gv.WithKind(resource.Kind)
+		if matchesKindFilters(gvk, conf.IgnoreKinds) || len(conf.SelectKinds) > 0 && !matchesKindFilters(gvk, conf.SelectKinds) {
+			continue
+		}
+		items, err := c.listAll(ctx, gv.WithResource(resource.Name), "", 0, conf)
+		if err != nil {
+			return refs, fmt.Errorf("unable to list %s in %s: %w", resource.Name, gv.String(), err)
+		}
+		for _, obj := range items {
+			if !conf.namespaceSelected(obj.GetNamespace(), resource.Namespaced) {
+				continue
+			}
+			refs = append(refs, ObjectRef{
+				APIVersion: gv.String(),
+				Kind:       resource.Kind,
+				Namespace:  obj.GetNamespace(),
+				Name:       obj.GetName(),
+			})
+		}
+	}
+	return refs, nil
+}
@@ -0,0 +1,28 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FindUsers(t *testing.T) {
+	server := newFakeApiServer(t)
+	gv := schema.GroupVersion{Group: "extensions", Version: "v1beta1"}
+	ingresses := server.addResource(gv.WithKind("Ingress"), "ingresses", true)
+	server.addResource(gv.WithKind("Scale"), "deployments/scale", true)
+	server.addResource(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "deployments", true)
+	server.addObject(ingresses, newFakeObject("extensions/v1beta1", "Ingress", "shop", "web"))
+	server.addObject(ingresses, newFakeObject("extensions/v1beta1", "Ingress", "kube-system", "dashboard"))
+	cluster := server.cluster(t)
+
+	refs, err := cluster.FindUsers(context.Background(), gv, &Config{IgnoreNamespaces: []string{"kube-system"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []ObjectRef{{APIVersion: "extensions/v1beta1", Kind: "Ingress", Namespace: "shop", Name: "web"}}, refs)
+	assert.Equal(t, []string{"/apis/extensions/v1beta1/ingresses"}, server.resourceRequests())
+
+	_, err = cluster.FindUsers(context.Background(), schema.GroupVersion{Group: "policy", Version: "v1beta1"}, &Config{})
+	assert.Error(t, err)
+}
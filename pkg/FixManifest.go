@@ -0,0 +1,82 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// clusterScopedDir is the directory of FixOutputDir the manifests of cluster-scoped objects are written to
+const clusterScopedDir = "_cluster"
+
+// serverPopulatedMetadata are the fields of metadata set by the api server, they are dropped from fixed manifests
+var serverPopulatedMetadata = []string{"managedFields", "resourceVersion", "uid", "generation", "creationTimestamp",
+	"deletionTimestamp", "deletionGracePeriodSeconds", "selfLink"}
+
+// IsAutoMigratable returns true if the object of vr is removed or deprecated and moves to its latest api version with
+// just an apiVersion change, ie it validates cleanly against the schema of that version
+func IsAutoMigratable(vr ValidationResult) bool {
+	return (vr.Deleted || vr.Deprecated) && len(vr.LatestAPIVersion) > 0 && len(vr.ErrorsForLatest) == 0 &&
+		len(vr.DeprecationForLatest) == 0 && len(vr.FieldMigrations) == 0
+}
+
+// FixedManifest returns obj with apiVersion set and stripped of its status and of the metadata populated by the api
+// server, as YAML. obj is not modified
+func FixedManifest(obj *unstructured.Unstructured, apiVersion string) ([]byte, error) {
+	return yaml.Marshal(migratedObject(obj, apiVersion).Object)
+}
+
+// migratedObject returns a copy of obj with apiVersion set and stripped of its status and of the metadata populated by
+// the api server, as it would be applied
+func migratedObject(obj *unstructured.Unstructured, apiVersion string) *unstructured.Unstructured {
+	fixed := obj.DeepCopy()
+	fixed.SetAPIVersion(apiVersion)
+	delete(fixed.Object, "status")
+	if metadata, ok := fixed.Object["metadata"].(map[string]interface{}); ok {
+		for _, field := range serverPopulatedMetadata {
+			delete(metadata, field)
+		}
+		if annotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+			delete(annotations, LastAppliedConfigAnnotation)
+			if len(annotations) == 0 {
+				delete(metadata, "annotations")
+			}
+		}
+	}
+	return fixed
+}
+
+// fixedManifestPath is the file of dir the fixed manifest of obj is written to
+func fixedManifestPath(dir string, obj *unstructured.Unstructured) string {
+	namespace := obj.GetNamespace()
+	if len(namespace) == 0 {
+		namespace = clusterScopedDir
+	}
+	return filepath.Join(dir, namespace, fmt.Sprintf("%s-%s.yaml", strings.ToLower(obj.GetKind()), obj.GetName()))
+}
+
+// WriteFixedManifest writes the FixedManifest of obj with the latest api version of vr to Config.FixOutputDir when it
+// is set and the object IsAutoMigratable, and records the file in vr.FixedManifest. Only files are written, the
+// objects of the cluster are never modified
+func WriteFixedManifest(vr ValidationResult, obj *unstructured.Unstructured, conf *Config) (ValidationResult, error) {
+	if len(conf.FixOutputDir) == 0 || !IsAutoMigratable(vr) {
+		return vr, nil
+	}
+	data, err := FixedManifest(obj, vr.LatestAPIVersion)
+	if err != nil {
+		return vr, err
+	}
+	path := fixedManifestPath(conf.FixOutputDir, obj)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return vr, err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return vr, fmt.Errorf("unable to write the fixed manifest of %s %s: %w", obj.GetKind(), obj.GetName(), err)
+	}
+	vr.FixedManifest = path
+	return vr, nil
+}
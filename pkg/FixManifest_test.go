@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestWriteFixedManifest(t *testing.T) {
+	deployment := &unstructured.Unstructured{Object: newFakeObject("apps/v1beta2", "Deployment", "shop", "web")}
+	metadata := deployment.Object["metadata"].(map[string]interface{})
+	metadata["uid"] = "0d6c5a1e"
+	metadata["resourceVersion"] = "4211"
+	metadata["labels"] = map[string]interface{}{"app": "web"}
+	metadata["annotations"] = map[string]interface{}{LastAppliedConfigAnnotation: "{}"}
+	deployment.Object["spec"] = map[string]interface{}{"replicas": int64(2)}
+	deployment.Object["status"] = map[string]interface{}{"readyReplicas": int64(2)}
+	migratable := ValidationResult{Kind: "Deployment", APIVersion: "apps/v1beta2", LatestAPIVersion: "apps/v1", Deleted: true, IsVersionSupported: 2}
+	dir := t.TempDir()
+
+	got, err := WriteFixedManifest(migratable, deployment, &Config{FixOutputDir: dir})
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "shop", "deployment-web.yaml"), got.FixedManifest)
+	data, err := os.ReadFile(got.FixedManifest)
+	assert.NoError(t, err)
+	var written map[string]interface{}
+	assert.NoError(t, yaml.Unmarshal(data, &written))
+	assert.Equal(t, map[string]interface{}{
+		"apiVersion": "apps/v1",
+		"kind":       "Deployment",
+		"metadata":   map[string]interface{}{"name": "web", "namespace": "shop", "labels": map[string]interface{}{"app": "web"}},
+		"spec":       map[string]interface{}{"replicas": float64(2)},
+	}, written)
+	// the object itself is left as it is
+	assert.Equal(t, "apps/v1beta2", deployment.GetAPIVersion())
+	assert.Contains(t, deployment.Object, "status")
+
+	needsEdits := []ValidationResult{
+		{Kind: "Ingress", APIVersion: "networking.k8s.io/v1beta1", LatestAPIVersion: "networking.k8s.io/v1", Deleted: true,
+			FieldMigrations: []FieldMigration{{Path: "spec.backend", Replacement: "spec.defaultBackend"}}},
+		{Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1", Deleted: true},
+		{Kind: "Deployment", APIVersion: "apps/v1", LatestAPIVersion: "apps/v1"},
+	}
+	for _, vr := range needsEdits {
+		got, err := WriteFixedManifest(vr, deployment, &Config{FixOutputDir: dir})
+		assert.NoError(t, err)
+		assert.Empty(t, got.FixedManifest, vr.Kind)
+	}
+	got, err = WriteFixedManifest(migratable, deployment, &Config{})
+	assert.NoError(t, err)
+	assert.Empty(t, got.FixedManifest)
+
+	namespace := &unstructured.Unstructured{Object: newFakeObject("v1", "Namespace", "", "shop")}
+	assert.Equal(t, filepath.Join(dir, "_cluster", "namespace-shop.yaml"), fixedManifestPath(dir, namespace))
+}
@@ -0,0 +1,66 @@
+package pkg
+
+// GatewayApiOverlay tracks the version progression of the Gateway API (gateway.networking.k8s.io), which is
+// released independently of kubernetes. Versions superseded by a newer one are marked deprecated.
+var GatewayApiOverlay = ApiOverlay{
+	Name:  "gateway-api",
+	Group: "gateway.networking.k8s.io",
+	Kinds: []KindLifecycle{
+		{
+			Kind: "GatewayClass",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2", Deprecated: true},
+				{Version: "v1beta1", Deprecated: true},
+				{Version: "v1"},
+			},
+		},
+		{
+			Kind: "Gateway",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2", Deprecated: true},
+				{Version: "v1beta1", Deprecated: true},
+				{Version: "v1"},
+			},
+		},
+		{
+			Kind: "HTTPRoute",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2", Deprecated: true},
+				{Version: "v1beta1", Deprecated: true},
+				{Version: "v1"},
+			},
+		},
+		{
+			Kind: "GRPCRoute",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2", Deprecated: true},
+				{Version: "v1"},
+			},
+		},
+		{
+			Kind: "ReferenceGrant",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2", Deprecated: true},
+				{Version: "v1beta1"},
+			},
+		},
+		{
+			Kind: "TCPRoute",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2"},
+			},
+		},
+		{
+			Kind: "TLSRoute",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2"},
+			},
+		},
+		{
+			Kind: "UDPRoute",
+			Versions: []VersionLifecycle{
+				{Version: "v1alpha2"},
+			},
+		},
+	},
+}
@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/restmapper"
+)
+
+// ResolveKind maps kind to the kind served by the cluster in its preferred version. kind is the name of a kind, its
+// plural or its short name eg Deployment, deployments or deploy, optionally qualified with its group as in the kind
+// filters eg Deployment.apps or Service.core. Kinds which are not served are reported as no match errors of meta
+func (c *Cluster) ResolveKind(kind string) (schema.GroupVersionKind, error) {
+	filter := ParseKindFilter(kind)
+	if isPattern(filter.Kind) || isPattern(filter.Group) {
+		return schema.GroupVersionKind{}, fmt.Errorf("kind %s is a pattern, a single kind is expected", kind)
+	}
+	if err := c.initClients(); err != nil {
+		return schema.GroupVersionKind{}, err
+	}
+	group := filter.Group
+	if group == "core" {
+		group = ""
+	}
+	cached := c.cachedDiscovery()
+	mapper := restmapper.NewShortcutExpander(c.mapper(cached), cached, func(string) {})
+	gvk, err := mapper.KindFor(schema.GroupVersionResource{Group: group, Resource: strings.ToLower(filter.Kind)})
+	if err != nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("kind %s is not served by cluster %s: %w", kind, c.name, err)
+	}
+	return gvk, nil
+}
+
+// GetObject fetches the object of gvk named name, namespaced objects are looked up in namespace or in the default
+// namespace when it is empty. The version of gvk is optional, objects are fetched in the preferred version of the
+// cluster unless it is set. Kinds which are not served are reported as no match errors of meta and missing objects
+// as not found errors of the api server
+func (c *Cluster) GetObject(ctx context.Context, gvk schema.GroupVersionKind, namespace, name string) (*unstructured.Unstructured, error) {
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	var versions []string
+	if len(gvk.Version) > 0 {
+		versions = append(versions, gvk.Version)
+	}
+	mapping, err := c.mapper(c.cachedDiscovery()).RESTMapping(gvk.GroupKind(), versions...)
+	if err != nil {
+		return nil, fmt.Errorf("kind %s is not served by cluster %s: %w", gvk.GroupKind(), c.name, err)
+	}
+	resource := c.dynamicClient().Resource(mapping.Resource)
+	var obj *unstructured.Unstructured
+	key := name
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if len(namespace) == 0 {
+			namespace = "default"
+		}
+		key = namespace + "/" + name
+		obj, err = resource.Namespace(namespace).Get(ctx, name, v1.GetOptions{})
+	} else {
+		obj, err = resource.Get(ctx, name, v1.GetOptions{})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to get %s %s from cluster %s: %w", mapping.GroupVersionKind.Kind, key, c.name, c.describeListError(err))
+	}
+	return obj, nil
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_ResolveKind(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	serviceGvk := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	server.setShortNames(server.addResource(deploymentGvk, "deployments", true), "deploy")
+	server.addResource(serviceGvk, "services", true)
+	cluster := server.cluster(t)
+
+	for _, kind := range []string{"Deployment", "deployment", "deployments", "deploy", "Deployment.apps", "deployments.apps"} {
+		gvk, err := cluster.ResolveKind(kind)
+		if assert.NoError(t, err, kind) {
+			assert.Equal(t, deploymentGvk, gvk, kind)
+		}
+	}
+	gvk, err := cluster.ResolveKind("Service.core")
+	assert.NoError(t, err)
+	assert.Equal(t, serviceGvk, gvk)
+
+	_, err = cluster.ResolveKind("Deployment.extensions")
+	assert.True(t, meta.IsNoMatchError(err), "%v", err)
+	_, err = cluster.ResolveKind("Deploy*")
+	assert.ErrorContains(t, err, "is a pattern")
+}
+
+func TestCluster_GetObject(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	namespaceGvk := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "payments", "api"))
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "default", "api"))
+	server.addObject(server.addResource(namespaceGvk, "namespaces", false), newFakeObject("v1", "Namespace", "", "payments"))
+	cluster := server.cluster(t)
+
+	obj, err := cluster.GetObject(context.Background(), deploymentGvk, "payments", "api")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "payments", obj.GetNamespace())
+		assert.Equal(t, "api", obj.GetName())
+	}
+	obj, err = cluster.GetObject(context.Background(), deploymentGvk.GroupKind().WithVersion(""), "", "api")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "default", obj.GetNamespace())
+	}
+	obj, err = cluster.GetObject(context.Background(), namespaceGvk, "ignored", "payments")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "payments", obj.GetName())
+	}
+
+	// missing objects are told apart from kinds which are not served
+	_, err = cluster.GetObject(context.Background(), deploymentGvk, "payments", "web")
+	assert.True(t, k8sErrors.IsNotFound(err), "%v", err)
+	assert.False(t, meta.IsNoMatchError(err))
+	assert.ErrorContains(t, err, "Deployment payments/web")
+	_, err = cluster.GetObject(context.Background(), schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}, "payments", "api")
+	assert.True(t, meta.IsNoMatchError(err), "%v", err)
+	assert.False(t, k8sErrors.IsNotFound(err))
+}
@@ -0,0 +1,200 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"sync"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// partialObjectMetadataList asks the api server to list only the metadata of objects
+const partialObjectMetadataList = "application/json;as=PartialObjectMetadataList;g=meta.k8s.io;v=v1,application/json"
+
+// InventoryEntry is the number of objects of a kind in a namespace, cluster scoped objects have no namespace
+type InventoryEntry struct {
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Count     int    `json:"count"`
+}
+
+// APIVersion returns the group and version of the entry as in the apiVersion of objects
+func (e InventoryEntry) APIVersion() string {
+	return schema.GroupVersion{Group: e.Group, Version: e.Version}.String()
+}
+
+// metadataResult is the outcome of listing the metadata of a resource
+type metadataResult struct {
+	items []v1.PartialObjectMetadata
+	err   error
+}
+
+// CountK8sObjects counts the objects of gvks served by the cluster grouped by kind and namespace, objects are filtered
+// as by FetchK8sObjects and counted in the version FetchK8sObjects would return them in. Only the metadata of objects
+// is listed, which is much faster than fetching them
+func (c *Cluster) CountK8sObjects(ctx context.Context, gvks []schema.GroupVersionKind, conf *Config) ([]InventoryEntry, *FetchSummary, error) {
+	summary := &FetchSummary{}
+	targets, err := c.fetchTargets(gvks, conf, summary)
+	if err != nil {
+		return nil, summary, err
+	}
+	if conf, err = c.resolveNamespaceLabelSelector(ctx, conf); err != nil {
+		return nil, summary, err
+	}
+	conf = c.resolveTerminatingNamespaces(ctx, conf)
+	if !conf.SkipAccessReview {
+		targets = c.reviewAccess(ctx, targets, conf, summary)
+	}
+	results := make([]metadataResult, len(targets))
+	tokens := make(chan struct{}, conf.concurrency())
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		tokens <- struct{}{}
+		go func(i int, target fetchTarget) {
+			defer wg.Done()
+			defer func() { <-tokens }()
+			results[i].items, results[i].err = c.listSelectedMetadata(ctx, target, conf)
+		}(i, target)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, summary, ctx.Err()
+	}
+
+	counts := map[InventoryEntry]int{}
+	// objects served in several versions or groups are counted once, in the version listed first
+	counted := map[types.UID]bool{}
+	for i, target := range targets {
+		if err := results[i].err; err != nil {
+			failure := fetchFailureOf(err)
+			err = c.describeListError(err)
+			conf.Emit(ScanEvent{Type: ScanEventResourceSkipped, Resource: target.resource.String(), Reason: err.Error()})
+			summary.Skipped = append(summary.Skipped, SkippedResource{Resource: target.resource.String(), Failure: failure, Message: err.Error()})
+			continue
+		}
+		summary.Listed++
+		for j := range results[i].items {
+			item := &results[i].items[j]
+			if uid := item.GetUID(); len(uid) > 0 {
+				if counted[uid] {
+					continue
+				}
+				counted[uid] = true
+			}
+			if !conf.namespaceSelected(item.GetNamespace(), target.namespaced) || conf.IgnoreOwnedObjects && ownedByController(item) {
+				continue
+			}
+			if conf.systemNamespaceExcluded(item.GetNamespace()) {
+				summary.SystemNamespaceObjects++
+				continue
+			}
+			// only the metadata is listed, the phase of Pods and the conditions of Jobs are not known
+			if !conf.IncludeTerminalObjects && (conf.terminatingNamespaces.Has(item.GetNamespace()) || target.kind.Group == "" && target.kind.Kind == "Namespace" && item.GetDeletionTimestamp() != nil) {
+				summary.TerminalObjects++
+				continue
+			}
+			if conf.ignoredByAnnotation(item) {
+				summary.Suppressed = append(summary.Suppressed, SuppressedObject{Kind: target.kind.Kind, Namespace: item.GetNamespace(), Name: item.GetName()})
+				continue
+			}
+			counts[InventoryEntry{Group: target.kind.Group, Version: target.kind.Version, Kind: target.kind.Kind, Namespace: item.GetNamespace()}]++
+		}
+	}
+	entries := make([]InventoryEntry, 0, len(counts))
+	for entry, count := range counts {
+		entry.Count = count
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Namespace < b.Namespace
+	})
+	return entries, summary, nil
+}
+
+// listSelectedMetadata lists the metadata of the objects of target in each of the selected namespaces, like
+// listSelectedNamespaces does for objects
+func (c *Cluster) listSelectedMetadata(ctx context.Context, target fetchTarget, conf *Config) ([]v1.PartialObjectMetadata, error) {
+	selected, ok := conf.listedNamespaces()
+	if !target.namespaced || !ok {
+		return c.listMetadata(ctx, target.resource, "", conf)
+	}
+	var items []v1.PartialObjectMetadata
+	for _, namespace := range selected {
+		namespaceItems, err := c.listMetadata(ctx, target.resource, namespace, conf)
+		if err != nil {
+			return nil, err
+		}
+		for i := range namespaceItems {
+			if len(namespaceItems[i].GetNamespace()) == 0 {
+				namespaceItems[i].SetNamespace(namespace)
+			}
+		}
+		items = append(items, namespaceItems...)
+	}
+	return items, nil
+}
+
+// listMetadata lists the metadata of the objects of resource in namespace, or across the cluster when it is empty,
+// matching the selectors of conf page by page. Api servers which do not support listing metadata only, eg some
+// aggregated api servers, return whole objects whose metadata is decoded all the same
+func (c *Cluster) listMetadata(ctx context.Context, resource schema.GroupVersionResource, namespace string, conf *Config) ([]v1.PartialObjectMetadata, error) {
+	prefix := "/apis/" + resource.Group
+	if len(resource.Group) == 0 {
+		prefix = "/api"
+	}
+	segments := []string{prefix, resource.Version}
+	if len(namespace) > 0 {
+		segments = append(segments, "namespaces", namespace)
+	}
+	resourcePath := path.Join(append(segments, resource.Resource)...)
+
+	var items []v1.PartialObjectMetadata
+	continueToken := ""
+	for {
+		req := c.disco.RESTClient().Get().AbsPath(resourcePath).
+			SetHeader("Accept", partialObjectMetadataList).
+			Param("limit", strconv.FormatInt(conf.pageSize(), 10))
+		if len(conf.LabelSelector) > 0 {
+			req = req.Param("labelSelector", conf.LabelSelector)
+		}
+		if len(conf.FieldSelector) > 0 {
+			req = req.Param("fieldSelector", conf.FieldSelector)
+		}
+		if len(continueToken) > 0 {
+			req = req.Param("continue", continueToken)
+		}
+		data, err := withRetry(ctx, conf.listRetries(), func() ([]byte, error) {
+			return req.Do(ctx).Raw()
+		})
+		if err != nil {
+			return nil, err
+		}
+		var page v1.PartialObjectMetadataList
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("failed to decode metadata of %s: %w", resource, err)
+		}
+		items = append(items, page.Items...)
+		if continueToken = page.GetContinue(); len(continueToken) == 0 {
+			return items, nil
+		}
+	}
+}
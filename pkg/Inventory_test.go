@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_CountK8sObjects(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	namespaceGvk := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	hpaV2 := schema.GroupVersionKind{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler"}
+	hpaV1 := schema.GroupVersionKind{Group: "autoscaling", Version: "v1", Kind: "HorizontalPodAutoscaler"}
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	for _, obj := range []map[string]interface{}{
+		newFakeObject("apps/v1", "Deployment", "shop", "cart"),
+		newFakeObject("apps/v1", "Deployment", "shop", "checkout"),
+		newFakeObject("apps/v1", "Deployment", "billing", "invoices"),
+		newFakeObject("apps/v1", "Deployment", "kube-system", "coredns"),
+		withAnnotation(newFakeObject("apps/v1", "Deployment", "vendor", "operator"), IgnoreAnnotation, "true"),
+	} {
+		server.addObject(deployments, obj)
+	}
+	server.addObject(server.addResource(namespaceGvk, "namespaces", false), newFakeObject("v1", "Namespace", "", "shop"))
+	// the same object is served in both versions and counted once
+	server.addObject(server.addResource(hpaV2, "horizontalpodautoscalers", true), newFakeObject("autoscaling/v2", "HorizontalPodAutoscaler", "shop", "cart"))
+	server.addObject(server.addResource(hpaV1, "horizontalpodautoscalers", true), newFakeObject("autoscaling/v1", "HorizontalPodAutoscaler", "shop", "cart"))
+	cluster := server.cluster(t)
+
+	gvks := []schema.GroupVersionKind{deploymentGvk, namespaceGvk, hpaV1, hpaV2}
+	entries, summary, err := cluster.CountK8sObjects(context.Background(), gvks, &Config{IgnoreNamespaces: []string{"kube-system"}, PageSize: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, []InventoryEntry{
+		{Version: "v1", Kind: "Namespace", Count: 1},
+		{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "billing", Count: 1},
+		{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "shop", Count: 2},
+		{Group: "autoscaling", Version: "v2", Kind: "HorizontalPodAutoscaler", Namespace: "shop", Count: 1},
+	}, entries)
+	assert.Equal(t, "apps/v1", entries[1].APIVersion())
+	assert.False(t, summary.Partial())
+	assert.Equal(t, 4, summary.Listed)
+	assert.Equal(t, []SuppressedObject{{Kind: "Deployment", Namespace: "vendor", Name: "operator"}}, summary.Suppressed)
+
+	// only metadata is asked for, page by page
+	var deploymentPages int
+	for _, r := range server.recorded() {
+		if r.Path == "/apis/apps/v1/deployments" {
+			deploymentPages++
+			assert.Contains(t, r.Header.Get("Accept"), "as=PartialObjectMetadataList")
+			assert.Equal(t, "2", r.Query.Get("limit"))
+		}
+	}
+	assert.Equal(t, 3, deploymentPages)
+
+	// kind and namespace filters apply as for a scan
+	entries, _, err = cluster.CountK8sObjects(context.Background(), gvks, &Config{SelectKinds: []string{"Deployment"}, SelectNamespaces: []string{"billing"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []InventoryEntry{{Group: "apps", Version: "v1", Kind: "Deployment", Namespace: "billing", Count: 1}}, entries)
+}
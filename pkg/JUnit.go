@@ -0,0 +1,220 @@
+package pkg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// junitTimestamp is the format of the timestamps of the junit schema, without time zone
+const junitTimestamp = "2006-01-02T15:04:05"
+
+// junitTestSuites is the document of the junit output, with a test suite per scan, see ScanMetadata
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string          `xml:"name,attr"`
+	Tests      int             `xml:"tests,attr"`
+	Failures   int             `xml:"failures,attr"`
+	Errors     int             `xml:"errors,attr"`
+	Skipped    int             `xml:"skipped,attr"`
+	Time       string          `xml:"time,attr"`
+	Timestamp  string          `xml:"timestamp,attr"`
+	Properties []junitProperty `xml:"properties>property"`
+	Cases      []junitTestCase `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+// junitTestCase is a scanned object, it fails with a failure per finding, see Findings
+type junitTestCase struct {
+	Name      string         `xml:"name,attr"`
+	Classname string         `xml:"classname,attr"`
+	Skipped   *junitSkipped  `xml:"skipped"`
+	Failures  []junitFailure `xml:"failure"`
+}
+
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitOutputManager reports results to stdout as a junit document for CI systems to render them as tests, an
+// object passes without findings and fails with a failure per finding
+type junitOutputManager struct {
+	w       io.Writer
+	now     func() time.Time
+	last    time.Time
+	elapsed time.Duration
+	results []ValidationResult
+	suites  []junitTestSuite
+}
+
+func newDefaultJUnitOutputManager() *junitOutputManager {
+	return newJUnitOutputManager(os.Stdout, time.Now)
+}
+
+func newJUnitOutputManager(w io.Writer, now func() time.Time) *junitOutputManager {
+	return &junitOutputManager{w: w, now: now, last: now()}
+}
+
+func (j *junitOutputManager) PutBulk(vrs []ValidationResult) error {
+	return j.PutScan(ScanMetadata{}, vrs, nil, nil)
+}
+
+func (j *junitOutputManager) Put(vr ValidationResult) error {
+	return j.PutBulk([]ValidationResult{vr})
+}
+
+// PutScan adds a test suite for the scan with a test case per object, the objects skipped by their annotations and
+// the resources which could not be listed are skipped test cases. The time of the suite is the time since the scan
+// before, or since the start of the run
+func (j *junitOutputManager) PutScan(scan ScanMetadata, results []ValidationResult, summary *FetchSummary, conf *Config) error {
+	now := j.now()
+	suite := junitTestSuite{
+		Name:      junitSuiteName(scan),
+		Time:      junitSeconds(now.Sub(j.last)),
+		Timestamp: now.UTC().Format(junitTimestamp),
+		Properties: []junitProperty{
+			{Name: "cluster", Value: scan.Cluster},
+			{Name: "sourceVersion", Value: scan.SourceVersion},
+			{Name: "targetVersion", Value: scan.TargetVersion},
+			{Name: "toolVersion", Value: BuildVersion},
+		},
+	}
+	j.elapsed += now.Sub(j.last)
+	j.last = now
+	for _, vr := range results {
+		if len(vr.Kind) == 0 {
+			continue
+		}
+		j.results = append(j.results, vr)
+		testCase := junitTestCase{Name: vr.ResourceName, Classname: junitClassname(vr.ResourceNamespace, vr.Kind)}
+		if len(testCase.Name) == 0 {
+			testCase.Name = vr.Location()
+		}
+		for _, f := range Findings(vr, conf) {
+			testCase.Failures = append(testCase.Failures, newJUnitFailure(vr, f))
+		}
+		if len(testCase.Failures) == 0 && len(vr.SuppressedFindings) > 0 {
+			testCase.Skipped = &junitSkipped{Message: fmt.Sprintf("%d finding(s) suppressed", len(vr.SuppressedFindings))}
+		}
+		suite.Cases = append(suite.Cases, testCase)
+	}
+	if summary != nil {
+		for _, s := range summary.Suppressed {
+			if len(s.Rules) == 0 {
+				suite.Cases = append(suite.Cases, junitTestCase{
+					Name:      s.Name,
+					Classname: junitClassname(s.Namespace, s.Kind),
+					Skipped:   &junitSkipped{Message: fmt.Sprintf("ignored by the %s annotation", IgnoreAnnotation)},
+				})
+			}
+		}
+		for _, skipped := range [][]SkippedResource{summary.Skipped, summary.Forbidden} {
+			for _, s := range skipped {
+				suite.Cases = append(suite.Cases, junitTestCase{
+					Name:      s.Resource,
+					Classname: "resources",
+					Skipped:   &junitSkipped{Message: fmt.Sprintf("%s: %s", s.Failure, s.Message)},
+				})
+			}
+		}
+	}
+	for _, testCase := range suite.Cases {
+		suite.Tests++
+		if len(testCase.Failures) > 0 {
+			suite.Failures++
+		} else if testCase.Skipped != nil {
+			suite.Skipped++
+		}
+	}
+	j.suites = append(j.suites, suite)
+	return nil
+}
+
+func (j *junitOutputManager) Flush() error {
+	doc := junitTestSuites{Name: "kubedd", Time: junitSeconds(j.elapsed), Suites: j.suites}
+	for _, suite := range j.suites {
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+	}
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, "%s%s\n", xml.Header, out)
+	return err
+}
+
+func (j *junitOutputManager) GetSummaryValidationResultBulk() []SummaryValidationResult {
+	var svrs []SummaryValidationResult
+	for _, vr := range j.results {
+		if HasFindings(vr) {
+			svrs = append(svrs, newSummaryValidationResult(vr))
+		}
+	}
+	return svrs
+}
+
+// newJUnitFailure is the failure of the finding f of vr, its message says what is wrong with the api version or
+// field and the api version to migrate to
+func newJUnitFailure(vr ValidationResult, f Finding) junitFailure {
+	message := f.Message
+	if len(f.Field) > 0 {
+		message = f.Field + ": " + message
+	}
+	if len(f.SuggestedAPIVersion) > 0 && f.Rule != RuleRemovedAPI && f.Rule != RuleAlreadyRemovedAPI && f.Rule != RuleDeprecatedAPI {
+		message = fmt.Sprintf("%s, migrate to %s", message, f.SuggestedAPIVersion)
+	}
+	text := []string{"rule: " + f.Rule, "severity: " + f.Severity, "apiVersion: " + vr.APIVersion}
+	if len(f.SuggestedAPIVersion) > 0 {
+		text = append(text, "suggested apiVersion: "+f.SuggestedAPIVersion)
+	}
+	if len(vr.FileName) > 0 {
+		text = append(text, "file: "+vr.Location())
+	}
+	return junitFailure{Message: message, Type: f.Rule, Text: strings.Join(text, "\n")}
+}
+
+// junitClassname is namespace/kind, or kind alone for cluster scoped objects
+func junitClassname(namespace, kind string) string {
+	if len(namespace) == 0 {
+		return kind
+	}
+	return namespace + "/" + kind
+}
+
+func junitSuiteName(scan ScanMetadata) string {
+	name := scan.Cluster
+	if len(name) == 0 {
+		name = "manifests"
+	}
+	if len(scan.TargetVersion) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s to %s", name, scan.TargetVersion)
+}
+
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
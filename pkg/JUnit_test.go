@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock returns a clock starting at start which moves by step every time it is read
+func fakeClock(start time.Time, step time.Duration) func() time.Time {
+	now := start.Add(-step)
+	return func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+}
+
+func TestJUnitOutputManager(t *testing.T) {
+	buf := &bytes.Buffer{}
+	output := newJUnitOutputManager(buf, fakeClock(time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), 1500*time.Millisecond))
+	summary := &FetchSummary{
+		Skipped:    []SkippedResource{{Resource: "widgets.example.com", Failure: FetchTimeout, Message: "the list timed out"}},
+		Suppressed: []SuppressedObject{{Kind: "Secret", Namespace: "shop", Name: "tls"}, {Kind: "Ingress", Namespace: "shop", Name: "admin", Rules: []string{RuleRemovedAPI}}},
+	}
+	results := append(goldenReportResults(), ValidationResult{
+		Kind: "Ingress", APIVersion: "networking.k8s.io/v1beta1", ResourceNamespace: "shop", ResourceName: "legacy",
+		SuppressedFindings: []SuppressedFinding{{Finding: RuleRemovedAPI, Owner: "web-team"}},
+	})
+	assert.NoError(t, output.PutScan(ScanMetadata{Cluster: "prod", SourceVersion: "1.24.17", TargetVersion: "1.25"}, results, summary, nil))
+	files := []ValidationResult{{FileName: "manifests.yaml", DocumentIndex: 2, Kind: "CronJob", APIVersion: "batch/v1beta1", Deleted: true, LatestAPIVersion: "batch/v1"}}
+	assert.NoError(t, output.PutScan(ScanMetadata{TargetVersion: "1.25"}, files, nil, nil))
+	assert.NoError(t, output.Flush())
+
+	assert.True(t, strings.HasPrefix(buf.String(), xml.Header+"<testsuites "))
+	var doc junitTestSuites
+	if !assert.NoError(t, xml.Unmarshal(buf.Bytes(), &doc)) || !assert.Len(t, doc.Suites, 2) {
+		return
+	}
+	assert.Equal(t, 9, doc.Tests)
+	assert.Equal(t, 5, doc.Failures)
+	assert.Equal(t, "3.000", doc.Time)
+
+	prod := doc.Suites[0]
+	assert.Equal(t, "prod to 1.25", prod.Name)
+	assert.Equal(t, 8, prod.Tests)
+	assert.Equal(t, 4, prod.Failures)
+	assert.Equal(t, 3, prod.Skipped)
+	assert.Equal(t, "1.500", prod.Time)
+	assert.Equal(t, "2024-05-01T12:30:01", prod.Timestamp)
+	assert.Contains(t, prod.Properties, junitProperty{Name: "sourceVersion", Value: "1.24.17"})
+
+	cases := map[string]junitTestCase{}
+	for _, c := range prod.Cases {
+		cases[c.Classname+" "+c.Name] = c
+	}
+	// clean objects pass
+	assert.Equal(t, junitTestCase{Name: "settings", Classname: "shop/ConfigMap"}, cases["shop/ConfigMap settings"])
+	assert.Len(t, cases["shop/Deployment cart"].Failures, 5)
+	ingress := cases["shop/Ingress storefront"].Failures
+	if assert.Len(t, ingress, 3) {
+		assert.Equal(t, junitFailure{
+			Message: "networking.k8s.io/v1beta1 Ingress is removed, migrate to networking.k8s.io/v1",
+			Type:    RuleRemovedAPI,
+			Text:    "rule: removed-api\nseverity: error\napiVersion: networking.k8s.io/v1beta1\nsuggested apiVersion: networking.k8s.io/v1",
+		}, ingress[0])
+		assert.Equal(t, "backend: invalid in networking.k8s.io/v1: Field must be set to integer or not be present, migrate to networking.k8s.io/v1", ingress[1].Message)
+	}
+	// cluster scoped objects are classed by their kind alone
+	assert.Len(t, cases["PodSecurityPolicy restricted"].Failures, 1)
+	assert.Equal(t, &junitSkipped{Message: "1 finding(s) suppressed"}, cases["shop/Ingress legacy"].Skipped)
+	assert.Equal(t, &junitSkipped{Message: "ignored by the kubedd.io/ignore annotation"}, cases["shop/Secret tls"].Skipped)
+	assert.NotContains(t, cases, "shop/Ingress admin")
+	assert.Equal(t, &junitSkipped{Message: "timeout: the list timed out"}, cases["resources widgets.example.com"].Skipped)
+
+	manifests := doc.Suites[1]
+	assert.Equal(t, "manifests to 1.25", manifests.Name)
+	if assert.Len(t, manifests.Cases, 1) {
+		assert.Equal(t, "manifests.yaml document 2", manifests.Cases[0].Name)
+		assert.Contains(t, manifests.Cases[0].Failures[0].Text, "file: manifests.yaml document 2")
+	}
+}
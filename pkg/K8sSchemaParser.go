@@ -22,6 +22,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/devtron-labs/silver-surfer/pkg/errors"
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
 	"github.com/getkin/kin-openapi/openapi2"
 	"github.com/getkin/kin-openapi/openapi2conv"
 	"github.com/getkin/kin-openapi/openapi3"
@@ -29,11 +30,12 @@ import (
 	"io"
 	"io/ioutil"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"net/http"
+	"sync"
 )
 
 const (
-	urlTemplate       = `https://raw.githubusercontent.com/kubernetes/kubernetes/release-%s/api/openapi-spec/swagger.json`
 	intOrStringPath   = "components.schemas.io\\.k8s\\.apimachinery\\.pkg\\.util\\.intstr\\.IntOrString"
 	intOrStringType   = `{"oneOf":[{"type": "string"},{"type": "integer"}]}`
 	intOrStringFormat = "definitions.io\\.k8s\\.apimachinery\\.pkg\\.util\\.intstr\\.IntOrString.format"
@@ -61,22 +63,54 @@ type KubeChecker interface {
 }
 
 type kubeCheckerImpl struct {
+	// mu guards versionMap, the specs are loaded on first use while objects may be validated at the same time
+	mu         sync.RWMutex
 	versionMap map[string]*kubeSpec
+	// cacheDir is the directory downloaded specs are cached in, see SchemaCacheDir
+	cacheDir string
+	// offline forbids downloading specs which are not cached
+	offline bool
+	// baseURL is the mirror of the kubernetes repository specs are downloaded from, see SchemaBaseURL
+	baseURL string
+	// checksums are the recorded sha256 of the specs of kubernetes versions, see SchemaChecksums
+	checksums map[string]string
+	// strict reports unknown fields, see Config.Strict
+	strict bool
 }
 
 func NewKubeCheckerImpl() *kubeCheckerImpl {
 	return &kubeCheckerImpl{versionMap: map[string]*kubeSpec{}}
 }
 
+// NewCachedKubeChecker returns a KubeChecker which caches the specs it downloads in conf.SchemaCacheDir and
+// reads them from there before any download, with conf.Offline specs which are not cached fail to load
+func NewCachedKubeChecker(conf *Config) *kubeCheckerImpl {
+	k := NewKubeCheckerImpl()
+	k.cacheDir, k.offline = conf.SchemaCacheDir, conf.Offline
+	k.baseURL, k.checksums = conf.SchemaBaseURL, conf.SchemaChecksums
+	k.strict = conf.Strict
+	return k
+}
+
 func (k *kubeCheckerImpl) hasReleaseVersion(releaseVersion string) bool {
-	_, ok := k.versionMap[releaseVersion]
+	_, ok := k.spec(releaseVersion)
 	return ok
 }
 
+// spec returns the loaded spec of releaseVersion
+func (k *kubeCheckerImpl) spec(releaseVersion string) (*kubeSpec, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ks, ok := k.versionMap[releaseVersion]
+	return ks, ok
+}
+
 func (k *kubeCheckerImpl) LoadFromPath(releaseVersion string, filePath string, force bool) error {
-	if _, ok := k.versionMap[releaseVersion]; ok && !force {
+	if _, ok := k.spec(releaseVersion); ok && !force {
 		return nil
 	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
 	data, err := ioutil.ReadFile(filePath)
 	if err != nil {
 		//kLog.Debug(fmt.Sprintf("%v", err))
@@ -86,29 +120,64 @@ func (k *kubeCheckerImpl) LoadFromPath(releaseVersion string, filePath string, f
 }
 
 func (k *kubeCheckerImpl) LoadFromUrl(releaseVersion string, force bool) error {
+	if _, ok := k.spec(releaseVersion); ok && !force {
+		return nil
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	// the spec may have been loaded while waiting for the lock
 	if _, ok := k.versionMap[releaseVersion]; ok && !force {
 		return nil
 	}
-	data, err := k.downloadFile(releaseVersion)
+	data, specVersion, err := k.findSchema(releaseVersion)
 	if err != nil {
 		//kLog.Debug(fmt.Sprintf("%v", err))
 		return err
 	}
-	return k.load(data, releaseVersion)
+	if err := k.load(data, releaseVersion); err != nil {
+		return err
+	}
+	k.versionMap[releaseVersion].specVersion = specVersion
+	return nil
 }
 
+// load parses data as the spec of releaseVersion, mu is held by the caller
 func (k *kubeCheckerImpl) load(data []byte, releaseVersion string) error {
 	openapi, err := k.loadOpenApi2(data)
 	if err != nil {
 		//kLog.Debug(fmt.Sprintf("%v", err))
 		return err
 	}
-	k.versionMap[releaseVersion] = newKubeSpec(openapi)
+	ks := newKubeSpec(openapi)
+	ks.strict = k.strict
+	k.versionMap[releaseVersion] = ks
 	return nil
 }
 
+// downloadFile downloads the spec of releaseVersion, retrying on errors of the network or the server, and verifies it
+// before it is used or cached
 func (k *kubeCheckerImpl) downloadFile(releaseVersion string) ([]byte, error) {
-	url := fmt.Sprintf(urlTemplate, releaseVersion)
+	url := schemaURL(k.baseURL, releaseVersion)
+	backoff := schemaDownloadBackoff
+	for attempt := 0; ; attempt++ {
+		data, err := getSchema(url)
+		if err == nil {
+			return data, verifySchema(releaseVersion, data, k.checksums)
+		}
+		if attempt >= schemaDownloadRetries || !isRetryableDownloadError(err) {
+			return []byte{}, err
+		}
+		delay := wait.Jitter(backoff, 0.5)
+		kLog.Warn(fmt.Sprintf("downloading the spec of kubernetes %s failed, retrying in %v: %v", releaseVersion, delay, err))
+		if err := retrySleep(context.Background(), delay); err != nil {
+			return []byte{}, err
+		}
+		backoff *= 2
+	}
+}
+
+// getSchema downloads the spec at url
+func getSchema(url string) ([]byte, error) {
 	resp, err := http.Get(url)
 	if err != nil {
 		//kLog.Debug(fmt.Sprintf("%v", err))
@@ -118,6 +187,9 @@ func (k *kubeCheckerImpl) downloadFile(releaseVersion string) ([]byte, error) {
 	if resp.StatusCode == http.StatusNotFound {
 		return []byte{}, errors.ErrOpenApiSpecNotFound
 	}
+	if resp.StatusCode != http.StatusOK {
+		return []byte{}, &schemaStatusError{url: url, code: resp.StatusCode}
+	}
 	var out bytes.Buffer
 	_, err = io.Copy(&out, resp.Body)
 	if err != nil {
@@ -192,7 +264,8 @@ func (k *kubeCheckerImpl) ValidateYaml(spec string, releaseVersion string) (Vali
 	if err != nil {
 		return ValidationResult{}, err
 	}
-	return k.versionMap[releaseVersion].ValidateYaml(spec)
+	ks, _ := k.spec(releaseVersion)
+	return ks.ValidateYaml(spec)
 }
 
 func (k *kubeCheckerImpl) ValidateJson(spec string, releaseVersion string) (ValidationResult, error) {
@@ -200,7 +273,8 @@ func (k *kubeCheckerImpl) ValidateJson(spec string, releaseVersion string) (Vali
 	if err != nil {
 		return ValidationResult{}, err
 	}
-	return k.versionMap[releaseVersion].ValidateJson(spec)
+	ks, _ := k.spec(releaseVersion)
+	return ks.ValidateJson(spec)
 }
 
 func (k *kubeCheckerImpl) ValidateObject(spec map[string]interface{}, releaseVersion string) (ValidationResult, error) {
@@ -208,7 +282,8 @@ func (k *kubeCheckerImpl) ValidateObject(spec map[string]interface{}, releaseVer
 	if err != nil {
 		return ValidationResult{}, err
 	}
-	return k.versionMap[releaseVersion].ValidateObject(spec)
+	ks, _ := k.spec(releaseVersion)
+	return ks.ValidateObject(spec)
 }
 
 func (k *kubeCheckerImpl) GetKinds(releaseVersion string) ([]schema.GroupVersionKind, error) {
@@ -216,7 +291,8 @@ func (k *kubeCheckerImpl) GetKinds(releaseVersion string) ([]schema.GroupVersion
 	if err != nil {
 		return make([]schema.GroupVersionKind, 0), err
 	}
-	return k.versionMap[releaseVersion].getLatestKinds(), nil
+	ks, _ := k.spec(releaseVersion)
+	return ks.getLatestKinds(), nil
 }
 
 func (k *kubeCheckerImpl) IsApiVersionSupported(releaseVersion, apiVersion, kind string) bool {
@@ -224,5 +300,6 @@ func (k *kubeCheckerImpl) IsApiVersionSupported(releaseVersion, apiVersion, kind
 	if err != nil {
 		return false
 	}
-	return k.versionMap[releaseVersion].isApiVersionSupported(apiVersion, kind)
+	ks, _ := k.spec(releaseVersion)
+	return ks.isApiVersionSupported(apiVersion, kind)
 }
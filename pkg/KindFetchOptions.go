@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// KindFetchOptions controls how objects of one kind are fetched from the cluster, see Config.PerKindOptions
+type KindFetchOptions struct {
+	// Limit is the maximum number of objects listed, Config.MaxObjectsPerResource applies when it is zero
+	Limit int64
+
+	// MetadataOnly drops everything but apiVersion, kind and metadata from the fetched objects, eg for Secrets
+	MetadataOnly bool
+
+	// Skip excludes the kind from the scan
+	Skip bool
+}
+
+func (c *Config) kindFetchOptions(kind string) KindFetchOptions {
+	if options, ok := c.PerKindOptions[kind]; ok {
+		return options
+	}
+	for k, options := range c.PerKindOptions {
+		if strings.EqualFold(k, kind) {
+			return options
+		}
+	}
+	return KindFetchOptions{}
+}
+
+// objectLimit is the maximum number of objects listed per resource of a kind with options, zero lists all objects
+func (c *Config) objectLimit(options KindFetchOptions) int64 {
+	if options.Limit > 0 {
+		return options.Limit
+	}
+	return c.MaxObjectsPerResource
+}
+
+func metadataOnly(obj unstructured.Unstructured) unstructured.Unstructured {
+	return unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": obj.GetAPIVersion(),
+		"kind":       obj.GetKind(),
+		"metadata":   obj.Object["metadata"],
+	}}
+}
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_perKindOptions(t *testing.T) {
+	server := newFakeApiServer(t)
+	secretGvk := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	eventGvk := schema.GroupVersionKind{Version: "v1", Kind: "Event"}
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	secrets := server.addResource(secretGvk, "secrets", true)
+	events := server.addResource(eventGvk, "events", true)
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	secret := newFakeObject("v1", "Secret", "shop", "db")
+	secret["data"] = map[string]interface{}{"password": "c2VjcmV0"}
+	server.addObject(secrets, secret)
+	for _, name := range []string{"a", "b", "c"} {
+		server.addObject(events, newFakeObject("v1", "Event", "shop", name))
+	}
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	cluster := server.cluster(t)
+
+	conf := &Config{
+		IncludeTerminalObjects: true,
+		SelectKinds:            []string{"Secret", "Event", "Deployment"},
+		PerKindOptions: map[string]KindFetchOptions{
+			"secret":     {MetadataOnly: true},
+			"Event":      {Limit: 2},
+			"Deployment": {Skip: true},
+		},
+	}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{secretGvk, eventGvk, deploymentGvk}, conf)
+	assert.NoError(t, err)
+	var got []string
+	for _, obj := range objs {
+		got = append(got, obj.GetKind()+"/"+obj.GetName())
+	}
+	assert.Equal(t, []string{"Secret/db", "Event/a", "Event/b"}, got)
+	assert.Equal(t, "v1", objs[0].GetAPIVersion())
+	assert.NotContains(t, objs[0].Object, "data")
+	assert.ElementsMatch(t, []string{"/api/v1/secrets", "/api/v1/events"}, server.resourceRequests())
+}
@@ -0,0 +1,104 @@
+package pkg
+
+import (
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// KindFilter is an entry of IgnoreKinds or SelectKinds. Entries may be qualified with the group of the kind, eg
+// Deployment.apps, Service.core or Workflow.argoproj.io, bare kinds match the kind in any group
+type KindFilter struct {
+	// Kind is the name or pattern of the kind
+	Kind string
+	// Group is the name or pattern of the group, core for the legacy group, and only set if Qualified
+	Group     string
+	Qualified bool
+}
+
+// ParseKindFilter parses an entry of the kind filters, regular expressions are matched against the kind only
+func ParseKindFilter(entry string) KindFilter {
+	if strings.HasPrefix(entry, RegexPatternPrefix) {
+		return KindFilter{Kind: entry}
+	}
+	kind, group, qualified := strings.Cut(entry, ".")
+	return KindFilter{Kind: kind, Group: group, Qualified: qualified}
+}
+
+// Matches returns true if gvk is matched by the filter
+func (f KindFilter) Matches(gvk schema.GroupVersionKind) bool {
+	return f.matches(gvk.Kind, gvk.Group)
+}
+
+func (f KindFilter) matches(name, group string) bool {
+	if !Contains(name, []string{f.Kind}) {
+		return false
+	}
+	if !f.Qualified {
+		return true
+	}
+	if len(group) == 0 {
+		group = "core"
+	}
+	return Contains(group, []string{f.Group})
+}
+
+// matchesKindFilters returns true if gvk is matched by any of the kind filters
+func matchesKindFilters(gvk schema.GroupVersionKind, filters []string) bool {
+	for _, filter := range filters {
+		if ParseKindFilter(filter).Matches(gvk) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesResourceFilters returns true if gvr is matched by any of filters, which use the syntax of the kind filters
+// with the plural name of the resource instead of the kind, eg allowlists.example.com
+func matchesResourceFilters(gvr schema.GroupVersionResource, filters []string) bool {
+	for _, filter := range filters {
+		if ParseKindFilter(filter).matches(gvr.Resource, gvr.Group) {
+			return true
+		}
+	}
+	return false
+}
+
+// kindSkipReason returns why the objects of gvk are left out of the scan by the custom resource, api group and kind
+// filters of conf, or the empty string if they are scanned
+func (c *Config) kindSkipReason(gvk schema.GroupVersionKind) string {
+	switch {
+	case c.fetchesDefinitions(gvk):
+	case !c.customResourcesSelected(gvk):
+		return "custom resources not selected"
+	case !c.apiGroupSelected(gvk.Group):
+		return "api group not selected"
+	case matchesKindFilters(gvk, c.IgnoreKinds):
+		return "kind ignored"
+	case len(c.SelectKinds) > 0 && !matchesKindFilters(gvk, c.SelectKinds):
+		return "kind not selected"
+	}
+	return ""
+}
+
+// apiGroupSelected applies SelectAPIGroups and IgnoreAPIGroups to group, the core group is matched by both the
+// empty string and core
+func (c *Config) apiGroupSelected(group string) bool {
+	if matchesAPIGroups(group, c.IgnoreAPIGroups) {
+		return false
+	}
+	return len(c.SelectAPIGroups) == 0 || matchesAPIGroups(group, c.SelectAPIGroups)
+}
+
+func matchesAPIGroups(group string, patterns []string) bool {
+	name := group
+	if len(name) == 0 {
+		name = "core"
+	}
+	for _, pattern := range patterns {
+		if len(pattern) == 0 && len(group) == 0 || Contains(name, []string{pattern}) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,195 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestParseKindFilter(t *testing.T) {
+	tests := []struct {
+		entry string
+		want  KindFilter
+	}{
+		{entry: "Deployment", want: KindFilter{Kind: "Deployment"}},
+		{entry: "Deployment.apps", want: KindFilter{Kind: "Deployment", Group: "apps", Qualified: true}},
+		{entry: "Workflow.argoproj.io", want: KindFilter{Kind: "Workflow", Group: "argoproj.io", Qualified: true}},
+		{entry: "*.example.com", want: KindFilter{Kind: "*", Group: "example.com", Qualified: true}},
+		{entry: "~^Cron.*$", want: KindFilter{Kind: "~^Cron.*$"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseKindFilter(tt.entry), tt.entry)
+	}
+}
+
+func TestKindFilter_Matches(t *testing.T) {
+	coreService := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	vendorService := schema.GroupVersionKind{Group: "vendor.example.com", Version: "v1", Kind: "Service"}
+	extensionsIngress := schema.GroupVersionKind{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}
+	networkingIngress := schema.GroupVersionKind{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+	tests := []struct {
+		filter string
+		gvk    schema.GroupVersionKind
+		want   bool
+	}{
+		{filter: "Service", gvk: coreService, want: true},
+		{filter: "service", gvk: vendorService, want: true},
+		{filter: "Service.core", gvk: coreService, want: true},
+		{filter: "Service.core", gvk: vendorService, want: false},
+		{filter: "Service.vendor.example.com", gvk: vendorService, want: true},
+		{filter: "Service.*.example.com", gvk: vendorService, want: true},
+		{filter: "Ingress.extensions", gvk: extensionsIngress, want: true},
+		{filter: "Ingress.extensions", gvk: networkingIngress, want: false},
+		{filter: "Ing*.networking.k8s.io", gvk: networkingIngress, want: true},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, ParseKindFilter(tt.filter).Matches(tt.gvk), "%s %s", tt.filter, tt.gvk)
+	}
+}
+
+func TestCluster_FetchK8sObjects_qualifiedKinds(t *testing.T) {
+	server := newFakeApiServer(t)
+	coreService := schema.GroupVersionKind{Version: "v1", Kind: "Service"}
+	vendorService := schema.GroupVersionKind{Group: "vendor.example.com", Version: "v1", Kind: "Service"}
+	server.addObject(server.addResource(coreService, "services", true), newFakeObject("v1", "Service", "shop", "cart"))
+	server.addObject(server.addResource(vendorService, "services", true), newFakeObject("vendor.example.com/v1", "Service", "shop", "cart"))
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{coreService, vendorService}
+
+	tests := []struct {
+		name string
+		conf *Config
+		want []string
+	}{
+		{
+			name: "bare kind matches every group",
+			conf: &Config{IgnoreKinds: []string{"Service"}},
+		},
+		{
+			name: "ignore the crd colliding with a built-in kind",
+			conf: &Config{IgnoreKinds: []string{"Service.vendor.example.com"}},
+			want: []string{"v1"},
+		},
+		{
+			name: "select the built-in kind only",
+			conf: &Config{SelectKinds: []string{"Service.core"}},
+			want: []string{"v1"},
+		},
+		{
+			name: "select the crd only",
+			conf: &Config{SelectKinds: []string{"Service.vendor.example.com"}},
+			want: []string{"vendor.example.com/v1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Concurrency = 1
+			objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, tt.conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetAPIVersion())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConfig_apiGroupSelected(t *testing.T) {
+	tests := []struct {
+		name  string
+		conf  *Config
+		group string
+		want  bool
+	}{
+		{name: "no filters", conf: &Config{}, group: "security.istio.io", want: true},
+		{name: "core by name", conf: &Config{SelectAPIGroups: []string{"core"}}, group: "", want: true},
+		{name: "core by empty string", conf: &Config{SelectAPIGroups: []string{""}}, group: "", want: true},
+		{name: "empty string is not every group", conf: &Config{SelectAPIGroups: []string{""}}, group: "apps", want: false},
+		{name: "suffix wildcard", conf: &Config{IgnoreAPIGroups: []string{"*.istio.io"}}, group: "security.istio.io", want: false},
+		{name: "suffix wildcard needs the suffix", conf: &Config{IgnoreAPIGroups: []string{"*.istio.io"}}, group: "apps", want: true},
+		{name: "ignore wins", conf: &Config{SelectAPIGroups: []string{"*.k8s.io"}, IgnoreAPIGroups: []string{"policy.k8s.io"}}, group: "policy.k8s.io", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.conf.apiGroupSelected(tt.group))
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_apiGroups(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	statefulSetGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "StatefulSet"}
+	gatewayGvk := schema.GroupVersionKind{Group: "networking.istio.io", Version: "v1", Kind: "Gateway"}
+	for gvk, resource := range map[schema.GroupVersionKind]string{configMapGvk: "configmaps", deploymentGvk: "deployments", statefulSetGvk: "statefulsets", gatewayGvk: "gateways"} {
+		gvr := server.addResource(gvk, resource, true)
+		server.addObject(gvr, newFakeObject(gvk.GroupVersion().String(), gvk.Kind, "shop", "cart"))
+		server.addObject(gvr, newFakeObject(gvk.GroupVersion().String(), gvk.Kind, "kube-system", "cart"))
+	}
+	cluster := server.cluster(t)
+
+	// groups are filtered first, then kinds, then namespaces: the selected Gateway kind is in an ignored group
+	conf := &Config{
+		IncludeTerminalObjects: true,
+		SelectAPIGroups:        []string{"apps", "*.istio.io"},
+		IgnoreAPIGroups:        []string{"networking.istio.io"},
+		SelectKinds:            []string{"Deployment", "Gateway", "ConfigMap"},
+		IgnoreNamespaces:       []string{"kube-system"},
+	}
+	gvks := []schema.GroupVersionKind{configMapGvk, deploymentGvk, statefulSetGvk, gatewayGvk}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, conf)
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 1) {
+		assert.Equal(t, "Deployment", objs[0].GetKind())
+		assert.Equal(t, "shop", objs[0].GetNamespace())
+	}
+	// skipped groups are never listed
+	assert.Equal(t, []string{"/apis/apps/v1/deployments"}, server.resourceRequests())
+}
+
+func TestCluster_FetchK8sObjects_listableResources(t *testing.T) {
+	server := newFakeApiServer(t)
+	allowListGvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "AllowList"}
+	reviewGvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "CodeReview"}
+	tokenReviewGvk := schema.GroupVersionKind{Group: "authentication.k8s.io", Version: "v1", Kind: "TokenReview"}
+	podMetricsGvk := schema.GroupVersionKind{Group: "metrics.k8s.io", Version: "v1beta1", Kind: "PodMetrics"}
+	// custom resources whose plurals contain the old magic substrings are listed
+	server.addObject(server.addResource(allowListGvk, "allowlists", true), newFakeObject("example.com/v1", "AllowList", "shop", "cart"))
+	server.addObject(server.addResource(reviewGvk, "codereviews", true), newFakeObject("example.com/v1", "CodeReview", "shop", "cart"))
+	server.setVerbs(server.addResource(tokenReviewGvk, "tokenreviews", false), "create")
+	podMetrics := server.addResource(podMetricsGvk, "pods", true)
+	server.setVerbs(podMetrics, "get")
+	server.addObject(podMetrics, newFakeObject("metrics.k8s.io/v1beta1", "PodMetrics", "shop", "cart"))
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{allowListGvk, reviewGvk, tokenReviewGvk, podMetricsGvk}
+
+	tests := []struct {
+		name string
+		conf *Config
+		want []string
+	}{
+		{
+			name: "resources advertising list",
+			conf: &Config{IncludeTerminalObjects: true},
+			want: []string{"/apis/example.com/v1/allowlists", "/apis/example.com/v1/codereviews"},
+		},
+		{
+			name: "forced inclusion and exclusion",
+			conf: &Config{IncludeTerminalObjects: true, IncludeResources: []string{"pods.metrics.k8s.io", "allowlists"}, ExcludeResources: []string{"allowlists.example.com"}},
+			want: []string{"/apis/example.com/v1/codereviews", "/apis/metrics.k8s.io/v1beta1/pods"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(server.resourceRequests())
+			tt.conf.Concurrency = 1
+			_, _, err := cluster.FetchK8sObjects(context.Background(), gvks, tt.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, server.resourceRequests()[before:])
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// KubeconfigStdin is the kubeconfig path meaning the kubeconfig is read from stdin
+const KubeconfigStdin = "-"
+
+// stdinKubeconfig reads the kubeconfig from reader once and keeps it in memory only, so that every cluster and
+// context of a run can be built from it
+type stdinKubeconfig struct {
+	reader io.Reader
+	once   sync.Once
+	data   []byte
+	err    error
+}
+
+var kubeconfigFromStdin = &stdinKubeconfig{reader: os.Stdin}
+
+func (s *stdinKubeconfig) read() ([]byte, error) {
+	s.once.Do(func() {
+		s.data, s.err = io.ReadAll(s.reader)
+		if s.err != nil {
+			s.err = fmt.Errorf("failed to read kubeconfig from stdin: %w", s.err)
+		}
+	})
+	return s.data, s.err
+}
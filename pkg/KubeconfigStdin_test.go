@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCluster_kubeconfigFromStdin(t *testing.T) {
+	production := newFakeApiServer(t)
+	staging := newFakeApiServer(t)
+	staging.version = "1.28"
+	kubeconfig := `apiVersion: v1
+kind: Config
+clusters:
+- name: production
+  cluster:
+    server: ` + production.URL + `
+- name: staging
+  cluster:
+    server: ` + staging.URL + `
+contexts:
+- name: production
+  context:
+    cluster: production
+- name: staging
+  context:
+    cluster: staging
+current-context: production
+`
+	reader, writer, err := os.Pipe()
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer func(kubeconfig *stdinKubeconfig) { kubeconfigFromStdin = kubeconfig }(kubeconfigFromStdin)
+	kubeconfigFromStdin = &stdinKubeconfig{reader: reader}
+	go func() {
+		_, _ = writer.Write([]byte(kubeconfig))
+		_ = writer.Close()
+	}()
+
+	contexts, err := ListContexts(KubeconfigStdin)
+	assert.NoError(t, err)
+	assert.Len(t, contexts, 2)
+
+	// stdin is only read once, every context can be built from it
+	for _, tt := range []struct{ kubecontext, name, version string }{
+		{kubecontext: "", name: "production", version: "1.27"},
+		{kubecontext: "staging", name: "staging", version: "1.28"},
+	} {
+		cluster, err := NewCluster(KubeconfigStdin, tt.kubecontext, nil)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		assert.Equal(t, tt.name, cluster.Name())
+		version, err := cluster.ServerVersion(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, tt.version, version)
+	}
+	_, err = NewCluster(KubeconfigStdin, "missing", nil)
+	assert.Error(t, err)
+}
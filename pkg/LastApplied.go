@@ -0,0 +1,37 @@
+package pkg
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Sources objects fetched from the cluster are validated from, see Config.UseLastApplied
+const (
+	SourceLive        = "live"
+	SourceLastApplied = "last-applied"
+)
+
+// LastAppliedObject returns the object to be validated for obj along with its source. With Config.UseLastApplied it
+// is the last applied configuration of obj, namespaced as obj when the configuration does not say, or else obj
+// itself along with a note explaining why the configuration could not be used. Without it obj is returned as it is
+func LastAppliedObject(obj *unstructured.Unstructured, conf *Config) (*unstructured.Unstructured, string, string) {
+	if !conf.UseLastApplied {
+		return obj, "", ""
+	}
+	val, ok := obj.GetAnnotations()[LastAppliedConfigAnnotation]
+	if !ok {
+		return obj, SourceLive, "no last-applied-configuration annotation"
+	}
+	applied := &unstructured.Unstructured{}
+	if err := applied.UnmarshalJSON([]byte(val)); err != nil {
+		return obj, SourceLive, fmt.Sprintf("unparseable last-applied-configuration: %s", err)
+	}
+	if applied.GetKind() != obj.GetKind() || applied.GetName() != obj.GetName() {
+		return obj, SourceLive, fmt.Sprintf("last-applied-configuration is of %s %s", applied.GetKind(), applied.GetName())
+	}
+	if len(applied.GetNamespace()) == 0 {
+		applied.SetNamespace(obj.GetNamespace())
+	}
+	return applied, SourceLastApplied, ""
+}
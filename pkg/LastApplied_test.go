@@ -0,0 +1,39 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLastAppliedObject(t *testing.T) {
+	conf := &Config{UseLastApplied: true}
+	live := &unstructured.Unstructured{Object: withAnnotation(newFakeObject("apps/v1", "Deployment", "shop", "web"), LastAppliedConfigAnnotation,
+		`{"apiVersion":"extensions/v1beta1","kind":"Deployment","metadata":{"name":"web"},"spec":{"replicas":2}}`)}
+
+	got, source, note := LastAppliedObject(live, conf)
+	assert.Equal(t, SourceLastApplied, source)
+	assert.Empty(t, note)
+	assert.Equal(t, "extensions/v1beta1", got.GetAPIVersion())
+	// the namespace is taken from the live object when the configuration leaves it out
+	assert.Equal(t, "shop", got.GetNamespace())
+
+	got, source, _ = LastAppliedObject(live, &Config{})
+	assert.Same(t, live, got)
+	assert.Empty(t, source)
+
+	for annotation, want := range map[string]string{
+		"{": "unparseable last-applied-configuration",
+		`{"kind":"Service","apiVersion":"v1","metadata":{"name":"web"}}`: "last-applied-configuration is of Service web",
+	} {
+		broken := &unstructured.Unstructured{Object: withAnnotation(newFakeObject("apps/v1", "Deployment", "shop", "web"), LastAppliedConfigAnnotation, annotation)}
+		got, source, note = LastAppliedObject(broken, conf)
+		assert.Same(t, broken, got)
+		assert.Equal(t, SourceLive, source)
+		assert.Contains(t, note, want)
+	}
+	_, source, note = LastAppliedObject(&unstructured.Unstructured{Object: newFakeObject("v1", "Pod", "shop", "cart")}, conf)
+	assert.Equal(t, SourceLive, source)
+	assert.Equal(t, "no last-applied-configuration annotation", note)
+}
@@ -0,0 +1,159 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultManifestIncludes are the globs of the files of directories validated when no include globs are given
+var DefaultManifestIncludes = []string{"**/*.yaml", "**/*.yml"}
+
+// ManifestFileOptions select the files of directories to be validated
+type ManifestFileOptions struct {
+	// Include are the globs of the files to be validated, DefaultManifestIncludes when empty. Globs are matched with
+	// the slash separated path of files relative to the directory they are found in, ** matches any number of
+	// directories
+	Include []string
+	// Exclude are the globs of the files and directories never to be validated, they take precedence over Include
+	Exclude []string
+	// Ignored returns true for the paths to be skipped, it is optional
+	Ignored func(path string) (bool, error)
+}
+
+// FindManifestFiles returns the files among paths along with the files of the directories among paths, searched
+// recursively, selected by opts. Files given as paths, and - for stdin, are always returned. Symbolic links to directories are
+// followed once, so that loops do not hang the walk
+func FindManifestFiles(paths []string, opts ManifestFileOptions) ([]string, error) {
+	for _, glob := range append(append([]string{}, opts.Include...), opts.Exclude...) {
+		if _, err := path.Match(strings.ReplaceAll(glob, "**", "*"), ""); err != nil {
+			return nil, fmt.Errorf("invalid glob %q: %w", glob, err)
+		}
+	}
+	var files []string
+	for _, p := range paths {
+		// stdin
+		if p == "-" {
+			files = append(files, p)
+			continue
+		}
+		info, err := os.Stat(p)
+		if err != nil {
+			return files, err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		w := &manifestWalker{root: p, opts: opts, visited: map[string]bool{}}
+		if err := w.walk(p); err != nil {
+			return files, err
+		}
+		files = append(files, w.files...)
+	}
+	return files, nil
+}
+
+type manifestWalker struct {
+	root    string
+	opts    ManifestFileOptions
+	visited map[string]bool
+	files   []string
+}
+
+func (w *manifestWalker) walk(dir string) error {
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	if w.visited[resolved] {
+		return nil
+	}
+	w.visited[resolved] = true
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		if w.opts.Ignored != nil {
+			ignored, err := w.opts.Ignored(p)
+			if err != nil {
+				return err
+			}
+			if ignored {
+				continue
+			}
+		}
+		rel, err := filepath.Rel(w.root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			info, err := os.Stat(p)
+			if err != nil {
+				// dangling links are not manifests
+				continue
+			}
+			isDir = info.IsDir()
+		}
+		if isDir {
+			// a and a/** exclude the directory a as well as the files below it
+			if !matchesAnyGlob(rel, w.opts.Exclude) && !matchesAnyGlob(rel+"/", w.opts.Exclude) {
+				if err := w.walk(p); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		includes := w.opts.Include
+		if len(includes) == 0 {
+			includes = DefaultManifestIncludes
+		}
+		if matchesAnyGlob(rel, includes) && !matchesAnyGlob(rel, w.opts.Exclude) {
+			w.files = append(w.files, p)
+		}
+	}
+	return nil
+}
+
+func matchesAnyGlob(name string, globs []string) bool {
+	for _, glob := range globs {
+		if matchGlob(glob, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches the slash separated name with glob, whose ** segments match any number of segments and whose
+// other segments are matched as by path.Match. Names ending with a slash are directories, which match the globs of
+// their files as a/** matches a/
+func matchGlob(glob, name string) bool {
+	return matchSegments(strings.Split(strings.TrimPrefix(glob, "./"), "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(glob, name []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(glob[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, err := path.Match(glob[0], name[0]); err != nil || !matched {
+			return false
+		}
+		glob, name = glob[1:], name[1:]
+	}
+	return len(name) == 0
+}
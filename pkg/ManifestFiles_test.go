@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindManifestFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app.yaml", "base/service.yml", "charts/redis/templates/deployment.yaml", "README.md", "values.json"} {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte("kind: ConfigMap\n"), 0o600))
+	}
+	// a loop back to the root must not hang the walk
+	assert.NoError(t, os.Symlink(dir, filepath.Join(dir, "base", "loop")))
+
+	got, err := FindManifestFiles([]string{dir}, ManifestFileOptions{})
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "app.yaml"),
+		filepath.Join(dir, "base", "service.yml"),
+		filepath.Join(dir, "charts", "redis", "templates", "deployment.yaml"),
+	}, got)
+
+	got, err = FindManifestFiles([]string{dir}, ManifestFileOptions{Include: []string{"**/*.yaml"}, Exclude: []string{"**/charts/**"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "app.yaml")}, got)
+
+	// files given explicitly are kept regardless of the globs
+	got, err = FindManifestFiles([]string{filepath.Join(dir, "values.json"), "-"}, ManifestFileOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "values.json"), "-"}, got)
+
+	_, err = FindManifestFiles([]string{dir}, ManifestFileOptions{Exclude: []string{"[a-"}})
+	assert.ErrorContains(t, err, "invalid glob")
+}
+
+func TestMatchGlob(t *testing.T) {
+	assert.True(t, matchGlob("**/*.yaml", "app.yaml"))
+	assert.True(t, matchGlob("**/*.yaml", "a/b/app.yaml"))
+	assert.True(t, matchGlob("./base/*.yaml", "base/app.yaml"))
+	assert.False(t, matchGlob("base/*.yaml", "base/overlay/app.yaml"))
+	assert.True(t, matchGlob("**/charts/**", "charts/"))
+	assert.True(t, matchGlob("**/charts/**", "apps/charts/redis/"))
+	assert.False(t, matchGlob("**/charts/**", "apps/chartsold/"))
+}
@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+var namespaces = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+
+// ListNamespaces returns the sorted names of the namespaces of the cluster matching labelSelector, every namespace
+// when it is empty
+func (c *Cluster) ListNamespaces(ctx context.Context, labelSelector string) ([]string, error) {
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	var names []string
+	opts := v1.ListOptions{LabelSelector: labelSelector, Limit: DefaultPageSize}
+	for {
+		list, err := c.dynamicClient().Resource(namespaces).List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			names = append(names, item.GetName())
+		}
+		if opts.Continue = list.GetContinue(); len(opts.Continue) == 0 {
+			break
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// resolveNamespaceLabelSelector returns a copy of conf in which the namespaces matching NamespaceLabelSelector are
+// resolved, conf is returned as is when no selector is set or when the user may not list namespaces
+func (c *Cluster) resolveNamespaceLabelSelector(ctx context.Context, conf *Config) (*Config, error) {
+	if len(conf.NamespaceLabelSelector) == 0 {
+		return conf, nil
+	}
+	names, err := c.ListNamespaces(ctx, conf.NamespaceLabelSelector)
+	if k8sErrors.IsForbidden(err) {
+		kLog.Warn(fmt.Sprintf("namespace selector %q of cluster %s ignored as namespaces may not be listed: %v", conf.NamespaceLabelSelector, c.name, err))
+		return conf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the namespaces of cluster %s matching %q: %w", c.name, conf.NamespaceLabelSelector, err)
+	}
+	resolved := *conf
+	resolved.labeledNamespaces = sets.New(names...)
+	return &resolved, nil
+}
+
+// listedNamespaces returns the namespaces in which namespaced resources are listed one by one, or false when they
+// are listed across the cluster, ie when no namespace is selected by name or label or when some are selected by
+// patterns only
+func (c *Config) listedNamespaces() ([]string, bool) {
+	if c.labeledNamespaces != nil {
+		var selected []string
+		for _, namespace := range sets.List(c.labeledNamespaces) {
+			if c.namespaceSelected(namespace, true) {
+				selected = append(selected, namespace)
+			}
+		}
+		return selected, true
+	}
+	if len(c.SelectNamespaces) == 0 || slices.ContainsFunc(c.SelectNamespaces, isPattern) {
+		return nil, false
+	}
+	return sets.List(sets.New(c.SelectNamespaces...)), true
+}
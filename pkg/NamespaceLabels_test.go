@@ -0,0 +1,122 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newLabeledNamespaceServer(t *testing.T) (*fakeApiServer, []schema.GroupVersionKind) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	clusterRoleGvk := schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}
+	namespaceResource := server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, "namespaces", false)
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	for _, ns := range []struct{ name, environment string }{{"billing", "prod"}, {"shop", "prod"}, {"staging", "dev"}} {
+		namespace := newFakeObject("v1", "Namespace", "", ns.name)
+		namespace["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"environment": ns.environment}
+		server.addObject(namespaceResource, namespace)
+		server.addObject(configMaps, newFakeObject("v1", "ConfigMap", ns.name, "settings"))
+	}
+	server.addObject(server.addResource(clusterRoleGvk, "clusterroles", false), newFakeObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "viewer"))
+	return server, []schema.GroupVersionKind{configMapGvk, clusterRoleGvk}
+}
+
+func TestCluster_ListNamespaces(t *testing.T) {
+	server, _ := newLabeledNamespaceServer(t)
+	cluster := server.cluster(t)
+
+	names, err := cluster.ListNamespaces(context.Background(), "environment=prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"billing", "shop"}, names)
+
+	names, err = cluster.ListNamespaces(context.Background(), "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"billing", "shop", "staging"}, names)
+}
+
+func TestCluster_FetchK8sObjects_namespaceLabelSelector(t *testing.T) {
+	server, gvks := newLabeledNamespaceServer(t)
+	cluster := server.cluster(t)
+
+	tests := []struct {
+		name     string
+		conf     *Config
+		want     []string
+		wantList []string
+	}{
+		{
+			name:     "namespaces matching the selector",
+			conf:     &Config{NamespaceLabelSelector: "environment=prod"},
+			want:     []string{"ConfigMap billing", "ConfigMap shop", "ClusterRole "},
+			wantList: []string{"/api/v1/namespaces/billing/configmaps", "/api/v1/namespaces/shop/configmaps"},
+		},
+		{
+			name:     "intersected with selected namespaces",
+			conf:     &Config{NamespaceLabelSelector: "environment=prod", SelectNamespaces: []string{"s*"}},
+			want:     []string{"ConfigMap shop", "ClusterRole "},
+			wantList: []string{"/api/v1/namespaces/shop/configmaps"},
+		},
+		{
+			name:     "minus ignored namespaces",
+			conf:     &Config{NamespaceLabelSelector: "environment=prod", IgnoreNamespaces: []string{"billing"}},
+			want:     []string{"ConfigMap shop", "ClusterRole "},
+			wantList: []string{"/api/v1/namespaces/shop/configmaps"},
+		},
+		{
+			name: "no namespace matching",
+			conf: &Config{NamespaceLabelSelector: "environment=test"},
+			want: []string{"ClusterRole "},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Concurrency = 1
+			before := len(server.resourceRequests())
+			objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, tt.conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind()+" "+obj.GetNamespace())
+			}
+			assert.Equal(t, tt.want, got)
+			var listed []string
+			for _, path := range server.resourceRequests()[before:] {
+				if strings.HasSuffix(path, "/configmaps") {
+					listed = append(listed, path)
+				}
+			}
+			assert.Equal(t, tt.wantList, listed)
+			// the config of the caller is left as is
+			assert.Nil(t, tt.conf.labeledNamespaces)
+		})
+	}
+
+	_, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{NamespaceLabelSelector: "environment in prod"})
+	assert.ErrorContains(t, err, "invalid namespace label selector")
+}
+
+func TestCluster_FetchK8sObjects_namespaceLabelSelectorForbidden(t *testing.T) {
+	server, gvks := newLabeledNamespaceServer(t)
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/api/v1/namespaces" {
+			writeStatus(w, http.StatusForbidden, "Forbidden", `namespaces is forbidden: User "ci" cannot list resource "namespaces"`)
+			return true
+		}
+		return false
+	}
+	cluster := server.cluster(t)
+
+	// the name based filters alone apply
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{NamespaceLabelSelector: "environment=prod", IgnoreNamespaces: []string{"staging"}})
+	assert.NoError(t, err)
+	var got []string
+	for _, obj := range objs {
+		got = append(got, obj.GetKind()+" "+obj.GetNamespace())
+	}
+	assert.Equal(t, []string{"ConfigMap billing", "ConfigMap shop", "ClusterRole "}, got)
+}
@@ -27,12 +27,15 @@ import (
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/mgutz/ansi"
 	"sort"
+	"strconv"
 	"strings"
 
 	//"github.com/olekukonko/tablewriter"
 	"github.com/tomlazar/table"
 	"log"
 	"os"
+	"sigs.k8s.io/yaml"
+	"time"
 )
 
 // OutputManager controls how results of the `kubedd` evaluation will be recorded
@@ -46,10 +49,22 @@ type OutputManager interface {
 	GetSummaryValidationResultBulk() []SummaryValidationResult
 }
 
+// ScanOutputManager is implemented by the output managers reporting a single document for the whole run, scans are
+// put along with their metadata and fetch summary instead of with PutBulk and nothing else may be written to stdout
+type ScanOutputManager interface {
+	OutputManager
+	// PutScan adds the results of scan with the severities of conf, see Report.AddScan
+	PutScan(scan ScanMetadata, results []ValidationResult, summary *FetchSummary, conf *Config) error
+}
+
 const (
-	outputSTD  = "stdout"
-	outputJSON = "json"
-	outputTAP  = "tap"
+	outputSTD       = "stdout"
+	outputJSON      = "json"
+	outputYAML      = "yaml"
+	outputTAP       = "tap"
+	outputBackstage = "backstage"
+	outputRunbook   = "runbook"
+	outputJUnit     = "junit"
 )
 
 var (
@@ -61,7 +76,11 @@ func validOutputs() []string {
 	return []string{
 		outputSTD,
 		outputJSON,
+		outputYAML,
 		outputTAP,
+		outputBackstage,
+		outputRunbook,
+		outputJUnit,
 	}
 }
 
@@ -71,8 +90,16 @@ func GetOutputManager(outFmt string, noColor bool) OutputManager {
 		return newSTDOutputManager(noColor)
 	case outputJSON:
 		return newDefaultJSONOutputManager()
+	case outputYAML:
+		return newDefaultYAMLOutputManager()
 	case outputTAP:
 		return newDefaultTAPOutputManager()
+	case outputBackstage:
+		return newDefaultBackstageOutputManager()
+	case outputRunbook:
+		return newDefaultRunbookOutputManager()
+	case outputJUnit:
+		return newDefaultJUnitOutputManager()
 	default:
 		return newSTDOutputManager(noColor)
 	}
@@ -109,14 +136,11 @@ func (s *STDOutputManager) PutBulk(results []ValidationResult) error {
 		} else if len(result.LatestAPIVersion) > 0 {
 			newerVersion = append(newerVersion, result)
 		} else {
-			if len(result.ErrorsForOriginal) == 0 && len(result.ErrorsForLatest) == 0 &&
-				len(result.DeprecationForOriginal) == 0 && len(result.DeprecationForLatest) == 0 {
-				unchanged = append(unchanged, result)
-			}
+			unchanged = append(unchanged, result)
 		}
 	}
 	if len(deleted) > 0 {
-		sort.Slice(deleted, func(i, j int) bool {
+		sort.SliceStable(deleted, func(i, j int) bool {
 			return len(deleted[i].ErrorsForLatest) > len(deleted[j].ErrorsForLatest)
 		})
 		color.NoColor = false
@@ -129,9 +153,11 @@ func (s *STDOutputManager) PutBulk(results []ValidationResult) error {
 		fmt.Println("")
 		s.ValidationErrorTableBodyOutput(deleted, false)
 		s.DeprecationTableBodyOutput(deleted, false)
+		s.UnknownFieldTableBodyOutput(deleted)
+		s.FieldMigrationTableBodyOutput(deleted)
 	}
 	if len(deprecated) > 0 {
-		sort.Slice(deprecated, func(i, j int) bool {
+		sort.SliceStable(deprecated, func(i, j int) bool {
 			return len(deprecated[i].ErrorsForLatest) > len(deprecated[j].ErrorsForLatest)
 		})
 		yellow := color.New(color.FgHiYellow, color.Underline).SprintFunc()
@@ -140,11 +166,13 @@ func (s *STDOutputManager) PutBulk(results []ValidationResult) error {
 		fmt.Println("")
 		s.DeprecationTableBodyOutput(deprecated, true)
 		s.ValidationErrorTableBodyOutput(deprecated, true)
+		s.UnknownFieldTableBodyOutput(deprecated)
 		s.DeprecationTableBodyOutput(deprecated, false)
 		s.ValidationErrorTableBodyOutput(deprecated, false)
+		s.FieldMigrationTableBodyOutput(deprecated)
 	}
 	if len(newerVersion) > 0 {
-		sort.Slice(newerVersion, func(i, j int) bool {
+		sort.SliceStable(newerVersion, func(i, j int) bool {
 			return len(newerVersion[i].ErrorsForLatest) > len(newerVersion[j].ErrorsForLatest)
 		})
 		yellow := color.New(color.FgHiYellow, color.Underline).SprintFunc()
@@ -153,8 +181,10 @@ func (s *STDOutputManager) PutBulk(results []ValidationResult) error {
 		fmt.Println("")
 		s.DeprecationTableBodyOutput(newerVersion, true)
 		s.ValidationErrorTableBodyOutput(newerVersion, true)
+		s.UnknownFieldTableBodyOutput(newerVersion)
 		s.DeprecationTableBodyOutput(newerVersion, false)
 		s.ValidationErrorTableBodyOutput(newerVersion, false)
+		s.FieldMigrationTableBodyOutput(newerVersion)
 	}
 	if len(unchanged) > 0 {
 
@@ -163,8 +193,17 @@ func (s *STDOutputManager) PutBulk(results []ValidationResult) error {
 		fmt.Println("")
 		s.DeprecationTableBodyOutput(unchanged, true)
 		s.ValidationErrorTableBodyOutput(unchanged, true)
+		s.UnknownFieldTableBodyOutput(unchanged)
 	}
 
+	s.DryRunTableOutput(results)
+	s.PodFieldDeprecationTableOutput(results)
+	s.CustomRuleTableOutput(results)
+	s.RemediationOutput(results)
+	s.ServerWarningTableOutput(results)
+	s.SuppressedFindingTableOutput(results)
+	s.SeverityCountsOutput(results)
+
 	if len(deleted)+len(deprecated)+len(newerVersion)+len(unchanged) == 0 {
 		fmt.Printf("%s\n", green("Great!!! Everything will work as it is in new version without any changes"))
 	}
@@ -172,7 +211,14 @@ func (s *STDOutputManager) PutBulk(results []ValidationResult) error {
 }
 
 func (s *STDOutputManager) SummaryTableBodyOutput(results []ValidationResult) {
-	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "API Version (Current Available)", "Replace With API Version (Latest Available)", "Migration Status"}}
+	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "API Version (Current Available)", "Replace With API Version (Latest Available)", "Severity", "Migration Status"}}
+	withSource := false
+	for _, result := range results {
+		withSource = withSource || len(result.ValidatedFrom) > 0
+	}
+	if withSource {
+		t.Headers = append(t.Headers, "Validated From")
+	}
 	c := table.DefaultConfig()
 	c.TitleColorCode = ansi.ColorCode("cyan+bu")
 	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:238")}
@@ -185,12 +231,54 @@ func (s *STDOutputManager) SummaryTableBodyOutput(results []ValidationResult) {
 		if result.IsVersionSupported == 2 {
 			migrationStatus = fmt.Sprintf("%s%s", "\033[31m", fmt.Sprintf("Alert! cannot migrate kubernetes version"))
 		}
-		t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, result.APIVersion, result.LatestAPIVersion, migrationStatus})
+		if result.AlreadyRemoved {
+			migrationStatus = fmt.Sprintf("%s%s%s", "\033[31m", "Alert! already removed in the source version, ", migrationStatus)
+		}
+		if len(result.FixedManifest) > 0 {
+			migrationStatus = "migrated automatically, see " + result.FixedManifest
+		}
+		row := []string{result.ResourceNamespace, result.ResourceName, result.Kind, result.APIVersion, result.LatestAPIVersion, s.severityCell(result.Severity), migrationStatus}
+		if withSource {
+			row = append(row, validatedFromCell(result))
+		}
+		t.Rows = append(t.Rows, row)
 	}
 	c.Color = !s.noColor
 	t.WriteTable(os.Stdout, c)
 }
 
+// validatedFromCell returns the source the result was validated from along with why it fell back to the live object
+func validatedFromCell(result ValidationResult) string {
+	if len(result.ValidatedFromNote) == 0 {
+		return result.ValidatedFrom
+	}
+	return result.ValidatedFrom + " (" + result.ValidatedFromNote + ")"
+}
+
+// severityColors are the colors the severities are printed in
+var severityColors = map[string]string{SeverityError: "\033[31m", SeverityWarning: "\033[33m", SeverityInfo: "\033[36m"}
+
+// severityCell returns severity colored by how severe it is unless colors are disabled
+func (s *STDOutputManager) severityCell(severity string) string {
+	if s.noColor || len(severity) == 0 {
+		return severity
+	}
+	return severityColors[severity] + severity + "\033[97m"
+}
+
+// SeverityCountsOutput prints the number of results with findings by severity, see ResultSeverity
+func (s *STDOutputManager) SeverityCountsOutput(results []ValidationResult) {
+	counts := SeverityCounts(results)
+	if len(counts) == 0 {
+		return
+	}
+	var parts []string
+	for _, severity := range []string{SeverityError, SeverityWarning, SeverityInfo} {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[severity], s.severityCell(severity)))
+	}
+	fmt.Printf("Findings by severity: %s\n", strings.Join(parts, ", "))
+}
+
 func (s *STDOutputManager) DeprecationTableBodyOutput(results []ValidationResult, currentVersion bool) {
 	hasData := false
 	for _, result := range results {
@@ -240,6 +328,274 @@ func (s *STDOutputManager) DeprecationTableBodyOutput(results []ValidationResult
 	fmt.Println("")
 }
 
+// DryRunTableOutput prints the objects rejected by the verify cluster on a dry-run apply and those which could not be
+// verified, see VerifyDryRun
+func (s *STDOutputManager) DryRunTableOutput(results []ValidationResult) {
+	hasData := false
+	for _, result := range results {
+		if len(result.DryRunRejection)+len(result.DryRunNote) > 0 {
+			hasData = true
+			break
+		}
+	}
+	if !hasData {
+		return
+	}
+	fmt.Printf("%s\n", hiWhite(">>>> Verify Cluster Dry Run <<<<"))
+	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "API Version", "Result", "Message"}}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, result := range results {
+		apiVersion := result.APIVersion
+		if len(result.LatestAPIVersion) > 0 {
+			apiVersion = result.LatestAPIVersion
+		}
+		if len(result.DryRunRejection) > 0 {
+			t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, apiVersion, "rejected", result.DryRunRejection})
+		} else if len(result.DryRunNote) > 0 {
+			t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, apiVersion, "not verified", result.DryRunNote})
+		}
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
+// PodFieldDeprecationTableOutput prints the deprecated fields of pod specs and templates with their replacement, see
+// CheckPodFieldDeprecations
+func (s *STDOutputManager) PodFieldDeprecationTableOutput(results []ValidationResult) {
+	hasData := false
+	for _, result := range results {
+		if len(result.PodFieldDeprecations) > 0 {
+			hasData = true
+			break
+		}
+	}
+	if !hasData {
+		return
+	}
+	fmt.Printf("%s\n", hiWhite(">>>> Deprecated Pod Fields <<<<"))
+	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "Field", "Replacement", "Reason"}}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, result := range results {
+		for _, d := range result.PodFieldDeprecations {
+			t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, d.Path, d.Replacement, d.Reason})
+		}
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
+// CustomRuleTableOutput prints the findings of the house rules of the rules file, see CheckCustomRules
+func (s *STDOutputManager) CustomRuleTableOutput(results []ValidationResult) {
+	hasData := false
+	for _, result := range results {
+		if len(result.CustomRuleFindings) > 0 {
+			hasData = true
+			break
+		}
+	}
+	if !hasData {
+		return
+	}
+	fmt.Printf("%s\n", hiWhite(">>>> Custom Rules <<<<"))
+	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "Rule", "Severity", "Field", "Message"}}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, result := range results {
+		for _, f := range result.CustomRuleFindings {
+			t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, f.Rule, f.Severity, f.Path, f.Message})
+		}
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
+// RemediationOutput prints the next steps of the results of removed kinds with a known migration, see AnalyzeRemovals
+func (s *STDOutputManager) RemediationOutput(results []ValidationResult) {
+	hasData := false
+	for _, result := range results {
+		if result.Remediation != nil {
+			hasData = true
+			break
+		}
+	}
+	if !hasData {
+		return
+	}
+	fmt.Printf("%s\n", hiWhite(">>>> Remediation <<<<"))
+	for _, result := range results {
+		if result.Remediation == nil {
+			continue
+		}
+		name := result.ResourceName
+		if len(result.ResourceNamespace) > 0 {
+			name = result.ResourceNamespace + "/" + name
+		}
+		fmt.Printf("%s %s: %s\n", result.Kind, name, result.Remediation.Summary)
+		for _, step := range result.Remediation.Steps {
+			fmt.Printf("  - %s\n", step)
+		}
+		for _, ref := range result.Remediation.Related {
+			if len(ref.Namespace) > 0 {
+				fmt.Printf("  * %s %s/%s\n", ref.Kind, ref.Namespace, ref.Name)
+			} else {
+				fmt.Printf("  * %s %s\n", ref.Kind, ref.Name)
+			}
+		}
+	}
+	fmt.Println("")
+}
+
+// ServerWarningTableOutput prints the warnings the api server returned for the kinds of the results along with the
+// number of objects of each kind, once per kind and api version
+func (s *STDOutputManager) ServerWarningTableOutput(results []ValidationResult) {
+	type kindWarning struct{ kind, apiVersion, message string }
+	counts := map[kindWarning]int{}
+	var warnings []kindWarning
+	for _, result := range results {
+		for _, message := range result.ServerWarnings {
+			w := kindWarning{result.Kind, result.APIVersion, message}
+			if counts[w] == 0 {
+				warnings = append(warnings, w)
+			}
+			counts[w]++
+		}
+	}
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Printf("%s\n", hiWhite(">>>> API Server Warnings <<<<"))
+	t := table.Table{Headers: []string{"Kind", "API Version", "Objects", "Warning"}}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, w := range warnings {
+		t.Rows = append(t.Rows, []string{w.kind, w.apiVersion, strconv.Itoa(counts[w]), w.message})
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
+// SuppressedFindingTableOutput prints the findings accepted by the suppressions file along with their justification
+func (s *STDOutputManager) SuppressedFindingTableOutput(results []ValidationResult) {
+	hasData := false
+	for _, result := range results {
+		if len(result.SuppressedFindings) > 0 {
+			hasData = true
+			break
+		}
+	}
+	if !hasData {
+		return
+	}
+	fmt.Printf("%s\n", hiWhite(">>>> Suppressed Findings <<<<"))
+	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "API Version", "Finding", "Owner", "Expires", "Comment"}}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, result := range results {
+		for _, f := range result.SuppressedFindings {
+			t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, result.APIVersion, f.Finding, f.Owner, f.Expires, f.Comment})
+		}
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
+// UnknownFieldTableBodyOutput prints the fields of the objects their schema does not define, see Config.Strict
+func (s *STDOutputManager) UnknownFieldTableBodyOutput(results []ValidationResult) {
+	hasData := false
+	for _, result := range results {
+		if len(result.UnknownFields) > 0 {
+			hasData = true
+			break
+		}
+	}
+	if !hasData {
+		return
+	}
+	fmt.Println(hiWhite(">>> Unknown fields against current api version, should be resolved before migration <<<"))
+	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "API Version (Current Available)", "Field"}}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, result := range results {
+		for _, path := range result.UnknownFields {
+			t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, result.APIVersion, path})
+		}
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
+// FieldMigrationTableBodyOutput prints the fields to change to move the objects to their latest api version
+func (s *STDOutputManager) FieldMigrationTableBodyOutput(results []ValidationResult) {
+	hasData := false
+	for _, result := range results {
+		if len(result.FieldMigrations) > 0 {
+			hasData = true
+			break
+		}
+	}
+	if !hasData {
+		return
+	}
+	fmt.Println(hiWhite("Fields to change for the latest api version"))
+	t := table.Table{Headers: []string{"Namespace", "Name", "Kind", "API Version (Latest Available)", "Field", "Replace With", "Reason"}}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, result := range results {
+		for _, m := range result.FieldMigrations {
+			t.Rows = append(t.Rows, []string{result.ResourceNamespace, result.ResourceName, result.Kind, result.LatestAPIVersion, m.Path, m.Replacement, m.Reason})
+		}
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
+// UpgradePathTableOutput prints the status against each target version of the objects with findings against any of
+// them, see UpgradePath
+func (s *STDOutputManager) UpgradePathTableOutput(targets []TargetResults) {
+	rows := UpgradePath(targets)
+	if len(rows) == 0 {
+		return
+	}
+	headers := []string{"Namespace", "Name", "Kind", "API Version"}
+	for _, target := range targets {
+		headers = append(headers, target.TargetVersion)
+	}
+	t := table.Table{Headers: headers}
+	c := table.DefaultConfig()
+	c.TitleColorCode = ansi.ColorCode("cyan+bu")
+	c.AltColorCodes = []string{ansi.LightWhite, ansi.ColorCode("white+h:237")}
+	c.ShowIndex = false
+	for _, row := range rows {
+		t.Rows = append(t.Rows, append([]string{row.Namespace, row.Name, row.Kind, row.APIVersion}, row.Status...))
+	}
+	c.Color = !s.noColor
+	t.WriteTable(os.Stdout, c)
+	fmt.Println("")
+}
+
 func (s *STDOutputManager) ValidationErrorTableBodyOutput(results []ValidationResult, currentVersion bool) {
 	hasData := false
 	for _, result := range results {
@@ -315,16 +671,21 @@ const (
 
 type dataEvalResult struct {
 	Filename string   `json:"filename"`
+	Document int      `json:"document,omitempty"`
+	Location string   `json:"-"`
 	Kind     string   `json:"kind"`
 	Status   status   `json:"status"`
 	Errors   []string `json:"errors"`
+	Severity string   `json:"severity,omitempty"`
 }
 
-// jsonOutputManager reports `ccheck` results to `stdout` as a json array..
+// jsonOutputManager reports `kubedd` results to `stdout` as a single json Report
 type jsonOutputManager struct {
 	logger *log.Logger
 
-	data []SummaryValidationResult
+	// results are kept for GetSummaryValidationResultBulk, the report holds their findings
+	results []ValidationResult
+	report  *Report
 }
 
 func newDefaultJSONOutputManager() *jsonOutputManager {
@@ -334,6 +695,7 @@ func newDefaultJSONOutputManager() *jsonOutputManager {
 func newJSONOutputManager(l *log.Logger) *jsonOutputManager {
 	return &jsonOutputManager{
 		logger: l,
+		report: NewReport(time.Now()),
 	}
 }
 
@@ -354,82 +716,49 @@ func getStatus(r ValidationResult) status {
 }
 
 func (j *jsonOutputManager) PutBulk(vrs []ValidationResult) error {
-	svrs := make([]SummaryValidationResult, 0, len(vrs))
-	for _, vr := range vrs {
-		if vr.Deleted == false && vr.Deprecated == false && len(vr.ErrorsForLatest) == 0 && len(vr.ErrorsForOriginal) == 0 && len(vr.DeprecationForLatest) == 0 && len(vr.DeprecationForOriginal) == 0 {
-			continue
-		}
-		svr := SummaryValidationResult{
-			Deleted:            vr.Deleted,
-			Deprecated:         vr.Deprecated,
-			Kind:               vr.Kind,
-			ResourceName:       vr.ResourceName,
-			APIVersion:         vr.APIVersion,
-			FileName:           vr.FileName,
-			IsVersionSupported: vr.IsVersionSupported,
-			LatestAPIVersion:   vr.LatestAPIVersion,
-			ResourceNamespace:  vr.ResourceNamespace,
-		}
-		for _, se := range vr.ErrorsForOriginal {
-			sse := &SummarySchemaError{
-				Path:        strings.Join(se.JSONPointer(), "/"),
-				SchemaField: se.SchemaField,
-				Reason:      se.Reason,
-				Origin:      se.Origin,
-			}
-			svr.ErrorsForOriginal = append(svr.ErrorsForOriginal, sse)
-		}
-		for _, se := range vr.ErrorsForLatest {
-			sse := &SummarySchemaError{
-				Path:        strings.Join(se.JSONPointer(), "/"),
-				SchemaField: se.SchemaField,
-				Reason:      se.Reason,
-				Origin:      se.Origin,
-			}
-			svr.ErrorsForLatest = append(svr.ErrorsForLatest, sse)
-		}
-		for _, se := range vr.DeprecationForOriginal {
-			sse := &SummarySchemaError{
-				Path:        strings.Join(se.JSONPointer(), "/"),
-				SchemaField: se.SchemaField,
-				Reason:      se.Reason,
-				Origin:      se.Origin,
-			}
-			svr.DeprecationForOriginal = append(svr.DeprecationForOriginal, sse)
-		}
-		for _, se := range vr.DeprecationForLatest {
-			sse := &SummarySchemaError{
-				Path:        strings.Join(se.JSONPointer(), "/"),
-				SchemaField: se.SchemaField,
-				Reason:      se.Reason,
-				Origin:      se.Origin,
-			}
-			svr.DeprecationForLatest = append(svr.DeprecationForLatest, sse)
-		}
-		svrs = append(svrs, svr)
-	}
-	j.data = svrs
+	j.results = append(j.results, vrs...)
+	j.report.addResults(ScanMetadata{}, vrs, nil)
 	return nil
 }
 
-func (j *jsonOutputManager) Put(vr ValidationResult) error {
-	// stringify gojsonschema errors
-	// use a pre-allocated slice to ensure the json will have an
-	// empty array in the "zero" case
-	//errs := make([]string, 0, len(r.Errors))
-	//for _, e := range r.Errors {
-	//	errs = append(errs, e.String())
-	//}
+func (j *jsonOutputManager) PutScan(scan ScanMetadata, results []ValidationResult, summary *FetchSummary, conf *Config) error {
+	j.results = append(j.results, results...)
+	j.report.AddScan(scan, results, summary, conf)
+	return nil
+}
+
+// HasFindings returns true if the result has anything to report against the target version
+func HasFindings(vr ValidationResult) bool {
+	return vr.Deleted || vr.Deprecated || len(vr.ErrorsForLatest) > 0 || len(vr.ErrorsForOriginal) > 0 || len(vr.DeprecationForLatest) > 0 || len(vr.DeprecationForOriginal) > 0 || len(vr.UnknownFields) > 0 || len(vr.DryRunRejection) > 0 || len(vr.PodFieldDeprecations) > 0 || len(vr.CustomRuleFindings) > 0
+}
 
+func newSummaryValidationResult(vr ValidationResult) SummaryValidationResult {
 	svr := SummaryValidationResult{
-		Deleted:            vr.Deleted,
-		Deprecated:         vr.Deprecated,
-		Kind:               vr.Kind,
-		ResourceName:       vr.ResourceName,
-		APIVersion:         vr.APIVersion,
-		FileName:           vr.FileName,
-		IsVersionSupported: vr.IsVersionSupported,
-		LatestAPIVersion:   vr.LatestAPIVersion,
+		Deleted:              vr.Deleted,
+		Deprecated:           vr.Deprecated,
+		Kind:                 vr.Kind,
+		ResourceName:         vr.ResourceName,
+		APIVersion:           vr.APIVersion,
+		FileName:             vr.FileName,
+		DocumentIndex:        vr.DocumentIndex,
+		Cluster:              vr.Cluster,
+		IsVersionSupported:   vr.IsVersionSupported,
+		LatestAPIVersion:     vr.LatestAPIVersion,
+		ResourceNamespace:    vr.ResourceNamespace,
+		RootOwner:            vr.RootOwner,
+		ValidatedFrom:        vr.ValidatedFrom,
+		ValidatedFromNote:    vr.ValidatedFromNote,
+		ServerWarnings:       vr.ServerWarnings,
+		DryRunRejection:      vr.DryRunRejection,
+		DryRunNote:           vr.DryRunNote,
+		PodFieldDeprecations: vr.PodFieldDeprecations,
+		CustomRuleFindings:   vr.CustomRuleFindings,
+		Remediation:          vr.Remediation,
+		FieldMigrations:      vr.FieldMigrations,
+		FixedManifest:        vr.FixedManifest,
+		UnknownFields:        vr.UnknownFields,
+		SuppressedFindings:   vr.SuppressedFindings,
+		Severity:             vr.Severity,
 	}
 	for _, se := range vr.ErrorsForOriginal {
 		sse := &SummarySchemaError{
@@ -467,14 +796,15 @@ func (j *jsonOutputManager) Put(vr ValidationResult) error {
 		}
 		svr.DeprecationForLatest = append(svr.DeprecationForLatest, sse)
 	}
+	return svr
+}
 
-	j.data = append(j.data, svr)
-
-	return nil
+func (j *jsonOutputManager) Put(vr ValidationResult) error {
+	return j.PutBulk([]ValidationResult{vr})
 }
 
 func (j *jsonOutputManager) Flush() error {
-	b, err := json.Marshal(j.data)
+	b, err := json.Marshal(j.report)
 	if err != nil {
 		return err
 	}
@@ -490,7 +820,36 @@ func (j *jsonOutputManager) Flush() error {
 }
 
 func (j *jsonOutputManager) GetSummaryValidationResultBulk() []SummaryValidationResult {
-	return j.data
+	var svrs []SummaryValidationResult
+	for _, vr := range j.results {
+		if HasFindings(vr) {
+			svrs = append(svrs, newSummaryValidationResult(vr))
+		}
+	}
+	return svrs
+}
+
+// yamlOutputManager reports `kubedd` results to `stdout` as a single yaml Report, the document of the json output
+// with the same field names and its keys sorted
+type yamlOutputManager struct {
+	*jsonOutputManager
+}
+
+func newDefaultYAMLOutputManager() *yamlOutputManager {
+	return newYAMLOutputManager(log.New(os.Stdout, "", 0))
+}
+
+func newYAMLOutputManager(l *log.Logger) *yamlOutputManager {
+	return &yamlOutputManager{newJSONOutputManager(l)}
+}
+
+func (y *yamlOutputManager) Flush() error {
+	out, err := yaml.Marshal(y.report)
+	if err != nil {
+		return err
+	}
+	y.logger.Print(string(out))
+	return nil
 }
 
 // tapOutputManager reports `conftest` results to stdout.
@@ -526,9 +885,12 @@ func (j *tapOutputManager) Put(r ValidationResult) error {
 
 	j.data = append(j.data, dataEvalResult{
 		Filename: r.FileName,
+		Document: r.DocumentIndex,
+		Location: r.Location(),
 		Kind:     r.Kind,
 		Status:   getStatus(r),
 		Errors:   errs,
+		Severity: r.Severity,
 	})
 
 	return nil
@@ -550,18 +912,18 @@ func (j *tapOutputManager) Flush() error {
 		for _, r := range j.data {
 			count = count + 1
 			var kindMarker string
-			if r.Kind == "" {
-				kindMarker = ""
-			} else {
+			if len(r.Kind) > 0 && len(r.Severity) > 0 {
+				kindMarker = fmt.Sprintf(" (%s, %s)", r.Kind, r.Severity)
+			} else if len(r.Kind) > 0 {
 				kindMarker = fmt.Sprintf(" (%s)", r.Kind)
 			}
 			if r.Status == "valid" {
-				j.logger.Print("ok ", count, " - ", r.Filename, kindMarker)
+				j.logger.Print("ok ", count, " - ", r.Location, kindMarker)
 			} else if r.Status == "skipped" {
-				j.logger.Print("ok ", count, " - ", r.Filename, kindMarker, " # SKIP")
+				j.logger.Print("ok ", count, " - ", r.Location, kindMarker, " # SKIP")
 			} else if r.Status == "invalid" {
 				for i, e := range r.Errors {
-					j.logger.Print("not ok ", count, " - ", r.Filename, kindMarker, " - ", e)
+					j.logger.Print("not ok ", count, " - ", r.Location, kindMarker, " - ", e)
 
 					// We have to skip adding 1 if it's the last error
 					if len(r.Errors) != i+1 {
@@ -577,3 +939,74 @@ func (j *tapOutputManager) Flush() error {
 func (j *tapOutputManager) GetSummaryValidationResultBulk() []SummaryValidationResult {
 	return nil
 }
+
+const (
+	labelPartOf         = "app.kubernetes.io/part-of"
+	labelName           = "app.kubernetes.io/name"
+	unassignedComponent = "unassigned"
+)
+
+// backstageOutputManager reports `kubedd` results to stdout as a json object keyed by the
+// component owning the resources, for consumption by service catalogs like Backstage.
+type backstageOutputManager struct {
+	logger *log.Logger
+
+	data map[string][]SummaryValidationResult
+}
+
+func newDefaultBackstageOutputManager() *backstageOutputManager {
+	return newBackstageOutputManager(log.New(os.Stdout, "", 0))
+}
+
+func newBackstageOutputManager(l *log.Logger) *backstageOutputManager {
+	return &backstageOutputManager{
+		logger: l,
+		data:   map[string][]SummaryValidationResult{},
+	}
+}
+
+// componentOf derives the catalog component of a resource from its recommended labels
+func componentOf(vr ValidationResult) string {
+	if component, ok := vr.ResourceLabels[labelPartOf]; ok && len(component) > 0 {
+		return component
+	}
+	if component, ok := vr.ResourceLabels[labelName]; ok && len(component) > 0 {
+		return component
+	}
+	return unassignedComponent
+}
+
+func (b *backstageOutputManager) PutBulk(vrs []ValidationResult) error {
+	for _, vr := range vrs {
+		if err := b.Put(vr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backstageOutputManager) Put(vr ValidationResult) error {
+	if len(vr.Kind) == 0 || !HasFindings(vr) {
+		return nil
+	}
+	component := componentOf(vr)
+	b.data[component] = append(b.data[component], newSummaryValidationResult(vr))
+	return nil
+}
+
+func (b *backstageOutputManager) Flush() error {
+	out, err := json.MarshalIndent(b.data, "", "\t")
+	if err != nil {
+		return err
+	}
+	b.logger.Print(string(out))
+	return nil
+}
+
+func (b *backstageOutputManager) GetSummaryValidationResultBulk() []SummaryValidationResult {
+	var svrs []SummaryValidationResult
+	for _, results := range b.data {
+		svrs = append(svrs, results...)
+	}
+	return svrs
+}
@@ -2,8 +2,10 @@ package pkg
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
 	"testing"
+	"time"
 
 	"github.com/xeipuuv/gojsonschema"
 
@@ -28,6 +30,8 @@ func newResultErrors(msgs []string) []gojsonschema.ResultError {
 }
 
 func Test_jsonOutputManager_put(t *testing.T) {
+	defer func(version string) { BuildVersion = version }(BuildVersion)
+	BuildVersion = "v1.2.3"
 	type args struct {
 		vr ValidationResult
 	}
@@ -43,60 +47,109 @@ func Test_jsonOutputManager_put(t *testing.T) {
 			args: args{
 				vr: ValidationResult{},
 			},
-			exp: `[
-	{
-		"filename": "",
-		"kind": "",
-		"status": "skipped",
-		"errors": []
+			exp: `{
+	"schemaVersion": "v1",
+	"metadata": {
+		"toolVersion": "v1.2.3",
+		"timestamp": "2026-10-16T09:30:00Z",
+		"scans": []
+	},
+	"findings": [],
+	"fetchSummaries": [],
+	"suppressed": [],
+	"counts": {
+		"objects": 0,
+		"objectsWithFindings": 0,
+		"findings": 0,
+		"bySeverity": {},
+		"byRule": {}
 	}
-]
+}
 `,
 		},
 		{
-			msg: "file with no errors",
+			msg: "file with no findings",
 			args: args{
 				vr: ValidationResult{
 					FileName:               "deployment.yaml",
-					Kind:                   "deployment",
+					Kind:                   "Deployment",
+					APIVersion:             "apps/v1",
 					ValidatedAgainstSchema: true,
-					Errors:                 nil,
 				},
 			},
-			exp: `[
-	{
-		"filename": "deployment.yaml",
-		"kind": "deployment",
-		"status": "valid",
-		"errors": []
+			exp: `{
+	"schemaVersion": "v1",
+	"metadata": {
+		"toolVersion": "v1.2.3",
+		"timestamp": "2026-10-16T09:30:00Z",
+		"scans": []
+	},
+	"findings": [],
+	"fetchSummaries": [],
+	"suppressed": [],
+	"counts": {
+		"objects": 1,
+		"objectsWithFindings": 0,
+		"findings": 0,
+		"bySeverity": {},
+		"byRule": {}
 	}
-]
+}
 `,
 		},
 		{
-			msg: "file with errors",
+			msg: "file with findings",
 			args: args{
 				vr: ValidationResult{
-					FileName:               "service.yaml",
-					Kind:                   "service",
+					FileName:               "ingress.yaml",
+					DocumentIndex:          2,
+					Kind:                   "Ingress",
+					APIVersion:             "extensions/v1beta1",
+					ResourceNamespace:      "shop",
+					ResourceName:           "storefront",
 					ValidatedAgainstSchema: true,
-					Errors: newResultErrors([]string{
-						"i am a error",
-						"i am another error",
-					}),
+					Deleted:                true,
+					LatestAPIVersion:       "networking.k8s.io/v1",
 				},
 			},
-			exp: `[
-	{
-		"filename": "service.yaml",
-		"kind": "service",
-		"status": "invalid",
-		"errors": [
-			"error: i am a error",
-			"error: i am another error"
-		]
+			exp: `{
+	"schemaVersion": "v1",
+	"metadata": {
+		"toolVersion": "v1.2.3",
+		"timestamp": "2026-10-16T09:30:00Z",
+		"scans": []
+	},
+	"findings": [
+		{
+			"cluster": "",
+			"targetVersion": "",
+			"file": "ingress.yaml",
+			"document": 2,
+			"kind": "Ingress",
+			"group": "extensions",
+			"version": "v1beta1",
+			"namespace": "shop",
+			"name": "storefront",
+			"rule": "removed-api",
+			"severity": "error",
+			"message": "extensions/v1beta1 Ingress is removed, migrate to networking.k8s.io/v1",
+			"suggestedApiVersion": "networking.k8s.io/v1"
+		}
+	],
+	"fetchSummaries": [],
+	"suppressed": [],
+	"counts": {
+		"objects": 1,
+		"objectsWithFindings": 1,
+		"findings": 1,
+		"bySeverity": {
+			"error": 1
+		},
+		"byRule": {
+			"removed-api": 1
+		}
 	}
-]
+}
 `,
 		},
 	}
@@ -104,6 +157,7 @@ func Test_jsonOutputManager_put(t *testing.T) {
 		t.Run(tt.msg, func(t *testing.T) {
 			buf := new(bytes.Buffer)
 			s := newJSONOutputManager(log.New(buf, "", 0))
+			s.report = NewReport(time.Date(2026, 10, 16, 9, 30, 0, 0, time.UTC))
 
 			// record results
 			err := s.Put(tt.args.vr)
@@ -201,3 +255,50 @@ ok 1 - deployment.yaml (Deployment) # SKIP
 		})
 	}
 }
+
+func Test_backstageOutputManager_putBulk(t *testing.T) {
+	results := []ValidationResult{
+		{
+			Kind:              "Ingress",
+			APIVersion:        "extensions/v1beta1",
+			ResourceName:      "web",
+			ResourceNamespace: "shop",
+			ResourceLabels:    map[string]string{labelPartOf: "storefront", labelName: "web"},
+			Deleted:           true,
+		},
+		{
+			Kind:              "CronJob",
+			APIVersion:        "batch/v1beta1",
+			ResourceName:      "report",
+			ResourceNamespace: "shop",
+			ResourceLabels:    map[string]string{labelName: "reporting"},
+			Deprecated:        true,
+		},
+		{
+			Kind:              "PodDisruptionBudget",
+			APIVersion:        "policy/v1beta1",
+			ResourceName:      "orphan",
+			ResourceNamespace: "default",
+			Deprecated:        true,
+		},
+		{
+			Kind:              "Deployment",
+			APIVersion:        "apps/v1",
+			ResourceName:      "clean",
+			ResourceNamespace: "shop",
+			ResourceLabels:    map[string]string{labelPartOf: "storefront"},
+		},
+	}
+	buf := new(bytes.Buffer)
+	s := newBackstageOutputManager(log.New(buf, "", 0))
+	assert.NoError(t, s.PutBulk(results))
+	assert.NoError(t, s.Flush())
+
+	var got map[string][]SummaryValidationResult
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+	assert.Len(t, got, 3)
+	assert.Len(t, got["storefront"], 1)
+	assert.Equal(t, "web", got["storefront"][0].ResourceName)
+	assert.Equal(t, "report", got["reporting"][0].ResourceName)
+	assert.Equal(t, "orphan", got[unassignedComponent][0].ResourceName)
+}
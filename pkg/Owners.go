@@ -0,0 +1,50 @@
+package pkg
+
+import (
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// ownedByController returns true if obj has an owner reference marked as its controller, whether or not the kind of
+// the owner is known
+func ownedByController(obj v1.Object) bool {
+	return v1.GetControllerOfNoCopy(obj) != nil
+}
+
+// OwnerIndex resolves the top-level controller of objects fetched from a cluster, eg the Deployment of a Pod
+type OwnerIndex struct {
+	byUID map[types.UID]*unstructured.Unstructured
+}
+
+// NewOwnerIndex indexes objs by uid, controllers of objects need to be part of objs to be followed
+func NewOwnerIndex(objs []unstructured.Unstructured) *OwnerIndex {
+	index := &OwnerIndex{byUID: make(map[types.UID]*unstructured.Unstructured, len(objs))}
+	for i := range objs {
+		if uid := objs[i].GetUID(); len(uid) > 0 {
+			index.byUID[uid] = &objs[i]
+		}
+	}
+	return index
+}
+
+// RootOwner returns the top-level controller of obj as Kind/name, or an empty string if obj is not owned by a
+// controller. The chain of controllers ends at the first controller which is not indexed, eg a custom resource of a
+// kind which was not fetched
+func (i *OwnerIndex) RootOwner(obj *unstructured.Unstructured) string {
+	var root *v1.OwnerReference
+	visited := map[types.UID]bool{obj.GetUID(): true}
+	for ref := v1.GetControllerOfNoCopy(obj); ref != nil && !visited[ref.UID]; {
+		visited[ref.UID] = true
+		root = ref
+		owner, ok := i.byUID[ref.UID]
+		if !ok {
+			break
+		}
+		ref = v1.GetControllerOfNoCopy(owner)
+	}
+	if root == nil {
+		return ""
+	}
+	return root.Kind + "/" + root.Name
+}
@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// withOwner adds an owner reference to the object built by newFakeObject
+func withOwner(obj map[string]interface{}, kind, name string, controller bool) map[string]interface{} {
+	metadata := obj["metadata"].(map[string]interface{})
+	refs, _ := metadata["ownerReferences"].([]interface{})
+	metadata["ownerReferences"] = append(refs, map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       kind,
+		"name":       name,
+		"uid":        newFakeObject("v1", kind, metadata["namespace"].(string), name)["metadata"].(map[string]interface{})["uid"],
+		"controller": controller,
+	})
+	return obj
+}
+
+func TestOwnerIndex_RootOwner(t *testing.T) {
+	deployment := newFakeObject("apps/v1", "Deployment", "shop", "cart")
+	replicaSet := withOwner(newFakeObject("apps/v1", "ReplicaSet", "shop", "cart-5d8f"), "Deployment", "cart", true)
+	pod := withOwner(newFakeObject("v1", "Pod", "shop", "cart-5d8f-x2k"), "ReplicaSet", "cart-5d8f", true)
+	rolloutPod := withOwner(newFakeObject("v1", "Pod", "shop", "rollout-a1"), "Rollout", "rollout", true)
+	gcPod := withOwner(newFakeObject("v1", "Pod", "shop", "gc"), "ConfigMap", "gc", false)
+	cyclic := withOwner(newFakeObject("v1", "Widget", "shop", "ouroboros"), "Widget", "ouroboros", true)
+	objs := []unstructured.Unstructured{{Object: deployment}, {Object: replicaSet}, {Object: pod}, {Object: rolloutPod}, {Object: gcPod}, {Object: cyclic}}
+	index := NewOwnerIndex(objs)
+
+	tests := []struct {
+		name string
+		obj  map[string]interface{}
+		want string
+	}{
+		{name: "top-level object", obj: deployment, want: ""},
+		{name: "owned by a top-level controller", obj: replicaSet, want: "Deployment/cart"},
+		{name: "controllers are followed to the top", obj: pod, want: "Deployment/cart"},
+		{name: "controller which was not fetched", obj: rolloutPod, want: "Rollout/rollout"},
+		{name: "owner which is not a controller", obj: gcPod, want: ""},
+		{name: "object controlling itself", obj: cyclic, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, index.RootOwner(&unstructured.Unstructured{Object: tt.obj}))
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_ignoreOwnedObjects(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	podGvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	pods := server.addResource(podGvk, "pods", true)
+	server.addObject(pods, withOwner(newFakeObject("v1", "Pod", "shop", "cart-5d8f-x2k"), "ReplicaSet", "cart-5d8f", true))
+	server.addObject(pods, withOwner(newFakeObject("v1", "Pod", "shop", "rollout-a1"), "Rollout", "rollout", true))
+	server.addObject(pods, withOwner(newFakeObject("v1", "Pod", "shop", "gc"), "ConfigMap", "gc", false))
+	server.addObject(pods, newFakeObject("v1", "Pod", "shop", "debug"))
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{deploymentGvk, podGvk}
+
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{Concurrency: 1})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 5)
+
+	objs, _, err = cluster.FetchK8sObjects(context.Background(), gvks, &Config{Concurrency: 1, IgnoreOwnedObjects: true})
+	assert.NoError(t, err)
+	var names []string
+	for _, obj := range objs {
+		names = append(names, obj.GetName())
+	}
+	assert.Equal(t, []string{"cart", "gc", "debug"}, names)
+}
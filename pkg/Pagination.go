@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// DefaultPageSize is the number of objects listed per request when PageSize is unset
+	DefaultPageSize int64 = 500
+	// DefaultConcurrency is the number of resources listed at the same time when Concurrency is unset
+	DefaultConcurrency = 5
+)
+
+func (c *Config) concurrency() int {
+	if c.Concurrency > 0 {
+		return c.Concurrency
+	}
+	return DefaultConcurrency
+}
+
+// ValidateSelectors returns an error if LabelSelector, FieldSelector or NamespaceLabelSelector cannot be parsed
+func (c *Config) ValidateSelectors() error {
+	if _, err := labels.Parse(c.LabelSelector); err != nil {
+		return fmt.Errorf("invalid label selector %q: %w", c.LabelSelector, err)
+	}
+	if _, err := fields.ParseSelector(c.FieldSelector); err != nil {
+		return fmt.Errorf("invalid field selector %q: %w", c.FieldSelector, err)
+	}
+	if _, err := labels.Parse(c.NamespaceLabelSelector); err != nil {
+		return fmt.Errorf("invalid namespace label selector %q: %w", c.NamespaceLabelSelector, err)
+	}
+	return nil
+}
+
+func (c *Config) pageSize() int64 {
+	if c.PageSize > 0 {
+		return c.PageSize
+	}
+	return DefaultPageSize
+}
+
+// unselected returns the options of conf to list objects other than the scanned ones, eg namespaces, without the
+// selectors of the scan
+func (c *Config) unselected() *Config {
+	return &Config{PageSize: c.PageSize, TransientErrorRetries: c.TransientErrorRetries, UseAPIServerCache: c.UseAPIServerCache}
+}
+
+// listAll lists the objects of the resource in namespace, or across the cluster when it is empty, matching the
+// selectors of conf page by page following the continue token, at most limit objects are listed unless it is zero.
+// The listing is restarted once when the continue token expires, and without the field selector, which is then
+// evaluated client side, when the resource does not support it. With UseAPIServerCache the objects are listed at once
+// from the watch cache of the api server.
+func (c *Cluster) listAll(ctx context.Context, resource schema.GroupVersionResource, namespace string, limit int64, conf *Config) ([]unstructured.Unstructured, error) {
+	items, _, err := c.listLimited(ctx, resource, namespace, limit, conf)
+	return items, err
+}
+
+// listLimited is listAll which also returns the approximate number of objects left unlisted once limit is reached,
+// based on the remainingItemCount of the last page. It is zero when every object was listed and negative when the
+// number is unknown.
+func (c *Cluster) listLimited(ctx context.Context, resource schema.GroupVersionResource, namespace string, limit int64, conf *Config) ([]unstructured.Unstructured, int64, error) {
+	pageSize := conf.pageSize()
+	if limit > 0 && limit < pageSize {
+		pageSize = limit
+	}
+	opts := v1.ListOptions{Limit: pageSize, LabelSelector: conf.LabelSelector, FieldSelector: conf.FieldSelector}
+	if conf.UseAPIServerCache {
+		// lists served from the watch cache ignore limit on some api server versions, the objects are listed at once
+		opts.ResourceVersion, opts.Limit = "0", 0
+	}
+	restarted := false
+	var clientSideSelector fields.Selector
+	var items []unstructured.Unstructured
+	for {
+		page, err := listWithRetry(ctx, c.dynamicClient().Resource(resource).Namespace(namespace), opts, conf.listRetries())
+		if len(opts.FieldSelector) > 0 && fieldSelectorNotSupported(err) {
+			kLog.Warn(fmt.Sprintf("field selector %q is not supported by %s, filtering objects client side: %v", opts.FieldSelector, resource, err))
+			if clientSideSelector, err = fields.ParseSelector(opts.FieldSelector); err != nil {
+				return nil, 0, err
+			}
+			opts.FieldSelector, opts.Continue, items = "", "", nil
+			continue
+		}
+		if len(opts.Continue) > 0 && !restarted && (k8sErrors.IsResourceExpired(err) || k8sErrors.IsGone(err)) {
+			// the continue token outlived the compacted resource version, the objects listed so far may be stale
+			restarted = true
+			opts.Continue, items = "", nil
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, item := range page.Items {
+			if clientSideSelector == nil || matchesFieldSelector(clientSideSelector, item) {
+				if !conf.KeepManagedFields {
+					pruneMetadata(&item, conf.UseLastApplied)
+				}
+				items = append(items, item)
+			}
+		}
+		if limit > 0 && int64(len(items)) >= limit {
+			unlisted := int64(len(items)) - limit
+			if len(page.GetContinue()) > 0 {
+				if remaining := page.GetRemainingItemCount(); remaining != nil {
+					unlisted += *remaining
+				} else {
+					unlisted = -1
+				}
+			}
+			return items[:limit], unlisted, nil
+		}
+		if len(page.GetContinue()) == 0 {
+			return items, 0, nil
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
@@ -0,0 +1,297 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_pagination(t *testing.T) {
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	tests := []struct {
+		name         string
+		conf         *Config
+		expire       bool
+		wantObjs     int
+		wantRequests []string
+	}{
+		{
+			name:         "pages follow the continue token",
+			conf:         &Config{PageSize: 2},
+			wantObjs:     5,
+			wantRequests: []string{"limit=2", "continue=2&limit=2", "continue=4&limit=2"},
+		},
+		{
+			name:         "default page size",
+			conf:         &Config{},
+			wantObjs:     5,
+			wantRequests: []string{"limit=500"},
+		},
+		{
+			name:         "per kind limit stops paging",
+			conf:         &Config{PageSize: 2, PerKindOptions: map[string]KindFetchOptions{"ConfigMap": {Limit: 3}}},
+			wantObjs:     3,
+			wantRequests: []string{"limit=2", "continue=2&limit=2"},
+		},
+		{
+			name:         "expired continue token restarts the listing once",
+			conf:         &Config{PageSize: 2},
+			expire:       true,
+			wantObjs:     5,
+			wantRequests: []string{"limit=2", "continue=2&limit=2", "limit=2", "continue=2&limit=2", "continue=4&limit=2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeApiServer(t)
+			configMaps := server.addResource(configMapGvk, "configmaps", true)
+			for i := 0; i < 5; i++ {
+				server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", fmt.Sprintf("cart-%d", i)))
+			}
+			expired := false
+			server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if !tt.expire || expired || len(r.URL.Query().Get("continue")) == 0 {
+					return false
+				}
+				expired = true
+				writeStatus(w, http.StatusGone, "Expired", "The provided continue parameter is too old")
+				return true
+			}
+			cluster := server.cluster(t)
+
+			objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, tt.conf)
+			assert.NoError(t, err)
+			assert.Len(t, objs, tt.wantObjs)
+			var requests []string
+			for _, r := range server.recorded() {
+				if r.Path == "/api/v1/configmaps" {
+					requests = append(requests, r.Query.Encode())
+				}
+			}
+			assert.Equal(t, tt.wantRequests, requests)
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_labelSelector(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secretGvk := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	secrets := server.addResource(secretGvk, "secrets", true)
+	for _, obj := range []struct {
+		gvr            schema.GroupVersionResource
+		kind, ns, name string
+		team           string
+	}{
+		{gvr: configMaps, kind: "ConfigMap", ns: "shop", name: "cart", team: "payments"},
+		{gvr: configMaps, kind: "ConfigMap", ns: "shop", name: "search", team: "discovery"},
+		{gvr: configMaps, kind: "ConfigMap", ns: "kube-system", name: "coredns", team: "payments"},
+		{gvr: secrets, kind: "Secret", ns: "shop", name: "cart", team: "payments"},
+	} {
+		fakeObj := newFakeObject("v1", obj.kind, obj.ns, obj.name)
+		fakeObj["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"team": obj.team}
+		server.addObject(obj.gvr, fakeObj)
+	}
+	cluster := server.cluster(t)
+
+	conf := &Config{LabelSelector: "team=payments", IgnoreNamespaces: []string{"kube-system"}, SelectKinds: []string{"ConfigMap"}}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk, secretGvk}, conf)
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 1) {
+		assert.Equal(t, "cart", objs[0].GetName())
+	}
+	requests := server.recorded()
+	var selectors []string
+	for _, request := range requests {
+		if request.Path == "/api/v1/configmaps" {
+			selectors = append(selectors, request.Query.Get("labelSelector"))
+		}
+	}
+	assert.Equal(t, []string{"team=payments"}, selectors)
+
+	// an invalid selector is rejected before the api server is called
+	_, _, err = cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, &Config{LabelSelector: "team in (payments"})
+	assert.ErrorContains(t, err, "invalid label selector")
+	assert.Len(t, server.recorded(), len(requests))
+}
+
+func TestCluster_FetchK8sObjects_fieldSelector(t *testing.T) {
+	server := newFakeApiServer(t)
+	podGvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	pods := server.addResource(podGvk, "pods", true)
+	for name, phase := range map[string]string{"cart": "Running", "migrate": "Succeeded", "search": "Pending"} {
+		pod := newFakeObject("v1", "Pod", "shop", name)
+		pod["status"] = map[string]interface{}{"phase": phase}
+		server.addObject(pods, pod)
+	}
+	cluster := server.cluster(t)
+
+	tests := []struct {
+		name          string
+		fieldSelector string
+		want          []string
+		wantSelectors []string
+	}{
+		{
+			name:          "evaluated by the api server",
+			fieldSelector: "metadata.name=cart",
+			want:          []string{"cart"},
+			wantSelectors: []string{"metadata.name=cart"},
+		},
+		{
+			name:          "unsupported fields are evaluated client side",
+			fieldSelector: "status.phase!=Succeeded",
+			want:          []string{"cart", "search"},
+			wantSelectors: []string{"status.phase!=Succeeded", ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(server.recorded())
+			objs, summary, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{podGvk}, &Config{FieldSelector: tt.fieldSelector})
+			assert.NoError(t, err)
+			assert.False(t, summary.Partial())
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetName())
+			}
+			assert.ElementsMatch(t, tt.want, got)
+			var selectors []string
+			for _, request := range server.recorded()[before:] {
+				if request.Path == "/api/v1/pods" {
+					selectors = append(selectors, request.Query.Get("fieldSelector"))
+				}
+			}
+			assert.Equal(t, tt.wantSelectors, selectors)
+		})
+	}
+
+	_, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{podGvk}, &Config{FieldSelector: "status.phase"})
+	assert.ErrorContains(t, err, "invalid field selector")
+}
+
+func TestCluster_FetchK8sObjects_maxObjectsPerResource(t *testing.T) {
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secretGvk := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	server := newFakeApiServer(t)
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	for i := 0; i < 5; i++ {
+		server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", fmt.Sprintf("cart-%d", i)))
+	}
+	secrets := server.addResource(secretGvk, "secrets", true)
+	for i := 0; i < 2; i++ {
+		server.addObject(secrets, newFakeObject("v1", "Secret", "shop", fmt.Sprintf("token-%d", i)))
+	}
+	cluster := server.cluster(t)
+
+	tests := []struct {
+		name          string
+		conf          *Config
+		wantObjs      int
+		wantTruncated []TruncatedResource
+	}{
+		{
+			name:          "cap applies per resource",
+			conf:          &Config{PageSize: 2, MaxObjectsPerResource: 3},
+			wantObjs:      5,
+			wantTruncated: []TruncatedResource{{Resource: "/v1, Resource=configmaps", Fetched: 3, Total: 5}},
+		},
+		{
+			name:     "cap reached by the last object",
+			conf:     &Config{PageSize: 5, MaxObjectsPerResource: 5},
+			wantObjs: 7,
+		},
+		{
+			name:          "per kind limit takes precedence",
+			conf:          &Config{PageSize: 2, MaxObjectsPerResource: 3, PerKindOptions: map[string]KindFetchOptions{"Secret": {Limit: 1}}},
+			wantObjs:      4,
+			wantTruncated: []TruncatedResource{{Resource: "/v1, Resource=configmaps", Fetched: 3, Total: 5}, {Resource: "/v1, Resource=secrets", Fetched: 1, Total: 2}},
+		},
+		{
+			name:          "namespaces left unlisted are not counted",
+			conf:          &Config{PageSize: 2, MaxObjectsPerResource: 3, SelectNamespaces: []string{"shop", "web"}},
+			wantObjs:      5,
+			wantTruncated: []TruncatedResource{{Resource: "/v1, Resource=configmaps", Fetched: 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.conf.Concurrency = 1
+			objs, summary, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk, secretGvk}, tt.conf)
+			assert.NoError(t, err)
+			assert.Len(t, objs, tt.wantObjs)
+			assert.Equal(t, tt.wantTruncated, summary.Truncated)
+			assert.False(t, summary.Partial())
+			assert.Equal(t, len(tt.wantTruncated) > 0, summary.Reportable())
+		})
+	}
+}
+
+func TestFetchSummary_String_truncated(t *testing.T) {
+	summary := &FetchSummary{Listed: 2, Truncated: []TruncatedResource{
+		{Resource: "/v1, Resource=configmaps", Fetched: 3, Total: 5},
+		{Resource: "/v1, Resource=secrets", Fetched: 1},
+	}}
+	assert.Equal(t, "all resources fetched\n"+
+		"2 resources truncated, results are sampled:\n"+
+		"/v1, Resource=configmaps: fetched 3 of about 5 objects\n"+
+		"/v1, Resource=secrets: fetched 1 objects, more were not counted", summary.String())
+}
+
+func TestCluster_FetchK8sObjects_useAPIServerCache(t *testing.T) {
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	tests := []struct {
+		name          string
+		conf          *Config
+		wantObjs      int
+		wantRequests  []string
+		wantTruncated []TruncatedResource
+	}{
+		{
+			name:         "quorum reads by default",
+			conf:         &Config{PageSize: 2},
+			wantObjs:     5,
+			wantRequests: []string{"limit=2", "continue=2&limit=2", "continue=4&limit=2"},
+		},
+		{
+			name:         "watch cache without pagination",
+			conf:         &Config{PageSize: 2, UseAPIServerCache: true},
+			wantObjs:     5,
+			wantRequests: []string{"resourceVersion=0"},
+		},
+		{
+			name:          "object cap applied to the cached list",
+			conf:          &Config{PageSize: 2, UseAPIServerCache: true, MaxObjectsPerResource: 3},
+			wantObjs:      3,
+			wantRequests:  []string{"resourceVersion=0"},
+			wantTruncated: []TruncatedResource{{Resource: "/v1, Resource=configmaps", Fetched: 3, Total: 5}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeApiServer(t)
+			configMaps := server.addResource(configMapGvk, "configmaps", true)
+			for i := 0; i < 5; i++ {
+				server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", fmt.Sprintf("cart-%d", i)))
+			}
+			cluster := server.cluster(t)
+
+			objs, summary, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk}, tt.conf)
+			assert.NoError(t, err)
+			assert.Len(t, objs, tt.wantObjs)
+			assert.Equal(t, tt.wantTruncated, summary.Truncated)
+			var requests []string
+			for _, r := range server.recorded() {
+				if r.Path == "/api/v1/configmaps" {
+					requests = append(requests, r.Query.Encode())
+				}
+			}
+			assert.Equal(t, tt.wantRequests, requests)
+		})
+	}
+}
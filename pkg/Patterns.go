@@ -0,0 +1,62 @@
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// RegexPatternPrefix marks filter entries which are RE2 regular expressions rather than glob patterns
+const RegexPatternPrefix = "~"
+
+var compiledPatterns sync.Map
+
+// matchPattern matches s against a filter entry, which is either a case-insensitive glob pattern, eg pr-*, or a
+// regular expression when prefixed with RegexPatternPrefix, eg ~^pr-[0-9]+$. Invalid patterns never match
+func matchPattern(s, pattern string) bool {
+	if strings.HasPrefix(pattern, RegexPatternPrefix) {
+		re, err := compilePattern(strings.TrimPrefix(pattern, RegexPatternPrefix))
+		return err == nil && re.MatchString(s)
+	}
+	if !isPattern(pattern) {
+		return false
+	}
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(s))
+	return err == nil && matched
+}
+
+// isPattern returns true if the filter entry is a pattern rather than a literal name
+func isPattern(entry string) bool {
+	return strings.HasPrefix(entry, RegexPatternPrefix) || strings.ContainsAny(entry, "*?[")
+}
+
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(expr, re)
+	return re, nil
+}
+
+// validatePatterns returns an error naming the first entry of patterns which is not a valid glob pattern or
+// regular expression
+func validatePatterns(name string, patterns []string) error {
+	for _, pattern := range patterns {
+		var err error
+		if strings.HasPrefix(pattern, RegexPatternPrefix) {
+			_, err = compilePattern(strings.TrimPrefix(pattern, RegexPatternPrefix))
+		} else {
+			_, err = path.Match(pattern, "")
+		}
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q in %s: %w", pattern, name, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestContains_patterns(t *testing.T) {
+	tests := []struct {
+		key      string
+		patterns []string
+		want     bool
+	}{
+		{key: "kube-system", patterns: []string{"kube-system"}, want: true},
+		{key: "Deployment", patterns: []string{"deployment"}, want: true},
+		{key: "pr-1234-web", patterns: []string{"pr-*"}, want: true},
+		{key: "pr-1234-web", patterns: []string{"pr-*-api"}, want: false},
+		{key: "pr-1234-api", patterns: []string{"pr-*-api"}, want: true},
+		{key: "CronJob", patterns: []string{"cron?ob"}, want: true},
+		{key: "pr-1234", patterns: []string{"~^pr-[0-9]+$"}, want: true},
+		{key: "pr-abc", patterns: []string{"~^pr-[0-9]+$"}, want: false},
+		{key: "pr-1234", patterns: []string{"~^pr-[0-9+$"}, want: false},
+		{key: "shop", patterns: []string{"pr-*", "~^kube-"}, want: false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Contains(tt.key, tt.patterns), "%s %v", tt.key, tt.patterns)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		conf    *Config
+		wantErr string
+	}{
+		{
+			name: "valid patterns",
+			conf: &Config{IgnoreNamespaces: []string{"kube-system", "pr-*", "~^preview-[0-9]+$"}, SelectKinds: []string{"Cron*"}},
+		},
+		{
+			name:    "invalid glob",
+			conf:    &Config{IgnoreNamespaces: []string{"kube-system", "pr-[0-9"}},
+			wantErr: `invalid pattern "pr-[0-9" in IgnoreNamespaces`,
+		},
+		{
+			name:    "invalid regular expression",
+			conf:    &Config{SelectKinds: []string{"~Job("}},
+			wantErr: `invalid pattern "~Job(" in SelectKinds`,
+		},
+		{
+			name:    "invalid selector",
+			conf:    &Config{LabelSelector: "team in (payments"},
+			wantErr: "invalid label selector",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.conf.Validate()
+			if len(tt.wantErr) > 0 {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestConfig_namespaceSelected_patterns(t *testing.T) {
+	conf := &Config{SelectNamespaces: []string{"pr-*", "shop"}, IgnoreNamespaces: []string{"~^pr-[0-9]+-db$"}}
+	assert.True(t, conf.namespaceSelected("shop", true))
+	assert.True(t, conf.namespaceSelected("pr-1234-web", true))
+	// ignore patterns win over select patterns
+	assert.False(t, conf.namespaceSelected("pr-1234-db", true))
+	assert.False(t, conf.namespaceSelected("kube-system", true))
+}
+
+func TestCluster_FetchK8sObjects_patterns(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	cronJobGvk := schema.GroupVersionKind{Group: "batch", Version: "v1", Kind: "CronJob"}
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	cronJobs := server.addResource(cronJobGvk, "cronjobs", true)
+	for _, ns := range []string{"pr-1234", "pr-5678", "shop"} {
+		server.addObject(configMaps, newFakeObject("v1", "ConfigMap", ns, "settings"))
+		server.addObject(cronJobs, newFakeObject("batch/v1", "CronJob", ns, "cleanup"))
+	}
+	cluster := server.cluster(t)
+
+	conf := &Config{IncludeTerminalObjects: true, SelectNamespaces: []string{"pr-*"}, IgnoreNamespaces: []string{"~5678$"}, IgnoreKinds: []string{"Cron*"}}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk, cronJobGvk}, conf)
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 1) {
+		assert.Equal(t, "ConfigMap", objs[0].GetKind())
+		assert.Equal(t, "pr-1234", objs[0].GetNamespace())
+	}
+	// namespaces selected by patterns are listed across the cluster
+	assert.Equal(t, []string{"/api/v1/configmaps"}, server.resourceRequests())
+}
@@ -0,0 +1,166 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RuleDeprecatedPodField is the rule of the deprecated fields, annotations and node labels of pod specs and templates
+// which the api server still accepts, see CheckPodFieldDeprecations
+const RuleDeprecatedPodField = "deprecated-pod-field"
+
+const (
+	seccompPodAnnotation             = "seccomp.security.alpha.kubernetes.io/pod"
+	seccompContainerAnnotationPrefix = "container.seccomp.security.alpha.kubernetes.io/"
+	apparmorAnnotationPrefix         = "container.apparmor.security.beta.kubernetes.io/"
+)
+
+// deprecatedNodeLabels are the well known node labels which are superseded, along with their replacement
+var deprecatedNodeLabels = map[string]string{
+	"beta.kubernetes.io/os":                    "kubernetes.io/os",
+	"beta.kubernetes.io/arch":                  "kubernetes.io/arch",
+	"beta.kubernetes.io/instance-type":         "node.kubernetes.io/instance-type",
+	"failure-domain.beta.kubernetes.io/zone":   "topology.kubernetes.io/zone",
+	"failure-domain.beta.kubernetes.io/region": "topology.kubernetes.io/region",
+}
+
+// CheckPodFieldDeprecations adds the deprecated fields of the pod spec embedded in a workload, or of a pod, to
+// result.PodFieldDeprecations with the field replacing them. Paths point into the workload, eg
+// spec.template.spec.serviceAccount. Fields the api server mirrors from their replacement are left out
+func CheckPodFieldDeprecations(result ValidationResult, object map[string]interface{}) ValidationResult {
+	podSpec, podSpecPath, ok := podSpecOf(object)
+	if !ok {
+		return result
+	}
+	specPath := strings.Join(podSpecPath, ".")
+	var deprecations []FieldMigration
+	if account, _ := podSpec["serviceAccount"].(string); len(account) > 0 && podSpec["serviceAccountName"] != account {
+		deprecations = append(deprecations, FieldMigration{Path: specPath + ".serviceAccount", Replacement: specPath + ".serviceAccountName",
+			Reason: "serviceAccount is a deprecated alias of serviceAccountName"})
+	}
+	deprecations = append(deprecations, securityAnnotationDeprecations(object, podSpec, podSpecPath)...)
+	deprecations = append(deprecations, nodeLabelDeprecations(podSpec, specPath)...)
+	result.PodFieldDeprecations = append(result.PodFieldDeprecations, deprecations...)
+	return result
+}
+
+// securityAnnotationDeprecations returns the seccomp and AppArmor annotations of the pod metadata next to podSpec
+// which are replaced by the fields of the security contexts, unless those fields are set already
+func securityAnnotationDeprecations(object, podSpec map[string]interface{}, podSpecPath []string) []FieldMigration {
+	metadataPath := append(append([]string(nil), podSpecPath[:len(podSpecPath)-1]...), "metadata")
+	current := object
+	for _, key := range metadataPath {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = next
+	}
+	annotations, _ := current["annotations"].(map[string]interface{})
+	specPath := strings.Join(podSpecPath, ".")
+	annotationPath := strings.Join(metadataPath, ".") + ".annotations"
+	var deprecations []FieldMigration
+	for _, annotation := range sortedKeys(annotations) {
+		var replacement, field string
+		var securityContext map[string]interface{}
+		switch {
+		case annotation == seccompPodAnnotation:
+			field = "seccompProfile"
+			securityContext, _ = podSpec["securityContext"].(map[string]interface{})
+			replacement = specPath + ".securityContext.seccompProfile"
+		case strings.HasPrefix(annotation, seccompContainerAnnotationPrefix), strings.HasPrefix(annotation, apparmorAnnotationPrefix):
+			field = "seccompProfile"
+			if strings.HasPrefix(annotation, apparmorAnnotationPrefix) {
+				field = "appArmorProfile"
+			}
+			name := annotation[strings.Index(annotation, "/")+1:]
+			path, container := containerNamed(object, name)
+			if container == nil {
+				continue
+			}
+			securityContext, _ = container["securityContext"].(map[string]interface{})
+			replacement = path + ".securityContext." + field
+		default:
+			continue
+		}
+		if _, ok := securityContext[field]; ok {
+			continue
+		}
+		deprecations = append(deprecations, FieldMigration{Path: fmt.Sprintf("%s[%s]", annotationPath, annotation), Replacement: replacement,
+			Reason: fmt.Sprintf("the %s annotation is deprecated in favour of the %s field of the security context", annotation, field)})
+	}
+	return deprecations
+}
+
+// containerNamed returns the container of the workload named name along with its path
+func containerNamed(object map[string]interface{}, name string) (string, map[string]interface{}) {
+	var path string
+	var found map[string]interface{}
+	walkContainers(object, func(p []string, container map[string]interface{}) {
+		if found == nil && container["name"] == name {
+			path, found = strings.Join(p[:len(p)-1], ".")+"["+p[len(p)-1]+"]", container
+		}
+	})
+	return path, found
+}
+
+// nodeLabelDeprecations returns the deprecated node labels podSpec selects nodes or spreads pods on
+func nodeLabelDeprecations(podSpec map[string]interface{}, specPath string) []FieldMigration {
+	var deprecations []FieldMigration
+	check := func(path, label string) {
+		if replacement, ok := deprecatedNodeLabels[label]; ok {
+			deprecations = append(deprecations, FieldMigration{Path: path, Replacement: replacement,
+				Reason: fmt.Sprintf("node label %s is deprecated, use %s", label, replacement)})
+		}
+	}
+	nodeSelector, _ := podSpec["nodeSelector"].(map[string]interface{})
+	for _, label := range sortedKeys(nodeSelector) {
+		check(fmt.Sprintf("%s.nodeSelector[%s]", specPath, label), label)
+	}
+	affinity, _ := podSpec["affinity"].(map[string]interface{})
+	nodeAffinity, _ := affinity["nodeAffinity"].(map[string]interface{})
+	required, _ := nodeAffinity["requiredDuringSchedulingIgnoredDuringExecution"].(map[string]interface{})
+	for i, term := range objectList(required["nodeSelectorTerms"]) {
+		termPath := fmt.Sprintf("%s.affinity.nodeAffinity.requiredDuringSchedulingIgnoredDuringExecution.nodeSelectorTerms[%d]", specPath, i)
+		for j, expression := range objectList(term["matchExpressions"]) {
+			key, _ := expression["key"].(string)
+			check(fmt.Sprintf("%s.matchExpressions[%d].key", termPath, j), key)
+		}
+	}
+	for i, preferred := range objectList(nodeAffinity["preferredDuringSchedulingIgnoredDuringExecution"]) {
+		preference, _ := preferred["preference"].(map[string]interface{})
+		for j, expression := range objectList(preference["matchExpressions"]) {
+			key, _ := expression["key"].(string)
+			check(fmt.Sprintf("%s.affinity.nodeAffinity.preferredDuringSchedulingIgnoredDuringExecution[%d].preference.matchExpressions[%d].key", specPath, i, j), key)
+		}
+	}
+	for i, constraint := range objectList(podSpec["topologySpreadConstraints"]) {
+		key, _ := constraint["topologyKey"].(string)
+		check(fmt.Sprintf("%s.topologySpreadConstraints[%d].topologyKey", specPath, i), key)
+	}
+	for _, kind := range []string{"podAffinity", "podAntiAffinity"} {
+		terms, _ := affinity[kind].(map[string]interface{})
+		for i, term := range objectList(terms["requiredDuringSchedulingIgnoredDuringExecution"]) {
+			key, _ := term["topologyKey"].(string)
+			check(fmt.Sprintf("%s.affinity.%s.requiredDuringSchedulingIgnoredDuringExecution[%d].topologyKey", specPath, kind, i), key)
+		}
+		for i, weighted := range objectList(terms["preferredDuringSchedulingIgnoredDuringExecution"]) {
+			term, _ := weighted["podAffinityTerm"].(map[string]interface{})
+			key, _ := term["topologyKey"].(string)
+			check(fmt.Sprintf("%s.affinity.%s.preferredDuringSchedulingIgnoredDuringExecution[%d].podAffinityTerm.topologyKey", specPath, kind, i), key)
+		}
+	}
+	return deprecations
+}
+
+// objectList returns the objects of a list value, values of other types are skipped
+func objectList(value interface{}) []map[string]interface{} {
+	list, _ := value.([]interface{})
+	objects := make([]map[string]interface{}, 0, len(list))
+	for _, item := range list {
+		if object, ok := item.(map[string]interface{}); ok {
+			objects = append(objects, object)
+		}
+	}
+	return objects
+}
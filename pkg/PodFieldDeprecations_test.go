@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPodFieldDeprecations(t *testing.T) {
+	deployment := newFakeObject("apps/v1", "Deployment", "default", "web")
+	deployment["spec"] = map[string]interface{}{
+		"template": map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]interface{}{
+					"seccomp.security.alpha.kubernetes.io/pod":            "runtime/default",
+					"container.apparmor.security.beta.kubernetes.io/web":  "runtime/default",
+					"container.apparmor.security.beta.kubernetes.io/gone": "runtime/default",
+				},
+			},
+			"spec": map[string]interface{}{
+				"serviceAccount": "web",
+				"nodeSelector":   map[string]interface{}{"beta.kubernetes.io/os": "linux", "disktype": "ssd"},
+				"containers": []interface{}{
+					map[string]interface{}{"name": "web"},
+				},
+				"affinity": map[string]interface{}{
+					"podAntiAffinity": map[string]interface{}{
+						"preferredDuringSchedulingIgnoredDuringExecution": []interface{}{
+							map[string]interface{}{"podAffinityTerm": map[string]interface{}{"topologyKey": "failure-domain.beta.kubernetes.io/zone"}},
+						},
+					},
+				},
+			},
+		},
+	}
+	result := CheckPodFieldDeprecations(ValidationResult{}, deployment)
+	var paths, replacements []string
+	for _, d := range result.PodFieldDeprecations {
+		paths = append(paths, d.Path)
+		replacements = append(replacements, d.Replacement)
+	}
+	assert.Equal(t, []string{
+		"spec.template.spec.serviceAccount",
+		"spec.template.metadata.annotations[container.apparmor.security.beta.kubernetes.io/web]",
+		"spec.template.metadata.annotations[seccomp.security.alpha.kubernetes.io/pod]",
+		"spec.template.spec.nodeSelector[beta.kubernetes.io/os]",
+		"spec.template.spec.affinity.podAntiAffinity.preferredDuringSchedulingIgnoredDuringExecution[0].podAffinityTerm.topologyKey",
+	}, paths)
+	assert.Equal(t, []string{
+		"spec.template.spec.serviceAccountName",
+		"spec.template.spec.containers[0].securityContext.appArmorProfile",
+		"spec.template.spec.securityContext.seccompProfile",
+		"kubernetes.io/os",
+		"topology.kubernetes.io/zone",
+	}, replacements)
+}
+
+func TestCheckPodFieldDeprecations_replaced(t *testing.T) {
+	pod := newFakeObject("v1", "Pod", "default", "web")
+	pod["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{"seccomp.security.alpha.kubernetes.io/pod": "runtime/default"}
+	pod["spec"] = map[string]interface{}{
+		"serviceAccount":     "web",
+		"serviceAccountName": "web",
+		"securityContext":    map[string]interface{}{"seccompProfile": map[string]interface{}{"type": "RuntimeDefault"}},
+	}
+	result := CheckPodFieldDeprecations(ValidationResult{}, pod)
+	assert.Empty(t, result.PodFieldDeprecations)
+	assert.Empty(t, CheckPodFieldDeprecations(ValidationResult{}, newFakeObject("v1", "ConfigMap", "default", "web")).PodFieldDeprecations)
+}
@@ -0,0 +1,58 @@
+package pkg
+
+import "strconv"
+
+// podSpecPaths is the path of the pod spec within each kind of workload
+var podSpecPaths = map[string][]string{
+	"Pod":                   {"spec"},
+	"PodTemplate":           {"template", "spec"},
+	"ReplicationController": {"spec", "template", "spec"},
+	"ReplicaSet":            {"spec", "template", "spec"},
+	"Deployment":            {"spec", "template", "spec"},
+	"StatefulSet":           {"spec", "template", "spec"},
+	"DaemonSet":             {"spec", "template", "spec"},
+	"Job":                   {"spec", "template", "spec"},
+	"CronJob":               {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+var containerFields = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// podSpecOf returns the pod spec of a workload along with its path, objects of other kinds have no pod spec
+func podSpecOf(object map[string]interface{}) (map[string]interface{}, []string, bool) {
+	kind, _ := object["kind"].(string)
+	path, ok := podSpecPaths[kind]
+	if !ok {
+		return nil, nil, false
+	}
+	current := object
+	for _, key := range path {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			return nil, nil, false
+		}
+		current = next
+	}
+	return current, path, true
+}
+
+// walkContainers calls fn with every container of the workload's pod spec and the path of the container
+func walkContainers(object map[string]interface{}, fn func(path []string, container map[string]interface{})) {
+	podSpec, podSpecPath, ok := podSpecOf(object)
+	if !ok {
+		return
+	}
+	for _, field := range containerFields {
+		containers, ok := podSpec[field].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, c := range containers {
+			container, ok := c.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			path := append(append([]string(nil), podSpecPath...), field, strconv.Itoa(i))
+			fn(path, container)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package pkg
+
+import (
+	"fmt"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// preferredVersion returns the version in which gvk is to be listed, an override from PreferredVersionOverrides
+// is used only if the cluster serves the kind in that version, otherwise a warning is logged
+func (c *Config) preferredVersion(mapper meta.RESTMapper, gvk schema.GroupVersionKind) string {
+	group := gvk.Group
+	if len(group) == 0 {
+		group = "core"
+	}
+	override, ok := c.PreferredVersionOverrides[group]
+	if !ok && len(gvk.Group) == 0 {
+		override, ok = c.PreferredVersionOverrides[""]
+	}
+	if !ok || len(override) == 0 || override == gvk.Version {
+		return gvk.Version
+	}
+	if _, err := mapper.RESTMapping(gvk.GroupKind(), override); err != nil {
+		kLog.Warn(fmt.Sprintf("version %s of %s is not served by the cluster, listing %s instead", override, gvk.GroupKind().String(), gvk.Version))
+		return gvk.Version
+	}
+	return override
+}
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_preferredVersionOverrides(t *testing.T) {
+	server := newFakeApiServer(t)
+	widgetGvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	server.addResource(widgetGvk, "widgets", true)
+	server.addResource(schema.GroupVersionKind{Group: "example.com", Version: "v1beta1", Kind: "Widget"}, "widgets", true)
+	server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "configmaps", true)
+	gvks := []schema.GroupVersionKind{widgetGvk, {Version: "v1", Kind: "ConfigMap"}}
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      []string
+	}{
+		{
+			name: "server preference",
+			want: []string{"/apis/example.com/v1/widgets", "/api/v1/configmaps"},
+		},
+		{
+			name:      "served override",
+			overrides: map[string]string{"example.com": "v1beta1"},
+			want:      []string{"/apis/example.com/v1beta1/widgets", "/api/v1/configmaps"},
+		},
+		{
+			name:      "unserved override falls back",
+			overrides: map[string]string{"example.com": "v2", "core": "v2"},
+			want:      []string{"/apis/example.com/v1/widgets", "/api/v1/configmaps"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(server.resourceRequests())
+			cluster := server.cluster(t)
+			_, _, _ = cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true, PreferredVersionOverrides: tt.overrides, Concurrency: 1})
+			assert.Equal(t, tt.want, server.resourceRequests()[before:])
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package pkg
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// PreflightFailure is the kind of problem found by Cluster.Preflight
+type PreflightFailure string
+
+const (
+	PreflightNetwork        PreflightFailure = "network"
+	PreflightTLS            PreflightFailure = "tls"
+	PreflightAuthentication PreflightFailure = "authentication"
+	PreflightAuthorization  PreflightFailure = "authorization"
+	PreflightServer         PreflightFailure = "server"
+)
+
+// PreflightError is returned by Cluster.Preflight when the cluster cannot be scanned
+type PreflightError struct {
+	Failure PreflightFailure
+	// Check is the step of the preflight which failed
+	Check string
+	Err   error
+}
+
+func (e *PreflightError) Error() string {
+	return fmt.Sprintf("preflight %s check failed with %s error: %v", e.Check, e.Failure, e.Err)
+}
+
+func (e *PreflightError) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests how the failure can be fixed
+func (e *PreflightError) Hint() string {
+	switch e.Failure {
+	case PreflightNetwork:
+		return "the api server is not reachable, check the VPN or proxy used to connect to the cluster"
+	case PreflightTLS:
+		return "the api server certificate could not be verified, check the certificate authority of the kubeconfig"
+	case PreflightAuthentication:
+		return "the credentials were rejected, refresh the credentials of the kubeconfig"
+	case PreflightAuthorization:
+		return "the user is not allowed to list resources, grant it read access to the cluster with RBAC"
+	default:
+		return "the api server responded unexpectedly, check that the kubeconfig points to a kubernetes api server"
+	}
+}
+
+// Preflight verifies the cluster can be scanned before fetching any objects: the api server must report its
+// version, serve the core group and allow listing namespaces, unless conf selects the namespaces to scan by name as
+// their resources are then listed namespace by namespace. conf may be nil. Failures are returned as *PreflightError.
+func (c *Cluster) Preflight(ctx context.Context, conf *Config) error {
+	if _, err := c.ServerVersion(ctx); err != nil {
+		return newPreflightError("version", err)
+	}
+	groups, err := c.disco.ServerGroups()
+	if err != nil {
+		return newPreflightError("discovery", err)
+	}
+	coreServed := false
+	for _, group := range groups.Groups {
+		if len(group.Name) == 0 {
+			coreServed = true
+		}
+	}
+	if !coreServed {
+		return &PreflightError{Failure: PreflightServer, Check: "discovery", Err: fmt.Errorf("core api group is not served")}
+	}
+	if conf != nil {
+		// namespace admins may list the resources of their namespaces but not the namespaces of the cluster
+		if _, ok := conf.listedNamespaces(); ok {
+			return nil
+		}
+	}
+	namespaces := schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	if _, err := c.dynamicClient().Resource(namespaces).List(ctx, v1.ListOptions{Limit: 1}); err != nil {
+		return newPreflightError("list namespaces", c.describeListError(err))
+	}
+	return nil
+}
+
+// newPreflightError classifies err returned by the check
+func newPreflightError(check string, err error) *PreflightError {
+	return &PreflightError{Failure: preflightFailureOf(err), Check: check, Err: err}
+}
+
+func preflightFailureOf(err error) PreflightFailure {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCertificate x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var verification *tls.CertificateVerificationError
+	var recordHeader tls.RecordHeaderError
+	var netErr net.Error
+	switch {
+	case k8sErrors.IsUnauthorized(err):
+		return PreflightAuthentication
+	case k8sErrors.IsForbidden(err):
+		return PreflightAuthorization
+	case errors.As(err, &unknownAuthority), errors.As(err, &invalidCertificate), errors.As(err, &hostname),
+		errors.As(err, &verification), errors.As(err, &recordHeader):
+		return PreflightTLS
+	case errors.As(err, &netErr):
+		return PreflightNetwork
+	default:
+		return PreflightServer
+	}
+}
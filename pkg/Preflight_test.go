@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/rest"
+)
+
+func TestCluster_Preflight(t *testing.T) {
+	rejecting := func(path string, code int, reason string) func(w http.ResponseWriter, r *http.Request) bool {
+		return func(w http.ResponseWriter, r *http.Request) bool {
+			if r.URL.Path != path {
+				return false
+			}
+			writeStatus(w, code, reason, reason)
+			return true
+		}
+	}
+	tests := []struct {
+		name        string
+		intercept   func(w http.ResponseWriter, r *http.Request) bool
+		conf        *Config
+		tls         bool
+		closed      bool
+		wantFailure PreflightFailure
+		wantCheck   string
+	}{
+		{name: "scannable cluster"},
+		{name: "unreachable", closed: true, wantFailure: PreflightNetwork, wantCheck: "version"},
+		{name: "unknown certificate", tls: true, wantFailure: PreflightTLS, wantCheck: "version"},
+		{name: "expired credentials", intercept: rejecting("/version", http.StatusUnauthorized, "Unauthorized"), wantFailure: PreflightAuthentication, wantCheck: "version"},
+		{name: "missing rbac", intercept: rejecting("/api/v1/namespaces", http.StatusForbidden, "Forbidden"), wantFailure: PreflightAuthorization, wantCheck: "list namespaces"},
+		{name: "namespace admin", intercept: rejecting("/api/v1/namespaces", http.StatusForbidden, "Forbidden"), conf: &Config{SelectNamespaces: []string{"shop"}}},
+		{name: "namespaces selected by pattern", intercept: rejecting("/api/v1/namespaces", http.StatusForbidden, "Forbidden"), conf: &Config{SelectNamespaces: []string{"shop-*"}}, wantFailure: PreflightAuthorization, wantCheck: "list namespaces"},
+		{name: "core group not served", intercept: rejecting("/api", http.StatusNotFound, "NotFound"), wantFailure: PreflightServer, wantCheck: "discovery"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeApiServer(t)
+			server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, "namespaces", false)
+			server.intercept = tt.intercept
+			restConfig := server.restConfig()
+			if tt.tls {
+				tlsServer := httptest.NewTLSServer(server)
+				t.Cleanup(tlsServer.Close)
+				restConfig = &rest.Config{Host: tlsServer.URL}
+			}
+			cluster, err := NewClusterFromEnvOrConfig(restConfig, ClusterOptions{}, nil)
+			if !assert.NoError(t, err) {
+				return
+			}
+			if tt.closed {
+				server.Close()
+			}
+
+			err = cluster.Preflight(context.Background(), tt.conf)
+			if len(tt.wantFailure) == 0 {
+				assert.NoError(t, err)
+				return
+			}
+			var preflightErr *PreflightError
+			if assert.True(t, errors.As(err, &preflightErr), "%v", err) {
+				assert.Equal(t, tt.wantFailure, preflightErr.Failure)
+				assert.Equal(t, tt.wantCheck, preflightErr.Check)
+				assert.NotEmpty(t, preflightErr.Hint())
+			}
+		})
+	}
+}
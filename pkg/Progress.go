@@ -0,0 +1,18 @@
+package pkg
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ProgressReporter is told how FetchK8sObjects progresses through the resources of a cluster, eg to show progress
+// during long scans. Calls are serialized, reporters need not be safe for concurrent use
+type ProgressReporter interface {
+	// ResourceStarted is called when listing resource starts, index counts from 1 up to the total number of resources
+	// to be listed
+	ResourceStarted(resource schema.GroupVersionResource, index, total int)
+	// ObjectsFetched is called with the number of objects listed once listing resource succeeded
+	ObjectsFetched(resource schema.GroupVersionResource, count int)
+	// ResourceFinished is called once listing resource is done, err is set if the resource was skipped or the scan
+	// stopped while it was being listed
+	ResourceFinished(resource schema.GroupVersionResource, err error)
+}
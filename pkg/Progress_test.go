@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// recordingProgress records the calls of a ProgressReporter
+type recordingProgress struct {
+	calls []string
+}
+
+func (r *recordingProgress) ResourceStarted(resource schema.GroupVersionResource, index, total int) {
+	r.calls = append(r.calls, fmt.Sprintf("started %s %d/%d", resource.Resource, index, total))
+}
+
+func (r *recordingProgress) ObjectsFetched(resource schema.GroupVersionResource, count int) {
+	r.calls = append(r.calls, fmt.Sprintf("fetched %s %d", resource.Resource, count))
+}
+
+func (r *recordingProgress) ResourceFinished(resource schema.GroupVersionResource, err error) {
+	r.calls = append(r.calls, fmt.Sprintf("finished %s %v", resource.Resource, err != nil))
+}
+
+func TestCluster_FetchK8sObjects_progress(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	secretGvk := schema.GroupVersionKind{Version: "v1", Kind: "Secret"}
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", "settings"))
+	server.addObject(configMaps, newFakeObject("v1", "ConfigMap", "shop", "flags"))
+	server.addResource(secretGvk, "secrets", true)
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/api/v1/secrets" {
+			writeStatus(w, http.StatusForbidden, "Forbidden", `secrets is forbidden: User "ci" cannot list resource "secrets"`)
+			return true
+		}
+		return false
+	}
+	cluster := server.cluster(t)
+
+	progress := &recordingProgress{}
+	conf := &Config{Concurrency: 1, SkipAccessReview: true, Progress: progress}
+	objs, summary, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{configMapGvk, secretGvk, deploymentGvk}, conf)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 3)
+	assert.Len(t, summary.Skipped, 1)
+	assert.Equal(t, []string{
+		"started configmaps 1/3",
+		"fetched configmaps 2",
+		"finished configmaps false",
+		"started secrets 2/3",
+		"finished secrets true",
+		"started deployments 3/3",
+		"fetched deployments 1",
+		"finished deployments false",
+	}, progress.calls)
+}
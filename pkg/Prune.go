@@ -0,0 +1,26 @@
+package pkg
+
+import (
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// LastAppliedConfigAnnotation holds the configuration last applied with kubectl apply
+const LastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// pruneMetadata drops the managed fields and the last applied configuration from the metadata of obj, which are
+// not needed for validation but often make up most of the object. Nothing outside of metadata is touched. The api
+// server cannot be asked to leave them out of list responses, so they are dropped as soon as a page is decoded. The
+// last applied configuration is kept with keepLastApplied, see Config.UseLastApplied
+func pruneMetadata(obj *unstructured.Unstructured, keepLastApplied bool) {
+	metadata, ok := obj.Object["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	delete(metadata, "managedFields")
+	if annotations, ok := metadata["annotations"].(map[string]interface{}); ok && !keepLastApplied {
+		delete(annotations, LastAppliedConfigAnnotation)
+		if len(annotations) == 0 {
+			delete(metadata, "annotations")
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// pruneTestSwagger is a minimal openapi spec of a kubernetes release serving apps/v1 Deployments
+const pruneTestSwagger = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.27.0"},
+  "paths": {
+    "/apis/apps/v1/namespaces/{namespace}/deployments": {
+      "parameters": [{"name": "namespace", "in": "path", "required": true, "type": "string"}],
+      "post": {
+        "responses": {"200": {"description": "OK"}},
+        "x-kubernetes-group-version-kind": {"group": "apps", "kind": "Deployment", "version": "v1"}
+      }
+    }
+  },
+  "definitions": {
+    "io.k8s.api.apps.v1.Deployment": {
+      "type": "object",
+      "properties": {
+        "apiVersion": {"type": "string"},
+        "kind": {"type": "string"},
+        "metadata": {"$ref": "#/definitions/io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta"},
+        "spec": {"$ref": "#/definitions/io.k8s.api.apps.v1.DeploymentSpec"}
+      },
+      "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+    },
+    "io.k8s.api.apps.v1.DeploymentSpec": {
+      "type": "object",
+      "required": ["selector"],
+      "properties": {
+        "replicas": {"type": "integer", "format": "int32"},
+        "selector": {"type": "object"},
+        "paused": {"type": "boolean", "description": "Deprecated: paused deployments are rolled out by hand."}
+      }
+    },
+    "io.k8s.apimachinery.pkg.apis.meta.v1.ObjectMeta": {
+      "type": "object",
+      "properties": {
+        "name": {"type": "string"},
+        "namespace": {"type": "string"},
+        "annotations": {"type": "object", "additionalProperties": {"type": "string"}},
+        "managedFields": {"type": "array", "items": {"type": "object"}}
+      }
+    }
+  }
+}`
+
+func newManagedDeployment() map[string]interface{} {
+	obj := withAnnotation(newFakeObject("apps/v1", "Deployment", "shop", "cart"), LastAppliedConfigAnnotation, `{"apiVersion":"apps/v1","kind":"Deployment"}`)
+	obj = withAnnotation(obj, "team", "payments")
+	obj["metadata"].(map[string]interface{})["managedFields"] = []interface{}{
+		map[string]interface{}{"manager": "kubectl", "operation": "Update", "fieldsType": "FieldsV1"},
+	}
+	obj["spec"] = map[string]interface{}{"replicas": "two", "paused": true, "managedFields": "kept"}
+	obj["status"] = map[string]interface{}{"annotations": map[string]interface{}{LastAppliedConfigAnnotation: "kept"}}
+	return obj
+}
+
+func TestPruneMetadata(t *testing.T) {
+	obj := unstructured.Unstructured{Object: newManagedDeployment()}
+	pruneMetadata(&obj, false)
+	assert.Nil(t, obj.GetManagedFields())
+	assert.Equal(t, map[string]string{"team": "payments"}, obj.GetAnnotations())
+	want := newManagedDeployment()
+	assert.Equal(t, want["spec"], obj.Object["spec"])
+	assert.Equal(t, want["status"], obj.Object["status"])
+
+	// annotations are dropped once empty
+	obj = unstructured.Unstructured{Object: withAnnotation(newFakeObject("v1", "Pod", "shop", "cart"), LastAppliedConfigAnnotation, "{}")}
+	pruneMetadata(&obj, false)
+	_, found := obj.Object["metadata"].(map[string]interface{})["annotations"]
+	assert.False(t, found)
+
+	// the last applied configuration is kept when it is to be validated
+	obj = unstructured.Unstructured{Object: withAnnotation(newFakeObject("v1", "Pod", "shop", "cart"), LastAppliedConfigAnnotation, "{}")}
+	pruneMetadata(&obj, true)
+	assert.Equal(t, map[string]string{LastAppliedConfigAnnotation: "{}"}, obj.GetAnnotations())
+}
+
+func TestPruneMetadata_validationResults(t *testing.T) {
+	kubeC := NewKubeCheckerImpl()
+	if !assert.NoError(t, kubeC.load([]byte(pruneTestSwagger), "1.27")) {
+		return
+	}
+	obj := unstructured.Unstructured{Object: newManagedDeployment()}
+	before, err := kubeC.ValidateObject(runtime.DeepCopyJSON(obj.Object), "1.27")
+	assert.NoError(t, err)
+	pruneMetadata(&obj, false)
+	after, err := kubeC.ValidateObject(obj.Object, "1.27")
+	assert.NoError(t, err)
+
+	// the object has findings so that the comparison means something
+	assert.NotEmpty(t, before.ErrorsForOriginal)
+	assert.NotEmpty(t, before.DeprecationForOriginal)
+	conf := &Config{}
+	assert.Equal(t, FilterValidationResults(before, conf), FilterValidationResults(after, conf))
+}
+
+func TestCluster_FetchK8sObjects_pruneMetadata(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newManagedDeployment())
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{deploymentGvk}
+
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{})
+	if assert.NoError(t, err) && assert.Len(t, objs, 1) {
+		assert.Nil(t, objs[0].GetManagedFields())
+		assert.NotContains(t, objs[0].GetAnnotations(), LastAppliedConfigAnnotation)
+	}
+
+	objs, _, err = cluster.FetchK8sObjects(context.Background(), gvks, &Config{KeepManagedFields: true})
+	if assert.NoError(t, err) && assert.Len(t, objs, 1) {
+		assert.Len(t, objs[0].GetManagedFields(), 1)
+		assert.Contains(t, objs[0].GetAnnotations(), LastAppliedConfigAnnotation)
+	}
+}
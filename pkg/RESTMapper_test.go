@@ -0,0 +1,43 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_SetRESTMapper(t *testing.T) {
+	server := newFakeApiServer(t)
+	widgetGvk := schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+	widgets := server.addResource(widgetGvk, "widgets", true)
+	server.addObject(widgets, newFakeObject("example.com/v1", "Widget", "shop", "cart"))
+	// discovery is unavailable, kinds can only be resolved through the static mapper
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/apis") && strings.Count(r.URL.Path, "/") <= 3 {
+			writeStatus(w, http.StatusServiceUnavailable, "ServiceUnavailable", "discovery disabled")
+			return true
+		}
+		return false
+	}
+	cluster := server.cluster(t)
+
+	gvks := []schema.GroupVersionKind{widgetGvk}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true})
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{widgetGvk.GroupVersion()})
+	mapper.Add(widgetGvk, meta.RESTScopeNamespace)
+	cluster.SetRESTMapper(mapper)
+	objs, _, err = cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true})
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 1) {
+		assert.Equal(t, "cart", objs[0].GetName())
+	}
+	assert.Equal(t, []string{"/apis/example.com/v1/widgets"}, server.resourceRequests())
+}
@@ -0,0 +1,362 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// Removal analyzers, see AnalyzeRemovals
+const (
+	AnalyzerPodSecurityPolicy = "pod-security-policy"
+	AnalyzerComponentStatus   = "component-status"
+	AnalyzerFlowControl       = "flow-control"
+)
+
+// Pod Security Admission labels and levels, see podSecurityLevel
+const (
+	podSecurityEnforceLabel = "pod-security.kubernetes.io/enforce"
+	podSecurityPrivileged   = "privileged"
+	podSecurityBaseline     = "baseline"
+	podSecurityRestricted   = "restricted"
+)
+
+// flowControlAutoUpdateAnnotation marks the flow control objects the api server maintains itself
+const flowControlAutoUpdateAnnotation = "apf.kubernetes.io/autoupdate-spec"
+
+var (
+	relatedNamespaces          = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+	relatedClusterRoles        = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"}
+	relatedRoles               = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"}
+	relatedClusterRoleBindings = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"}
+	relatedRoleBindings        = schema.GroupVersionResource{Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"}
+)
+
+// Remediation is the next step for an object of a removed or deprecated kind with a known migration, see
+// AnalyzeRemovals
+type Remediation struct {
+	Analyzer string
+	Summary  string
+	Steps    []string `json:",omitempty"`
+	// Related are the objects the steps are about, eg the bindings granting use of a PodSecurityPolicy
+	Related []ObjectRef `json:",omitempty"`
+}
+
+// removalAnalyzer produces the remediation of the results it applies to
+type removalAnalyzer struct {
+	name    string
+	applies func(vr ValidationResult) bool
+	analyze func(ctx context.Context, obj *unstructured.Unstructured, related *relatedObjects) *Remediation
+}
+
+var removalAnalyzers = []removalAnalyzer{
+	{name: AnalyzerPodSecurityPolicy, applies: isRemovedPodSecurityPolicy, analyze: analyzePodSecurityPolicy},
+	{name: AnalyzerComponentStatus, applies: isDeprecatedComponentStatus, analyze: analyzeComponentStatus},
+	{name: AnalyzerFlowControl, applies: isBetaFlowControl, analyze: analyzeFlowControl},
+}
+
+// RemovalAnalyzerNames are the names of the removal analyzers, which may be disabled by Config.DisableAnalyzers
+func RemovalAnalyzerNames() []string {
+	names := make([]string, 0, len(removalAnalyzers))
+	for _, a := range removalAnalyzers {
+		names = append(names, a.name)
+	}
+	return names
+}
+
+func validateDisableAnalyzers(names []string) error {
+	for _, name := range names {
+		if !slices.Contains(RemovalAnalyzerNames(), name) {
+			return fmt.Errorf("unknown analyzer %q in DisableAnalyzers, expected one of %s", name, strings.Join(RemovalAnalyzerNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// AnalyzeRemovals sets the Remediation of the results of removed or deprecated kinds with a known migration, eg the
+// namespaces to label for Pod Security Admission in place of a PodSecurityPolicy. Related objects are looked up among
+// objects and listed from cluster, unless it is nil, when none of their kind were fetched. Analyzers disabled by
+// Config.DisableAnalyzers are skipped and the others only run when a result of their kind is present
+func AnalyzeRemovals(ctx context.Context, results []ValidationResult, objects []unstructured.Unstructured, cluster *Cluster, conf *Config) {
+	var index map[string]*unstructured.Unstructured
+	related := &relatedObjects{objects: objects, cluster: cluster, conf: conf, listed: map[schema.GroupVersionResource]relatedList{}}
+	for _, analyzer := range removalAnalyzers {
+		if slices.Contains(conf.DisableAnalyzers, analyzer.name) {
+			continue
+		}
+		for i := range results {
+			vr := &results[i]
+			if !analyzer.applies(*vr) {
+				continue
+			}
+			if index == nil {
+				index = make(map[string]*unstructured.Unstructured, len(objects))
+				for j := range objects {
+					index[objectKey(objects[j].GetKind(), objects[j].GetNamespace(), objects[j].GetName())] = &objects[j]
+				}
+			}
+			obj, ok := index[objectKey(vr.Kind, vr.ResourceNamespace, vr.ResourceName)]
+			if !ok {
+				continue
+			}
+			if remediation := analyzer.analyze(ctx, obj, related); remediation != nil {
+				remediation.Analyzer = analyzer.name
+				vr.Remediation = remediation
+			}
+		}
+	}
+}
+
+func objectKey(kind, namespace, name string) string {
+	return kind + "/" + namespace + "/" + name
+}
+
+// relatedObjects looks up the objects analyzers need, once per resource
+type relatedObjects struct {
+	objects []unstructured.Unstructured
+	cluster *Cluster
+	conf    *Config
+	listed  map[schema.GroupVersionResource]relatedList
+}
+
+type relatedList struct {
+	items []unstructured.Unstructured
+	err   error
+}
+
+// list returns the objects of resource of kind among the fetched objects, in any version, or lists them from the
+// cluster when none were fetched
+func (r *relatedObjects) list(ctx context.Context, resource schema.GroupVersionResource, kind string) ([]unstructured.Unstructured, error) {
+	if l, ok := r.listed[resource]; ok {
+		return l.items, l.err
+	}
+	var l relatedList
+	for _, obj := range r.objects {
+		if obj.GetKind() == kind && obj.GroupVersionKind().Group == resource.Group {
+			l.items = append(l.items, obj)
+		}
+	}
+	if len(l.items) == 0 && r.cluster != nil {
+		if l.err = r.cluster.initClients(); l.err == nil {
+			l.items, l.err = r.cluster.listAll(ctx, resource, "", 0, r.conf.unselected())
+		}
+	}
+	r.listed[resource] = l
+	return l.items, l.err
+}
+
+func isRemovedPodSecurityPolicy(vr ValidationResult) bool {
+	gv, _ := schema.ParseGroupVersion(vr.APIVersion)
+	return vr.Kind == "PodSecurityPolicy" && (gv.Group == "policy" || gv.Group == "extensions") && (vr.Deleted || vr.Deprecated)
+}
+
+// analyzePodSecurityPolicy maps the policy to a Pod Security Standard level and looks up the namespaces relying on it
+// through the roles granting use of it and their bindings
+func analyzePodSecurityPolicy(ctx context.Context, obj *unstructured.Unstructured, related *relatedObjects) *Remediation {
+	level := podSecurityLevel(obj.Object)
+	remediation := &Remediation{Summary: fmt.Sprintf("PodSecurityPolicy is replaced by Pod Security Admission, %s matches the %s Pod Security Standard", obj.GetName(), level)}
+	grantingRoles := sets.New[string]()
+	for _, kind := range []struct {
+		resource schema.GroupVersionResource
+		kind     string
+	}{{relatedClusterRoles, "ClusterRole"}, {relatedRoles, "Role"}} {
+		roles, err := related.list(ctx, kind.resource, kind.kind)
+		if err != nil {
+			remediation.Steps = append(remediation.Steps, fmt.Sprintf("check which namespaces use the policy, %s could not be listed: %v", kind.resource.Resource, err))
+			return remediation
+		}
+		for _, role := range roles {
+			if grantsPodSecurityPolicy(role.Object, obj.GetName()) {
+				grantingRoles.Insert(objectKey(role.GetKind(), role.GetNamespace(), role.GetName()))
+			}
+		}
+	}
+	namespaces, clusterWide := sets.New[string](), false
+	for _, kind := range []struct {
+		resource schema.GroupVersionResource
+		kind     string
+	}{{relatedClusterRoleBindings, "ClusterRoleBinding"}, {relatedRoleBindings, "RoleBinding"}} {
+		bindings, err := related.list(ctx, kind.resource, kind.kind)
+		if err != nil {
+			remediation.Steps = append(remediation.Steps, fmt.Sprintf("check which namespaces use the policy, %s could not be listed: %v", kind.resource.Resource, err))
+			return remediation
+		}
+		for _, binding := range bindings {
+			roleKind, _, _ := unstructured.NestedString(binding.Object, "roleRef", "kind")
+			roleName, _, _ := unstructured.NestedString(binding.Object, "roleRef", "name")
+			roleNamespace := ""
+			if roleKind == "Role" {
+				roleNamespace = binding.GetNamespace()
+			}
+			if !grantingRoles.Has(objectKey(roleKind, roleNamespace, roleName)) {
+				continue
+			}
+			remediation.Related = append(remediation.Related, ObjectRef{APIVersion: binding.GetAPIVersion(), Kind: binding.GetKind(), Namespace: binding.GetNamespace(), Name: binding.GetName()})
+			if len(binding.GetNamespace()) > 0 {
+				namespaces.Insert(binding.GetNamespace())
+				continue
+			}
+			subjectNamespaces, all := bindingSubjectNamespaces(binding.Object)
+			namespaces.Insert(subjectNamespaces...)
+			clusterWide = clusterWide || all
+		}
+	}
+	if len(remediation.Related) == 0 {
+		remediation.Steps = append(remediation.Steps, fmt.Sprintf("no binding grants use of the policy, delete it: kubectl delete podsecuritypolicy %s", obj.GetName()))
+		return remediation
+	}
+	remediation.Steps = append(remediation.Steps, "try the level with the pod-security.kubernetes.io/warn and pod-security.kubernetes.io/audit labels first, pods violating it are then reported without being rejected")
+	if clusterWide {
+		remediation.Steps = append(remediation.Steps, fmt.Sprintf("the policy is granted cluster-wide, label every namespace pods run in: kubectl label --overwrite namespace --all %s=%s", podSecurityEnforceLabel, level))
+	}
+	enforced := map[string]string{}
+	if nsList, err := related.list(ctx, relatedNamespaces, "Namespace"); err == nil {
+		for _, ns := range nsList {
+			if value, ok := ns.GetLabels()[podSecurityEnforceLabel]; ok {
+				enforced[ns.GetName()] = value
+			}
+		}
+	}
+	for _, ns := range sets.List(namespaces) {
+		if value, ok := enforced[ns]; ok {
+			remediation.Steps = append(remediation.Steps, fmt.Sprintf("namespace %s already enforces the %s level", ns, value))
+			continue
+		}
+		remediation.Steps = append(remediation.Steps, fmt.Sprintf("kubectl label --overwrite namespace %s %s=%s", ns, podSecurityEnforceLabel, level))
+	}
+	remediation.Steps = append(remediation.Steps, "delete the policy and the bindings granting use of it once the namespaces are labelled")
+	return remediation
+}
+
+// grantsPodSecurityPolicy returns true if a rule of the role allows to use the policy named name
+func grantsPodSecurityPolicy(role map[string]interface{}, name string) bool {
+	rules, _, _ := unstructured.NestedSlice(role, "rules")
+	for _, r := range objectList(rules) {
+		groups, _, _ := unstructured.NestedStringSlice(r, "apiGroups")
+		resources, _, _ := unstructured.NestedStringSlice(r, "resources")
+		verbs, _, _ := unstructured.NestedStringSlice(r, "verbs")
+		names, _, _ := unstructured.NestedStringSlice(r, "resourceNames")
+		if (slices.Contains(groups, "policy") || slices.Contains(groups, "extensions") || slices.Contains(groups, "*")) &&
+			(slices.Contains(resources, "podsecuritypolicies") || slices.Contains(resources, "*")) &&
+			(slices.Contains(verbs, "use") || slices.Contains(verbs, "*")) &&
+			(len(names) == 0 || slices.Contains(names, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bindingSubjectNamespaces returns the namespaces of the service accounts a cluster role binding is for, all is true
+// when it is for users, or groups other than the service accounts of a namespace
+func bindingSubjectNamespaces(binding map[string]interface{}) (namespaces []string, all bool) {
+	subjects, _, _ := unstructured.NestedSlice(binding, "subjects")
+	for _, subject := range objectList(subjects) {
+		kind, _ := subject["kind"].(string)
+		name, _ := subject["name"].(string)
+		switch {
+		case kind == "ServiceAccount":
+			namespace, _ := subject["namespace"].(string)
+			namespaces = append(namespaces, namespace)
+		case kind == "Group" && strings.HasPrefix(name, "system:serviceaccounts:"):
+			namespaces = append(namespaces, strings.TrimPrefix(name, "system:serviceaccounts:"))
+		default:
+			all = true
+		}
+	}
+	return namespaces, all
+}
+
+// podSecurityLevel returns the Pod Security Standard level closest to the policy: privileged when it allows host
+// namespaces, ports or paths, privileged containers or added capabilities, restricted when it requires non-root
+// users, drops every capability, denies privilege escalation and only allows restricted volume types
+func podSecurityLevel(psp map[string]interface{}) string {
+	spec, _ := psp["spec"].(map[string]interface{})
+	for _, field := range []string{"privileged", "hostNetwork", "hostPID", "hostIPC"} {
+		if allowed, _ := spec[field].(bool); allowed {
+			return podSecurityPrivileged
+		}
+	}
+	volumes, _, _ := unstructured.NestedStringSlice(spec, "volumes")
+	capabilities, _, _ := unstructured.NestedStringSlice(spec, "allowedCapabilities")
+	hostPorts, _, _ := unstructured.NestedSlice(spec, "hostPorts")
+	if len(hostPorts) > 0 || slices.Contains(volumes, "hostPath") || slices.Contains(volumes, "*") || len(capabilities) > 0 {
+		return podSecurityPrivileged
+	}
+	restrictedVolumes := sets.New("configMap", "csi", "downwardAPI", "emptyDir", "ephemeral", "persistentVolumeClaim", "projected", "secret")
+	runAsUser, _, _ := unstructured.NestedString(spec, "runAsUser", "rule")
+	dropped, _, _ := unstructured.NestedStringSlice(spec, "requiredDropCapabilities")
+	escalation, found, _ := unstructured.NestedBool(spec, "allowPrivilegeEscalation")
+	if runAsUser == "MustRunAsNonRoot" && slices.Contains(dropped, "ALL") && found && !escalation && restrictedVolumes.HasAll(volumes...) {
+		return podSecurityRestricted
+	}
+	return podSecurityBaseline
+}
+
+func isDeprecatedComponentStatus(vr ValidationResult) bool {
+	return vr.Kind == "ComponentStatus" && vr.APIVersion == "v1" && (vr.Deleted || vr.Deprecated)
+}
+
+// analyzeComponentStatus points to the health endpoint of the component replacing its status
+func analyzeComponentStatus(_ context.Context, obj *unstructured.Unstructured, _ *relatedObjects) *Remediation {
+	remediation := &Remediation{Summary: "ComponentStatus is deprecated since kubernetes 1.19, query the health endpoints of the control plane components instead"}
+	name := obj.GetName()
+	switch {
+	case name == "scheduler":
+		remediation.Steps = append(remediation.Steps, "curl -k https://<scheduler-host>:10259/livez on the control plane nodes")
+	case name == "controller-manager":
+		remediation.Steps = append(remediation.Steps, "curl -k https://<controller-manager-host>:10257/livez on the control plane nodes")
+	case strings.HasPrefix(name, "etcd"):
+		remediation.Steps = append(remediation.Steps, "kubectl get --raw='/readyz/etcd'")
+	}
+	remediation.Steps = append(remediation.Steps, "kubectl get --raw='/readyz?verbose' for the checks of the api server, update monitoring and scripts calling kubectl get componentstatuses")
+	return remediation
+}
+
+func isBetaFlowControl(vr ValidationResult) bool {
+	gv, _ := schema.ParseGroupVersion(vr.APIVersion)
+	return gv.Group == "flowcontrol.apiserver.k8s.io" && strings.HasPrefix(gv.Version, "v1beta") &&
+		(vr.Kind == "FlowSchema" || vr.Kind == "PriorityLevelConfiguration") &&
+		(vr.Deleted || vr.Deprecated || len(vr.LatestAPIVersion) > 0)
+}
+
+// analyzeFlowControl lists the field changes between the beta versions of flow control and v1 and the flow schemas
+// depending on a priority level
+func analyzeFlowControl(ctx context.Context, obj *unstructured.Unstructured, related *relatedObjects) *Remediation {
+	if obj.GetAnnotations()[flowControlAutoUpdateAnnotation] == "true" {
+		return &Remediation{Summary: fmt.Sprintf("%s %s is maintained by the api server, which keeps it up to date in the served versions, nothing to migrate", obj.GetKind(), obj.GetName())}
+	}
+	remediation := &Remediation{Summary: fmt.Sprintf("%s %s must be applied as flowcontrol.apiserver.k8s.io/v1 before %s is removed", obj.GetKind(), obj.GetName(), obj.GetAPIVersion())}
+	switch obj.GetKind() {
+	case "PriorityLevelConfiguration":
+		if shares, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "limited", "assuredConcurrencyShares"); found {
+			remediation.Steps = append(remediation.Steps, fmt.Sprintf("rename spec.limited.assuredConcurrencyShares to spec.limited.nominalConcurrencyShares: %v", shares))
+		}
+		if shares, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "limited", "nominalConcurrencyShares"); found && fmt.Sprint(shares) == "0" {
+			remediation.Steps = append(remediation.Steps, "spec.limited.nominalConcurrencyShares 0 means the default of 30 before v1 and no concurrency in v1, set it to 30 or remove it")
+		}
+		schemas, err := related.list(ctx, obj.GroupVersionKind().GroupVersion().WithResource("flowschemas"), "FlowSchema")
+		if err != nil {
+			remediation.Steps = append(remediation.Steps, fmt.Sprintf("check the flow schemas referencing the priority level, they could not be listed: %v", err))
+			break
+		}
+		for _, fs := range schemas {
+			if level, _, _ := unstructured.NestedString(fs.Object, "spec", "priorityLevelConfiguration", "name"); level == obj.GetName() {
+				remediation.Related = append(remediation.Related, ObjectRef{APIVersion: fs.GetAPIVersion(), Kind: fs.GetKind(), Name: fs.GetName()})
+			}
+		}
+		if len(remediation.Related) > 0 {
+			remediation.Steps = append(remediation.Steps, "migrate the flow schemas referencing the priority level along with it")
+		}
+	case "FlowSchema":
+		if level, _, _ := unstructured.NestedString(obj.Object, "spec", "priorityLevelConfiguration", "name"); len(level) > 0 {
+			remediation.Related = append(remediation.Related, ObjectRef{APIVersion: obj.GetAPIVersion(), Kind: "PriorityLevelConfiguration", Name: level})
+			remediation.Steps = append(remediation.Steps, fmt.Sprintf("migrate priority level %s along with the flow schema", level))
+		}
+	}
+	return remediation
+}
@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newFakePodSecurityPolicy(name string, spec map[string]interface{}) map[string]interface{} {
+	obj := newFakeObject("policy/v1beta1", "PodSecurityPolicy", "", name)
+	obj["spec"] = spec
+	return obj
+}
+
+func newFakeBinding(kind, namespace, name, roleKind, roleName string, subjects ...interface{}) map[string]interface{} {
+	obj := newFakeObject("rbac.authorization.k8s.io/v1", kind, namespace, name)
+	obj["roleRef"] = map[string]interface{}{"apiGroup": "rbac.authorization.k8s.io", "kind": roleKind, "name": roleName}
+	obj["subjects"] = subjects
+	return obj
+}
+
+func TestAnalyzeRemovals_podSecurityPolicy(t *testing.T) {
+	role := newFakeObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "psp-restricted")
+	role["rules"] = []interface{}{map[string]interface{}{
+		"apiGroups": []interface{}{"policy"}, "resources": []interface{}{"podsecuritypolicies"},
+		"verbs": []interface{}{"use"}, "resourceNames": []interface{}{"restricted"},
+	}}
+	labeled := newFakeObject("v1", "Namespace", "", "web")
+	labeled["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{podSecurityEnforceLabel: "baseline"}
+	objects := []unstructured.Unstructured{
+		{Object: newFakePodSecurityPolicy("restricted", map[string]interface{}{
+			"runAsUser":                map[string]interface{}{"rule": "MustRunAsNonRoot"},
+			"requiredDropCapabilities": []interface{}{"ALL"},
+			"allowPrivilegeEscalation": false,
+			"volumes":                  []interface{}{"configMap", "secret"},
+		})},
+		{Object: newFakePodSecurityPolicy("unused", map[string]interface{}{"privileged": true})},
+		{Object: role},
+		{Object: newFakeBinding("RoleBinding", "shop", "psp", "ClusterRole", "psp-restricted")},
+		{Object: newFakeBinding("ClusterRoleBinding", "", "psp-web", "ClusterRole", "psp-restricted",
+			map[string]interface{}{"kind": "Group", "name": "system:serviceaccounts:web"})},
+		{Object: newFakeBinding("RoleBinding", "shop", "other", "Role", "psp-restricted")},
+		{Object: labeled},
+	}
+	results := []ValidationResult{
+		{Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1", ResourceName: "restricted", Deleted: true},
+		{Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1", ResourceName: "unused", Deleted: true},
+		{Kind: "ClusterRole", APIVersion: "rbac.authorization.k8s.io/v1", ResourceName: "psp-restricted"},
+	}
+	AnalyzeRemovals(context.Background(), results, objects, nil, &Config{})
+
+	remediation := results[0].Remediation
+	if assert.NotNil(t, remediation) {
+		assert.Equal(t, AnalyzerPodSecurityPolicy, remediation.Analyzer)
+		assert.Contains(t, remediation.Summary, "restricted Pod Security Standard")
+		assert.Contains(t, remediation.Steps, "kubectl label --overwrite namespace shop pod-security.kubernetes.io/enforce=restricted")
+		assert.Contains(t, remediation.Steps, "namespace web already enforces the baseline level")
+		assert.Equal(t, []ObjectRef{
+			{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding", Name: "psp-web"},
+			{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "RoleBinding", Namespace: "shop", Name: "psp"},
+		}, remediation.Related)
+	}
+	if assert.NotNil(t, results[1].Remediation) {
+		assert.Contains(t, results[1].Remediation.Summary, "privileged Pod Security Standard")
+		assert.Equal(t, []string{"no binding grants use of the policy, delete it: kubectl delete podsecuritypolicy unused"}, results[1].Remediation.Steps)
+	}
+	assert.Nil(t, results[2].Remediation)
+
+	results[0].Remediation = nil
+	AnalyzeRemovals(context.Background(), results[:1], objects, nil, &Config{DisableAnalyzers: []string{AnalyzerPodSecurityPolicy}})
+	assert.Nil(t, results[0].Remediation)
+}
+
+func TestAnalyzeRemovals_listsRelatedObjects(t *testing.T) {
+	server := newFakeApiServer(t)
+	server.addResource(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRole"}, "clusterroles", false)
+	server.addResource(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "Role"}, "roles", true)
+	server.addResource(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "ClusterRoleBinding"}, "clusterrolebindings", false)
+	bindings := server.addResource(schema.GroupVersionKind{Group: "rbac.authorization.k8s.io", Version: "v1", Kind: "RoleBinding"}, "rolebindings", true)
+	server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, "namespaces", false)
+	server.addObject(bindings, newFakeBinding("RoleBinding", "shop", "psp", "ClusterRole", "psp-all"))
+	role := newFakeObject("rbac.authorization.k8s.io/v1", "ClusterRole", "", "psp-all")
+	role["rules"] = []interface{}{map[string]interface{}{
+		"apiGroups": []interface{}{"*"}, "resources": []interface{}{"*"}, "verbs": []interface{}{"*"},
+	}}
+	objects := []unstructured.Unstructured{{Object: newFakePodSecurityPolicy("default", map[string]interface{}{})}, {Object: role}}
+	results := []ValidationResult{{Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1", ResourceName: "default", Deleted: true}}
+
+	// the selectors of the scan do not apply to related objects
+	AnalyzeRemovals(context.Background(), results, objects, server.cluster(t), &Config{LabelSelector: "app=web"})
+	if assert.NotNil(t, results[0].Remediation) {
+		assert.Contains(t, results[0].Remediation.Steps, "kubectl label --overwrite namespace shop pod-security.kubernetes.io/enforce=baseline")
+	}
+}
+
+func TestAnalyzeRemovals_componentStatusAndFlowControl(t *testing.T) {
+	level := newFakeObject("flowcontrol.apiserver.k8s.io/v1beta2", "PriorityLevelConfiguration", "", "batch")
+	level["spec"] = map[string]interface{}{"type": "Limited", "limited": map[string]interface{}{"assuredConcurrencyShares": int64(5)}}
+	flowSchema := newFakeObject("flowcontrol.apiserver.k8s.io/v1beta2", "FlowSchema", "", "batch-jobs")
+	flowSchema["spec"] = map[string]interface{}{"priorityLevelConfiguration": map[string]interface{}{"name": "batch"}}
+	exempt := withAnnotation(newFakeObject("flowcontrol.apiserver.k8s.io/v1beta2", "FlowSchema", "", "exempt"), flowControlAutoUpdateAnnotation, "true")
+	objects := []unstructured.Unstructured{
+		{Object: newFakeObject("v1", "ComponentStatus", "", "scheduler")}, {Object: level}, {Object: flowSchema}, {Object: exempt},
+	}
+	results := []ValidationResult{
+		{Kind: "ComponentStatus", APIVersion: "v1", ResourceName: "scheduler", Deprecated: true},
+		{Kind: "PriorityLevelConfiguration", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2", ResourceName: "batch", Deleted: true},
+		{Kind: "FlowSchema", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2", ResourceName: "batch-jobs", Deleted: true},
+		{Kind: "FlowSchema", APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2", ResourceName: "exempt", Deleted: true},
+	}
+	AnalyzeRemovals(context.Background(), results, objects, nil, &Config{})
+
+	assert.Equal(t, AnalyzerComponentStatus, results[0].Remediation.Analyzer)
+	assert.Contains(t, results[0].Remediation.Steps[0], ":10259/livez")
+	assert.Equal(t, []string{
+		"rename spec.limited.assuredConcurrencyShares to spec.limited.nominalConcurrencyShares: 5",
+		"migrate the flow schemas referencing the priority level along with it",
+	}, results[1].Remediation.Steps)
+	assert.Equal(t, []ObjectRef{{APIVersion: "flowcontrol.apiserver.k8s.io/v1beta2", Kind: "FlowSchema", Name: "batch-jobs"}}, results[1].Remediation.Related)
+	assert.Equal(t, []string{"migrate priority level batch along with the flow schema"}, results[2].Remediation.Steps)
+	assert.Contains(t, results[3].Remediation.Summary, "maintained by the api server")
+	assert.Empty(t, results[3].Remediation.Steps)
+}
+
+func TestConfig_Validate_disableAnalyzers(t *testing.T) {
+	assert.NoError(t, (&Config{DisableAnalyzers: []string{AnalyzerFlowControl}}).Validate())
+	assert.ErrorContains(t, (&Config{DisableAnalyzers: []string{"psp"}}).Validate(), `unknown analyzer "psp"`)
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RemovedComponentFlag is a command-line flag of a kubernetes component which is no longer accepted from RemovedIn
+type RemovedComponentFlag struct {
+	Component string
+	Flag      string
+	RemovedIn string
+}
+
+// DefaultRemovedComponentFlags lists well known flags of kubernetes components removed in past releases,
+// used unless Config.RemovedComponentFlags is set
+var DefaultRemovedComponentFlags = []RemovedComponentFlag{
+	{Component: "kube-apiserver", Flag: "--kubelet-https", RemovedIn: "1.22"},
+	{Component: "kube-apiserver", Flag: "--insecure-port", RemovedIn: "1.24"},
+	{Component: "kube-apiserver", Flag: "--insecure-bind-address", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--network-plugin", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--network-plugin-mtu", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--cni-bin-dir", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--cni-conf-dir", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--cni-cache-dir", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--docker-endpoint", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--dynamic-config-dir", RemovedIn: "1.24"},
+	{Component: "kubelet", Flag: "--container-runtime", RemovedIn: "1.27"},
+}
+
+// CheckRemovedComponentFlags adds a deprecation for every container argument or command of a workload passing a
+// component flag removed in the target version, against the latest api version for removed api versions and
+// against the current one otherwise. Flags are matched on the raw strings so the findings are of low confidence,
+// eg an argument of an unrelated binary may share the name.
+func CheckRemovedComponentFlags(result ValidationResult, object map[string]interface{}, conf *Config) ValidationResult {
+	flags := conf.RemovedComponentFlags
+	if flags == nil {
+		flags = DefaultRemovedComponentFlags
+	}
+	var removed []RemovedComponentFlag
+	for _, flag := range flags {
+		if ok, err := isVersionAtLeast(conf.TargetKubernetesVersion, flag.RemovedIn); err == nil && ok {
+			removed = append(removed, flag)
+		}
+	}
+	if len(removed) == 0 {
+		return result
+	}
+	walkContainers(object, func(path []string, container map[string]interface{}) {
+		for _, field := range []string{"command", "args"} {
+			values, ok := container[field].([]interface{})
+			if !ok {
+				continue
+			}
+			for i, v := range values {
+				arg, ok := v.(string)
+				if !ok {
+					continue
+				}
+				for _, token := range strings.Fields(arg) {
+					flag, ok := matchRemovedFlag(token, removed)
+					if !ok {
+						continue
+					}
+					argPath := append(append([]string(nil), path...), field, strconv.Itoa(i))
+					deprecation := &SchemaError{
+						Value:       arg,
+						reversePath: reversePath(argPath),
+						Reason: fmt.Sprintf("%s flag %s is removed in kubernetes %s (low confidence, matched in container %s)",
+							flag.Component, flag.Flag, flag.RemovedIn, field),
+					}
+					if result.Deleted {
+						result.DeprecationForLatest = append(result.DeprecationForLatest, deprecation)
+					} else {
+						result.DeprecationForOriginal = append(result.DeprecationForOriginal, deprecation)
+					}
+				}
+			}
+		}
+	})
+	return result
+}
+
+func matchRemovedFlag(token string, flags []RemovedComponentFlag) (RemovedComponentFlag, bool) {
+	name := strings.SplitN(token, "=", 2)[0]
+	for _, flag := range flags {
+		if name == flag.Flag {
+			return flag, true
+		}
+	}
+	return RemovedComponentFlag{}, false
+}
+
+func reversePath(path []string) []string {
+	reversed := make([]string, len(path))
+	for i, p := range path {
+		reversed[len(path)-1-i] = p
+	}
+	return reversed
+}
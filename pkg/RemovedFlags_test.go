@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFakeDaemonSet(command []interface{}, args []interface{}) map[string]interface{} {
+	obj := newFakeObject("apps/v1", "DaemonSet", "kube-system", "kubelet")
+	obj["spec"] = map[string]interface{}{
+		"template": map[string]interface{}{
+			"spec": map[string]interface{}{
+				"containers": []interface{}{
+					map[string]interface{}{"name": "kubelet", "command": command, "args": args},
+				},
+			},
+		},
+	}
+	return obj
+}
+
+func TestCheckRemovedComponentFlags(t *testing.T) {
+	daemonSet := newFakeDaemonSet(
+		[]interface{}{"/bin/sh", "-c", "kubelet --network-plugin=cni --v=2"},
+		[]interface{}{"--container-runtime", "remote", "--container-runtime-endpoint=unix:///run/containerd.sock"},
+	)
+	tests := []struct {
+		name          string
+		object        map[string]interface{}
+		result        ValidationResult
+		conf          *Config
+		wantOriginal  []string
+		wantLatestLen int
+	}{
+		{
+			name:         "flags removed up to target version",
+			object:       daemonSet,
+			conf:         &Config{TargetKubernetesVersion: "1.27"},
+			wantOriginal: []string{"spec/template/spec/containers/0/command/2", "spec/template/spec/containers/0/args/0"},
+		},
+		{
+			name:         "flags removed after target version are ignored",
+			object:       daemonSet,
+			conf:         &Config{TargetKubernetesVersion: "1.25"},
+			wantOriginal: []string{"spec/template/spec/containers/0/command/2"},
+		},
+		{
+			name:          "removed api version reports against latest",
+			object:        daemonSet,
+			result:        ValidationResult{Deleted: true},
+			conf:          &Config{TargetKubernetesVersion: "1.24"},
+			wantLatestLen: 1,
+		},
+		{
+			name:   "configured flags replace the defaults",
+			object: daemonSet,
+			conf: &Config{
+				TargetKubernetesVersion: "1.30",
+				RemovedComponentFlags:   []RemovedComponentFlag{{Component: "kubelet", Flag: "--v", RemovedIn: "1.30"}},
+			},
+			wantOriginal: []string{"spec/template/spec/containers/0/command/2"},
+		},
+		{
+			name:   "objects without pod spec",
+			object: newFakeObject("v1", "ConfigMap", "shop", "cart"),
+			conf:   &Config{TargetKubernetesVersion: "1.27"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := CheckRemovedComponentFlags(tt.result, tt.object, tt.conf)
+			var got []string
+			for _, e := range result.DeprecationForOriginal {
+				got = append(got, strings.Join(e.JSONPointer(), "/"))
+				assert.Contains(t, e.Reason, "low confidence")
+			}
+			assert.Equal(t, tt.wantOriginal, got)
+			assert.Len(t, result.DeprecationForLatest, tt.wantLatestLen)
+		})
+	}
+}
+
+func TestIsVersionAtLeast(t *testing.T) {
+	for _, tt := range []struct {
+		version, min string
+		want         bool
+	}{
+		{version: "1.24", min: "1.24", want: true},
+		{version: "1.23", min: "1.24", want: false},
+		{version: "v1.27.3", min: "1.24", want: true},
+		{version: "master", min: "1.40", want: true},
+	} {
+		got, err := isVersionAtLeast(tt.version, tt.min)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, got, tt.version)
+	}
+}
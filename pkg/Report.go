@@ -0,0 +1,318 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ReportSchemaVersion is the version of the schema of Report. Fields may be added within a version, it changes when
+// fields are removed, renamed or change meaning
+const ReportSchemaVersion = "v1"
+
+// Report is the document of the json output, a single document for the whole run whichever clusters, dumps or
+// files were scanned and against however many target versions
+type Report struct {
+	SchemaVersion string         `json:"schemaVersion"`
+	Metadata      ReportMetadata `json:"metadata"`
+	// Findings are the findings of all scans in the order of their results, see SortResults
+	Findings []Finding `json:"findings"`
+	// FetchSummaries are the summaries of the scanned clusters and cluster dumps, one per cluster
+	FetchSummaries []ClusterFetchSummary `json:"fetchSummaries"`
+	// Suppressed are the findings accepted by Config.Suppressions, they are left out of Findings and Counts
+	Suppressed []SuppressedReportFinding `json:"suppressed"`
+	Counts     ReportCounts              `json:"counts"`
+}
+
+// ReportMetadata describes the run the report is about
+type ReportMetadata struct {
+	// ToolVersion is the version of silver-surfer, see BuildVersion
+	ToolVersion string    `json:"toolVersion"`
+	Timestamp   time.Time `json:"timestamp"`
+	// Scans are the scans of the run, one per cluster, cluster dump or set of files and target version
+	Scans []ScanMetadata `json:"scans"`
+}
+
+// ScanMetadata describes a scan of the objects of a cluster, cluster dump or files against a target version
+type ScanMetadata struct {
+	// Cluster is the name of the scanned cluster or the path of the cluster dump, empty for files
+	Cluster string `json:"cluster"`
+	// SourceVersion is the version of the scanned cluster or else the given source version, empty when unknown
+	SourceVersion string `json:"sourceVersion"`
+	TargetVersion string `json:"targetVersion"`
+}
+
+// Finding is a single finding of an object, a result has a finding per rule and field, see Findings
+type Finding struct {
+	// Cluster and TargetVersion are those of the scan the finding belongs to, see ScanMetadata
+	Cluster       string `json:"cluster"`
+	TargetVersion string `json:"targetVersion"`
+	// File and Document locate the object among the scanned files, see ValidationResult.Location
+	File     string `json:"file,omitempty"`
+	Document int    `json:"document,omitempty"`
+	Kind     string `json:"kind"`
+	// Group is empty for the core api group
+	Group     string `json:"group"`
+	Version   string `json:"version"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Rule is one of the rules of Config.SeverityOverrides or the id of a custom rule
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	// Field is the path of the field the finding is about, empty for findings about the whole object
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+	// SuggestedAPIVersion is the api version to migrate the object to, empty when it need not or cannot be migrated
+	SuggestedAPIVersion string `json:"suggestedApiVersion,omitempty"`
+	// FieldMigrations and Remediation say how to migrate the object, they are set on the findings of its removed or
+	// deprecated api version
+	FieldMigrations []ReportFieldMigration `json:"fieldMigrations,omitempty"`
+	Remediation     *ReportRemediation     `json:"remediation,omitempty"`
+	// RootOwner is the top-level controller of the object as Kind/name, see Config.AnnotateRootOwner
+	RootOwner string `json:"rootOwner,omitempty"`
+	// ValidatedFrom is the source the object was validated from with Config.UseLastApplied, along with
+	// ValidatedFromNote saying why the last applied configuration could not be used
+	ValidatedFrom     string `json:"validatedFrom,omitempty"`
+	ValidatedFromNote string `json:"validatedFromNote,omitempty"`
+	// ServerWarnings are the warnings the api server returned for the kind and api version of the object
+	ServerWarnings []string `json:"serverWarnings,omitempty"`
+}
+
+// ReportFieldMigration is a field to change to move an object to its suggested api version, see FieldMigration
+type ReportFieldMigration struct {
+	Path        string `json:"path"`
+	Replacement string `json:"replacement,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+// ReportRemediation is the next step for an object of a removed kind with a known migration, see Remediation
+type ReportRemediation struct {
+	Analyzer string   `json:"analyzer"`
+	Summary  string   `json:"summary"`
+	Steps    []string `json:"steps,omitempty"`
+	// Related are the objects the steps are about
+	Related []ReportObjectRef `json:"related,omitempty"`
+}
+
+// ReportObjectRef is an object a remediation is about, see ObjectRef
+type ReportObjectRef struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+// SuppressedReportFinding is a finding of an object accepted by a suppression along with its justification, see
+// SuppressedFinding
+type SuppressedReportFinding struct {
+	Cluster       string `json:"cluster"`
+	TargetVersion string `json:"targetVersion"`
+	File          string `json:"file,omitempty"`
+	Document      int    `json:"document,omitempty"`
+	Kind          string `json:"kind"`
+	Group         string `json:"group"`
+	Version       string `json:"version"`
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	// Finding is the suppressed finding as named in the suppressions file
+	Finding string `json:"finding"`
+	Owner   string `json:"owner,omitempty"`
+	Comment string `json:"comment,omitempty"`
+	Expires string `json:"expires,omitempty"`
+}
+
+// ClusterFetchSummary is the fetch summary of a cluster or cluster dump, see FetchSummary
+type ClusterFetchSummary struct {
+	Cluster string `json:"cluster"`
+	*FetchSummary
+}
+
+// ReportCounts are the aggregate counts of the report
+type ReportCounts struct {
+	// Objects is the number of validated objects, with or without findings
+	Objects             int `json:"objects"`
+	ObjectsWithFindings int `json:"objectsWithFindings"`
+	Findings            int `json:"findings"`
+	// BySeverity and ByRule are the number of findings of each severity and rule, those without any are left out
+	BySeverity map[string]int `json:"bySeverity"`
+	ByRule     map[string]int `json:"byRule"`
+}
+
+// NewReport returns an empty report of a run started at timestamp
+func NewReport(timestamp time.Time) *Report {
+	return &Report{
+		SchemaVersion:  ReportSchemaVersion,
+		Metadata:       ReportMetadata{ToolVersion: BuildVersion, Timestamp: timestamp.UTC().Truncate(time.Second), Scans: []ScanMetadata{}},
+		Findings:       []Finding{},
+		FetchSummaries: []ClusterFetchSummary{},
+		Suppressed:     []SuppressedReportFinding{},
+		Counts:         ReportCounts{BySeverity: map[string]int{}, ByRule: map[string]int{}},
+	}
+}
+
+// AddScan adds the scan along with the findings of its results to the report, the severities of the findings are
+// those of conf. A nil summary is not added, as when a cluster is scanned against several target versions its
+// summary is added with a single one of them
+func (r *Report) AddScan(scan ScanMetadata, results []ValidationResult, summary *FetchSummary, conf *Config) {
+	r.Metadata.Scans = append(r.Metadata.Scans, scan)
+	if summary != nil {
+		r.FetchSummaries = append(r.FetchSummaries, ClusterFetchSummary{Cluster: scan.Cluster, FetchSummary: summary})
+	}
+	r.addResults(scan, results, conf)
+}
+
+// addResults adds the findings of results to the report as findings of scan
+func (r *Report) addResults(scan ScanMetadata, results []ValidationResult, conf *Config) {
+	for _, vr := range results {
+		if len(vr.Kind) == 0 {
+			continue
+		}
+		r.Counts.Objects++
+		findings := Findings(vr, conf)
+		if len(findings) > 0 {
+			r.Counts.ObjectsWithFindings++
+		}
+		for _, f := range findings {
+			f.Cluster, f.TargetVersion = scan.Cluster, scan.TargetVersion
+			r.Findings = append(r.Findings, f)
+			r.Counts.Findings++
+			r.Counts.BySeverity[f.Severity]++
+			r.Counts.ByRule[f.Rule]++
+		}
+		r.Suppressed = append(r.Suppressed, suppressedFindings(scan, vr)...)
+	}
+}
+
+// suppressedFindings returns the findings of vr accepted by suppressions as suppressed findings of scan
+func suppressedFindings(scan ScanMetadata, vr ValidationResult) []SuppressedReportFinding {
+	gv, _ := schema.ParseGroupVersion(vr.APIVersion)
+	var suppressed []SuppressedReportFinding
+	for _, f := range vr.SuppressedFindings {
+		suppressed = append(suppressed, SuppressedReportFinding{
+			Cluster:       scan.Cluster,
+			TargetVersion: scan.TargetVersion,
+			File:          vr.FileName,
+			Document:      vr.DocumentIndex,
+			Kind:          vr.Kind,
+			Group:         gv.Group,
+			Version:       gv.Version,
+			Namespace:     vr.ResourceNamespace,
+			Name:          vr.ResourceName,
+			Finding:       f.Finding,
+			Owner:         f.Owner,
+			Comment:       f.Comment,
+			Expires:       f.Expires,
+		})
+	}
+	return suppressed
+}
+
+// Findings returns the findings of vr, one per rule and field, with the severity conf gives their rule. The findings
+// of kinds downgraded to info are info, see ResultSeverity
+func Findings(vr ValidationResult, conf *Config) []Finding {
+	gv, _ := schema.ParseGroupVersion(vr.APIVersion)
+	informational := IsInformational(vr, conf)
+	var findings []Finding
+	add := func(rule, severity, field, message string) *Finding {
+		if informational {
+			severity = SeverityInfo
+		}
+		findings = append(findings, Finding{
+			File:                vr.FileName,
+			Document:            vr.DocumentIndex,
+			Kind:                vr.Kind,
+			Group:               gv.Group,
+			Version:             gv.Version,
+			Namespace:           vr.ResourceNamespace,
+			Name:                vr.ResourceName,
+			Rule:                rule,
+			Severity:            severity,
+			Field:               field,
+			Message:             message,
+			SuggestedAPIVersion: vr.LatestAPIVersion,
+			RootOwner:           vr.RootOwner,
+			ValidatedFrom:       vr.ValidatedFrom,
+			ValidatedFromNote:   vr.ValidatedFromNote,
+			ServerWarnings:      vr.ServerWarnings,
+		})
+		return &findings[len(findings)-1]
+	}
+	addAPIVersion := func(rule, message string) {
+		f := add(rule, conf.ruleSeverity(rule), "", message)
+		f.FieldMigrations, f.Remediation = reportFieldMigrations(vr.FieldMigrations), reportRemediation(vr.Remediation)
+	}
+	if vr.AlreadyRemoved {
+		addAPIVersion(RuleAlreadyRemovedAPI, apiVersionMessage(vr, "already removed in the source version"))
+	} else if vr.Deleted {
+		addAPIVersion(RuleRemovedAPI, apiVersionMessage(vr, "removed"))
+	}
+	if vr.Deprecated {
+		addAPIVersion(RuleDeprecatedAPI, apiVersionMessage(vr, "deprecated"))
+	}
+	for _, e := range vr.ErrorsForOriginal {
+		add(RuleSchemaError, conf.ruleSeverity(RuleSchemaError), strings.Join(e.JSONPointer(), "/"), e.Reason)
+	}
+	for _, e := range vr.ErrorsForLatest {
+		add(RuleSchemaError, conf.ruleSeverity(RuleSchemaError), strings.Join(e.JSONPointer(), "/"), fmt.Sprintf("invalid in %s: %s", vr.LatestAPIVersion, e.Reason))
+	}
+	for _, e := range vr.DeprecationForOriginal {
+		add(RuleDeprecatedField, conf.ruleSeverity(RuleDeprecatedField), strings.Join(e.JSONPointer(), "/"), e.Reason)
+	}
+	for _, e := range vr.DeprecationForLatest {
+		add(RuleDeprecatedField, conf.ruleSeverity(RuleDeprecatedField), strings.Join(e.JSONPointer(), "/"), fmt.Sprintf("deprecated in %s: %s", vr.LatestAPIVersion, e.Reason))
+	}
+	for _, path := range vr.UnknownFields {
+		add(RuleUnknownField, conf.ruleSeverity(RuleUnknownField), path, fmt.Sprintf("the field is not defined by the schema of %s", vr.APIVersion))
+	}
+	if len(vr.DryRunRejection) > 0 {
+		add(RuleDryRunRejected, conf.ruleSeverity(RuleDryRunRejected), "", vr.DryRunRejection)
+	}
+	for _, d := range vr.PodFieldDeprecations {
+		add(RuleDeprecatedPodField, conf.ruleSeverity(RuleDeprecatedPodField), d.Path, fieldMigrationMessage(d))
+	}
+	for _, f := range vr.CustomRuleFindings {
+		add(f.Rule, f.Severity, f.Path, f.Message)
+	}
+	return findings
+}
+
+// apiVersionMessage says the api version of vr is removed or deprecated, and what to migrate to along with the
+// remediation of the result when it has one
+func apiVersionMessage(vr ValidationResult, state string) string {
+	message := fmt.Sprintf("%s %s is %s, migrate to %s", vr.APIVersion, vr.Kind, state, vr.LatestAPIVersion)
+	if len(vr.LatestAPIVersion) == 0 {
+		message = fmt.Sprintf("%s %s is %s without a replacement", vr.APIVersion, vr.Kind, state)
+	}
+	if vr.Remediation != nil {
+		message += ": " + vr.Remediation.Summary
+	}
+	return message
+}
+
+func reportFieldMigrations(migrations []FieldMigration) []ReportFieldMigration {
+	var report []ReportFieldMigration
+	for _, m := range migrations {
+		report = append(report, ReportFieldMigration{Path: m.Path, Replacement: m.Replacement, Reason: m.Reason})
+	}
+	return report
+}
+
+func reportRemediation(remediation *Remediation) *ReportRemediation {
+	if remediation == nil {
+		return nil
+	}
+	report := &ReportRemediation{Analyzer: remediation.Analyzer, Summary: remediation.Summary, Steps: remediation.Steps}
+	for _, o := range remediation.Related {
+		report.Related = append(report.Related, ReportObjectRef{APIVersion: o.APIVersion, Kind: o.Kind, Namespace: o.Namespace, Name: o.Name})
+	}
+	return report
+}
+
+func fieldMigrationMessage(m FieldMigration) string {
+	if len(m.Replacement) > 0 {
+		return fmt.Sprintf("replace with %s", m.Replacement)
+	}
+	return m.Reason
+}
@@ -0,0 +1,188 @@
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/yaml"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of testdata")
+
+// invalidField returns the schema error of field being a string instead of an integer
+func invalidField(field string) *openapi3.SchemaError {
+	err := openapi3.NewObjectSchema().WithProperty(field, openapi3.NewIntegerSchema()).VisitJSON(map[string]interface{}{field: "two"})
+	schemaErr, _ := err.(*openapi3.SchemaError)
+	return schemaErr
+}
+
+// deprecatedField returns the deprecation of the field at path
+func deprecatedField(reason string, path ...string) *SchemaError {
+	var err error = &SchemaError{Reason: reason}
+	for i := len(path) - 1; i >= 0; i-- {
+		err = markSchemaErrorKey(err, path[i])
+	}
+	return err.(*SchemaError)
+}
+
+// goldenReportResults are results with every kind of finding along with an object without any
+func goldenReportResults() []ValidationResult {
+	return []ValidationResult{
+		{
+			Kind: "ConfigMap", APIVersion: "v1", ResourceNamespace: "shop", ResourceName: "settings",
+			ValidatedAgainstSchema: true,
+		},
+		{
+			Kind: "Deployment", APIVersion: "apps/v1", ResourceNamespace: "shop", ResourceName: "cart",
+			ErrorsForOriginal:    []*openapi3.SchemaError{invalidField("replicas")},
+			UnknownFields:        []string{"spec/template/spec/serviceAccount"},
+			PodFieldDeprecations: []FieldMigration{{Path: "spec/template/spec/serviceAccount", Replacement: "spec/template/spec/serviceAccountName"}},
+			CustomRuleFindings:   []CustomRuleFinding{{Rule: "require-limits", Severity: SeverityError, Path: "spec/template/spec/containers/0/resources", Message: "containers must set limits"}},
+			DryRunRejection:      "admission webhook denied the request",
+			RootOwner:            "Rollout/cart",
+			ValidatedFrom:        SourceLive,
+			ValidatedFromNote:    "the last applied configuration is missing",
+		},
+		{
+			Kind: "Ingress", APIVersion: "networking.k8s.io/v1beta1", ResourceNamespace: "shop", ResourceName: "storefront",
+			Deleted: true, LatestAPIVersion: "networking.k8s.io/v1",
+			ErrorsForLatest:      []*openapi3.SchemaError{invalidField("backend")},
+			DeprecationForLatest: []*SchemaError{deprecatedField("use spec.ingressClassName instead", "metadata", "annotations", "kubernetes.io~1ingress.class")},
+			FieldMigrations:      []FieldMigration{{Path: "spec/backend", Replacement: "spec/defaultBackend", Reason: "field backend does not exist in the latest api version"}},
+			ServerWarnings:       []string{"networking.k8s.io/v1beta1 Ingress is deprecated in v1.19+, unavailable in v1.22+; use networking.k8s.io/v1 Ingress"},
+			SuppressedFindings:   []SuppressedFinding{{Finding: FindingDeprecated, Owner: "web-team", Comment: "migrated with the next release", Expires: "2024-06-30"}},
+		},
+		{
+			Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1", ResourceName: "restricted",
+			Deleted:     true,
+			Remediation: &Remediation{
+				Analyzer: "psp", Summary: "replace with Pod Security Admission",
+				Steps:   []string{"label the namespaces using the policy with pod-security.kubernetes.io/enforce=restricted"},
+				Related: []ObjectRef{{APIVersion: "rbac.authorization.k8s.io/v1", Kind: "ClusterRoleBinding", Name: "psp-restricted"}},
+			},
+		},
+		{
+			Kind: "CronJob", APIVersion: "batch/v1beta1", ResourceNamespace: "ops", ResourceName: "backup",
+			Deprecated: true, LatestAPIVersion: "batch/v1",
+			DeprecationForOriginal: []*SchemaError{deprecatedField("deprecated field", "spec", "legacy")},
+		},
+	}
+}
+
+// putGoldenScans puts the scans of two clusters, one of them against two target versions, and of files to output
+// and flushes it
+func putGoldenScans(t *testing.T, output *jsonOutputManager, flush func() error) {
+	output.report = NewReport(time.Date(2024, 5, 1, 12, 30, 0, 0, time.FixedZone("CEST", 2*60*60)))
+	conf := &Config{SeverityOverrides: map[string]string{RuleUnknownField: SeverityWarning}, DowngradeToInfo: []string{"CronJob"}}
+	summary := &FetchSummary{
+		Listed:    12,
+		Skipped:   []SkippedResource{{Resource: "widgets.example.com", Failure: FetchTimeout, Message: "the list timed out", Retries: 2}},
+		Forbidden: []SkippedResource{{Resource: "secrets", Failure: FetchForbidden, Message: "secrets is forbidden"}},
+	}
+	assert.NoError(t, output.PutScan(ScanMetadata{Cluster: "prod", SourceVersion: "1.24.17", TargetVersion: "1.25"}, goldenReportResults(), summary, conf))
+	assert.NoError(t, output.PutScan(ScanMetadata{Cluster: "prod", SourceVersion: "1.24.17", TargetVersion: "1.26"}, goldenReportResults()[3:4], nil, conf))
+	assert.NoError(t, output.PutScan(ScanMetadata{Cluster: "staging", SourceVersion: "1.25.3", TargetVersion: "1.26"}, goldenReportResults()[1:3], &FetchSummary{Listed: 9}, conf))
+	files := []ValidationResult{{FileName: "manifests.yaml", DocumentIndex: 2, Kind: "CronJob", APIVersion: "batch/v1beta1", ResourceName: "report", Deleted: true, LatestAPIVersion: "batch/v1"}}
+	assert.NoError(t, output.PutScan(ScanMetadata{TargetVersion: "1.25"}, files, nil, nil))
+	assert.NoError(t, flush())
+}
+
+// TestReport_golden locks the schema of the json output, run the tests with -update to change testdata after a
+// deliberate change of the schema, which must bump ReportSchemaVersion unless fields are only added
+func TestReport_golden(t *testing.T) {
+	defer func(version string) { BuildVersion = version }(BuildVersion)
+	BuildVersion = "v1.2.3"
+
+	buf := &bytes.Buffer{}
+	output := newJSONOutputManager(log.New(buf, "", 0))
+	putGoldenScans(t, output, output.Flush)
+
+	golden := filepath.Join("testdata", "report.golden.json")
+	if *updateGolden {
+		assert.NoError(t, os.WriteFile(golden, buf.Bytes(), 0644))
+	}
+	want, err := os.ReadFile(golden)
+	assert.NoError(t, err)
+	assert.Equal(t, string(want), buf.String())
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &report))
+	assert.Equal(t, ReportSchemaVersion, report.SchemaVersion)
+	assert.Equal(t, report.Counts.Findings, len(report.Findings))
+	// the suppressed findings are listed once per scan of the object and left out of the findings
+	assert.Len(t, report.Suppressed, 2)
+	assert.Equal(t, FindingDeprecated, report.Suppressed[0].Finding)
+	assert.Equal(t, "web-team", report.Suppressed[0].Owner)
+	// the results with findings are kept for the grpc service
+	assert.Len(t, output.GetSummaryValidationResultBulk(), 8)
+}
+
+// TestReport_yamlRoundTrip reads back the yaml output as the report the json output is read back as, which is the
+// report the results were put to
+func TestReport_yamlRoundTrip(t *testing.T) {
+	jsonBuf := &bytes.Buffer{}
+	jsonOutput := newJSONOutputManager(log.New(jsonBuf, "", 0))
+	putGoldenScans(t, jsonOutput, jsonOutput.Flush)
+	var fromJSON Report
+	assert.NoError(t, json.Unmarshal(jsonBuf.Bytes(), &fromJSON))
+
+	yamlBuf := &bytes.Buffer{}
+	yamlOutput := newYAMLOutputManager(log.New(yamlBuf, "", 0))
+	putGoldenScans(t, yamlOutput.jsonOutputManager, yamlOutput.Flush)
+	var fromYAML Report
+	assert.NoError(t, yaml.UnmarshalStrict(yamlBuf.Bytes(), &fromYAML))
+
+	assert.Equal(t, *jsonOutput.report, fromJSON)
+	assert.Equal(t, fromJSON, fromYAML)
+	assert.Contains(t, yamlBuf.String(), "schemaVersion: v1\n")
+	// a single document whose keys are sorted for two scans to diff line by line
+	assert.NotContains(t, yamlBuf.String(), "\n---")
+	assert.True(t, strings.HasPrefix(yamlBuf.String(), "counts:\n"))
+	assert.Less(t, strings.Index(yamlBuf.String(), "\n  kind:"), strings.Index(yamlBuf.String(), "\n  name:"))
+	assert.Len(t, fromYAML.FetchSummaries, 2)
+	assert.Equal(t, "staging", fromYAML.FetchSummaries[1].Cluster)
+
+	again := &bytes.Buffer{}
+	yamlOutput = newYAMLOutputManager(log.New(again, "", 0))
+	putGoldenScans(t, yamlOutput.jsonOutputManager, yamlOutput.Flush)
+	assert.Equal(t, yamlBuf.String(), again.String())
+}
+
+func TestFindings(t *testing.T) {
+	vr := ValidationResult{Kind: "Ingress", APIVersion: "extensions/v1beta1", ResourceNamespace: "shop", ResourceName: "storefront", Deleted: true}
+	findings := Findings(vr, nil)
+	assert.Equal(t, []Finding{{
+		Kind: "Ingress", Group: "extensions", Version: "v1beta1", Namespace: "shop", Name: "storefront",
+		Rule: RuleRemovedAPI, Severity: SeverityError, Message: "extensions/v1beta1 Ingress is removed without a replacement",
+	}}, findings)
+
+	assert.Equal(t, SeverityInfo, Findings(vr, &Config{DowngradeToInfo: []string{"Ingress"}})[0].Severity)
+	assert.Equal(t, SeverityWarning, Findings(vr, &Config{SeverityOverrides: map[string]string{RuleRemovedAPI: "Warning"}})[0].Severity)
+	assert.Empty(t, Findings(ValidationResult{Kind: "ConfigMap", APIVersion: "v1"}, nil))
+
+	// how to migrate the object is told by the findings of its api version, where it was validated from by all of them
+	vr.FieldMigrations = []FieldMigration{{Path: "spec/backend", Replacement: "spec/defaultBackend"}}
+	vr.Remediation = &Remediation{Analyzer: "ingress", Summary: "migrate to networking.k8s.io/v1", Steps: []string{"set spec.ingressClassName"}}
+	vr.UnknownFields = []string{"spec/backend"}
+	vr.ValidatedFrom, vr.RootOwner = SourceLastApplied, "Application/shop"
+	findings = Findings(vr, nil)
+	if assert.Len(t, findings, 2) {
+		assert.Equal(t, []ReportFieldMigration{{Path: "spec/backend", Replacement: "spec/defaultBackend"}}, findings[0].FieldMigrations)
+		assert.Equal(t, &ReportRemediation{Analyzer: "ingress", Summary: "migrate to networking.k8s.io/v1", Steps: []string{"set spec.ingressClassName"}}, findings[0].Remediation)
+		assert.Empty(t, findings[1].FieldMigrations)
+		assert.Nil(t, findings[1].Remediation)
+		for _, f := range findings {
+			assert.Equal(t, SourceLastApplied, f.ValidatedFrom)
+			assert.Equal(t, "Application/shop", f.RootOwner)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package pkg
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// SortResults orders results by cluster, or file for manifests, namespace, api group, kind and name, and the schema
+// errors of every result by the path of their field. Objects are fetched, discovered and validated in no particular
+// order, results are sorted once they are final for two scans of the same objects to be reported the same
+func SortResults(results []ValidationResult) {
+	for i := range results {
+		sortSchemaErrors(results[i].ErrorsForOriginal)
+		sortSchemaErrors(results[i].ErrorsForLatest)
+		sortDeprecations(results[i].DeprecationForOriginal)
+		sortDeprecations(results[i].DeprecationForLatest)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return lessKeys(resultKey(results[i]), resultKey(results[j]))
+	})
+}
+
+// resultKey is what results are sorted by, see SortResults
+func resultKey(vr ValidationResult) []string {
+	gv, _ := schema.ParseGroupVersion(vr.APIVersion)
+	return []string{vr.Cluster, vr.FileName, vr.ResourceNamespace, gv.Group, vr.Kind, vr.ResourceName, gv.Version}
+}
+
+// lessKeys compares the keys a and b of the same length field by field
+func lessKeys(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+func sortSchemaErrors(errs []*openapi3.SchemaError) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		return lessKeys(
+			[]string{strings.Join(errs[i].JSONPointer(), "/"), errs[i].Reason},
+			[]string{strings.Join(errs[j].JSONPointer(), "/"), errs[j].Reason})
+	})
+}
+
+func sortDeprecations(errs []*SchemaError) {
+	sort.SliceStable(errs, func(i, j int) bool {
+		return lessKeys(
+			[]string{strings.Join(errs[i].JSONPointer(), "/"), errs[i].Reason},
+			[]string{strings.Join(errs[j].JSONPointer(), "/"), errs[j].Reason})
+	})
+}
+
+// Sort orders the resources and objects of the summary by name, they are added as resources are listed
+func (s *FetchSummary) Sort() {
+	if s == nil {
+		return
+	}
+	for _, skipped := range [][]SkippedResource{s.Skipped, s.Forbidden} {
+		sort.SliceStable(skipped, func(i, j int) bool { return skipped[i].Resource < skipped[j].Resource })
+	}
+	sort.SliceStable(s.Truncated, func(i, j int) bool { return s.Truncated[i].Resource < s.Truncated[j].Resource })
+	sort.SliceStable(s.Suppressed, func(i, j int) bool {
+		a, b := s.Suppressed[i], s.Suppressed[j]
+		return lessKeys([]string{a.Namespace, a.Kind, a.Name}, []string{b.Namespace, b.Kind, b.Name})
+	})
+	sort.Strings(s.MissingDefinitions)
+}
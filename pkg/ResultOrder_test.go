@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestSortResults(t *testing.T) {
+	results := []ValidationResult{
+		{Cluster: "prod", Kind: "Deployment", APIVersion: "apps/v1", ResourceNamespace: "web", ResourceName: "cart"},
+		{Cluster: "prod", Kind: "Ingress", APIVersion: "networking.k8s.io/v1", ResourceNamespace: "shop", ResourceName: "cart"},
+		{Cluster: "dev", Kind: "Deployment", APIVersion: "apps/v1", ResourceNamespace: "web", ResourceName: "cart"},
+		{Cluster: "prod", Kind: "ConfigMap", APIVersion: "v1", ResourceNamespace: "shop", ResourceName: "settings"},
+		{Cluster: "prod", Kind: "Deployment", APIVersion: "apps/v1", ResourceNamespace: "shop", ResourceName: "cart"},
+		{Cluster: "prod", Kind: "Deployment", APIVersion: "apps/v1", ResourceNamespace: "shop", ResourceName: "api"},
+	}
+	SortResults(results)
+	var got []string
+	for _, vr := range results {
+		got = append(got, vr.Cluster+" "+vr.ResourceNamespace+" "+vr.Kind+" "+vr.ResourceName)
+	}
+	assert.Equal(t, []string{
+		"dev web Deployment cart",
+		"prod shop ConfigMap settings",
+		"prod shop Deployment api",
+		"prod shop Deployment cart",
+		"prod shop Ingress cart",
+		"prod web Deployment cart",
+	}, got)
+}
+
+func TestFetchSummary_Sort(t *testing.T) {
+	summary := &FetchSummary{
+		Skipped:            []SkippedResource{{Resource: "widgets.example.com"}, {Resource: "cronjobs.batch"}},
+		Suppressed:         []SuppressedObject{{Kind: "Pod", Namespace: "web", Name: "a"}, {Kind: "Job", Namespace: "shop", Name: "b"}},
+		MissingDefinitions: []string{"example.com", "acme.io"},
+	}
+	summary.Sort()
+	assert.Equal(t, []SkippedResource{{Resource: "cronjobs.batch"}, {Resource: "widgets.example.com"}}, summary.Skipped)
+	assert.Equal(t, []SuppressedObject{{Kind: "Job", Namespace: "shop", Name: "b"}, {Kind: "Pod", Namespace: "web", Name: "a"}}, summary.Suppressed)
+	assert.Equal(t, []string{"acme.io", "example.com"}, summary.MissingDefinitions)
+	(*FetchSummary)(nil).Sort()
+}
+
+// TestSortResults_scanTwice scans a fake cluster twice, validating its objects in a different order each time as
+// discovery and map iteration may, and reports the same output both times
+func TestSortResults_scanTwice(t *testing.T) {
+	server := newFakeApiServer(t)
+	gvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deployments := server.addResource(gvk, "deployments", true)
+	for _, ref := range [][2]string{{"web", "cart"}, {"shop", "cart"}, {"web", "api"}, {"shop", "billing"}} {
+		obj := newFakeObject("apps/v1", "Deployment", ref[0], ref[1])
+		// several invalid fields for the errors of every result to be sorted too
+		obj["spec"] = map[string]interface{}{"replicas": "two", "paused": "yes", "selector": "app"}
+		server.addObject(deployments, obj)
+	}
+	kubeC := NewKubeCheckerImpl()
+	if !assert.NoError(t, kubeC.load([]byte(pruneTestSwagger), "1.27")) {
+		return
+	}
+	cluster := server.cluster(t)
+
+	scan := func(reversed bool) string {
+		objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{gvk}, &Config{})
+		assert.NoError(t, err)
+		var results []ValidationResult
+		for i := range objs {
+			obj := objs[i]
+			if reversed {
+				obj = objs[len(objs)-1-i]
+			}
+			vr, err := kubeC.ValidateObject(obj.Object, "1.27")
+			assert.NoError(t, err)
+			vr.Cluster = cluster.Name()
+			results = append(results, vr)
+		}
+		SortResults(results)
+		buf := &bytes.Buffer{}
+		output := newJSONOutputManager(log.New(buf, "", 0))
+		// both reports are of the same run
+		output.report = NewReport(time.Time{})
+		assert.NoError(t, output.PutBulk(results))
+		assert.NoError(t, output.Flush())
+		return buf.String()
+	}
+	first := scan(false)
+	assert.Contains(t, first, `"name": "billing"`)
+	assert.Equal(t, first, scan(true))
+}
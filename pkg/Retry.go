@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+)
+
+// DefaultListRetries is the number of times listing a resource is retried unless configured otherwise
+const DefaultListRetries = 2
+
+// listRetryBackoff is the wait before the first retry, doubled on every further retry and jittered
+var listRetryBackoff = 500 * time.Millisecond
+
+// maxRetryAfter caps the wait requested by the api server in the Retry-After header of throttled requests
+var maxRetryAfter = 30 * time.Second
+
+// retrySleep waits for d or until ctx is done, replaced in tests to observe the backoff
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// isTransientError returns true for errors caused by the connection to the api server being dropped,
+// eg a connection reset or an HTTP/2 GOAWAY from a load balanced control plane, which are worth retrying
+func isTransientError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if utilnet.IsConnectionReset(err) || utilnet.IsProbableEOF(err) || utilnet.IsHTTP2ConnectionLost(err) {
+		return true
+	}
+	// errors reading a truncated response body are wrapped by the rest client
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+	return strings.Contains(err.Error(), "GOAWAY")
+}
+
+// isRetryableError returns true for errors of an overloaded or unreachable api server, ie throttling, server errors
+// and timeouts, besides transient errors. Errors caused by the request, eg forbidden or not found, are not retried
+func isRetryableError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isTransientError(err) {
+		return true
+	}
+	var statusErr k8sErrors.APIStatus
+	if errors.As(err, &statusErr) && statusErr.Status().Code >= http.StatusInternalServerError {
+		return true
+	}
+	if k8sErrors.IsTooManyRequests(err) || k8sErrors.IsServerTimeout(err) || k8sErrors.IsTimeout(err) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// retryDelay returns the wait before retrying after err, the delay asked for by the api server if any and backoff
+// with jitter otherwise
+func retryDelay(err error, backoff time.Duration) time.Duration {
+	if seconds, ok := k8sErrors.SuggestsClientDelay(err); ok && seconds > 0 {
+		return min(time.Duration(seconds)*time.Second, maxRetryAfter)
+	}
+	return wait.Jitter(backoff, 0.5)
+}
+
+// listRetries returns the number of times listing a resource is retried, never when TransientErrorRetries is negative
+func (c *Config) listRetries() int {
+	switch {
+	case c.TransientErrorRetries < 0:
+		return 0
+	case c.TransientErrorRetries == 0:
+		return DefaultListRetries
+	}
+	return c.TransientErrorRetries
+}
+
+type retryCounterKey struct{}
+
+// withRetryCounter returns a context whose list retries are counted in retried
+func withRetryCounter(ctx context.Context, retried *atomic.Int64) context.Context {
+	return context.WithValue(ctx, retryCounterKey{}, retried)
+}
+
+// listWithRetry lists the resource retrying up to retries times on retryable errors
+func listWithRetry(ctx context.Context, resInf dynamic.ResourceInterface, opts v1.ListOptions, retries int) (*unstructured.UnstructuredList, error) {
+	return withRetry(ctx, retries, func() (*unstructured.UnstructuredList, error) {
+		return resInf.List(ctx, opts)
+	})
+}
+
+// withRetry calls list retrying up to retries times on retryable errors
+func withRetry[T any](ctx context.Context, retries int, list func() (T, error)) (T, error) {
+	backoff := listRetryBackoff
+	for attempt := 0; ; attempt++ {
+		result, err := list()
+		if err == nil || attempt >= retries || ctx.Err() != nil || !isRetryableError(err) {
+			return result, err
+		}
+		delay := retryDelay(err, backoff)
+		kLog.Warn(fmt.Sprintf("retryable error while listing, retrying in %v: %v", delay, err))
+		if retried, ok := ctx.Value(retryCounterKey{}).(*atomic.Int64); ok {
+			retried.Add(1)
+		}
+		if err := retrySleep(ctx, delay); err != nil {
+			var zero T
+			return zero, err
+		}
+		backoff *= 2
+	}
+}
+
+// refreshCredentials rebuilds the dynamic client of the cluster over a new transport built from a copy of the rest
+// config. The new transport reads token files again and exec credential plugins, whose cached credentials were
+// dropped on the unauthorized response, issue a new token.
+func (c *Cluster) refreshCredentials() error {
+	config := rest.CopyConfig(c.restConfig)
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return fmt.Errorf("failed to refresh credentials of cluster %s: %w", c.name, err)
+	}
+	client, err := dynamic.NewForConfigAndClient(config, httpClient)
+	if err != nil {
+		return fmt.Errorf("failed to refresh credentials of cluster %s: %w", c.name, err)
+	}
+	c.SetDynamicClient(client)
+	return nil
+}
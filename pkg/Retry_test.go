@@ -0,0 +1,266 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+func TestIsTransientError(t *testing.T) {
+	assert.False(t, isTransientError(nil))
+	assert.False(t, isTransientError(errors.New("forbidden")))
+	assert.True(t, isTransientError(errors.New("read tcp 10.0.0.1:443: connection reset by peer")))
+	assert.True(t, isTransientError(errors.New("http2: server sent GOAWAY and closed the connection; LastStreamID=19")))
+	assert.True(t, isTransientError(fmt.Errorf("unexpected error when reading response body: %w", io.ErrUnexpectedEOF)))
+}
+
+func TestCluster_FetchK8sObjects_transientErrors(t *testing.T) {
+	defer func(backoff time.Duration) { listRetryBackoff = backoff }(listRetryBackoff)
+	listRetryBackoff = time.Millisecond
+
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	var resets int32
+	// the connection is dropped half way through the first list response
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/apis/apps/v1/deployments" || atomic.AddInt32(&resets, 1) > 1 {
+			return false
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Length", "1024")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"kind":"DeploymentList","items":[`))
+		w.(http.Flusher).Flush()
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if assert.NoError(t, err) {
+			_ = conn.Close()
+		}
+		return true
+	}
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{deploymentGvk}
+
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true, TransientErrorRetries: 1})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	assert.Equal(t, []string{"/apis/apps/v1/deployments", "/apis/apps/v1/deployments"}, server.resourceRequests())
+
+	// without retries the resource is skipped
+	atomic.StoreInt32(&resets, 0)
+	objs, summary, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true, TransientErrorRetries: -1})
+	assert.NoError(t, err)
+	assert.Empty(t, objs)
+	if assert.True(t, summary.Partial()) {
+		assert.Equal(t, "apps/v1, Resource=deployments", summary.Skipped[0].Resource)
+		assert.Equal(t, FetchError, summary.Skipped[0].Failure)
+	}
+}
+
+func TestCluster_FetchK8sObjects_unauthorized(t *testing.T) {
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	gvks := []schema.GroupVersionKind{deploymentGvk, configMapGvk}
+
+	tests := []struct {
+		name         string
+		unauthorized int32
+		wantObjs     int
+		wantRequests []string
+		wantErr      bool
+	}{
+		{
+			name:         "expired token is refreshed",
+			unauthorized: 1,
+			wantObjs:     2,
+			wantRequests: []string{"/apis/apps/v1/deployments", "/apis/apps/v1/deployments", "/api/v1/configmaps"},
+		},
+		{
+			name:         "revoked token fails fast",
+			unauthorized: 2,
+			wantRequests: []string{"/apis/apps/v1/deployments", "/apis/apps/v1/deployments"},
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeApiServer(t)
+			server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+			server.addObject(server.addResource(configMapGvk, "configmaps", true), newFakeObject("v1", "ConfigMap", "shop", "cart"))
+			var unauthorized int32
+			server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+				if r.URL.Path != "/apis/apps/v1/deployments" || atomic.AddInt32(&unauthorized, 1) > tt.unauthorized {
+					return false
+				}
+				writeStatus(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized")
+				return true
+			}
+			cluster := server.cluster(t)
+			objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{IncludeTerminalObjects: true, Concurrency: 1})
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "authentication to cluster")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Len(t, objs, tt.wantObjs)
+			assert.Equal(t, tt.wantRequests, server.resourceRequests())
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_rotatedToken(t *testing.T) {
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	server := newFakeApiServer(t)
+	server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("first"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// the first token expires on the first list, by then the token file holds the second token
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path != "/apis/apps/v1/deployments" || r.Header.Get("Authorization") == "Bearer second" {
+			return false
+		}
+		if err := os.WriteFile(tokenFile, []byte("second"), 0600); err != nil {
+			t.Error(err)
+		}
+		writeStatus(w, http.StatusUnauthorized, "Unauthorized", "Unauthorized")
+		return true
+	}
+	restConfig := server.restConfig()
+	restConfig.BearerTokenFile = tokenFile
+	cluster, err := NewClusterFromEnvOrConfig(restConfig, ClusterOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{deploymentGvk}, &Config{IncludeTerminalObjects: true})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	var tokens []string
+	for _, r := range server.recorded() {
+		if r.Path == "/apis/apps/v1/deployments" {
+			tokens = append(tokens, r.Header.Get("Authorization"))
+		}
+	}
+	assert.Equal(t, []string{"Bearer first", "Bearer second"}, tokens)
+}
+
+// flakyResource fails the first List calls with errs and then lists an empty list
+type flakyResource struct {
+	dynamic.ResourceInterface
+	errs  []error
+	calls int
+}
+
+func (r *flakyResource) List(context.Context, v1.ListOptions) (*unstructured.UnstructuredList, error) {
+	r.calls++
+	if r.calls <= len(r.errs) {
+		return nil, r.errs[r.calls-1]
+	}
+	return &unstructured.UnstructuredList{}, nil
+}
+
+func TestListWithRetry(t *testing.T) {
+	defer func(backoff time.Duration, sleep func(context.Context, time.Duration) error) {
+		listRetryBackoff, retrySleep = backoff, sleep
+	}(listRetryBackoff, retrySleep)
+	listRetryBackoff = time.Second
+	var waits []time.Duration
+	retrySleep = func(_ context.Context, d time.Duration) error {
+		waits = append(waits, d)
+		return nil
+	}
+
+	deployments := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	throttled := k8sErrors.NewTooManyRequests("rate limited", 3)
+	unavailable := k8sErrors.NewServiceUnavailable("etcd leader changed")
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantWaits []time.Duration
+		wantErr   bool
+	}{
+		{
+			name:      "retry after of throttled requests is honored",
+			errs:      []error{throttled, throttled},
+			wantCalls: 3,
+			wantWaits: []time.Duration{3 * time.Second, 3 * time.Second},
+		},
+		{
+			name:      "server errors back off exponentially",
+			errs:      []error{unavailable, k8sErrors.NewInternalError(errors.New("boom"))},
+			wantCalls: 3,
+			wantWaits: []time.Duration{time.Second, 2 * time.Second},
+		},
+		{
+			name:      "timeouts are retried",
+			errs:      []error{&net.OpError{Op: "dial", Err: timeoutError{}}, k8sErrors.NewTimeoutError("list timed out", 1)},
+			wantCalls: 3,
+			wantWaits: []time.Duration{time.Second, time.Second},
+		},
+		{
+			name:      "forbidden is not retried",
+			errs:      []error{k8sErrors.NewForbidden(deployments, "", errors.New("rbac"))},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "not found is not retried",
+			errs:      []error{k8sErrors.NewNotFound(deployments, "")},
+			wantCalls: 1,
+			wantErr:   true,
+		},
+		{
+			name:      "retries are capped",
+			errs:      []error{unavailable, unavailable, unavailable},
+			wantCalls: 3,
+			wantWaits: []time.Duration{time.Second, 2 * time.Second},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			waits = nil
+			resource := &flakyResource{errs: tt.errs}
+			var retried atomic.Int64
+			_, err := listWithRetry(withRetryCounter(context.Background(), &retried), resource, v1.ListOptions{}, 2)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantCalls, resource.calls)
+			assert.Equal(t, int64(len(tt.wantWaits)), retried.Load())
+			if assert.Len(t, waits, len(tt.wantWaits)) {
+				for i, want := range tt.wantWaits {
+					// backoff is jittered by up to half of it
+					assert.GreaterOrEqual(t, waits[i], want)
+					assert.LessOrEqual(t, waits[i], want+want/2)
+				}
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
@@ -0,0 +1,63 @@
+package pkg
+
+const (
+	// RiskBandGreen means the removed and deprecated api versions found are below their thresholds, see RiskThresholds
+	RiskBandGreen = "Green"
+	// RiskBandYellow means deprecated api versions reached their threshold, they should be migrated before long
+	RiskBandYellow = "Yellow"
+	// RiskBandRed means api versions removed in the target version reached their threshold, the upgrade breaks them
+	RiskBandRed = "Red"
+)
+
+// RiskThresholds controls how many findings of a kind are needed before a cluster
+// is moved into the corresponding risk band, zero values are treated as 1
+type RiskThresholds struct {
+	// Removed is the number of objects using an api version removed in the target version
+	// at which the band becomes Red
+	Removed int
+
+	// Deprecated is the number of objects using an api version deprecated in the target version
+	// at which the band becomes Yellow
+	Deprecated int
+}
+
+// DefaultRiskThresholds returns thresholds where a single removed object makes the band Red
+// and a single deprecated object makes it Yellow
+func DefaultRiskThresholds() RiskThresholds {
+	return RiskThresholds{
+		Removed:    1,
+		Deprecated: 1,
+	}
+}
+
+// RiskBand classifies the overall upgrade readiness of a scan as Green, Yellow or Red
+// based on the number of removed and deprecated api versions found against the target version
+func RiskBand(results []ValidationResult, thresholds RiskThresholds) string {
+	removedThreshold := thresholds.Removed
+	if removedThreshold <= 0 {
+		removedThreshold = 1
+	}
+	deprecatedThreshold := thresholds.Deprecated
+	if deprecatedThreshold <= 0 {
+		deprecatedThreshold = 1
+	}
+	removed := 0
+	deprecated := 0
+	for _, result := range results {
+		if len(result.Kind) == 0 {
+			continue
+		}
+		if result.Deleted {
+			removed++
+		} else if result.Deprecated {
+			deprecated++
+		}
+	}
+	if removed >= removedThreshold {
+		return RiskBandRed
+	}
+	if deprecated >= deprecatedThreshold {
+		return RiskBandYellow
+	}
+	return RiskBandGreen
+}
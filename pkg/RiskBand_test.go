@@ -0,0 +1,61 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRiskBand(t *testing.T) {
+	removed := ValidationResult{Kind: "PodSecurityPolicy", Deleted: true}
+	deprecated := ValidationResult{Kind: "Ingress", Deprecated: true}
+	unchanged := ValidationResult{Kind: "Deployment"}
+	tests := []struct {
+		name       string
+		results    []ValidationResult
+		thresholds RiskThresholds
+		want       string
+	}{
+		{
+			name:       "no findings",
+			results:    []ValidationResult{unchanged},
+			thresholds: DefaultRiskThresholds(),
+			want:       RiskBandGreen,
+		},
+		{
+			name:       "deprecated only",
+			results:    []ValidationResult{unchanged, deprecated},
+			thresholds: DefaultRiskThresholds(),
+			want:       RiskBandYellow,
+		},
+		{
+			name:       "removed wins over deprecated",
+			results:    []ValidationResult{deprecated, removed},
+			thresholds: DefaultRiskThresholds(),
+			want:       RiskBandRed,
+		},
+		{
+			name:       "removed below threshold",
+			results:    []ValidationResult{removed, deprecated},
+			thresholds: RiskThresholds{Removed: 2, Deprecated: 1},
+			want:       RiskBandYellow,
+		},
+		{
+			name:       "zero thresholds default to one",
+			results:    []ValidationResult{removed},
+			thresholds: RiskThresholds{},
+			want:       RiskBandRed,
+		},
+		{
+			name:       "results without kind are ignored",
+			results:    []ValidationResult{{Deleted: true}},
+			thresholds: DefaultRiskThresholds(),
+			want:       RiskBandGreen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, RiskBand(tt.results, tt.thresholds))
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package pkg
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+const (
+	RunbookMechanical   = "mechanical"
+	RunbookConfigChange = "config change"
+	RunbookManual       = "manual redesign"
+)
+
+// runbookBuckets are rendered in order of increasing effort
+var runbookBuckets = []struct {
+	name     string
+	title    string
+	guidance string
+}{
+	{
+		name:     RunbookMechanical,
+		title:    "Mechanical (auto-migratable)",
+		guidance: "Only the apiVersion has to be changed, the rest of the manifest is valid against the replacement version.",
+	},
+	{
+		name:     RunbookConfigChange,
+		title:    "Config change",
+		guidance: "The manifest uses fields which are invalid or deprecated in the replacement version, fix the listed fields while changing the apiVersion.",
+	},
+	{
+		name:     RunbookManual,
+		title:    "Manual redesign",
+		guidance: "The api version is removed in the target version without a replacement, the resource has to be replaced by a different mechanism before upgrading.",
+	},
+}
+
+// RunbookBucket returns the effort bucket of a result, an empty bucket is returned for results with nothing to report
+func RunbookBucket(vr ValidationResult) string {
+	if len(vr.Kind) == 0 || !HasFindings(vr) {
+		return ""
+	}
+	if vr.IsVersionSupported == 2 || vr.Deleted && len(vr.LatestAPIVersion) == 0 {
+		return RunbookManual
+	}
+	if len(vr.ErrorsForLatest) > 0 || len(vr.ErrorsForOriginal) > 0 || len(vr.DeprecationForLatest) > 0 || len(vr.DeprecationForOriginal) > 0 || len(vr.FieldMigrations) > 0 || len(vr.PodFieldDeprecations) > 0 || len(vr.CustomRuleFindings) > 0 || len(vr.UnknownFields) > 0 {
+		return RunbookConfigChange
+	}
+	return RunbookMechanical
+}
+
+// WriteRunbook renders the results as a Markdown checklist with the results grouped by the effort needed to
+// migrate them, cheapest first
+func WriteRunbook(w io.Writer, results []ValidationResult) error {
+	buckets := map[string][]ValidationResult{}
+	total := 0
+	for _, vr := range results {
+		if bucket := RunbookBucket(vr); len(bucket) > 0 {
+			buckets[bucket] = append(buckets[bucket], vr)
+			total++
+		}
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString("# Upgrade runbook\n\n")
+	if total == 0 {
+		buf.WriteString("Nothing to migrate, all resources will work as they are in the target version.\n")
+		_, err := w.Write(buf.Bytes())
+		return err
+	}
+	fmt.Fprintf(buf, "%d resource(s) to migrate:\n\n", total)
+	for _, b := range runbookBuckets {
+		fmt.Fprintf(buf, "- %s: %d\n", b.title, len(buckets[b.name]))
+	}
+	for i, b := range runbookBuckets {
+		if len(buckets[b.name]) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "\n## %d. %s (%d)\n\n%s\n\n", i+1, b.title, len(buckets[b.name]), b.guidance)
+		for _, vr := range buckets[b.name] {
+			writeRunbookItem(buf, vr)
+		}
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func writeRunbookItem(buf *bytes.Buffer, vr ValidationResult) {
+	name := vr.ResourceName
+	if len(vr.ResourceNamespace) > 0 {
+		name = vr.ResourceNamespace + "/" + vr.ResourceName
+	}
+	if len(vr.FileName) > 0 && len(name) == 0 {
+		name = vr.Location()
+	}
+	fmt.Fprintf(buf, "- [ ] %s `%s` (%s)", vr.Kind, name, vr.APIVersion)
+	if len(vr.Severity) > 0 {
+		fmt.Fprintf(buf, " [%s]", vr.Severity)
+	}
+	if len(vr.LatestAPIVersion) > 0 {
+		fmt.Fprintf(buf, ": change apiVersion to `%s`", vr.LatestAPIVersion)
+	}
+	if len(vr.FixedManifest) > 0 {
+		fmt.Fprintf(buf, ", migrated in `%s`", vr.FixedManifest)
+	}
+	buf.WriteString("\n")
+	for _, m := range vr.FieldMigrations {
+		if len(m.Replacement) > 0 {
+			fmt.Fprintf(buf, "  - move `%s` to `%s`\n", m.Path, m.Replacement)
+		} else {
+			fmt.Fprintf(buf, "  - remove `%s`: %s\n", m.Path, m.Reason)
+		}
+	}
+	for _, d := range vr.PodFieldDeprecations {
+		fmt.Fprintf(buf, "  - replace `%s` with `%s`\n", d.Path, d.Replacement)
+	}
+	if vr.Remediation != nil {
+		fmt.Fprintf(buf, "  - %s\n", vr.Remediation.Summary)
+		for _, step := range vr.Remediation.Steps {
+			fmt.Fprintf(buf, "    - %s\n", step)
+		}
+	}
+	for _, f := range vr.CustomRuleFindings {
+		fmt.Fprintf(buf, "  - %s `%s`: %s\n", f.Rule, f.Path, f.Message)
+	}
+	for _, path := range vr.UnknownFields {
+		fmt.Fprintf(buf, "  - fix unknown field `%s`\n", path)
+	}
+	for _, errs := range [][]*openapi3.SchemaError{vr.ErrorsForLatest, vr.ErrorsForOriginal} {
+		for _, e := range errs {
+			fmt.Fprintf(buf, "  - fix%s: %s\n", fieldRef(e.JSONPointer()), e.Reason)
+		}
+	}
+	for _, deprecations := range [][]*SchemaError{vr.DeprecationForLatest, vr.DeprecationForOriginal} {
+		for _, e := range deprecations {
+			fmt.Fprintf(buf, "  - replace deprecated%s: %s\n", fieldRef(e.JSONPointer()), e.Reason)
+		}
+	}
+}
+
+func fieldRef(path []string) string {
+	if len(path) == 0 {
+		return ""
+	}
+	return " `" + strings.Join(path, "/") + "`"
+}
+
+// runbookOutputManager collects results and renders them as a runbook to stdout when flushed
+type runbookOutputManager struct {
+	w       io.Writer
+	results []ValidationResult
+}
+
+func newDefaultRunbookOutputManager() *runbookOutputManager {
+	return &runbookOutputManager{w: os.Stdout}
+}
+
+func (r *runbookOutputManager) PutBulk(vrs []ValidationResult) error {
+	r.results = append(r.results, vrs...)
+	return nil
+}
+
+func (r *runbookOutputManager) Put(vr ValidationResult) error {
+	r.results = append(r.results, vr)
+	return nil
+}
+
+func (r *runbookOutputManager) Flush() error {
+	return WriteRunbook(r.w, r.results)
+}
+
+func (r *runbookOutputManager) GetSummaryValidationResultBulk() []SummaryValidationResult {
+	var svrs []SummaryValidationResult
+	for _, vr := range r.results {
+		if len(RunbookBucket(vr)) > 0 {
+			svrs = append(svrs, newSummaryValidationResult(vr))
+		}
+	}
+	return svrs
+}
@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteRunbook(t *testing.T) {
+	mechanical := ValidationResult{Kind: "Ingress", APIVersion: "networking.k8s.io/v1beta1", LatestAPIVersion: "networking.k8s.io/v1",
+		ResourceNamespace: "shop", ResourceName: "web", Deprecated: true}
+	configChange := ValidationResult{Kind: "CronJob", APIVersion: "batch/v1beta1", LatestAPIVersion: "batch/v1",
+		ResourceNamespace: "shop", ResourceName: "report", Deleted: true,
+		ErrorsForLatest: []*openapi3.SchemaError{{Reason: "property \"foo\" is unsupported"}}}
+	manual := ValidationResult{Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1", ResourceName: "restricted", Deleted: true, IsVersionSupported: 2}
+	unchanged := ValidationResult{Kind: "Deployment", APIVersion: "apps/v1", ResourceName: "cart"}
+
+	assert.Equal(t, RunbookMechanical, RunbookBucket(mechanical))
+	assert.Equal(t, RunbookConfigChange, RunbookBucket(configChange))
+	assert.Equal(t, RunbookManual, RunbookBucket(manual))
+	assert.Equal(t, "", RunbookBucket(unchanged))
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteRunbook(&buf, []ValidationResult{manual, unchanged, configChange, mechanical}))
+	assert.Equal(t, `# Upgrade runbook
+
+3 resource(s) to migrate:
+
+- Mechanical (auto-migratable): 1
+- Config change: 1
+- Manual redesign: 1
+
+## 1. Mechanical (auto-migratable) (1)
+
+Only the apiVersion has to be changed, the rest of the manifest is valid against the replacement version.
+
+- [ ] Ingress `+"`shop/web`"+` (networking.k8s.io/v1beta1): change apiVersion to `+"`networking.k8s.io/v1`"+`
+
+## 2. Config change (1)
+
+The manifest uses fields which are invalid or deprecated in the replacement version, fix the listed fields while changing the apiVersion.
+
+- [ ] CronJob `+"`shop/report`"+` (batch/v1beta1): change apiVersion to `+"`batch/v1`"+`
+  - fix: property "foo" is unsupported
+
+## 3. Manual redesign (1)
+
+The api version is removed in the target version without a replacement, the resource has to be replaced by a different mechanism before upgrading.
+
+- [ ] PodSecurityPolicy `+"`restricted`"+` (policy/v1beta1)
+`, buf.String())
+
+	buf.Reset()
+	assert.NoError(t, WriteRunbook(&buf, []ValidationResult{unchanged}))
+	assert.Contains(t, buf.String(), "Nothing to migrate")
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+type ScanEventType string
+
+const (
+	ScanEventResourceStarted  ScanEventType = "ResourceStarted"
+	ScanEventResourceFinished ScanEventType = "ResourceFinished"
+	ScanEventResourceSkipped  ScanEventType = "ResourceSkipped"
+	ScanEventFindingFound     ScanEventType = "FindingFound"
+	ScanEventScanComplete     ScanEventType = "ScanComplete"
+)
+
+// ScanEvent is emitted to Config.EventSink while a cluster is being scanned. Resource is set for resource
+// events, Count is the number of objects fetched for ResourceFinished and the number of results for
+// ScanComplete, Reason explains ResourceSkipped and Finding carries the result of FindingFound. Severities counts
+// the results of ScanComplete by severity, see SeverityCounts
+type ScanEvent struct {
+	Type       ScanEventType            `json:"type"`
+	Time       time.Time                `json:"time"`
+	Resource   string                   `json:"resource,omitempty"`
+	Count      int                      `json:"count,omitempty"`
+	Reason     string                   `json:"reason,omitempty"`
+	Finding    *SummaryValidationResult `json:"finding,omitempty"`
+	Severities map[string]int           `json:"severities,omitempty"`
+}
+
+// Emit sends the event to EventSink, if one is configured
+func (c *Config) Emit(event ScanEvent) {
+	if c == nil || c.EventSink == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	c.EventSink(event)
+}
+
+// EmitFinding sends a FindingFound event for the result if it has anything to report
+func (c *Config) EmitFinding(vr ValidationResult) {
+	if len(vr.Kind) == 0 || !HasFindings(vr) {
+		return
+	}
+	finding := newSummaryValidationResult(vr)
+	c.Emit(ScanEvent{Type: ScanEventFindingFound, Resource: vr.Kind, Finding: &finding})
+}
+
+// NewJSONLinesEventSink returns an EventSink writing every event as a single line of json to w
+func NewJSONLinesEventSink(w io.Writer) func(ScanEvent) {
+	var lock sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(event ScanEvent) {
+		lock.Lock()
+		defer lock.Unlock()
+		_ = encoder.Encode(event)
+	}
+}
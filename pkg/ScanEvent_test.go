@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_events(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "kube-system", "coredns"))
+	cluster := server.cluster(t)
+
+	var events []ScanEvent
+	conf := &Config{
+		IgnoreKinds:      []string{"Event"},
+		IgnoreNamespaces: []string{"kube-system"},
+		EventSink:        func(event ScanEvent) { events = append(events, event) },
+	}
+	gvks := []schema.GroupVersionKind{
+		deploymentGvk,
+		{Version: "v1", Kind: "Event"},
+		{Group: "batch", Version: "v1", Kind: "CronJob"},
+	}
+	objs, _, err := cluster.FetchK8sObjects(context.Background(), gvks, conf)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+
+	var got []string
+	for _, event := range events {
+		assert.False(t, event.Time.IsZero())
+		got = append(got, strings.Join([]string{string(event.Type), event.Resource, event.Reason}, "|"))
+	}
+	assert.Equal(t, []string{
+		"ResourceSkipped|/v1, Kind=Event|kind ignored",
+		"ResourceSkipped|batch/v1, Kind=CronJob|not served by cluster",
+		"ResourceStarted|apps/v1, Resource=deployments|",
+		"ResourceFinished|apps/v1, Resource=deployments|",
+	}, got)
+	assert.Equal(t, 1, events[3].Count)
+}
+
+func TestNewJSONLinesEventSink(t *testing.T) {
+	var buf bytes.Buffer
+	conf := &Config{EventSink: NewJSONLinesEventSink(&buf)}
+	conf.EmitFinding(ValidationResult{Kind: "Deployment"})
+	conf.EmitFinding(ValidationResult{Kind: "Ingress", APIVersion: "extensions/v1beta1", ResourceName: "web", Deleted: true})
+	conf.Emit(ScanEvent{Type: ScanEventScanComplete, Count: 2})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+	var finding, complete ScanEvent
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &finding))
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &complete))
+	assert.Equal(t, ScanEventFindingFound, finding.Type)
+	assert.Equal(t, "web", finding.Finding.ResourceName)
+	assert.Equal(t, ScanEventScanComplete, complete.Type)
+	assert.Equal(t, 2, complete.Count)
+
+	// emitting without a sink is a no-op
+	(&Config{}).Emit(ScanEvent{Type: ScanEventScanComplete})
+}
@@ -0,0 +1,149 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	kErrors "github.com/devtron-labs/silver-surfer/pkg/errors"
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+
+	"k8s.io/client-go/util/homedir"
+)
+
+// DefaultSchemaCacheDir returns the directory the openapi specs of kubernetes releases are cached in by the kubedd
+// command
+func DefaultSchemaCacheDir() string {
+	return filepath.Join(homedir.HomeDir(), ".cache", "silver-surfer", "schemas")
+}
+
+// SchemaNotCachedError is returned in offline mode for the kubernetes versions whose spec is not cached
+type SchemaNotCachedError struct {
+	Dir      string
+	Versions []string
+}
+
+func (e *SchemaNotCachedError) Error() string {
+	files := make([]string, 0, len(e.Versions))
+	for _, version := range e.Versions {
+		files = append(files, schemaCacheFile(e.Dir, version))
+	}
+	return fmt.Sprintf("offline and the spec of kubernetes %s is not cached, missing %s: run download-specs %s on a connected machine and copy its schema cache dir",
+		strings.Join(e.Versions, ", "), strings.Join(files, ", "), strings.Join(e.Versions, " "))
+}
+
+// schemaCacheFile is the file the spec of the kubernetes version is cached in
+func schemaCacheFile(dir, version string) string {
+	return filepath.Join(dir, fmt.Sprintf("swagger-%s.json", version))
+}
+
+// MissingSchemas returns a SchemaNotCachedError naming the versions whose spec is not cached when Offline, so that
+// all of them are reported at once rather than one by one as they are loaded. The spec of the minor version of a patch
+// version stands for it, see specCandidates
+func (c *Config) MissingSchemas(versions ...string) error {
+	if !c.Offline {
+		return nil
+	}
+	missing := &SchemaNotCachedError{Dir: c.SchemaCacheDir}
+	for _, version := range versions {
+		if slices.Contains(missing.Versions, version) {
+			continue
+		}
+		if len(c.SchemaCacheDir) == 0 {
+			missing.Versions = append(missing.Versions, version)
+			continue
+		}
+		cached := false
+		for _, candidate := range specCandidates(version) {
+			if _, err := os.Stat(schemaCacheFile(c.SchemaCacheDir, candidate)); err == nil {
+				cached = true
+				break
+			}
+		}
+		if !cached {
+			missing.Versions = append(missing.Versions, version)
+		}
+	}
+	if len(missing.Versions) == 0 {
+		return nil
+	}
+	return missing
+}
+
+// readCachedSchema returns the cached spec of releaseVersion, or nil when it is not cached and may be downloaded
+func (k *kubeCheckerImpl) readCachedSchema(releaseVersion string) ([]byte, error) {
+	if len(k.cacheDir) > 0 {
+		data, err := os.ReadFile(schemaCacheFile(k.cacheDir, releaseVersion))
+		if err == nil {
+			return data, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			kLog.Warn(fmt.Sprintf("unable to read the cached spec of kubernetes %s: %v", releaseVersion, err))
+		}
+	}
+	if k.offline {
+		return nil, &SchemaNotCachedError{Dir: k.cacheDir, Versions: []string{releaseVersion}}
+	}
+	return nil, nil
+}
+
+// cacheSchema stores the downloaded spec of releaseVersion, failures are only warned about as the spec is used all
+// the same
+func (k *kubeCheckerImpl) cacheSchema(releaseVersion string, data []byte) {
+	if len(k.cacheDir) == 0 {
+		return
+	}
+	if err := writeSchemaFile(k.cacheDir, releaseVersion, data); err != nil {
+		kLog.Warn(fmt.Sprintf("unable to cache the spec of kubernetes %s: %v", releaseVersion, err))
+	}
+}
+
+// writeSchemaFile writes the spec of version to dir through a temporary file, so that concurrent runs never read a
+// partially written spec
+func writeSchemaFile(dir, version string, data []byte) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".swagger-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), schemaCacheFile(dir, version))
+}
+
+// DownloadSchemas downloads the specs of versions into conf.SchemaCacheDir, replacing the cached ones, so that the
+// cache can be copied to air-gapped machines. Patch versions without a spec fall back to the latest spec of their
+// minor version, see specCandidates. Versions which fail do not stop the others from being downloaded
+func DownloadSchemas(conf *Config, versions []string) error {
+	k := NewCachedKubeChecker(conf)
+	var errs []error
+	for _, version := range versions {
+		candidates := specCandidates(version)
+		for i, candidate := range candidates {
+			data, err := k.downloadFile(candidate)
+			if err == nil {
+				err = writeSchemaFile(conf.SchemaCacheDir, candidate, data)
+			}
+			if i < len(candidates)-1 && errors.Is(err, kErrors.ErrOpenApiSpecNotFound) {
+				warnSpecFallback(candidate, candidates[i+1])
+				continue
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to download the spec of kubernetes %s: %w", version, err))
+			}
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
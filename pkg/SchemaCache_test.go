@@ -0,0 +1,81 @@
+package pkg
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	kErrors "github.com/devtron-labs/silver-surfer/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+const minimalSwagger = `{"swagger": "2.0", "info": {"title": "Kubernetes", "version": "v1.29.0"}, "paths": {"/version/": {"get": {"responses": {"200": {"description": "OK"}}}}}, "definitions": {}}`
+
+// serveSwagger serves minimalSwagger for the release branches of versions and counts the requests, it returns the
+// base url of the server
+func serveSwagger(t *testing.T, versions ...string) (string, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		for _, version := range versions {
+			if strings.Contains(r.URL.Path, "/release-"+version+"/") {
+				_, _ = w.Write([]byte(minimalSwagger))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &requests
+}
+
+func TestKubeChecker_LoadFromUrl_schemaCache(t *testing.T) {
+	baseURL, requests := serveSwagger(t, "1.29")
+	dir := t.TempDir()
+
+	// downloaded specs are cached
+	assert.NoError(t, NewCachedKubeChecker(&Config{SchemaCacheDir: dir, SchemaBaseURL: baseURL}).LoadFromUrl("1.29", false))
+	assert.FileExists(t, filepath.Join(dir, "swagger-1.29.json"))
+	assert.Equal(t, int32(1), requests.Load())
+
+	// cached specs are read before any download
+	assert.NoError(t, NewCachedKubeChecker(&Config{SchemaCacheDir: dir, SchemaBaseURL: baseURL, Offline: true}).LoadFromUrl("1.29", false))
+	assert.NoError(t, NewCachedKubeChecker(&Config{SchemaCacheDir: dir, SchemaBaseURL: baseURL}).LoadFromUrl("1.29", false))
+	assert.Equal(t, int32(1), requests.Load())
+
+	err := NewCachedKubeChecker(&Config{SchemaCacheDir: dir, SchemaBaseURL: baseURL, Offline: true}).LoadFromUrl("1.30", false)
+	var notCached *SchemaNotCachedError
+	assert.True(t, errors.As(err, &notCached))
+	assert.ErrorContains(t, err, filepath.Join(dir, "swagger-1.30.json"))
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestConfig_MissingSchemas(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "swagger-1.29.json"), []byte(minimalSwagger), 0o600))
+
+	assert.NoError(t, (&Config{SchemaCacheDir: dir}).MissingSchemas("1.30"))
+	assert.NoError(t, (&Config{SchemaCacheDir: dir, Offline: true}).MissingSchemas("1.29"))
+	err := (&Config{SchemaCacheDir: dir, Offline: true}).MissingSchemas("1.28", "1.29", "1.30", "1.28")
+	var notCached *SchemaNotCachedError
+	assert.True(t, errors.As(err, &notCached))
+	assert.Equal(t, []string{"1.28", "1.30"}, notCached.Versions)
+	assert.ErrorContains(t, err, "run download-specs 1.28 1.30")
+}
+
+func TestDownloadSchemas(t *testing.T) {
+	baseURL, _ := serveSwagger(t, "1.28", "1.29")
+	dir := filepath.Join(t.TempDir(), "schemas")
+
+	err := DownloadSchemas(&Config{SchemaCacheDir: dir, SchemaBaseURL: baseURL}, []string{"1.28", "0.1", "1.29"})
+	assert.ErrorIs(t, err, kErrors.ErrOpenApiSpecNotFound)
+	assert.ErrorContains(t, err, "kubernetes 0.1")
+	assert.FileExists(t, filepath.Join(dir, "swagger-1.28.json"))
+	assert.FileExists(t, filepath.Join(dir, "swagger-1.29.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "swagger-0.1.json"))
+}
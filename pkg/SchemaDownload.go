@@ -0,0 +1,84 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultSchemaBaseURL is the kubernetes repository the specs of releases are downloaded from unless SchemaBaseURL
+// points to a mirror
+const DefaultSchemaBaseURL = "https://raw.githubusercontent.com/kubernetes/kubernetes"
+
+var (
+	// schemaDownloadRetries is the number of times a failed spec download is retried
+	schemaDownloadRetries = 3
+	// schemaDownloadBackoff is the wait before the first retry of a spec download, doubled on every further retry
+	schemaDownloadBackoff = time.Second
+)
+
+// schemaURL returns the url of the spec of the kubernetes release in the repository at baseURL, mirrors are expected
+// to keep the layout of raw.githubusercontent.com. Patch versions are looked up by their tag and minor versions by
+// their release branch
+func schemaURL(baseURL, releaseVersion string) string {
+	if len(baseURL) == 0 {
+		baseURL = DefaultSchemaBaseURL
+	}
+	ref := "release-" + releaseVersion
+	if v, err := parseSpecVersion(releaseVersion); err == nil {
+		ref = v.ref()
+	}
+	return fmt.Sprintf("%s/%s/api/openapi-spec/swagger.json", strings.TrimSuffix(baseURL, "/"), ref)
+}
+
+// schemaStatusError is a spec download answered with an unexpected status
+type schemaStatusError struct {
+	url  string
+	code int
+}
+
+func (e *schemaStatusError) Error() string {
+	return fmt.Sprintf("downloading %s failed with status %d %s", e.url, e.code, http.StatusText(e.code))
+}
+
+// isRetryableDownloadError returns true for errors of the network, eg flaky dns, and of the server, eg rate limits,
+// which are worth retrying
+func isRetryableDownloadError(err error) bool {
+	var statusErr *schemaStatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code == http.StatusTooManyRequests || statusErr.code >= http.StatusInternalServerError
+	}
+	if isTransientError(err) {
+		return true
+	}
+	// hosts which do not exist are not retried, unlike lookups failing on an unreachable resolver
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary || dnsErr.IsTimeout
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// verifySchema checks that the downloaded spec of releaseVersion is json and matches its recorded sha256 if any, so
+// that truncated or tampered specs are never used or cached
+func verifySchema(releaseVersion string, data []byte, checksums map[string]string) error {
+	if !json.Valid(data) {
+		return fmt.Errorf("the downloaded spec of kubernetes %s is not valid json", releaseVersion)
+	}
+	expected, ok := checksums[releaseVersion]
+	if !ok {
+		return nil
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); !strings.EqualFold(actual, expected) {
+		return fmt.Errorf("the sha256 of the downloaded spec of kubernetes %s is %s, expected %s", releaseVersion, actual, expected)
+	}
+	return nil
+}
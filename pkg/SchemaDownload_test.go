@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubeChecker_downloadFile(t *testing.T) {
+	defer func(sleep func(context.Context, time.Duration) error) { retrySleep = sleep }(retrySleep)
+	retrySleep = func(context.Context, time.Duration) error { return nil }
+
+	var requests atomic.Int32
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/mirror/release-1.29/api/openapi-spec/swagger.json":
+			// rate limited before succeeding
+			if requests.Add(1) < 3 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			_, _ = w.Write([]byte(minimalSwagger))
+		case "/mirror/release-1.28/api/openapi-spec/swagger.json":
+			_, _ = w.Write([]byte(minimalSwagger[:40]))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+	sum := sha256.Sum256([]byte(minimalSwagger))
+	checksum := hex.EncodeToString(sum[:])
+	dir := t.TempDir()
+
+	k := NewCachedKubeChecker(&Config{SchemaCacheDir: dir, SchemaBaseURL: server.URL + "/mirror/", SchemaChecksums: map[string]string{"1.29": checksum}})
+	data, err := k.downloadFile("1.29")
+	assert.NoError(t, err)
+	assert.Equal(t, minimalSwagger, string(data))
+	assert.Equal(t, int32(3), requests.Load())
+
+	// errors of the request are not retried
+	paths = nil
+	_, err = k.downloadFile("1.30")
+	assert.ErrorContains(t, err, "status 403 Forbidden")
+	assert.Equal(t, []string{"/mirror/release-1.30/api/openapi-spec/swagger.json"}, paths)
+
+	// truncated and tampered specs are never cached
+	assert.ErrorContains(t, k.LoadFromUrl("1.28", false), "not valid json")
+	assert.NoFileExists(t, filepath.Join(dir, "swagger-1.28.json"))
+	k.checksums = map[string]string{"1.29": "0000"}
+	k.cacheDir = filepath.Join(dir, "tampered")
+	assert.ErrorContains(t, k.LoadFromUrl("1.29", false), "expected 0000")
+	assert.NoFileExists(t, filepath.Join(dir, "tampered", "swagger-1.29.json"))
+}
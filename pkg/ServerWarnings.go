@@ -0,0 +1,156 @@
+package pkg
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilnet "k8s.io/apimachinery/pkg/util/net"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// ServerWarning is a warning the api server returned while the cluster was scanned, eg the deprecation warning of
+// every list of a deprecated resource
+type ServerWarning struct {
+	// Resource is the resource the request returning the warning was for, empty for requests outside of resources
+	Resource schema.GroupVersionResource `json:"-"`
+	// APIVersion and Kind are those of Resource when it was listed by the scan
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Message    string `json:"message"`
+	// Count is the number of responses the warning was returned in
+	Count int `json:"count"`
+}
+
+// serverWarnings collects the warnings returned by the api server, identical warnings for the same resource are
+// recorded once
+type serverWarnings struct {
+	mu       sync.Mutex
+	warnings []*ServerWarning
+	kinds    map[schema.GroupVersionResource]schema.GroupVersionKind
+	// show prints each warning once when it is first returned, see Config.ShowServerWarnings
+	show bool
+}
+
+func newServerWarnings(conf *Config) *serverWarnings {
+	return &serverWarnings{kinds: map[schema.GroupVersionResource]schema.GroupVersionKind{}, show: conf != nil && conf.ShowServerWarnings}
+}
+
+// collect makes the requests of restConfig record the warnings of their responses in w rather than have client-go
+// print them
+func (w *serverWarnings) collect(restConfig *rest.Config) {
+	restConfig.WarningHandler = rest.NoWarnings{}
+	restConfig.WrapTransport = transport.Wrappers(restConfig.WrapTransport, func(rt http.RoundTripper) http.RoundTripper {
+		return &warningRoundTripper{rt: rt, warnings: w}
+	})
+}
+
+// setKind records the kind the objects of resource are listed as
+func (w *serverWarnings) setKind(resource schema.GroupVersionResource, kind schema.GroupVersionKind) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.kinds[resource] = kind
+}
+
+func (w *serverWarnings) record(resource schema.GroupVersionResource, message string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, warning := range w.warnings {
+		if warning.Resource == resource && warning.Message == message {
+			warning.Count++
+			return
+		}
+	}
+	w.warnings = append(w.warnings, &ServerWarning{Resource: resource, Message: message, Count: 1})
+	if w.show {
+		kLog.Warn(fmt.Sprintf("api server warning for %s: %s", resource.String(), message))
+	}
+}
+
+// list returns the warnings recorded so far sorted by resource
+func (w *serverWarnings) list() []ServerWarning {
+	if w == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	warnings := make([]ServerWarning, 0, len(w.warnings))
+	for _, warning := range w.warnings {
+		listed := *warning
+		if kind, ok := w.kinds[warning.Resource]; ok {
+			listed.APIVersion, listed.Kind = kind.GroupVersion().String(), kind.Kind
+		}
+		warnings = append(warnings, listed)
+	}
+	sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Resource.String() < warnings[j].Resource.String() })
+	return warnings
+}
+
+type warningRoundTripper struct {
+	rt       http.RoundTripper
+	warnings *serverWarnings
+}
+
+func (t *warningRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	if resp == nil {
+		return resp, err
+	}
+	if headers := resp.Header.Values("Warning"); len(headers) > 0 {
+		parsed, _ := utilnet.ParseWarningHeaders(headers)
+		for _, warning := range parsed {
+			// 299 is the code of the warnings of kubernetes, others come from proxies
+			if warning.Code == 299 && len(warning.Text) > 0 {
+				t.warnings.record(resourceOfPath(req.URL.Path), warning.Text)
+			}
+		}
+	}
+	return resp, err
+}
+
+// resourceOfPath returns the resource of an api path like /apis/<group>/<version>/namespaces/<ns>/<resource>
+func resourceOfPath(path string) schema.GroupVersionResource {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	var gvr schema.GroupVersionResource
+	switch {
+	case len(parts) >= 2 && parts[0] == "api":
+		gvr.Version, parts = parts[1], parts[2:]
+	case len(parts) >= 3 && parts[0] == "apis":
+		gvr.Group, gvr.Version, parts = parts[1], parts[2], parts[3:]
+	default:
+		return gvr
+	}
+	if len(parts) >= 3 && parts[0] == "namespaces" {
+		parts = parts[2:]
+	}
+	if len(parts) > 0 {
+		gvr.Resource = parts[0]
+	}
+	return gvr
+}
+
+// ServerWarnings returns the warnings the api server returned so far, identical warnings for the same resource are
+// only listed once along with the number of times they were returned
+func (c *Cluster) ServerWarnings() []ServerWarning {
+	return c.warnings.list()
+}
+
+// AttachServerWarnings adds the messages of warnings to the results of objects of the kind and api version they were
+// returned for, so that they back the findings of the schemas
+func AttachServerWarnings(results []ValidationResult, warnings []ServerWarning) {
+	for i := range results {
+		for _, warning := range warnings {
+			if len(warning.Kind) > 0 && warning.Kind == results[i].Kind && warning.APIVersion == results[i].APIVersion {
+				results[i].ServerWarnings = append(results[i].ServerWarnings, warning.Message)
+			}
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package pkg
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_ServerWarnings(t *testing.T) {
+	server := newFakeApiServer(t)
+	pspGvk := schema.GroupVersionKind{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}
+	pspGvr := server.addResource(pspGvk, "podsecuritypolicies", false)
+	server.addObject(pspGvr, newFakeObject("policy/v1beta1", "PodSecurityPolicy", "", "restricted"))
+	const deprecation = "policy/v1beta1 PodSecurityPolicy is deprecated in v1.21+, unavailable in v1.25+"
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Path == "/apis/policy/v1beta1/podsecuritypolicies" {
+			w.Header().Add("Warning", `299 - "`+deprecation+`"`)
+			w.Header().Add("Warning", `199 proxy "not from the api server"`)
+		}
+		return false
+	}
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{pspGvk}
+
+	// identical warnings are recorded once
+	for i := 0; i < 2; i++ {
+		_, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, []ServerWarning{{Resource: pspGvr, APIVersion: "policy/v1beta1", Kind: "PodSecurityPolicy", Message: deprecation, Count: 2}}, cluster.ServerWarnings())
+
+	results := []ValidationResult{{Kind: "PodSecurityPolicy", APIVersion: "policy/v1beta1"}, {Kind: "PodSecurityPolicy", APIVersion: "policy/v1"}}
+	AttachServerWarnings(results, cluster.ServerWarnings())
+	assert.Equal(t, []string{deprecation}, results[0].ServerWarnings)
+	assert.Empty(t, results[1].ServerWarnings)
+}
+
+func TestResourceOfPath(t *testing.T) {
+	for path, want := range map[string]schema.GroupVersionResource{
+		"/api/v1/pods":            {Version: "v1", Resource: "pods"},
+		"/api/v1/namespaces":      {Version: "v1", Resource: "namespaces"},
+		"/api/v1/namespaces/shop": {Version: "v1", Resource: "namespaces"},
+		"/apis/networking.k8s.io/v1beta1/namespaces/shop/ingresses/web": {Group: "networking.k8s.io", Version: "v1beta1", Resource: "ingresses"},
+		"/version": {},
+	} {
+		assert.Equal(t, want, resourceOfPath(path), path)
+	}
+}
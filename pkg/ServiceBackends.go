@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// FetchServiceBackends returns the Service along with the Pods selected by it and the workloads owning those
+// Pods, walking owner references up to the top level controller, eg Pod -> ReplicaSet -> Deployment. Owners which
+// cannot be resolved or fetched do not stop the others, the objects fetched are returned along with their errors
+func (c *Cluster) FetchServiceBackends(ctx context.Context, namespace, serviceName string) ([]unstructured.Unstructured, error) {
+	if err := c.initClients(); err != nil {
+		return nil, err
+	}
+	serviceGvr := schema.GroupVersionResource{Version: "v1", Resource: "services"}
+	svc, err := c.dynamicClient().Resource(serviceGvr).Namespace(namespace).Get(ctx, serviceName, v1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch service %s/%s: %w", namespace, serviceName, err)
+	}
+	objs := []unstructured.Unstructured{*svc}
+	selector, _, err := unstructured.NestedStringMap(svc.Object, "spec", "selector")
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector on service %s/%s: %w", namespace, serviceName, err)
+	}
+	// services without selector are backed by manually managed endpoints
+	if len(selector) == 0 {
+		return objs, nil
+	}
+
+	podGvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	pods, err := c.dynamicClient().Resource(podGvr).Namespace(namespace).List(ctx, v1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pods of service %s/%s: %w", namespace, serviceName, err)
+	}
+
+	mapper := c.mapper(c.cachedDiscovery())
+	seen := map[string]bool{}
+	var errs []error
+	for _, pod := range pods.Items {
+		objs = append(objs, pod)
+		owners := pod.GetOwnerReferences()
+		for len(owners) > 0 {
+			owner := controllerOf(owners)
+			if owner == nil || seen[string(owner.UID)] {
+				break
+			}
+			seen[string(owner.UID)] = true
+			gv, err := schema.ParseGroupVersion(owner.APIVersion)
+			if err != nil {
+				break
+			}
+			mapping, err := mapper.RESTMapping(gv.WithKind(owner.Kind).GroupKind(), gv.Version)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to resolve owner %s %s/%s of pod %s: %w", owner.Kind, namespace, owner.Name, pod.GetName(), err))
+				break
+			}
+			obj, err := c.dynamicClient().Resource(mapping.Resource).Namespace(namespace).Get(ctx, owner.Name, v1.GetOptions{})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("unable to fetch owner %s %s/%s of pod %s: %w", owner.Kind, namespace, owner.Name, pod.GetName(), err))
+				break
+			}
+			objs = append(objs, *obj)
+			owners = obj.GetOwnerReferences()
+		}
+	}
+	return objs, errors.Join(errs...)
+}
+
+// controllerOf returns the managing controller among owners, falling back to the first owner
+func controllerOf(owners []v1.OwnerReference) *v1.OwnerReference {
+	for i := range owners {
+		if owners[i].Controller != nil && *owners[i].Controller {
+			return &owners[i]
+		}
+	}
+	if len(owners) > 0 {
+		return &owners[0]
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchServiceBackends(t *testing.T) {
+	server := newFakeApiServer(t)
+	services := server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "Service"}, "services", true)
+	pods := server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "Pod"}, "pods", true)
+	replicaSets := server.addResource(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "ReplicaSet"}, "replicasets", true)
+	deployments := server.addResource(schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}, "deployments", true)
+
+	svc := newFakeObject("v1", "Service", "shop", "cart")
+	svc["spec"] = map[string]interface{}{"selector": map[string]interface{}{"app": "cart"}}
+	server.addObject(services, svc)
+	headless := newFakeObject("v1", "Service", "shop", "external")
+	server.addObject(services, headless)
+
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	rs := newFakeObject("apps/v1", "ReplicaSet", "shop", "cart-5d8f")
+	rs["metadata"].(map[string]interface{})["ownerReferences"] = []interface{}{
+		map[string]interface{}{"apiVersion": "apps/v1", "kind": "Deployment", "name": "cart", "uid": "deployment-shop-cart", "controller": true},
+	}
+	server.addObject(replicaSets, rs)
+	for _, name := range []string{"cart-5d8f-a", "cart-5d8f-b"} {
+		pod := newFakeObject("v1", "Pod", "shop", name)
+		metadata := pod["metadata"].(map[string]interface{})
+		metadata["labels"] = map[string]interface{}{"app": "cart"}
+		metadata["ownerReferences"] = []interface{}{
+			map[string]interface{}{"apiVersion": "apps/v1", "kind": "ReplicaSet", "name": "cart-5d8f", "uid": "replicaset-shop-cart-5d8f", "controller": true},
+		}
+		server.addObject(pods, pod)
+	}
+	other := newFakeObject("v1", "Pod", "shop", "checkout")
+	other["metadata"].(map[string]interface{})["labels"] = map[string]interface{}{"app": "checkout"}
+	server.addObject(pods, other)
+
+	orders := newFakeObject("v1", "Service", "shop", "orders")
+	orders["spec"] = map[string]interface{}{"selector": map[string]interface{}{"app": "orders"}}
+	server.addObject(services, orders)
+	orphan := newFakeObject("v1", "Pod", "shop", "orders-7c9b-a")
+	orphanMetadata := orphan["metadata"].(map[string]interface{})
+	orphanMetadata["labels"] = map[string]interface{}{"app": "orders"}
+	orphanMetadata["ownerReferences"] = []interface{}{
+		map[string]interface{}{"apiVersion": "apps/v1", "kind": "ReplicaSet", "name": "orders-7c9b", "uid": "replicaset-shop-orders-7c9b", "controller": true},
+	}
+	server.addObject(pods, orphan)
+
+	cluster := server.cluster(t)
+	tests := []struct {
+		name    string
+		service string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "selected pods and their owners",
+			service: "cart",
+			want:    []string{"Service/cart", "Pod/cart-5d8f-a", "ReplicaSet/cart-5d8f", "Deployment/cart", "Pod/cart-5d8f-b"},
+		},
+		{
+			name:    "service without selector",
+			service: "external",
+			want:    []string{"Service/external"},
+		},
+		{
+			name:    "missing owner",
+			service: "orders",
+			want:    []string{"Service/orders", "Pod/orders-7c9b-a"},
+			wantErr: true,
+		},
+		{
+			name:    "missing service",
+			service: "payments",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs, err := cluster.FetchServiceBackends(context.Background(), "shop", tt.service)
+			if tt.wantErr {
+				assert.Error(t, err)
+				if tt.want == nil {
+					return
+				}
+			} else {
+				assert.NoError(t, err)
+			}
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind()+"/"+obj.GetName())
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities of the findings of results, from the least to the most severe
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// RuleUnknownField is the rule of the fields of objects their schema does not define, see Config.Strict
+const RuleUnknownField = "unknown-field"
+
+// RuleAlreadyRemovedAPI is the rule of objects whose api version is already removed in the source version, see
+// MarkAlreadyRemoved
+const RuleAlreadyRemovedAPI = "already-removed-api"
+
+// DefaultFailThreshold is the severity from which findings fail the run when Config.FailThreshold is not set
+const DefaultFailThreshold = SeverityWarning
+
+var severityRanks = map[string]int{SeverityInfo: 1, SeverityWarning: 2, SeverityError: 3}
+
+// defaultRuleSeverities are the severities of the findings of each rule unless overridden by
+// Config.SeverityOverrides, unknown fields are errors with Config.Strict
+var defaultRuleSeverities = map[string]string{
+	RuleRemovedAPI:         SeverityError,
+	RuleAlreadyRemovedAPI:  SeverityError,
+	RuleDeprecatedAPI:      SeverityWarning,
+	RuleSchemaError:        SeverityWarning,
+	RuleDeprecatedField:    SeverityInfo,
+	RuleUnknownField:       SeverityInfo,
+	RuleDryRunRejected:     SeverityError,
+	RuleDeprecatedPodField: SeverityWarning,
+}
+
+// IsInformational returns true if findings of the result are reported but must never fail a run,
+// which is the case for kinds listed in Config.DowngradeToInfo
+func IsInformational(vr ValidationResult, conf *Config) bool {
+	return conf != nil && len(vr.Kind) > 0 && Contains(vr.Kind, conf.DowngradeToInfo)
+}
+
+// ruleSeverity returns the severity of the findings of rule
+func (c *Config) ruleSeverity(rule string) string {
+	if c != nil {
+		if severity, ok := c.SeverityOverrides[rule]; ok {
+			return strings.ToLower(severity)
+		}
+		if rule == RuleUnknownField && c.Strict {
+			return SeverityError
+		}
+	}
+	return defaultRuleSeverities[rule]
+}
+
+// ResultSeverity returns the severity of the most severe finding of the result, or an empty string when it has
+// none. The findings of kinds downgraded to info are info at most
+func ResultSeverity(vr ValidationResult, conf *Config) string {
+	rules := []struct {
+		rule    string
+		present bool
+	}{
+		{RuleRemovedAPI, vr.Deleted && !vr.AlreadyRemoved},
+		{RuleAlreadyRemovedAPI, vr.AlreadyRemoved},
+		{RuleDeprecatedAPI, vr.Deprecated},
+		{RuleSchemaError, len(vr.ErrorsForOriginal)+len(vr.ErrorsForLatest) > 0},
+		{RuleDeprecatedField, len(vr.DeprecationForOriginal)+len(vr.DeprecationForLatest) > 0},
+		{RuleUnknownField, len(vr.UnknownFields) > 0},
+		{RuleDryRunRejected, len(vr.DryRunRejection) > 0},
+		{RuleDeprecatedPodField, len(vr.PodFieldDeprecations) > 0},
+	}
+	severity := ""
+	for _, r := range rules {
+		if r.present && severityRanks[conf.ruleSeverity(r.rule)] > severityRanks[severity] {
+			severity = conf.ruleSeverity(r.rule)
+		}
+	}
+	for _, f := range vr.CustomRuleFindings {
+		if severityRanks[f.Severity] > severityRanks[severity] {
+			severity = f.Severity
+		}
+	}
+	if len(severity) > 0 && IsInformational(vr, conf) {
+		return SeverityInfo
+	}
+	return severity
+}
+
+// failThreshold returns the severity from which findings fail the run
+func (c *Config) failThreshold() string {
+	if c == nil || len(c.FailThreshold) == 0 {
+		return DefaultFailThreshold
+	}
+	return strings.ToLower(c.FailThreshold)
+}
+
+// HasGatingFindings returns true if any of the results has findings at least as severe as the fail threshold of
+// conf, see ResultSeverity, ignoring the results downgraded to info. By default removed or deprecated api versions
+// and schema errors fail the run
+func HasGatingFindings(results []ValidationResult, conf *Config) bool {
+	threshold := severityRanks[conf.failThreshold()]
+	for _, r := range results {
+		if IsInformational(r, conf) {
+			continue
+		}
+		if severity := ResultSeverity(r, conf); len(severity) > 0 && severityRanks[severity] >= threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// SeverityCounts returns the number of results by severity, results without findings are not counted
+func SeverityCounts(results []ValidationResult) map[string]int {
+	counts := map[string]int{}
+	for _, r := range results {
+		if len(r.Severity) > 0 {
+			counts[r.Severity]++
+		}
+	}
+	return counts
+}
+
+// validateSeverities returns an error if the fail threshold or the severity overrides of c are invalid
+func (c *Config) validateSeverities() error {
+	if _, ok := severityRanks[c.failThreshold()]; !ok {
+		return fmt.Errorf("invalid fail threshold %q, expected one of %s, %s or %s", c.FailThreshold, SeverityInfo, SeverityWarning, SeverityError)
+	}
+	for rule, severity := range c.SeverityOverrides {
+		if _, ok := defaultRuleSeverities[rule]; !ok {
+			return fmt.Errorf("unknown rule %q in severity overrides, expected one of %s, %s, %s, %s, %s, %s, %s or %s", rule,
+				RuleRemovedAPI, RuleAlreadyRemovedAPI, RuleDeprecatedAPI, RuleSchemaError, RuleDeprecatedField, RuleUnknownField, RuleDryRunRejected, RuleDeprecatedPodField)
+		}
+		if _, ok := severityRanks[strings.ToLower(severity)]; !ok {
+			return fmt.Errorf("invalid severity %q of rule %s, expected one of %s, %s or %s", severity, rule, SeverityInfo, SeverityWarning, SeverityError)
+		}
+	}
+	return nil
+}
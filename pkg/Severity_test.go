@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasGatingFindings(t *testing.T) {
+	removedJob := ValidationResult{Kind: "Job", APIVersion: "batch/v1beta1", Deleted: true}
+	deprecatedCronJob := ValidationResult{Kind: "CronJob", APIVersion: "batch/v1beta1", Deprecated: true}
+	invalidDeployment := ValidationResult{Kind: "Deployment", ErrorsForLatest: []*openapi3.SchemaError{{Reason: "invalid"}}}
+	tests := []struct {
+		name    string
+		results []ValidationResult
+		conf    *Config
+		want    bool
+	}{
+		{
+			name:    "removed api fails the run",
+			results: []ValidationResult{removedJob},
+			conf:    &Config{},
+			want:    true,
+		},
+		{
+			name:    "downgraded kinds never fail the run",
+			results: []ValidationResult{removedJob, deprecatedCronJob},
+			conf:    &Config{DowngradeToInfo: []string{"job", "Cron*"}},
+			want:    false,
+		},
+		{
+			name:    "other kinds still fail the run",
+			results: []ValidationResult{removedJob, invalidDeployment},
+			conf:    &Config{DowngradeToInfo: []string{"Job"}},
+			want:    true,
+		},
+		{
+			name:    "nil config",
+			results: []ValidationResult{deprecatedCronJob},
+			want:    true,
+		},
+		{
+			name:    "no findings",
+			results: []ValidationResult{{Kind: "Job"}},
+			conf:    &Config{},
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, HasGatingFindings(tt.results, tt.conf))
+		})
+	}
+}
+
+func TestResultSeverity(t *testing.T) {
+	deprecatedField := []*SchemaError{{}}
+	tests := []struct {
+		name string
+		vr   ValidationResult
+		conf *Config
+		want string
+	}{
+		{name: "removed", vr: ValidationResult{Kind: "Job", Deleted: true, Deprecated: true}, want: SeverityError},
+		{name: "deprecated", vr: ValidationResult{Kind: "CronJob", Deprecated: true, DeprecationForOriginal: deprecatedField}, want: SeverityWarning},
+		{name: "schema error", vr: ValidationResult{Kind: "Deployment", ErrorsForLatest: []*openapi3.SchemaError{{Reason: "invalid"}}}, want: SeverityWarning},
+		{name: "deprecated field", vr: ValidationResult{Kind: "Pod", DeprecationForLatest: deprecatedField}, want: SeverityInfo},
+		{name: "unknown field", vr: ValidationResult{Kind: "Pod", UnknownFields: []string{"spec.replica"}}, conf: &Config{}, want: SeverityInfo},
+		{name: "unknown field when strict", vr: ValidationResult{Kind: "Pod", UnknownFields: []string{"spec.replica"}}, conf: &Config{Strict: true}, want: SeverityError},
+		{name: "overridden", vr: ValidationResult{Kind: "CronJob", Deprecated: true}, conf: &Config{SeverityOverrides: map[string]string{RuleDeprecatedAPI: "Error"}}, want: SeverityError},
+		{name: "downgraded kind", vr: ValidationResult{Kind: "Job", Deleted: true}, conf: &Config{DowngradeToInfo: []string{"Job"}}, want: SeverityInfo},
+		{name: "no findings", vr: ValidationResult{Kind: "Job"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ResultSeverity(tt.vr, tt.conf))
+		})
+	}
+}
+
+func TestHasGatingFindings_failThreshold(t *testing.T) {
+	deprecated := ValidationResult{Kind: "CronJob", Deprecated: true}
+	deprecatedField := ValidationResult{Kind: "Pod", DeprecationForLatest: []*SchemaError{{}}}
+
+	assert.False(t, HasGatingFindings([]ValidationResult{deprecatedField}, &Config{}))
+	assert.True(t, HasGatingFindings([]ValidationResult{deprecatedField}, &Config{FailThreshold: SeverityInfo}))
+	assert.False(t, HasGatingFindings([]ValidationResult{deprecated, deprecatedField}, &Config{FailThreshold: SeverityError}))
+	assert.True(t, HasGatingFindings([]ValidationResult{{Kind: "Job", Deleted: true}}, &Config{FailThreshold: SeverityError}))
+
+	assert.Equal(t, map[string]int{SeverityWarning: 2, SeverityInfo: 1}, SeverityCounts([]ValidationResult{
+		{Severity: SeverityWarning}, {Severity: SeverityInfo}, {Severity: SeverityWarning}, {},
+	}))
+
+	assert.NoError(t, (&Config{FailThreshold: "Error", SeverityOverrides: map[string]string{RuleSchemaError: "info"}}).validateSeverities())
+	assert.ErrorContains(t, (&Config{FailThreshold: "fatal"}).validateSeverities(), `invalid fail threshold "fatal"`)
+	assert.ErrorContains(t, (&Config{SeverityOverrides: map[string]string{"typo": "info"}}).validateSeverities(), `unknown rule "typo"`)
+	assert.ErrorContains(t, (&Config{SeverityOverrides: map[string]string{RuleSchemaError: "minor"}}).validateSeverities(), `invalid severity "minor"`)
+}
@@ -0,0 +1,110 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	kErrors "github.com/devtron-labs/silver-surfer/pkg/errors"
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+)
+
+// specVersionPattern matches the kubernetes versions specs are looked up by, with or without a leading v
+var specVersionPattern = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+// specVersion is a kubernetes version the spec of which is looked up, major.minor for the latest spec of the minor
+// version or major.minor.patch for the spec of an exact release
+type specVersion struct {
+	major, minor, patch int
+	exact               bool
+}
+
+// parseSpecVersion parses version as major.minor or major.minor.patch, eg 1.28, 1.28.9 or v1.28.9
+func parseSpecVersion(version string) (specVersion, error) {
+	match := specVersionPattern.FindStringSubmatch(strings.TrimSpace(version))
+	if match == nil {
+		return specVersion{}, fmt.Errorf("invalid kubernetes version %q: expected major.minor or major.minor.patch eg 1.28, 1.28.9 or v1.28.9", version)
+	}
+	v := specVersion{exact: len(match[3]) > 0}
+	v.major, _ = strconv.Atoi(match[1])
+	v.minor, _ = strconv.Atoi(match[2])
+	if v.exact {
+		v.patch, _ = strconv.Atoi(match[3])
+	}
+	return v, nil
+}
+
+func (v specVersion) String() string {
+	if v.exact {
+		return fmt.Sprintf("%d.%d.%d", v.major, v.minor, v.patch)
+	}
+	return v.minorVersion()
+}
+
+func (v specVersion) minorVersion() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+// ref is the tag of the exact release or else the release branch of the minor version the spec is published under
+func (v specVersion) ref() string {
+	if v.exact {
+		return "v" + v.String()
+	}
+	return "release-" + v.minorVersion()
+}
+
+// validateKubernetesVersions returns an error for the first of versions which is neither master nor a version specs
+// are looked up by, see parseSpecVersion
+func validateKubernetesVersions(versions ...string) error {
+	for _, version := range versions {
+		if version == "master" {
+			continue
+		}
+		if _, err := parseSpecVersion(version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// specCandidates returns the versions the spec of version is looked up by in order: the exact release of a patch
+// version and then the latest spec of its minor version
+func specCandidates(version string) []string {
+	v, err := parseSpecVersion(version)
+	if err != nil {
+		return []string{version}
+	}
+	if !v.exact {
+		return []string{v.String()}
+	}
+	return []string{v.String(), v.minorVersion()}
+}
+
+// findSchema returns the spec of releaseVersion along with the version it is the spec of, see specCandidates. Each
+// candidate is read from the cache and else downloaded before falling back to the next one
+func (k *kubeCheckerImpl) findSchema(releaseVersion string) ([]byte, string, error) {
+	candidates := specCandidates(releaseVersion)
+	for i, candidate := range candidates {
+		data, err := k.readCachedSchema(candidate)
+		if err == nil && data == nil {
+			if data, err = k.downloadFile(candidate); err == nil {
+				k.cacheSchema(candidate, data)
+			}
+		}
+		if err == nil {
+			return data, candidate, nil
+		}
+		var notCached *SchemaNotCachedError
+		if i == len(candidates)-1 || !errors.Is(err, kErrors.ErrOpenApiSpecNotFound) && !errors.As(err, &notCached) {
+			return nil, "", err
+		}
+		warnSpecFallback(candidate, candidates[i+1])
+	}
+	return nil, "", kErrors.ErrOpenApiSpecNotFound
+}
+
+func warnSpecFallback(version, fallback string) {
+	kLog.Warn(fmt.Sprintf("no spec of kubernetes %s is available, falling back to the latest spec of %s", version, fallback))
+}
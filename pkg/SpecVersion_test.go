@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSpecVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+		wantRef string
+		wantErr bool
+	}{
+		{version: "1.28", want: "1.28", wantRef: "release-1.28"},
+		{version: "v1.28", want: "1.28", wantRef: "release-1.28"},
+		{version: "1.28.9", want: "1.28.9", wantRef: "v1.28.9"},
+		{version: " v1.28.9", want: "1.28.9", wantRef: "v1.28.9"},
+		{version: "v1.28.x", wantErr: true},
+		{version: "1.28.9-eks", wantErr: true},
+		{version: "1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := parseSpecVersion(tt.version)
+			if tt.wantErr {
+				assert.ErrorContains(t, err, "expected major.minor or major.minor.patch")
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+			assert.Equal(t, tt.wantRef, got.ref())
+		})
+	}
+	assert.Equal(t, []string{"1.28.9", "1.28"}, specCandidates("v1.28.9"))
+	assert.Equal(t, []string{"1.28"}, specCandidates("1.28"))
+	assert.Equal(t, "https://raw.githubusercontent.com/kubernetes/kubernetes/v1.28.9/api/openapi-spec/swagger.json", schemaURL("", "1.28.9"))
+}
+
+func TestConfig_Validate_targetVersions(t *testing.T) {
+	assert.NoError(t, (&Config{TargetKubernetesVersion: "1.27,v1.28.9,master"}).Validate())
+	assert.ErrorContains(t, (&Config{TargetKubernetesVersion: "1.27,v1.28.x"}).Validate(), `invalid kubernetes version "v1.28.x"`)
+	assert.ErrorContains(t, (&Config{SourceKubernetesVersion: "latest"}).Validate(), `invalid kubernetes version "latest"`)
+}
+
+// serveReleases serves minimalSwagger for the tags and release branches in refs and counts the requests, it returns
+// the base url of the server
+func serveReleases(t *testing.T, refs ...string) (string, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		for _, ref := range refs {
+			if r.URL.Path == "/"+ref+"/api/openapi-spec/swagger.json" {
+				_, _ = w.Write([]byte(minimalSwagger))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL, &requests
+}
+
+func TestKubeChecker_LoadFromUrl_patchVersion(t *testing.T) {
+	baseURL, requests := serveReleases(t, "v1.29.1", "release-1.29", "release-1.28")
+	dir := t.TempDir()
+	object := map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"name": "settings"}}
+
+	// the spec of the exact release is used when it is published
+	k := NewCachedKubeChecker(&Config{SchemaCacheDir: dir, SchemaBaseURL: baseURL})
+	assert.NoError(t, k.LoadFromUrl("v1.29.1", false))
+	result, err := k.ValidateObject(object, "v1.29.1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.29.1", result.SpecVersion)
+	assert.FileExists(t, filepath.Join(dir, "swagger-1.29.1.json"))
+	assert.Equal(t, int32(1), requests.Load())
+
+	// and else the latest spec of its minor version
+	assert.NoError(t, k.LoadFromUrl("1.28.9", false))
+	result, err = k.ValidateObject(object, "1.28.9")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.28", result.SpecVersion)
+	assert.FileExists(t, filepath.Join(dir, "swagger-1.28.json"))
+	assert.NoFileExists(t, filepath.Join(dir, "swagger-1.28.9.json"))
+	assert.Equal(t, int32(3), requests.Load())
+
+	// the cached spec of the minor version stands for the patch version offline
+	offline := &Config{SchemaCacheDir: dir, Offline: true}
+	assert.NoError(t, offline.MissingSchemas("1.28.9", "1.29.1"))
+	k = NewCachedKubeChecker(offline)
+	assert.NoError(t, k.LoadFromUrl("1.28.9", false))
+	result, err = k.ValidateObject(object, "1.28.9")
+	assert.NoError(t, err)
+	assert.Equal(t, "1.28", result.SpecVersion)
+	var notCached *SchemaNotCachedError
+	assert.True(t, errors.As(k.LoadFromUrl("1.27.3", false), &notCached))
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestDownloadSchemas_patchVersion(t *testing.T) {
+	baseURL, _ := serveReleases(t, "v1.29.1", "release-1.28")
+	dir := filepath.Join(t.TempDir(), "schemas")
+
+	assert.NoError(t, DownloadSchemas(&Config{SchemaCacheDir: dir, SchemaBaseURL: baseURL}, []string{"1.29.1", "v1.28.9"}))
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	var files []string
+	for _, entry := range entries {
+		files = append(files, entry.Name())
+	}
+	assert.Equal(t, []string{"swagger-1.28.json", "swagger-1.29.1.json"}, files)
+}
@@ -0,0 +1,202 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// FetchK8sObjectsStream lists the objects of gvks served by the cluster like FetchK8sObjects, but delivers them one
+// by one so that callers can process each object without holding every object of the cluster in memory. Objects are
+// delivered in the same order as FetchK8sObjects returns them and listing blocks while the consumer is busy.
+// The object channel is closed once every object has been delivered, the error channel then yields the error which
+// stopped the scan, if any, and is closed. Once ctx is done both channels are closed without further objects, so
+// callers which stop reading objects must cancel ctx.
+func (c *Cluster) FetchK8sObjectsStream(ctx context.Context, gvks []schema.GroupVersionKind, conf *Config) (<-chan unstructured.Unstructured, <-chan error) {
+	objc, errc, _ := c.streamK8sObjects(ctx, ctx, gvks, conf)
+	return objc, errc
+}
+
+// fetchResult is the outcome of fetchResource
+type fetchResult struct {
+	objs      []unstructured.Unstructured
+	truncated *TruncatedResource
+	skipped   *SkippedResource
+	retries   int
+	err       error
+}
+
+// streamK8sObjects lists resources until ctx is done and delivers their objects until deliverCtx is done. Resources
+// are listed concurrently but at most conf.concurrency() of them are held waiting for delivery. The summary is
+// complete once the error channel is closed.
+func (c *Cluster) streamK8sObjects(ctx, deliverCtx context.Context, gvks []schema.GroupVersionKind, conf *Config) (<-chan unstructured.Unstructured, <-chan error, *FetchSummary) {
+	objc := make(chan unstructured.Unstructured)
+	errc := make(chan error, 1)
+	summary := &FetchSummary{}
+	// objects are filtered by their age at the start of the scan
+	scanStart := time.Now()
+	targets, err := c.fetchTargets(gvks, conf, summary)
+	if err == nil {
+		conf, err = c.resolveNamespaceLabelSelector(ctx, conf)
+	}
+	if err == nil {
+		conf = c.resolveTerminatingNamespaces(ctx, conf)
+	}
+	if err != nil {
+		errc <- err
+		close(objc)
+		close(errc)
+		return objc, errc, summary
+	}
+	if !conf.SkipAccessReview {
+		targets = c.reviewAccess(ctx, targets, conf, summary)
+	}
+
+	go func() {
+		defer close(errc)
+		defer close(objc)
+		fetchCtx, cancel := context.WithCancel(ctx)
+		// event sinks are not expected to be safe for concurrent use
+		var emitMu sync.Mutex
+		emit := func(event ScanEvent) {
+			emitMu.Lock()
+			defer emitMu.Unlock()
+			conf.Emit(event)
+		}
+		// progress is reported under the same lock as events
+		progress := func(report func(ProgressReporter)) {
+			if conf.Progress == nil {
+				return
+			}
+			emitMu.Lock()
+			defer emitMu.Unlock()
+			report(conf.Progress)
+		}
+		// the first error which stops the scan, resources being listed are then cancelled
+		var fatalOnce sync.Once
+		var fatal error
+		stop := func(err error) {
+			fatalOnce.Do(func() { fatal = err })
+			cancel()
+		}
+
+		results := make([]chan fetchResult, len(targets))
+		for i := range results {
+			results[i] = make(chan fetchResult, 1)
+		}
+		// a token is held by every resource from the time it is listed until its objects are delivered
+		tokens := make(chan struct{}, conf.concurrency())
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i, target := range targets {
+				select {
+				case tokens <- struct{}{}:
+				case <-fetchCtx.Done():
+					for _, result := range results[i:] {
+						result <- fetchResult{err: fetchCtx.Err()}
+					}
+					return
+				}
+				wg.Add(1)
+				go func(result chan<- fetchResult, target fetchTarget, index int) {
+					defer wg.Done()
+					progress(func(p ProgressReporter) { p.ResourceStarted(target.resource, index, len(targets)) })
+					var retried atomic.Int64
+					objs, truncated, skipped, err := c.fetchResource(withRetryCounter(fetchCtx, &retried), target, conf, emit)
+					if err != nil && !errors.Is(err, context.Canceled) {
+						stop(err)
+					}
+					if skipped != nil {
+						skipped.Retries = int(retried.Load())
+					}
+					progress(func(p ProgressReporter) {
+						switch {
+						case err != nil:
+							p.ResourceFinished(target.resource, err)
+						case skipped != nil:
+							p.ResourceFinished(target.resource, errors.New(skipped.Message))
+						default:
+							p.ObjectsFetched(target.resource, len(objs))
+							p.ResourceFinished(target.resource, nil)
+						}
+					})
+					result <- fetchResult{objs: objs, truncated: truncated, skipped: skipped, retries: int(retried.Load()), err: err}
+				}(results[i], target, i+1)
+			}
+		}()
+
+		// objects served in several versions or groups, eg autoscaling/v1 and autoscaling/v2, are delivered once
+		delivered := map[types.UID]bool{}
+	deliver:
+		for _, resultc := range results {
+			var result fetchResult
+			select {
+			case result = <-resultc:
+			case <-deliverCtx.Done():
+				break deliver
+			}
+			summary.Retries += result.retries
+			if result.skipped != nil {
+				summary.Skipped = append(summary.Skipped, *result.skipped)
+			} else if result.err == nil {
+				summary.Listed++
+			}
+			if result.truncated != nil {
+				summary.Truncated = append(summary.Truncated, *result.truncated)
+			}
+			if result.err != nil {
+				break
+			}
+			for _, obj := range result.objs {
+				if uid := obj.GetUID(); len(uid) > 0 {
+					if delivered[uid] {
+						continue
+					}
+					delivered[uid] = true
+				}
+				if filter := conf.ageExcluded(&obj, scanStart); len(filter) > 0 {
+					summary.countAgeExcluded(filter)
+					continue
+				}
+				if conf.systemNamespaceExcluded(obj.GetNamespace()) {
+					summary.SystemNamespaceObjects++
+					continue
+				}
+				if conf.terminalObject(&obj) {
+					summary.TerminalObjects++
+					continue
+				}
+				if conf.ignoredByAnnotation(&obj) {
+					summary.Suppressed = append(summary.Suppressed, NewSuppressedObject(&obj, nil))
+					continue
+				}
+				select {
+				case objc <- obj:
+				case <-deliverCtx.Done():
+					break deliver
+				}
+			}
+			<-tokens
+		}
+		cancel()
+		wg.Wait()
+
+		switch {
+		case ctx.Err() != nil:
+			errc <- ctx.Err()
+		case fatal != nil:
+			errc <- fatal
+		case deliverCtx.Err() != nil:
+			errc <- deliverCtx.Err()
+		}
+	}()
+	return objc, errc, summary
+}
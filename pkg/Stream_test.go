@@ -0,0 +1,86 @@
+package pkg
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCluster_FetchK8sObjectsStream(t *testing.T) {
+	server, gvks := newConcurrencyFakeApiServer(t, time.Millisecond)
+	cluster := server.cluster(t)
+
+	want, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{})
+	assert.NoError(t, err)
+	objc, errc := cluster.FetchK8sObjectsStream(context.Background(), gvks, &Config{})
+	var got []string
+	for obj := range objc {
+		got = append(got, obj.GetKind())
+	}
+	assert.NoError(t, <-errc)
+	var wantKinds []string
+	for _, obj := range want {
+		wantKinds = append(wantKinds, obj.GetKind())
+	}
+	assert.Equal(t, wantKinds, got)
+
+	_, errc = cluster.FetchK8sObjectsStream(context.Background(), gvks, &Config{SelectKinds: []string{"~("}})
+	assert.ErrorContains(t, <-errc, "invalid pattern")
+}
+
+func TestCluster_FetchK8sObjectsStream_backpressure(t *testing.T) {
+	server, gvks := newConcurrencyFakeApiServer(t, 0)
+	cluster := server.cluster(t)
+
+	started := make(chan ScanEvent, len(gvks))
+	conf := &Config{
+		Concurrency: 2,
+		EventSink: func(event ScanEvent) {
+			if event.Type == ScanEventResourceStarted {
+				started <- event
+			}
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	objc, _ := cluster.FetchK8sObjectsStream(ctx, gvks, conf)
+	<-objc
+	time.Sleep(100 * time.Millisecond)
+	// one resource waits for the consumer, the others listed are bounded by the concurrency
+	assert.LessOrEqual(t, len(started), conf.Concurrency+1)
+}
+
+func TestCluster_FetchK8sObjectsStream_cancel(t *testing.T) {
+	server, gvks := newConcurrencyFakeApiServer(t, time.Millisecond)
+	cluster := server.cluster(t)
+	closeConnections := func() {
+		cluster.Close()
+		server.CloseClientConnections()
+	}
+	_, _, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{})
+	assert.NoError(t, err)
+	closeConnections()
+	time.Sleep(50 * time.Millisecond)
+	baseline := runtime.NumGoroutine()
+
+	// the consumer walks away after the first object without draining the channels
+	ctx, cancel := context.WithCancel(context.Background())
+	objc, errc := cluster.FetchK8sObjectsStream(ctx, gvks, &Config{})
+	<-objc
+	cancel()
+	for deadline := time.Now().Add(5 * time.Second); runtime.NumGoroutine() > baseline && time.Now().Before(deadline); {
+		closeConnections()
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline, "goroutines of the stream leaked")
+
+	// both channels are closed once ctx is done
+	for range objc {
+	}
+	assert.ErrorIs(t, <-errc, context.Canceled)
+	_, ok := <-errc
+	assert.False(t, ok)
+}
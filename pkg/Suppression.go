@@ -0,0 +1,102 @@
+package pkg
+
+import (
+	"fmt"
+	"strings"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+const (
+	// IgnoreAnnotation set to "true" on an object skips it altogether
+	IgnoreAnnotation = "kubedd.io/ignore"
+	// IgnoreRulesAnnotation suppresses the findings of a comma-separated list of rules for an object, eg removed-api
+	IgnoreRulesAnnotation = "kubedd.io/ignore-rules"
+)
+
+// Rules whose findings can be suppressed with IgnoreRulesAnnotation
+const (
+	RuleRemovedAPI      = "removed-api"
+	RuleDeprecatedAPI   = "deprecated-api"
+	RuleDeprecatedField = "deprecated-field"
+	RuleSchemaError     = "schema-error"
+)
+
+var suppressibleRules = sets.New(RuleRemovedAPI, RuleDeprecatedAPI, RuleDeprecatedField, RuleSchemaError)
+
+// SuppressedObject is an object opted out of the scan by its annotations
+type SuppressedObject struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+	// Rules are the rules whose findings are suppressed, the object is skipped altogether when empty
+	Rules []string `json:"rules,omitempty"`
+}
+
+// NewSuppressedObject describes obj whose findings of rules are suppressed, or which is skipped when rules is empty
+func NewSuppressedObject(obj *unstructured.Unstructured, rules []string) SuppressedObject {
+	return SuppressedObject{Kind: obj.GetKind(), Namespace: obj.GetNamespace(), Name: obj.GetName(), Rules: rules}
+}
+
+func (s SuppressedObject) String() string {
+	name := s.Name
+	if len(s.Namespace) > 0 {
+		name = s.Namespace + "/" + name
+	}
+	if len(s.Rules) == 0 {
+		return fmt.Sprintf("%s %s: ignored", s.Kind, name)
+	}
+	return fmt.Sprintf("%s %s: %s", s.Kind, name, strings.Join(s.Rules, ","))
+}
+
+// ignoredByAnnotation returns true if obj is opted out of the scan with IgnoreAnnotation, unless annotations are
+// disabled by NoAnnotations
+func (c *Config) ignoredByAnnotation(obj v1.Object) bool {
+	return !c.NoAnnotations && strings.EqualFold(strings.TrimSpace(obj.GetAnnotations()[IgnoreAnnotation]), "true")
+}
+
+// SuppressRules removes the findings of the rules listed in the IgnoreRulesAnnotation of obj from result and returns
+// the rules which are suppressed, none when annotations are disabled by NoAnnotations. Unknown rules are ignored
+func SuppressRules(result ValidationResult, obj *unstructured.Unstructured, conf *Config) (ValidationResult, []string) {
+	value, ok := obj.GetAnnotations()[IgnoreRulesAnnotation]
+	if conf.NoAnnotations || !ok {
+		return result, nil
+	}
+	rules := sets.New[string]()
+	for _, rule := range strings.Split(value, ",") {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if len(rule) == 0 {
+			continue
+		}
+		if !suppressibleRules.Has(rule) && !conf.hasCustomRule(rule) {
+			kLog.Warn(fmt.Sprintf("unknown rule %q in annotation %s of %s %s/%s", rule, IgnoreRulesAnnotation, obj.GetKind(), obj.GetNamespace(), obj.GetName()))
+			continue
+		}
+		rules.Insert(rule)
+	}
+	if rules.Has(RuleRemovedAPI) {
+		result.Deleted = false
+	}
+	if rules.Has(RuleDeprecatedAPI) {
+		result.Deprecated = false
+	}
+	if rules.Has(RuleDeprecatedField) {
+		result.DeprecationForOriginal, result.DeprecationForLatest, result.PodFieldDeprecations = nil, nil, nil
+	}
+	if rules.Has(RuleSchemaError) {
+		result.ErrorsForOriginal, result.ErrorsForLatest, result.UnknownFields = nil, nil, nil
+	}
+	if len(result.CustomRuleFindings) > 0 {
+		var findings []CustomRuleFinding
+		for _, f := range result.CustomRuleFindings {
+			if !rules.Has(f.Rule) {
+				findings = append(findings, f)
+			}
+		}
+		result.CustomRuleFindings = findings
+	}
+	return result, sets.List(rules)
+}
@@ -0,0 +1,165 @@
+package pkg
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Findings which can be suppressed by the entries of a suppressions file
+const (
+	FindingRemoved         = "removed"
+	FindingDeprecated      = "deprecated"
+	FindingValidationError = "validation-error"
+)
+
+// suppressionDateLayout is the layout of the expiry dates of suppressions
+const suppressionDateLayout = "2006-01-02"
+
+// Suppression is an entry of a suppressions file accepting the findings of the objects it matches. Empty fields
+// match any object, the others are case-insensitive names or patterns, see matchPattern. The core api group may be
+// given as core
+type Suppression struct {
+	Kind      string `json:"kind,omitempty"`
+	Group     string `json:"group,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name,omitempty"`
+	// Findings are the findings suppressed among FindingRemoved, FindingDeprecated and FindingValidationError, all of
+	// them when empty
+	Findings []string `json:"findings,omitempty"`
+	// Expires is the date, as 2006-01-02, from which the suppression no longer applies
+	Expires string `json:"expires,omitempty"`
+	Owner   string `json:"owner,omitempty"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// SuppressedFinding is a finding of an object accepted by a Suppression, it is reported along with its justification
+type SuppressedFinding struct {
+	Finding string
+	Owner   string `json:",omitempty"`
+	Comment string `json:",omitempty"`
+	Expires string `json:",omitempty"`
+}
+
+// suppressionsFile is the format of Config.SuppressionsFile
+type suppressionsFile struct {
+	Suppressions []Suppression `json:"suppressions"`
+}
+
+// LoadSuppressions reads the entries of SuppressionsFile into Suppressions. Entries which have expired are left out
+// with a warning so that their findings are reported again
+func (c *Config) LoadSuppressions() error {
+	if len(c.SuppressionsFile) == 0 {
+		return nil
+	}
+	data, err := os.ReadFile(c.SuppressionsFile)
+	if err != nil {
+		return fmt.Errorf("unable to read the suppressions file: %w", err)
+	}
+	var file suppressionsFile
+	if err := yaml.UnmarshalStrict(data, &file); err != nil {
+		return fmt.Errorf("unable to parse the suppressions file %s: %w", c.SuppressionsFile, err)
+	}
+	suppressions, err := activeSuppressions(file.Suppressions, time.Now())
+	if err != nil {
+		return fmt.Errorf("invalid suppressions file %s: %w", c.SuppressionsFile, err)
+	}
+	c.Suppressions = suppressions
+	return nil
+}
+
+// activeSuppressions validates suppressions and returns those which have not expired at now
+func activeSuppressions(suppressions []Suppression, now time.Time) ([]Suppression, error) {
+	var active []Suppression
+	for i, s := range suppressions {
+		name := fmt.Sprintf("suppressions[%d]", i)
+		if err := validatePatterns(name, []string{s.Kind, s.Group, s.Namespace, s.Name}); err != nil {
+			return nil, err
+		}
+		for _, finding := range s.Findings {
+			switch finding {
+			case FindingRemoved, FindingDeprecated, FindingValidationError:
+			default:
+				return nil, fmt.Errorf("unknown finding %q in %s, expected one of %s, %s or %s", finding, name, FindingRemoved, FindingDeprecated, FindingValidationError)
+			}
+		}
+		if len(s.Expires) > 0 {
+			expires, err := time.Parse(suppressionDateLayout, s.Expires)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expiry date %q in %s, expected a date as %s", s.Expires, name, suppressionDateLayout)
+			}
+			if !now.Before(expires) {
+				kLog.Warn(fmt.Sprintf("%s %s expired on %s, its findings are reported again", name, s, s.Expires))
+				continue
+			}
+		}
+		active = append(active, s)
+	}
+	return active, nil
+}
+
+func (s Suppression) String() string {
+	var fields []string
+	for _, field := range []struct{ name, value string }{{"kind", s.Kind}, {"group", s.Group}, {"namespace", s.Namespace}, {"name", s.Name}, {"owner", s.Owner}} {
+		if len(field.value) > 0 {
+			fields = append(fields, field.name+"="+field.value)
+		}
+	}
+	return "(" + strings.Join(fields, " ") + ")"
+}
+
+// matches returns true if the suppression applies to an object of gvk in namespace named name
+func (s Suppression) matches(gvk schema.GroupVersionKind, namespace, name string) bool {
+	groupMatches := matchesEntry(gvk.Group, s.Group) || strings.EqualFold(s.Group, "core") && len(gvk.Group) == 0
+	return groupMatches && matchesEntry(gvk.Kind, s.Kind) && matchesEntry(namespace, s.Namespace) && matchesEntry(name, s.Name)
+}
+
+// suppresses returns true if the suppression applies to finding
+func (s Suppression) suppresses(finding string) bool {
+	return len(s.Findings) == 0 || slices.Contains(s.Findings, finding)
+}
+
+func matchesEntry(s, entry string) bool {
+	return len(entry) == 0 || strings.EqualFold(s, entry) || matchPattern(s, entry)
+}
+
+// ApplySuppressions removes the findings of result accepted by the Suppressions of conf matching obj and records them
+// in result.SuppressedFindings with the justification of the first suppression accepting them
+func ApplySuppressions(result ValidationResult, obj *unstructured.Unstructured, conf *Config) ValidationResult {
+	if len(conf.Suppressions) == 0 {
+		return result
+	}
+	findings := []struct {
+		finding string
+		present bool
+		clear   func()
+	}{
+		{FindingRemoved, result.Deleted, func() { result.Deleted = false }},
+		{FindingDeprecated, result.Deprecated, func() { result.Deprecated = false }},
+		{FindingValidationError, len(result.ErrorsForOriginal)+len(result.ErrorsForLatest)+len(result.UnknownFields) > 0, func() {
+			result.ErrorsForOriginal, result.ErrorsForLatest, result.UnknownFields = nil, nil, nil
+		}},
+	}
+	gvk := obj.GroupVersionKind()
+	for _, f := range findings {
+		if !f.present {
+			continue
+		}
+		for _, s := range conf.Suppressions {
+			if !s.matches(gvk, obj.GetNamespace(), obj.GetName()) || !s.suppresses(f.finding) {
+				continue
+			}
+			f.clear()
+			result.SuppressedFindings = append(result.SuppressedFindings, SuppressedFinding{Finding: f.finding, Owner: s.Owner, Comment: s.Comment, Expires: s.Expires})
+			break
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestConfig_LoadSuppressions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`suppressions:
+- kind: PodSecurityPolicy
+  group: policy
+  findings: [removed, deprecated]
+  expires: "2999-01-01"
+  owner: platform-team
+  comment: the vendor operator needs policy/v1beta1 until its next release
+- namespace: legacy-*
+  expires: "2001-01-01"
+`), 0o600))
+
+	conf := &Config{SuppressionsFile: path}
+	assert.NoError(t, conf.LoadSuppressions())
+	// the expired suppression is left out
+	assert.Len(t, conf.Suppressions, 1)
+	assert.Equal(t, "platform-team", conf.Suppressions[0].Owner)
+
+	assert.NoError(t, os.WriteFile(path, []byte("suppressions:\n- kind: Ingress\n  findings: [typo]\n"), 0o600))
+	assert.ErrorContains(t, (&Config{SuppressionsFile: path}).LoadSuppressions(), `unknown finding "typo" in suppressions[0]`)
+	assert.NoError(t, os.WriteFile(path, []byte("suppressions:\n- kind: Ingress\n  expires: next week\n"), 0o600))
+	assert.ErrorContains(t, (&Config{SuppressionsFile: path}).LoadSuppressions(), `invalid expiry date "next week"`)
+	assert.NoError(t, os.WriteFile(path, []byte("suppressions:\n- kinds: Ingress\n"), 0o600))
+	assert.ErrorContains(t, (&Config{SuppressionsFile: path}).LoadSuppressions(), "unable to parse")
+}
+
+func TestApplySuppressions(t *testing.T) {
+	suppressions, err := activeSuppressions([]Suppression{
+		{Kind: "PodSecurityPolicy", Group: "policy", Findings: []string{FindingRemoved}, Owner: "platform-team", Comment: "vendor operator", Expires: "2999-01-01"},
+		{Kind: "ConfigMap", Group: "core", Namespace: "legacy-*", Findings: []string{FindingValidationError}},
+	}, time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	conf := &Config{Suppressions: suppressions}
+
+	psp := &unstructured.Unstructured{Object: newFakeObject("policy/v1beta1", "PodSecurityPolicy", "", "restricted")}
+	got := ApplySuppressions(ValidationResult{Kind: "PodSecurityPolicy", Deleted: true, Deprecated: true}, psp, conf)
+	assert.False(t, got.Deleted)
+	// only the listed findings are suppressed
+	assert.True(t, got.Deprecated)
+	assert.Equal(t, []SuppressedFinding{{Finding: FindingRemoved, Owner: "platform-team", Comment: "vendor operator", Expires: "2999-01-01"}}, got.SuppressedFindings)
+
+	configMap := &unstructured.Unstructured{Object: newFakeObject("v1", "ConfigMap", "legacy-shop", "settings")}
+	got = ApplySuppressions(ValidationResult{Kind: "ConfigMap", ErrorsForOriginal: []*openapi3.SchemaError{{Reason: "invalid"}}, UnknownFields: []string{"dta"}}, configMap, conf)
+	assert.Empty(t, got.ErrorsForOriginal)
+	assert.Empty(t, got.UnknownFields)
+	assert.Equal(t, []SuppressedFinding{{Finding: FindingValidationError}}, got.SuppressedFindings)
+
+	other := &unstructured.Unstructured{Object: newFakeObject("v1", "ConfigMap", "shop", "settings")}
+	got = ApplySuppressions(ValidationResult{Kind: "ConfigMap", UnknownFields: []string{"dta"}}, other, conf)
+	assert.Equal(t, []string{"dta"}, got.UnknownFields)
+	assert.Empty(t, got.SuppressedFindings)
+
+	_, err = activeSuppressions([]Suppression{{Name: "~("}}, time.Now())
+	assert.ErrorContains(t, err, "invalid pattern")
+}
@@ -0,0 +1,101 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// withAnnotation sets an annotation on the object built by newFakeObject
+func withAnnotation(obj map[string]interface{}, key, value string) map[string]interface{} {
+	metadata := obj["metadata"].(map[string]interface{})
+	annotations, _ := metadata["annotations"].(map[string]interface{})
+	if annotations == nil {
+		annotations = map[string]interface{}{}
+	}
+	annotations[key] = value
+	metadata["annotations"] = annotations
+	return obj
+}
+
+func TestSuppressRules(t *testing.T) {
+	result := ValidationResult{
+		Kind:                 "Ingress",
+		Deleted:              true,
+		Deprecated:           true,
+		ErrorsForLatest:      []*openapi3.SchemaError{{Reason: "invalid"}},
+		DeprecationForLatest: []*SchemaError{{Reason: "deprecated"}},
+	}
+	tests := []struct {
+		name      string
+		value     string
+		conf      *Config
+		want      ValidationResult
+		wantRules []string
+	}{
+		{
+			name:  "no annotation",
+			conf:  &Config{},
+			want:  result,
+			value: "",
+		},
+		{
+			name:      "removed and deprecated apis",
+			value:     " Removed-API,deprecated-api,,unknown-rule",
+			conf:      &Config{},
+			want:      ValidationResult{Kind: "Ingress", ErrorsForLatest: result.ErrorsForLatest, DeprecationForLatest: result.DeprecationForLatest},
+			wantRules: []string{RuleDeprecatedAPI, RuleRemovedAPI},
+		},
+		{
+			name:      "field findings",
+			value:     "deprecated-field,schema-error",
+			conf:      &Config{},
+			want:      ValidationResult{Kind: "Ingress", Deleted: true, Deprecated: true},
+			wantRules: []string{RuleDeprecatedField, RuleSchemaError},
+		},
+		{
+			name:  "annotations disabled",
+			value: RuleRemovedAPI,
+			conf:  &Config{NoAnnotations: true},
+			want:  result,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := newFakeObject("extensions/v1beta1", "Ingress", "shop", "web")
+			if len(tt.value) > 0 {
+				obj = withAnnotation(obj, IgnoreRulesAnnotation, tt.value)
+			}
+			got, rules := SuppressRules(result, &unstructured.Unstructured{Object: obj}, tt.conf)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRules, rules)
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_ignoreAnnotation(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	server.addObject(deployments, withAnnotation(newFakeObject("apps/v1", "Deployment", "vendor", "operator"), IgnoreAnnotation, "true"))
+	server.addObject(deployments, withAnnotation(newFakeObject("apps/v1", "Deployment", "shop", "billing"), IgnoreAnnotation, "false"))
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{deploymentGvk}
+
+	objs, summary, err := cluster.FetchK8sObjects(context.Background(), gvks, &Config{})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 2)
+	assert.False(t, summary.Partial())
+	assert.Equal(t, []SuppressedObject{{Kind: "Deployment", Namespace: "vendor", Name: "operator"}}, summary.Suppressed)
+	assert.Equal(t, "all resources fetched\n1 objects suppressed by annotations:\nDeployment vendor/operator: ignored", summary.String())
+
+	objs, summary, err = cluster.FetchK8sObjects(context.Background(), gvks, &Config{NoAnnotations: true})
+	assert.NoError(t, err)
+	assert.Len(t, objs, 3)
+	assert.False(t, summary.HasSuppressed())
+}
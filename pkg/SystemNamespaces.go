@@ -0,0 +1,39 @@
+package pkg
+
+import (
+	"strings"
+)
+
+// DefaultSystemNamespaces are the namespaces of the control plane and of add-ons managed by cloud providers, which
+// users cannot change. Their objects are not scanned unless Config.IncludeSystemNamespaces is set or they are named
+// in Config.SelectNamespaces. Entries may be patterns, library users may extend or replace the list
+var DefaultSystemNamespaces = []string{
+	"kube-system",
+	"kube-public",
+	"kube-node-lease",
+	// GKE
+	"gke-managed-*",
+	"gke-gmp-system",
+	"gmp-system",
+	"gmp-public",
+	// EKS
+	"amazon-cloudwatch",
+	"amazon-guardduty",
+	// AKS
+	"aks-command",
+	"azure-arc",
+}
+
+// systemNamespaceExcluded returns true if namespace is excluded by DefaultSystemNamespaces, namespaces named in
+// SelectNamespaces are never excluded
+func (c *Config) systemNamespaceExcluded(namespace string) bool {
+	if c.IncludeSystemNamespaces || len(namespace) == 0 || !Contains(namespace, DefaultSystemNamespaces) {
+		return false
+	}
+	for _, selected := range c.SelectNamespaces {
+		if !isPattern(selected) && strings.EqualFold(selected, namespace) {
+			return false
+		}
+	}
+	return true
+}
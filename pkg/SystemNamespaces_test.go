@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_FetchK8sObjects_systemNamespaces(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	namespaceGvk := schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	for _, namespace := range []string{"shop", "kube-system", "kube-public", "gke-managed-cim", "istio-system"} {
+		server.addObject(configMaps, newFakeObject("v1", "ConfigMap", namespace, "settings"))
+	}
+	server.addObject(server.addResource(namespaceGvk, "namespaces", false), newFakeObject("v1", "Namespace", "", "kube-system"))
+	cluster := server.cluster(t)
+	gvks := []schema.GroupVersionKind{configMapGvk, namespaceGvk}
+
+	tests := []struct {
+		name         string
+		conf         *Config
+		extra        []string
+		want         []string
+		wantExcluded int
+	}{
+		{
+			name:         "system namespaces are skipped by default",
+			conf:         &Config{Concurrency: 1},
+			want:         []string{"ConfigMap shop", "ConfigMap istio-system", "Namespace "},
+			wantExcluded: 3,
+		},
+		{
+			name: "system namespaces included",
+			conf: &Config{Concurrency: 1, IncludeSystemNamespaces: true},
+			want: []string{"ConfigMap shop", "ConfigMap kube-system", "ConfigMap kube-public", "ConfigMap gke-managed-cim", "ConfigMap istio-system", "Namespace "},
+		},
+		{
+			name:         "selected namespaces win over the defaults",
+			conf:         &Config{Concurrency: 1, SelectNamespaces: []string{"shop", "kube-public", "gke-*"}},
+			want:         []string{"ConfigMap shop", "ConfigMap kube-public", "Namespace "},
+			wantExcluded: 1,
+		},
+		{
+			name:         "defaults extended by library users",
+			conf:         &Config{Concurrency: 1},
+			extra:        []string{"istio-system"},
+			want:         []string{"ConfigMap shop", "Namespace "},
+			wantExcluded: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(defaults []string) { DefaultSystemNamespaces = defaults }(DefaultSystemNamespaces)
+			DefaultSystemNamespaces = append(append([]string(nil), DefaultSystemNamespaces...), tt.extra...)
+			objs, summary, err := cluster.FetchK8sObjects(context.Background(), gvks, tt.conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range objs {
+				got = append(got, obj.GetKind()+" "+obj.GetNamespace())
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantExcluded, summary.SystemNamespaceObjects)
+			assert.Equal(t, tt.wantExcluded > 0, summary.Reportable())
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	DefaultUpgradePlanConfigMap = "kube-system/cluster-upgrade-plan"
+	DefaultUpgradePlanKey       = "targetVersion"
+)
+
+// ResolveTargetVersion returns the kubernetes version the cluster should be validated against. An explicitly
+// configured TargetKubernetesVersion always wins, otherwise the version recorded in the upgrade plan ConfigMap
+// is used and if that is not found the next minor version of the cluster is assumed, or the one after with
+// SkipMinorVersion.
+func ResolveTargetVersion(ctx context.Context, cluster *Cluster, conf *Config) (string, error) {
+	if len(conf.TargetKubernetesVersion) > 0 {
+		return conf.TargetKubernetesVersion, nil
+	}
+	configMap := conf.UpgradePlanConfigMap
+	if len(configMap) == 0 {
+		configMap = DefaultUpgradePlanConfigMap
+	}
+	key := conf.UpgradePlanKey
+	if len(key) == 0 {
+		key = DefaultUpgradePlanKey
+	}
+	plannedVersion, err := cluster.PlannedUpgradeVersion(ctx, configMap, key)
+	if err != nil {
+		kLog.Warn(fmt.Sprintf("unable to read upgrade plan from configmap %s: %v", configMap, err))
+	}
+	if len(plannedVersion) > 0 {
+		if err := validateKubernetesVersions(plannedVersion); err != nil {
+			return "", fmt.Errorf("the upgrade plan of configmap %s holds %w", configMap, err)
+		}
+		return strings.TrimPrefix(plannedVersion, "v"), nil
+	}
+	serverVersion, err := cluster.ServerVersion(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to determine target kubernetes version, set it explicitly: %w", err)
+	}
+	step := 1
+	if conf.SkipMinorVersion {
+		step = 2
+	}
+	return nextMinorVersion(serverVersion, step)
+}
+
+// nextMinorVersion returns the major.minor version which is step minor versions after version,
+// provider suffixes like 27+ and patch versions are ignored
+func nextMinorVersion(version string, step int) (string, error) {
+	major, minor, err := parseMajorMinor(version)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d.%d", major, minor+step), nil
+}
+
+// MarkAlreadyRemoved marks the result of an object whose api version is removed in the target version of conf as
+// AlreadyRemoved when the source version does not serve it either, eg a stale manifest, whether the target version
+// is above or below the source version. Nothing is marked when both versions are the same, as the source version
+// of manifests defaults to the target one, nor for custom resources and the kinds of api overlays or when the spec
+// of the source version cannot be loaded
+func MarkAlreadyRemoved(result ValidationResult, kubeC KubeChecker, conf *Config) ValidationResult {
+	source := conf.SourceKubernetesVersion
+	if !result.Deleted || len(source) == 0 || source == conf.TargetKubernetesVersion {
+		return result
+	}
+	gv, err := schema.ParseGroupVersion(result.APIVersion)
+	if err != nil || !IsBuiltinAPIGroup(gv.Group) {
+		return result
+	}
+	if _, _, ok := lookupKindLifecycle(result.APIVersion, result.Kind); ok {
+		return result
+	}
+	if err := kubeC.LoadFromUrl(source, false); err != nil {
+		return result
+	}
+	result.AlreadyRemoved = !kubeC.IsApiVersionSupported(source, result.APIVersion, result.Kind)
+	return result
+}
+
+// isVersionAtLeast returns true if the kubernetes version is min or newer, master is newer than any release
+func isVersionAtLeast(version, min string) (bool, error) {
+	if strings.EqualFold(strings.TrimSpace(version), "master") {
+		return true, nil
+	}
+	major, minor, err := parseMajorMinor(version)
+	if err != nil {
+		return false, err
+	}
+	minMajor, minMinor, err := parseMajorMinor(min)
+	if err != nil {
+		return false, err
+	}
+	return major > minMajor || major == minMajor && minor >= minMinor, nil
+}
+
+func parseMajorMinor(version string) (int, int, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(version), "v"), ".")
+	if len(parts) < 2 {
+		return 0, 0, fmt.Errorf("unable to parse kubernetes version %q", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse major version of %q: %w", version, err)
+	}
+	minor, err := strconv.Atoi(strings.TrimRight(parts[1], "+"))
+	if err != nil {
+		return 0, 0, fmt.Errorf("unable to parse minor version of %q: %w", version, err)
+	}
+	return major, minor, nil
+}
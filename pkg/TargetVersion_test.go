@@ -0,0 +1,148 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNextMinorVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		step    int
+		want    string
+		wantErr bool
+	}{
+		{version: "1.27", step: 1, want: "1.28"},
+		{version: "1.27+", step: 1, want: "1.28"},
+		{version: "v1.28.9", step: 2, want: "1.30"},
+		{version: "master", step: 1, wantErr: true},
+		{version: "1.x", step: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			got, err := nextMinorVersion(tt.version, tt.step)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolveTargetVersion(t *testing.T) {
+	server := newFakeApiServer(t)
+	configMaps := server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}, "configmaps", true)
+	plan := newFakeObject("v1", "ConfigMap", "kube-system", "cluster-upgrade-plan")
+	plan["data"] = map[string]interface{}{"targetVersion": "v1.29"}
+	server.addObject(configMaps, plan)
+	annotated := newFakeObject("v1", "ConfigMap", "ops", "upgrade")
+	annotated["metadata"].(map[string]interface{})["annotations"] = map[string]interface{}{"upgrade.example.com/next": "1.30"}
+	server.addObject(configMaps, annotated)
+	cluster := server.cluster(t)
+
+	tests := []struct {
+		name string
+		conf *Config
+		want string
+	}{
+		{
+			name: "explicit target version",
+			conf: &Config{TargetKubernetesVersion: "1.25"},
+			want: "1.25",
+		},
+		{
+			name: "default upgrade plan configmap",
+			conf: &Config{},
+			want: "1.29",
+		},
+		{
+			name: "annotation on custom configmap",
+			conf: &Config{UpgradePlanConfigMap: "ops/upgrade", UpgradePlanKey: "upgrade.example.com/next"},
+			want: "1.30",
+		},
+		{
+			name: "missing configmap falls back to next minor",
+			conf: &Config{UpgradePlanConfigMap: "ops/missing"},
+			want: "1.28",
+		},
+		{
+			name: "skipping a minor version",
+			conf: &Config{UpgradePlanConfigMap: "ops/missing", SkipMinorVersion: true},
+			want: "1.29",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveTargetVersion(context.Background(), cluster, tt.conf)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// deploymentsSwagger is a minimal openapi spec of a kubernetes release serving apps/v1 Deployments only
+const deploymentsSwagger = `{
+  "swagger": "2.0",
+  "info": {"title": "Kubernetes", "version": "v1.27.0"},
+  "paths": {
+    "/apis/apps/v1/namespaces/{namespace}/deployments": {
+      "parameters": [{"name": "namespace", "in": "path", "required": true, "type": "string"}],
+      "post": {
+        "responses": {"200": {"description": "OK"}},
+        "x-kubernetes-group-version-kind": {"group": "apps", "kind": "Deployment", "version": "v1"}
+      }
+    }
+  },
+  "definitions": {
+    "io.k8s.api.apps.v1.Deployment": {
+      "type": "object",
+      "properties": {"apiVersion": {"type": "string"}, "kind": {"type": "string"}},
+      "x-kubernetes-group-version-kind": [{"group": "apps", "kind": "Deployment", "version": "v1"}]
+    }
+  }
+}`
+
+func TestMarkAlreadyRemoved(t *testing.T) {
+	// the spec of the source version serves apps/v1 Deployments only, the one of 1.26 cannot be loaded
+	kubeC := NewCachedKubeChecker(&Config{SchemaCacheDir: t.TempDir(), Offline: true})
+	if !assert.NoError(t, kubeC.load([]byte(deploymentsSwagger), "1.27")) {
+		return
+	}
+	conf := &Config{SourceKubernetesVersion: "1.27", TargetKubernetesVersion: "1.29"}
+	stale := ValidationResult{Kind: "Deployment", APIVersion: "extensions/v1beta1", ResourceNamespace: "shop", ResourceName: "web", Deleted: true}
+	tests := []struct {
+		name   string
+		result ValidationResult
+		conf   *Config
+		want   bool
+	}{
+		{name: "removed in the target and the source version", result: stale, conf: conf, want: true},
+		{name: "against a target below the source version", result: stale, conf: &Config{SourceKubernetesVersion: "1.27", TargetKubernetesVersion: "1.15"}, want: true},
+		{name: "served by the source version", result: ValidationResult{Kind: "Deployment", APIVersion: "apps/v1", Deleted: true}, conf: &Config{SourceKubernetesVersion: "1.27", TargetKubernetesVersion: "1.8"}},
+		{name: "not removed in the target version", result: ValidationResult{Kind: "Deployment", APIVersion: "extensions/v1beta1"}, conf: conf},
+		{name: "source version defaulted to the target one", result: stale, conf: &Config{SourceKubernetesVersion: "1.29", TargetKubernetesVersion: "1.29"}},
+		{name: "custom resource", result: ValidationResult{Kind: "Widget", APIVersion: "example.com/v1alpha1", Deleted: true}, conf: conf},
+		{name: "kind of an api overlay", result: ValidationResult{Kind: "GatewayClass", APIVersion: "gateway.networking.k8s.io/v1alpha1", Deleted: true}, conf: conf},
+		{name: "spec of the source version missing", result: stale, conf: &Config{SourceKubernetesVersion: "1.26", TargetKubernetesVersion: "1.29"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MarkAlreadyRemoved(tt.result, kubeC, tt.conf).AlreadyRemoved)
+		})
+	}
+
+	marked := MarkAlreadyRemoved(stale, kubeC, conf)
+	assert.True(t, HasGatingFindings([]ValidationResult{marked}, conf))
+	assert.Equal(t, SeverityError, ResultSeverity(marked, conf))
+	if findings := Findings(marked, conf); assert.Len(t, findings, 1) {
+		assert.Equal(t, RuleAlreadyRemovedAPI, findings[0].Rule)
+		assert.Equal(t, "extensions/v1beta1 Deployment is already removed in the source version without a replacement", findings[0].Message)
+	}
+	assert.Equal(t, []UpgradePathRow{{Namespace: "shop", Name: "web", Kind: "Deployment", APIVersion: "extensions/v1beta1", Status: []string{"already removed"}}},
+		UpgradePath([]TargetResults{{TargetVersion: "1.29", Results: []ValidationResult{marked}}}))
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"slices"
+	"strings"
+)
+
+// TargetResults are the results of validating the objects of a cluster against one of several target versions
+type TargetResults struct {
+	TargetVersion string
+	Results       []ValidationResult
+}
+
+// UpgradePathRow is an object with findings against at least one of several target versions along with its status
+// against each of them
+type UpgradePathRow struct {
+	Namespace  string
+	Name       string
+	Kind       string
+	APIVersion string
+	// Status is the status of the object against each target version in order: already removed, removed, deprecated,
+	// invalid, deprecated fields or ok, - when the object could not be validated against the version
+	Status []string
+}
+
+// TargetVersions returns the target versions of TargetKubernetesVersion, which holds a comma-separated list of
+// versions when validating against each step of an upgrade eg 1.27,1.29. Repeated versions are dropped
+func (c *Config) TargetVersions() []string {
+	var versions []string
+	for _, version := range strings.Split(c.TargetKubernetesVersion, ",") {
+		version = strings.TrimSpace(version)
+		if len(version) > 0 && !slices.Contains(versions, version) {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// ForTarget returns a copy of conf validating against the target version
+func (c *Config) ForTarget(version string) *Config {
+	targetConf := *c
+	targetConf.TargetKubernetesVersion = version
+	return &targetConf
+}
+
+// ForSource returns a copy of conf with version as the source version, eg the version of a scanned cluster
+func (c *Config) ForSource(version string) *Config {
+	sourceConf := *c
+	sourceConf.SourceKubernetesVersion = version
+	return &sourceConf
+}
+
+// UpgradePath returns the objects with findings against any of targets, in the order they were validated, along
+// with their status against each target so that the step of an upgrade an object breaks at can be told
+func UpgradePath(targets []TargetResults) []UpgradePathRow {
+	var rows []UpgradePathRow
+	index := map[string]int{}
+	for i, target := range targets {
+		for _, result := range target.Results {
+			key := strings.Join([]string{result.Kind, result.APIVersion, result.ResourceNamespace, result.ResourceName}, "/")
+			row, ok := index[key]
+			if !ok {
+				row = len(rows)
+				index[key] = row
+				status := make([]string, len(targets))
+				for j := range status {
+					status[j] = "-"
+				}
+				rows = append(rows, UpgradePathRow{Namespace: result.ResourceNamespace, Name: result.ResourceName, Kind: result.Kind, APIVersion: result.APIVersion, Status: status})
+			}
+			rows[row].Status[i] = upgradeStatus(result)
+		}
+	}
+	var withFindings []UpgradePathRow
+	for _, row := range rows {
+		for _, status := range row.Status {
+			if status != "ok" && status != "-" {
+				withFindings = append(withFindings, row)
+				break
+			}
+		}
+	}
+	return withFindings
+}
+
+func upgradeStatus(result ValidationResult) string {
+	switch {
+	case result.AlreadyRemoved:
+		return "already removed"
+	case result.Deleted:
+		return "removed"
+	case result.Deprecated:
+		return "deprecated"
+	case len(result.ErrorsForLatest) > 0 || len(result.ErrorsForOriginal) > 0 || len(result.UnknownFields) > 0:
+		return "invalid"
+	case len(result.DeprecationForLatest) > 0 || len(result.DeprecationForOriginal) > 0 || len(result.PodFieldDeprecations) > 0:
+		return "deprecated fields"
+	}
+	return "ok"
+}
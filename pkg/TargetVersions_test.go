@@ -0,0 +1,42 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_TargetVersions(t *testing.T) {
+	assert.Equal(t, []string{"1.22"}, (&Config{TargetKubernetesVersion: "1.22"}).TargetVersions())
+	assert.Equal(t, []string{"1.27", "1.29"}, (&Config{TargetKubernetesVersion: " 1.27, 1.29,,1.27"}).TargetVersions())
+	assert.Empty(t, (&Config{}).TargetVersions())
+
+	conf := &Config{TargetKubernetesVersion: "1.27,1.29", SelectKinds: []string{"Ingress"}}
+	targetConf := conf.ForTarget("1.29")
+	assert.Equal(t, "1.29", targetConf.TargetKubernetesVersion)
+	assert.Equal(t, conf.SelectKinds, targetConf.SelectKinds)
+	assert.Equal(t, "1.27,1.29", conf.TargetKubernetesVersion)
+}
+
+func TestUpgradePath(t *testing.T) {
+	cronJob := ValidationResult{Kind: "CronJob", APIVersion: "batch/v1beta1", ResourceNamespace: "shop", ResourceName: "report"}
+	deprecatedCronJob, removedCronJob := cronJob, cronJob
+	deprecatedCronJob.Deprecated = true
+	removedCronJob.Deleted = true
+	deployment := ValidationResult{Kind: "Deployment", APIVersion: "apps/v1", ResourceNamespace: "shop", ResourceName: "web"}
+	invalidDeployment := deployment
+	invalidDeployment.ErrorsForLatest = []*openapi3.SchemaError{{Reason: "invalid"}}
+	service := ValidationResult{Kind: "Service", APIVersion: "v1", ResourceNamespace: "shop", ResourceName: "web"}
+
+	rows := UpgradePath([]TargetResults{
+		{TargetVersion: "1.23", Results: []ValidationResult{deprecatedCronJob, deployment, service}},
+		{TargetVersion: "1.25", Results: []ValidationResult{removedCronJob, invalidDeployment, service}},
+		{TargetVersion: "1.27", Results: []ValidationResult{removedCronJob}},
+	})
+	// objects without findings against every target are left out
+	assert.Equal(t, []UpgradePathRow{
+		{Namespace: "shop", Name: "report", Kind: "CronJob", APIVersion: "batch/v1beta1", Status: []string{"deprecated", "removed", "removed"}},
+		{Namespace: "shop", Name: "web", Kind: "Deployment", APIVersion: "apps/v1", Status: []string{"ok", "invalid", "-"}},
+	}, rows)
+}
@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// terminalObject returns true if obj is left out unless IncludeTerminalObjects is set: a Pod which succeeded or
+// failed, a Job which completed or failed, a Namespace being deleted or an object of one
+func (c *Config) terminalObject(obj *unstructured.Unstructured) bool {
+	if c.IncludeTerminalObjects {
+		return false
+	}
+	gvk := obj.GroupVersionKind()
+	switch {
+	case gvk.Group == "" && gvk.Kind == "Pod":
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		if phase == "Succeeded" || phase == "Failed" {
+			return true
+		}
+	case gvk.Group == "batch" && gvk.Kind == "Job":
+		conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+		for _, condition := range objectList(conditions) {
+			if (condition["type"] == "Complete" || condition["type"] == "Failed") && condition["status"] == "True" {
+				return true
+			}
+		}
+	case gvk.Group == "" && gvk.Kind == "Namespace":
+		if obj.GetDeletionTimestamp() != nil {
+			return true
+		}
+	}
+	return c.terminatingNamespaces.Has(obj.GetNamespace())
+}
+
+// resolveTerminatingNamespaces returns a copy of conf in which the namespaces being deleted are resolved to be
+// skipped, conf is returned as is when IncludeTerminalObjects is set. The objects of terminating namespaces are
+// scanned with a warning when namespaces cannot be listed, eg when the user may not
+func (c *Cluster) resolveTerminatingNamespaces(ctx context.Context, conf *Config) *Config {
+	if conf.IncludeTerminalObjects {
+		return conf
+	}
+	items, err := c.listAll(ctx, namespaces, "", 0, conf.unselected())
+	if err != nil {
+		kLog.Warn(fmt.Sprintf("objects of terminating namespaces of cluster %s are scanned as namespaces could not be listed: %v", c.name, err))
+		return conf
+	}
+	return conf.withTerminatingNamespaces(items)
+}
+
+// withTerminatingNamespaces returns a copy of conf in which the namespaces among objs being deleted are resolved to
+// be skipped, conf is returned as is when IncludeTerminalObjects is set
+func (c *Config) withTerminatingNamespaces(objs []unstructured.Unstructured) *Config {
+	if c.IncludeTerminalObjects {
+		return c
+	}
+	resolved := *c
+	resolved.terminatingNamespaces = sets.New[string]()
+	for _, obj := range objs {
+		gvk := obj.GroupVersionKind()
+		if gvk.Group == "" && gvk.Kind == "Namespace" && obj.GetDeletionTimestamp() != nil {
+			resolved.terminatingNamespaces.Insert(obj.GetName())
+		}
+	}
+	return &resolved
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newFakeTerminalObjects() []map[string]interface{} {
+	succeeded := newFakeObject("v1", "Pod", "shop", "succeeded")
+	succeeded["status"] = map[string]interface{}{"phase": "Succeeded"}
+	running := newFakeObject("v1", "Pod", "shop", "running")
+	running["status"] = map[string]interface{}{"phase": "Running"}
+	complete := newFakeObject("batch/v1", "Job", "shop", "complete")
+	complete["status"] = map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Complete", "status": "True"}}}
+	active := newFakeObject("batch/v1", "Job", "shop", "active")
+	active["status"] = map[string]interface{}{"conditions": []interface{}{map[string]interface{}{"type": "Failed", "status": "False"}}}
+	terminating := newFakeObject("v1", "Namespace", "", "old")
+	terminating["metadata"].(map[string]interface{})["deletionTimestamp"] = "2024-01-01T00:00:00Z"
+	return []map[string]interface{}{
+		succeeded, running, complete, active, terminating,
+		newFakeObject("v1", "Namespace", "", "shop"),
+		newFakeObject("v1", "Pod", "old", "leftover"),
+	}
+}
+
+func TestFilterObjects_terminalObjects(t *testing.T) {
+	var objs []unstructured.Unstructured
+	for _, obj := range newFakeTerminalObjects() {
+		objs = append(objs, unstructured.Unstructured{Object: obj})
+	}
+	tests := []struct {
+		name        string
+		conf        *Config
+		want        []string
+		wantSkipped int
+	}{
+		{
+			name:        "terminal objects skipped",
+			conf:        &Config{},
+			want:        []string{"Pod shop/running", "Job shop/active", "Namespace /shop"},
+			wantSkipped: 4,
+		},
+		{
+			name: "terminal objects included",
+			conf: &Config{IncludeTerminalObjects: true},
+			want: []string{"Pod shop/succeeded", "Pod shop/running", "Job shop/complete", "Job shop/active", "Namespace /old", "Namespace /shop", "Pod old/leftover"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filtered, summary, err := FilterObjects(objs, tt.conf)
+			assert.NoError(t, err)
+			var got []string
+			for _, obj := range filtered {
+				got = append(got, obj.GetKind()+" "+obj.GetNamespace()+"/"+obj.GetName())
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantSkipped, summary.TerminalObjects)
+			assert.Equal(t, tt.wantSkipped > 0, summary.Reportable())
+		})
+	}
+}
+
+func TestCluster_FetchK8sObjects_terminalObjects(t *testing.T) {
+	server := newFakeApiServer(t)
+	podGvk := schema.GroupVersionKind{Version: "v1", Kind: "Pod"}
+	pods := server.addResource(podGvk, "pods", true)
+	namespaces := server.addResource(schema.GroupVersionKind{Version: "v1", Kind: "Namespace"}, "namespaces", false)
+	for _, obj := range newFakeTerminalObjects() {
+		switch obj["kind"] {
+		case "Pod":
+			server.addObject(pods, obj)
+		case "Namespace":
+			server.addObject(namespaces, obj)
+		}
+	}
+	objs, summary, err := server.cluster(t).FetchK8sObjects(context.Background(), []schema.GroupVersionKind{podGvk}, &Config{})
+	assert.NoError(t, err)
+	if assert.Len(t, objs, 1) {
+		assert.Equal(t, "running", objs[0].GetName())
+	}
+	assert.Equal(t, 2, summary.TerminalObjects)
+	assert.Contains(t, summary.String(), "2 terminal objects skipped")
+}
+
+func TestAddKubeaddFlags_includeTerminal(t *testing.T) {
+	conf := &Config{}
+	cmd := AddKubeaddFlags(&cobra.Command{}, conf)
+	assert.False(t, conf.IncludeTerminalObjects)
+	assert.NoError(t, cmd.ParseFlags([]string{"--include-terminal"}))
+	assert.True(t, conf.IncludeTerminalObjects)
+}
@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	SpanValidateCluster = "silver-surfer.ValidateCluster"
+	SpanFetchResource   = "silver-surfer.FetchResource"
+	EventFinding        = "finding"
+)
+
+// StartSpan starts a span with Config.Tracer, without a tracer a no-op span is returned and ctx is left untouched
+func (c *Config) StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if c == nil || c.Tracer == nil {
+		return ctx, trace.SpanFromContext(context.Background())
+	}
+	return c.Tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordFinding adds a finding event to span if the result has anything to report
+func RecordFinding(span trace.Span, vr ValidationResult) {
+	if !span.IsRecording() || len(vr.Kind) == 0 || !HasFindings(vr) {
+		return
+	}
+	span.AddEvent(EventFinding, trace.WithAttributes(
+		attribute.String("k8s.kind", vr.Kind),
+		attribute.String("k8s.api_version", vr.APIVersion),
+		attribute.String("k8s.namespace", vr.ResourceNamespace),
+		attribute.String("k8s.name", vr.ResourceName),
+		attribute.String("k8s.latest_api_version", vr.LatestAPIVersion),
+		attribute.Bool("k8s.deleted", vr.Deleted),
+		attribute.Bool("k8s.deprecated", vr.Deprecated),
+	))
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// recordingTracer keeps every started span in memory
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordingSpan
+}
+
+type recordingSpan struct {
+	trace.Span
+	name   string
+	parent string
+	attrs  []attribute.KeyValue
+	events []string
+	status codes.Code
+	ended  bool
+}
+
+type recordingSpanKey struct{}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	config := trace.NewSpanStartConfig(opts...)
+	span := &recordingSpan{Span: trace.SpanFromContext(context.Background()), name: name, attrs: config.Attributes()}
+	if parent, ok := ctx.Value(recordingSpanKey{}).(*recordingSpan); ok {
+		span.parent = parent.name
+	}
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, recordingSpanKey{}, span), span
+}
+
+func (s *recordingSpan) IsRecording() bool                      { return true }
+func (s *recordingSpan) End(...trace.SpanEndOption)             { s.ended = true }
+func (s *recordingSpan) SetStatus(code codes.Code, _ string)    { s.status = code }
+func (s *recordingSpan) SetAttributes(kv ...attribute.KeyValue) { s.attrs = append(s.attrs, kv...) }
+func (s *recordingSpan) AddEvent(name string, _ ...trace.EventOption) {
+	s.events = append(s.events, name)
+}
+
+func TestConfig_StartSpan(t *testing.T) {
+	server := newFakeApiServer(t)
+	deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	deployments := server.addResource(deploymentGvk, "deployments", true)
+	server.addObject(deployments, newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+	cluster := server.cluster(t)
+
+	tracer := &recordingTracer{}
+	conf := &Config{Tracer: tracer}
+	ctx, scan := conf.StartSpan(context.Background(), SpanValidateCluster)
+	objs, _, err := cluster.FetchK8sObjects(ctx, []schema.GroupVersionKind{deploymentGvk}, conf)
+	assert.NoError(t, err)
+	assert.Len(t, objs, 1)
+	RecordFinding(scan, ValidationResult{Kind: "Deployment"})
+	RecordFinding(scan, ValidationResult{Kind: "Ingress", Deleted: true})
+	scan.End()
+
+	if assert.Len(t, tracer.spans, 2) {
+		fetch := tracer.spans[1]
+		assert.Equal(t, SpanFetchResource, fetch.name)
+		assert.Equal(t, SpanValidateCluster, fetch.parent)
+		assert.True(t, fetch.ended)
+		assert.Contains(t, fetch.attrs, attribute.String("k8s.resource", "apps/v1, Resource=deployments"))
+		assert.Contains(t, fetch.attrs, attribute.Int("k8s.object_count", 1))
+		assert.Equal(t, []string{EventFinding}, tracer.spans[0].events)
+	}
+
+	// without a tracer spans are no-ops
+	noop := &Config{}
+	noopCtx, span := noop.StartSpan(ctx, SpanFetchResource)
+	assert.Equal(t, ctx, noopCtx)
+	assert.False(t, span.IsRecording())
+}
@@ -43,7 +43,10 @@ func (f ValidFormat) IsFormat(input interface{}) bool {
 // ValidationResult contains the details from
 // validating a given Kubernetes resource
 type ValidationResult struct {
-	FileName               string
+	FileName string
+	// DocumentIndex is the 1-based position of the object among the documents of FileName
+	DocumentIndex          int
+	Cluster                string
 	Kind                   string
 	APIVersion             string
 	ValidatedAgainstSchema bool
@@ -54,10 +57,49 @@ type ValidationResult struct {
 	DeprecationForLatest   []*SchemaError
 	ResourceName           string
 	ResourceNamespace      string
+	ResourceLabels         map[string]string
 	Deleted                bool
 	Deprecated             bool
 	LatestAPIVersion       string
 	IsVersionSupported     int
+	// FieldMigrations are the fields to change to move the object to LatestAPIVersion
+	FieldMigrations []FieldMigration
+	// UnknownFields are the paths of the fields of the object its schema does not define, they are only looked up
+	// with Config.Strict
+	UnknownFields []string
+	// Severity is the severity of the most severe finding of the object, see ResultSeverity
+	Severity string
+	// SuppressedFindings are the findings of the object accepted by Config.Suppressions, they are removed from the
+	// result
+	SuppressedFindings []SuppressedFinding
+	// FixedManifest is the file the object was written to with LatestAPIVersion, see Config.FixOutputDir
+	FixedManifest string
+	// ValidatedFrom is the source the object was validated from with Config.UseLastApplied, SourceLastApplied or
+	// SourceLive along with ValidatedFromNote saying why the last applied configuration could not be used
+	ValidatedFrom     string
+	ValidatedFromNote string
+	// ServerWarnings are the warnings the api server returned for the kind and api version of the object while the
+	// cluster was scanned, see AttachServerWarnings
+	ServerWarnings []string
+	// DryRunRejection is the message of the verify cluster rejecting the dry-run apply of the object, see VerifyDryRun
+	DryRunRejection string
+	// DryRunNote says why the object could not be verified on the verify cluster
+	DryRunNote string
+	// PodFieldDeprecations are the deprecated fields of the pod spec or template of the object with their replacement,
+	// see CheckPodFieldDeprecations
+	PodFieldDeprecations []FieldMigration
+	// CustomRuleFindings are the findings of the house rules of Config.CustomRules, see CheckCustomRules
+	CustomRuleFindings []CustomRuleFinding
+	// Remediation is the next step for objects of removed kinds with a known migration, see AnalyzeRemovals
+	Remediation *Remediation
+	// RootOwner is the top-level controller of the object as Kind/name, set when Config.AnnotateRootOwner is
+	RootOwner string
+	// SpecVersion is the kubernetes version of the downloaded spec the object was validated against, the exact
+	// release of a patch target version or the minor version it fell back to when the release has no spec
+	SpecVersion string
+	// AlreadyRemoved is set along with Deleted when the api version is not served by the source version either, the
+	// object cannot exist in a cluster at that version, see MarkAlreadyRemoved
+	AlreadyRemoved bool
 }
 
 type SummarySchemaError struct {
@@ -69,10 +111,21 @@ type SummarySchemaError struct {
 
 type SummaryValidationResult struct {
 	FileName               string
+	DocumentIndex          int `json:",omitempty"`
+	Cluster                string
 	Kind                   string
 	APIVersion             string
 	ResourceName           string
 	ResourceNamespace      string
+	RootOwner              string              `json:",omitempty"`
+	ValidatedFrom          string              `json:",omitempty"`
+	ValidatedFromNote      string              `json:",omitempty"`
+	ServerWarnings         []string            `json:",omitempty"`
+	DryRunRejection        string              `json:",omitempty"`
+	DryRunNote             string              `json:",omitempty"`
+	PodFieldDeprecations   []FieldMigration    `json:",omitempty"`
+	CustomRuleFindings     []CustomRuleFinding `json:",omitempty"`
+	Remediation            *Remediation        `json:",omitempty"`
 	Deleted                bool
 	Deprecated             bool
 	LatestAPIVersion       string
@@ -81,6 +134,11 @@ type SummaryValidationResult struct {
 	ErrorsForLatest        []*SummarySchemaError
 	DeprecationForOriginal []*SummarySchemaError
 	DeprecationForLatest   []*SummarySchemaError
+	FieldMigrations        []FieldMigration    `json:",omitempty"`
+	FixedManifest          string              `json:",omitempty"`
+	UnknownFields          []string            `json:",omitempty"`
+	SuppressedFindings     []SuppressedFinding `json:",omitempty"`
+	Severity               string              `json:",omitempty"`
 }
 
 // VersionKind returns a string representation of this result's apiVersion and kind
@@ -88,6 +146,14 @@ func (v *ValidationResult) VersionKind() string {
 	return v.APIVersion + "/" + v.Kind
 }
 
+// Location returns the file of the result along with the position of its document in it, eg stdin document 2
+func (v *ValidationResult) Location() string {
+	if v.DocumentIndex == 0 {
+		return v.FileName
+	}
+	return fmt.Sprintf("%s document %d", v.FileName, v.DocumentIndex)
+}
+
 // QualifiedName returns a string of the [namespace.]name of the k8s resource
 func (v *ValidationResult) QualifiedName() string {
 	if v.ResourceName == "" {
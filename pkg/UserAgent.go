@@ -0,0 +1,19 @@
+package pkg
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// BuildVersion is the version of silver-surfer sent in the user agent of api requests, set by the binary at startup
+var BuildVersion = "dev"
+
+// userAgent returns the user agent identifying silver-surfer in the audit logs of the api server, suffix identifies
+// the caller of the library and is appended when set
+func userAgent(suffix string) string {
+	agent := fmt.Sprintf("silver-surfer/%s (%s/%s) kubedd", BuildVersion, runtime.GOOS, runtime.GOARCH)
+	if len(suffix) > 0 {
+		agent += " " + suffix
+	}
+	return agent
+}
@@ -0,0 +1,49 @@
+package pkg
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCluster_userAgent(t *testing.T) {
+	defer func(version string) { BuildVersion = version }(BuildVersion)
+	BuildVersion = "1.2.3"
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+
+	tests := []struct {
+		name string
+		conf *Config
+		want string
+	}{
+		{name: "nil config", want: "silver-surfer/1.2.3 (" + platform + ") kubedd"},
+		{name: "suffix", conf: &Config{UserAgentSuffix: "tenant/shop"}, want: "silver-surfer/1.2.3 (" + platform + ") kubedd tenant/shop"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeApiServer(t)
+			deploymentGvk := schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+			server.addObject(server.addResource(deploymentGvk, "deployments", true), newFakeObject("apps/v1", "Deployment", "shop", "cart"))
+			cluster, err := NewClusterFromEnvOrConfig(server.restConfig(), ClusterOptions{}, tt.conf)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, "1.27", cluster.Version())
+			_, _, err = cluster.FetchK8sObjects(context.Background(), []schema.GroupVersionKind{deploymentGvk}, &Config{})
+			assert.NoError(t, err)
+
+			paths := map[string]bool{}
+			for _, r := range server.recorded() {
+				paths[r.Path] = true
+				assert.Equal(t, tt.want, r.Header.Get("User-Agent"), r.Path)
+			}
+			// version, discovery and list requests are all covered
+			assert.True(t, paths["/version"])
+			assert.True(t, paths["/apis"])
+			assert.True(t, paths["/apis/apps/v1/deployments"])
+		})
+	}
+}
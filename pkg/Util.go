@@ -182,12 +182,13 @@ func getVersionType(apiVersion string) int {
 	return gaVersion
 }
 
+// Contains returns true if key matches any of patterns, see matchPattern for the supported patterns
 func Contains(key string, patterns []string) bool {
 	for _, ignoreKey := range patterns {
 		if strings.EqualFold(ignoreKey, key) {
 			return true
 		}
-		if RegexMatch(key, ignoreKey) {
+		if RegexMatch(key, ignoreKey) || matchPattern(key, ignoreKey) {
 			return true
 		}
 	}
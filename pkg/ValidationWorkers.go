@@ -0,0 +1,68 @@
+package pkg
+
+import (
+	"runtime"
+	"sync"
+
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// ValidationWorkers returns the number of objects validated at the same time, ValidationConcurrency or the number of
+// CPUs usable by the process when it is unset
+func (c *Config) ValidationWorkers() int {
+	if c.ValidationConcurrency > 0 {
+		return c.ValidationConcurrency
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// ForEachConcurrently calls fn with every index below n from at most workers goroutines and returns once all calls
+// returned. fn is expected to store what it computes at its index so that the results keep the order of the indexes
+func ForEachConcurrently(n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// precompilePatterns compiles the patterns of the string schemas reachable from scm. openapi3 compiles them on first
+// use otherwise, which races when objects are validated against the same schema at the same time
+func precompilePatterns(scm *openapi3.Schema, seen map[*openapi3.Schema]bool) {
+	if scm == nil || seen[scm] {
+		return
+	}
+	seen[scm] = true
+	if scm.Pattern != "" && (scm.Type == "" || scm.Type == "string") {
+		_ = scm.VisitJSONString("")
+	}
+	refs := append(append(append(openapi3.SchemaRefs{scm.Items, scm.AdditionalProperties, scm.Not}, scm.AllOf...), scm.AnyOf...), scm.OneOf...)
+	for _, property := range scm.Properties {
+		refs = append(refs, property)
+	}
+	for _, ref := range refs {
+		if ref != nil {
+			precompilePatterns(ref.Value, seen)
+		}
+	}
+}
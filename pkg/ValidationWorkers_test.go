@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestForEachConcurrently(t *testing.T) {
+	for _, workers := range []int{0, 1, 3, 100} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			var lock sync.Mutex
+			running, maxRunning := 0, 0
+			squares := make([]int, 50)
+			ForEachConcurrently(len(squares), workers, func(i int) {
+				lock.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				lock.Unlock()
+				squares[i] = i * i
+				lock.Lock()
+				running--
+				lock.Unlock()
+			})
+			for i, square := range squares {
+				assert.Equal(t, i*i, square)
+			}
+			if workers > 1 {
+				assert.LessOrEqual(t, maxRunning, workers)
+			} else {
+				assert.Equal(t, 1, maxRunning)
+			}
+		})
+	}
+}
+
+// TestCRDIndex_ValidateCustomResource_concurrently is meant to be run with -race, the patterns of the schemas of
+// custom resources are compiled before objects are validated against them at the same time
+func TestCRDIndex_ValidateCustomResource_concurrently(t *testing.T) {
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(8))
+	version := crdVersionSpec("v1", true, true, false)
+	version["schema"].(map[string]interface{})["openAPIV3Schema"].(map[string]interface{})["properties"].(map[string]interface{})["spec"].(map[string]interface{})["properties"].(map[string]interface{})["color"] = map[string]interface{}{
+		"type": "string", "pattern": "^(red|blue)$",
+	}
+	crds := NewCRDIndex([]unstructured.Unstructured{{Object: newFakeSchemaCRD("example.com", "Widget", "widgets", []interface{}{"v1"}, version)}})
+	objs := make([]*unstructured.Unstructured, 2000)
+	for i := range objs {
+		objs[i] = newFakeCustomResource("example.com/v1", "Widget", fmt.Sprintf("widget-%d", i), int64(i))
+		objs[i].Object["spec"].(map[string]interface{})["color"] = []string{"red", "green"}[i%2]
+	}
+	invalid := make([]bool, len(objs))
+	ForEachConcurrently(len(objs), 8, func(i int) {
+		vr, err := crds.ValidateCustomResource(objs[i])
+		invalid[i] = err != nil || len(vr.ErrorsForOriginal) > 0
+	})
+	for i := range objs {
+		assert.Equal(t, i%2 == 1, invalid[i], objs[i].GetName())
+	}
+}
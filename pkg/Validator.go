@@ -31,11 +31,19 @@ import (
 type kubeSpec struct {
 	*openapi3.T
 	kindInfoMap map[string][]*KindInfo
+	// strict reports the fields of objects their schema does not define, see Config.Strict
+	strict bool
+	// specVersion is the kubernetes version the spec was downloaded for, see ValidationResult.SpecVersion
+	specVersion string
 }
 
 func newKubeSpec(openapi *openapi3.T) *kubeSpec {
 	ks := &kubeSpec{T: openapi}
 	ks.kindInfoMap = ks.buildKindInfoMap()
+	seen := map[*openapi3.Schema]bool{}
+	for _, ref := range ks.Components.Schemas {
+		precompilePatterns(ref.Value, seen)
+	}
 	return ks
 }
 
@@ -64,9 +72,13 @@ func (ks *kubeSpec) ValidateJson(spec string) (ValidationResult, error) {
 func (ks *kubeSpec) ValidateObject(object map[string]interface{}) (ValidationResult, error) {
 	validationResult, err := ks.populateValidationResult(object)
 	validationResult.ValidatedAgainstSchema = true
+	validationResult.SpecVersion = ks.specVersion
 	if err != nil {
 		return validationResult, err
 	}
+	if kl, version, ok := lookupKindLifecycle(validationResult.APIVersion, validationResult.Kind); ok {
+		return applyKindLifecycle(validationResult, kl, version), nil
+	}
 	original, latest, err := ks.getKindsMappings(object)
 	if err != nil {
 		return validationResult, err
@@ -88,6 +100,11 @@ func (ks *kubeSpec) ValidateObject(object map[string]interface{}) (ValidationRes
 		validationResult.ErrorsForOriginal = ves
 		validationResult.DeprecationForOriginal = des
 		validationResult.Deprecated = deprecated
+		if ks.strict {
+			if scm, err := ks.schemaLookup(original); err == nil {
+				validationResult.UnknownFields = unknownFields(object, scm)
+			}
+		}
 		//if original == latest {
 		//	validationResult.ErrorsForLatest = ves
 		//	validationResult.DeprecationForLatest = des
@@ -116,6 +133,9 @@ func (ks *kubeSpec) ValidateObject(object map[string]interface{}) (ValidationRes
 		validationResult.ErrorsForLatest = ves
 		validationResult.DeprecationForLatest = des
 		validationResult.LatestAPIVersion, err = ks.getKeyForGVFromToken(latest)
+		if scm, err := ks.schemaLookup(latest); err == nil {
+			validationResult.FieldMigrations = fieldMigrations(object, scm)
+		}
 	}
 	return validationResult, nil
 }
@@ -0,0 +1,299 @@
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	kLog "github.com/devtron-labs/silver-surfer/pkg/log"
+
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+)
+
+// DefaultWatchDebounce is how long the updates of an object are coalesced when WatchDebounce is unset
+const DefaultWatchDebounce = 2 * time.Second
+
+// watchBackoff is the wait before a failed watch is restarted, doubled on every further failure up to maxWatchBackoff
+var (
+	watchBackoff    = time.Second
+	maxWatchBackoff = 30 * time.Second
+)
+
+// WatchEvent is an object created or updated in the cluster, see Cluster.WatchK8sObjects
+type WatchEvent struct {
+	// Type is watch.Added or watch.Modified
+	Type   watch.EventType
+	Object unstructured.Unstructured
+}
+
+func (c *Config) watchDebounce() time.Duration {
+	if c.WatchDebounce > 0 {
+		return c.WatchDebounce
+	}
+	return DefaultWatchDebounce
+}
+
+// WatchK8sObjects delivers the objects of gvks created or updated in the cluster until ctx is done, subject to the
+// same filters as FetchK8sObjects. Objects which exist when it is called are not delivered, the updates of an object
+// within conf.WatchDebounce are delivered once with its latest state. Expired watches are restarted from a fresh
+// listing which delivers the objects changed in the meantime, resources which may not be watched are skipped with a
+// warning. The error channel receives the error which stopped watching, if any, once the event channel is closed.
+func (c *Cluster) WatchK8sObjects(ctx context.Context, gvks []schema.GroupVersionKind, conf *Config) (<-chan WatchEvent, <-chan error) {
+	events := make(chan WatchEvent)
+	errc := make(chan error, 1)
+	targets, err := c.fetchTargets(gvks, conf, &FetchSummary{})
+	if err == nil {
+		conf, err = c.resolveNamespaceLabelSelector(ctx, conf)
+	}
+	if err != nil {
+		errc <- err
+		close(events)
+		close(errc)
+		return events, errc
+	}
+
+	watched := make(chan WatchEvent)
+	var wg sync.WaitGroup
+	for _, target := range targets {
+		namespaces := []string{""}
+		if selected, ok := conf.listedNamespaces(); ok && target.namespaced {
+			namespaces = selected
+		}
+		for _, namespace := range namespaces {
+			wg.Add(1)
+			go func(target fetchTarget, namespace string) {
+				defer wg.Done()
+				c.watchResource(ctx, target, namespace, conf, watched)
+			}(target, namespace)
+		}
+	}
+	go func() {
+		wg.Wait()
+		close(watched)
+	}()
+	go func() {
+		defer close(errc)
+		defer close(events)
+		coalesceWatchEvents(ctx, watched, events, conf.watchDebounce())
+		if ctx.Err() != nil {
+			errc <- ctx.Err()
+		}
+	}()
+	return events, errc
+}
+
+// coalesceWatchEvents delivers the events of in to out every window, so that the successive updates of an object are
+// delivered once with its latest state
+func coalesceWatchEvents(ctx context.Context, in <-chan WatchEvent, out chan<- WatchEvent, window time.Duration) {
+	var pending []WatchEvent
+	index := map[string]int{}
+	flush := func() bool {
+		for _, event := range pending {
+			select {
+			case out <- event:
+			case <-ctx.Done():
+				return false
+			}
+		}
+		pending, index = nil, map[string]int{}
+		return true
+	}
+	ticker := time.NewTicker(window)
+	defer ticker.Stop()
+	for {
+		select {
+		case event, ok := <-in:
+			if !ok {
+				flush()
+				return
+			}
+			key := watchKey(&event.Object)
+			if i, ok := index[key]; ok {
+				// an object created and then updated is still new
+				if pending[i].Type == watch.Added {
+					event.Type = watch.Added
+				}
+				pending[i] = event
+				continue
+			}
+			index[key] = len(pending)
+			pending = append(pending, event)
+		case <-ticker.C:
+			if !flush() {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchKey identifies obj across the group versions it is served in
+func watchKey(obj *unstructured.Unstructured) string {
+	if uid := obj.GetUID(); len(uid) > 0 {
+		return string(uid)
+	}
+	return obj.GetKind() + "/" + obj.GetNamespace() + "/" + obj.GetName()
+}
+
+// watchResource watches the objects of target in namespace, or across the cluster when it is empty, until ctx is done
+// or watching fails with an error which is not worth retrying, eg forbidden
+func (c *Cluster) watchResource(ctx context.Context, target fetchTarget, namespace string, conf *Config, out chan<- WatchEvent) {
+	resource := target.resource
+	client := c.dynamicClient().Resource(resource).Namespace(namespace)
+	// the resource versions of the objects last seen, relisting delivers only the objects changed since
+	var seen map[string]string
+	resourceVersion := ""
+	backoff := watchBackoff
+	refreshed := false
+	for ctx.Err() == nil {
+		var err error
+		if len(resourceVersion) == 0 {
+			var items []unstructured.Unstructured
+			if items, resourceVersion, err = listWithResourceVersion(ctx, client, conf); err == nil {
+				current := make(map[string]string, len(items))
+				for i := range items {
+					obj := &items[i]
+					if len(obj.GetNamespace()) == 0 && len(namespace) > 0 {
+						obj.SetNamespace(namespace)
+					}
+					key := watchKey(obj)
+					current[key] = obj.GetResourceVersion()
+					previous, ok := seen[key]
+					if seen == nil || ok && previous == obj.GetResourceVersion() {
+						continue
+					}
+					eventType := watch.Modified
+					if !ok {
+						eventType = watch.Added
+					}
+					if !deliverWatchEvent(ctx, target, eventType, obj, conf, out) {
+						return
+					}
+				}
+				seen = current
+			}
+		}
+		if err == nil {
+			var w watch.Interface
+			opts := v1.ListOptions{ResourceVersion: resourceVersion, AllowWatchBookmarks: true, LabelSelector: conf.LabelSelector, FieldSelector: conf.FieldSelector}
+			if w, err = client.Watch(ctx, opts); err == nil {
+				backoff, refreshed = watchBackoff, false
+				resourceVersion, err = consumeWatch(ctx, w, target, namespace, resourceVersion, seen, conf, out)
+			}
+		}
+		switch {
+		case err == nil || ctx.Err() != nil:
+		case k8sErrors.IsResourceExpired(err) || k8sErrors.IsGone(err):
+			// the resource version was compacted, objects changed in the meantime are found by relisting
+			resourceVersion = ""
+		case k8sErrors.IsUnauthorized(err) && !refreshed:
+			// credentials issued by exec plugins may have expired while watching
+			refreshed = true
+			if err = c.refreshCredentials(); err != nil {
+				kLog.Warn(fmt.Sprintf("stopped watching %s of cluster %s: %v", resource, c.name, err))
+				return
+			}
+		case isRetryableError(err):
+			kLog.Warn(fmt.Sprintf("watching %s failed, retrying in %s: %v", resource, backoff, err))
+			if retrySleep(ctx, backoff) != nil {
+				return
+			}
+			backoff = min(2*backoff, maxWatchBackoff)
+		default:
+			kLog.Warn(fmt.Sprintf("stopped watching %s of cluster %s: %v", resource, c.name, c.describeListError(err)))
+			return
+		}
+	}
+}
+
+// listWithResourceVersion lists the objects matching the selectors of conf page by page and returns them along with
+// the resource version of the listing, which watches start from
+func listWithResourceVersion(ctx context.Context, client dynamic.ResourceInterface, conf *Config) ([]unstructured.Unstructured, string, error) {
+	opts := v1.ListOptions{Limit: conf.pageSize(), LabelSelector: conf.LabelSelector, FieldSelector: conf.FieldSelector}
+	var items []unstructured.Unstructured
+	for {
+		page, err := listWithRetry(ctx, client, opts, conf.listRetries())
+		if err != nil {
+			return nil, "", err
+		}
+		items = append(items, page.Items...)
+		if len(page.GetContinue()) == 0 {
+			return items, page.GetResourceVersion(), nil
+		}
+		opts.Continue = page.GetContinue()
+	}
+}
+
+// consumeWatch delivers the objects created or updated in the events of w until it is closed, returning the resource
+// version to restart watching from along with the error the watch ended with, if any
+func consumeWatch(ctx context.Context, w watch.Interface, target fetchTarget, namespace, resourceVersion string, seen map[string]string, conf *Config, out chan<- WatchEvent) (string, error) {
+	defer w.Stop()
+	for {
+		var event watch.Event
+		var ok bool
+		select {
+		case event, ok = <-w.ResultChan():
+		case <-ctx.Done():
+			return resourceVersion, ctx.Err()
+		}
+		if !ok {
+			// the api server ends watches after a timeout
+			return resourceVersion, nil
+		}
+		if event.Type == watch.Error {
+			return resourceVersion, k8sErrors.FromObject(event.Object)
+		}
+		obj, isObj := event.Object.(*unstructured.Unstructured)
+		if !isObj {
+			continue
+		}
+		resourceVersion = obj.GetResourceVersion()
+		if len(obj.GetNamespace()) == 0 && len(namespace) > 0 {
+			obj.SetNamespace(namespace)
+		}
+		key := watchKey(obj)
+		switch event.Type {
+		case watch.Added, watch.Modified:
+			if previous, ok := seen[key]; ok && previous == obj.GetResourceVersion() {
+				continue
+			}
+			seen[key] = obj.GetResourceVersion()
+			if !deliverWatchEvent(ctx, target, event.Type, obj, conf, out) {
+				return resourceVersion, ctx.Err()
+			}
+		case watch.Deleted:
+			delete(seen, key)
+		}
+	}
+}
+
+// deliverWatchEvent sends obj to out unless it is filtered out by conf like the objects fetched by FetchK8sObjects,
+// it returns false once ctx is done
+func deliverWatchEvent(ctx context.Context, target fetchTarget, eventType watch.EventType, obj *unstructured.Unstructured, conf *Config, out chan<- WatchEvent) bool {
+	if !conf.namespaceSelected(obj.GetNamespace(), target.namespaced) || conf.systemNamespaceExcluded(obj.GetNamespace()) {
+		return true
+	}
+	if conf.ignoredByAnnotation(obj) || conf.IgnoreOwnedObjects && ownedByController(obj) || len(conf.ageExcluded(obj, time.Now())) > 0 || conf.terminalObject(obj) {
+		return true
+	}
+	watched := *obj.DeepCopy()
+	if !conf.KeepManagedFields {
+		pruneMetadata(&watched, conf.UseLastApplied)
+	}
+	if target.options.MetadataOnly {
+		watched = metadataOnly(watched)
+	}
+	select {
+	case out <- WatchEvent{Type: eventType, Object: watched}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
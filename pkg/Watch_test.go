@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newFakeVersionedObject(apiVersion, kind, namespace, name, resourceVersion string) map[string]interface{} {
+	obj := newFakeObject(apiVersion, kind, namespace, name)
+	obj["metadata"].(map[string]interface{})["resourceVersion"] = resourceVersion
+	return obj
+}
+
+// writeWatchEvents streams events to a watch request and keeps it open until the client goes away unless closed
+func writeWatchEvents(w http.ResponseWriter, r *http.Request, closed bool, events ...map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	encoder := json.NewEncoder(w)
+	for _, event := range events {
+		_ = encoder.Encode(event)
+	}
+	w.(http.Flusher).Flush()
+	if !closed {
+		<-r.Context().Done()
+	}
+}
+
+func watchEvent(eventType watch.EventType, obj map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{"type": string(eventType), "object": obj}
+}
+
+// receiveWatchEvents returns the events delivered until none is delivered for wait
+func receiveWatchEvents(events <-chan WatchEvent, wait time.Duration) []string {
+	var got []string
+	for {
+		select {
+		case event := <-events:
+			got = append(got, string(event.Type)+" "+event.Object.GetNamespace()+"/"+event.Object.GetName()+" "+event.Object.GetResourceVersion())
+		case <-time.After(wait):
+			return got
+		}
+	}
+}
+
+func TestCluster_WatchK8sObjects(t *testing.T) {
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	server := newFakeApiServer(t)
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	server.addObject(configMaps, newFakeVersionedObject("v1", "ConfigMap", "shop", "settings", "1"))
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Query().Get("watch") != "true" {
+			return false
+		}
+		writeWatchEvents(w, r, false,
+			watchEvent(watch.Modified, newFakeVersionedObject("v1", "ConfigMap", "shop", "settings", "1")),
+			watchEvent(watch.Added, newFakeVersionedObject("v1", "ConfigMap", "shop", "cart", "2")),
+			watchEvent(watch.Modified, newFakeVersionedObject("v1", "ConfigMap", "shop", "cart", "3")),
+			watchEvent(watch.Added, newFakeVersionedObject("v1", "ConfigMap", "sandbox", "cart", "4")),
+			watchEvent(watch.Bookmark, map[string]interface{}{"apiVersion": "v1", "kind": "ConfigMap", "metadata": map[string]interface{}{"resourceVersion": "5"}}),
+		)
+		return true
+	}
+	cluster := server.cluster(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	conf := &Config{IgnoreNamespaces: []string{"sandbox"}, WatchDebounce: 50 * time.Millisecond}
+	events, errc := cluster.WatchK8sObjects(ctx, []schema.GroupVersionKind{configMapGvk}, conf)
+
+	// the listed object is not delivered, the updates of cart are coalesced and the ignored namespace is filtered out
+	assert.Equal(t, []string{"ADDED shop/cart 3"}, receiveWatchEvents(events, 300*time.Millisecond))
+	var watches []string
+	for _, r := range server.recorded() {
+		if r.Path == "/api/v1/configmaps" {
+			watches = append(watches, r.Query.Get("watch")+r.Query.Get("resourceVersion"))
+		}
+	}
+	assert.Equal(t, []string{"", "true1"}, watches)
+
+	cancel()
+	for range events {
+	}
+	assert.ErrorIs(t, <-errc, context.Canceled)
+}
+
+func TestCluster_WatchK8sObjects_expired(t *testing.T) {
+	configMapGvk := schema.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	server := newFakeApiServer(t)
+	configMaps := server.addResource(configMapGvk, "configmaps", true)
+	server.addObject(configMaps, newFakeVersionedObject("v1", "ConfigMap", "shop", "settings", "1"))
+	server.addObject(configMaps, newFakeVersionedObject("v1", "ConfigMap", "shop", "orders", "1"))
+	var once sync.Once
+	server.intercept = func(w http.ResponseWriter, r *http.Request) bool {
+		if r.URL.Query().Get("watch") != "true" {
+			return false
+		}
+		expired := false
+		once.Do(func() {
+			expired = true
+			// objects change while the watch is down
+			server.mu.Lock()
+			server.objects[configMaps][0].SetResourceVersion("7")
+			server.objects[configMaps] = append(server.objects[configMaps], &unstructured.Unstructured{Object: newFakeVersionedObject("v1", "ConfigMap", "shop", "cart", "8")})
+			server.mu.Unlock()
+		})
+		if !expired {
+			writeWatchEvents(w, r, false)
+			return true
+		}
+		status := map[string]interface{}{"kind": "Status", "apiVersion": "v1", "status": "Failure", "reason": "Expired", "message": "too old resource version", "code": http.StatusGone}
+		writeWatchEvents(w, r, true, watchEvent(watch.Error, status))
+		return true
+	}
+	cluster := server.cluster(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, _ := cluster.WatchK8sObjects(ctx, []schema.GroupVersionKind{configMapGvk}, &Config{WatchDebounce: 50 * time.Millisecond})
+
+	// relisting delivers the objects changed in the meantime only
+	assert.Equal(t, []string{"MODIFIED shop/settings 7", "ADDED shop/cart 8"}, receiveWatchEvents(events, 300*time.Millisecond))
+	var requests []string
+	for _, r := range server.recorded() {
+		if r.Path == "/api/v1/configmaps" {
+			requests = append(requests, "watch="+r.Query.Get("watch"))
+		}
+	}
+	assert.Equal(t, []string{"watch=", "watch=true", "watch=", "watch=true"}, requests)
+}
+
+func TestCoalesceWatchEvents(t *testing.T) {
+	in := make(chan WatchEvent)
+	out := make(chan WatchEvent, 10)
+	go func() {
+		for _, event := range []WatchEvent{
+			{Type: watch.Modified, Object: unstructured.Unstructured{Object: newFakeVersionedObject("v1", "ConfigMap", "shop", "settings", "2")}},
+			{Type: watch.Added, Object: unstructured.Unstructured{Object: newFakeVersionedObject("v1", "ConfigMap", "shop", "cart", "3")}},
+			{Type: watch.Modified, Object: unstructured.Unstructured{Object: newFakeVersionedObject("v1", "ConfigMap", "shop", "settings", "4")}},
+			{Type: watch.Modified, Object: unstructured.Unstructured{Object: newFakeVersionedObject("v1", "ConfigMap", "shop", "cart", "5")}},
+		} {
+			in <- event
+		}
+		close(in)
+	}()
+	coalesceWatchEvents(context.Background(), in, out, time.Hour)
+	close(out)
+	var got []string
+	for event := range out {
+		got = append(got, string(event.Type)+" "+event.Object.GetName()+" "+event.Object.GetResourceVersion())
+	}
+	assert.Equal(t, []string{"MODIFIED settings 4", "ADDED cart 5"}, got)
+}
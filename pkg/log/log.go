@@ -21,17 +21,32 @@ import (
 	"fmt"
 	"github.com/fatih/color"
 	multierror "github.com/hashicorp/go-multierror"
+	"io"
+	"os"
 	"strings"
 )
 
+// out is where messages are written, stdout unless changed with SetOutput
+var out io.Writer = os.Stdout
+
+// SetOutput writes messages to w, eg stderr when stdout is reserved for a document like the json output
+func SetOutput(w io.Writer) {
+	out = w
+}
+
 func Success(message ...string) {
 	green := color.New(color.FgGreen).SprintFunc()
-	fmt.Printf("%s - %v\n", green("PASS"), strings.Join(message, " "))
+	fmt.Fprintf(out, "%s - %v\n", green("PASS"), strings.Join(message, " "))
+}
+
+func Info(message ...string) {
+	cyan := color.New(color.FgCyan).SprintFunc()
+	fmt.Fprintf(out, "%s - %v\n", cyan("INFO"), strings.Join(message, " "))
 }
 
 func Warn(message ...string) {
 	yellow := color.New(color.FgYellow).SprintFunc()
-	fmt.Printf("%s - %v\n", yellow("WARN"), strings.Join(message, " "))
+	fmt.Fprintf(out, "%s - %v\n", yellow("WARN"), strings.Join(message, " "))
 }
 
 func Error(message error) {
@@ -41,11 +56,11 @@ func Error(message error) {
 		}
 	} else {
 		red := color.New(color.FgRed).SprintFunc()
-		fmt.Printf("%s - %v\n", red("ERR "), message)
+		fmt.Fprintf(out, "%s - %v\n", red("ERR "), message)
 	}
 }
 
 func Debug(message ...string) {
 	yellow := color.New(color.FgWhite).SprintFunc()
-	fmt.Printf("%s - %v\n", yellow("DEBUG"), strings.Join(message, " "))
+	fmt.Fprintf(out, "%s - %v\n", yellow("DEBUG"), strings.Join(message, " "))
 }
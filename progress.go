@@ -0,0 +1,77 @@
+/*
+ * Copyright (c) 2021 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"fmt"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"os"
+	"time"
+)
+
+// progressLogInterval is how often progress is logged when stderr is not a terminal
+var progressLogInterval = 10 * time.Second
+
+// progressReporter renders the progress of a scan to stderr, as a single line updated in place on a terminal and as
+// a line logged every progressLogInterval otherwise
+type progressReporter struct {
+	out      *os.File
+	terminal bool
+	total    int
+	finished int
+	objects  int
+	lastLog  time.Time
+}
+
+func newProgressReporter(out *os.File) *progressReporter {
+	info, err := out.Stat()
+	return &progressReporter{out: out, terminal: err == nil && info.Mode()&os.ModeCharDevice != 0, lastLog: time.Now()}
+}
+
+func (r *progressReporter) ResourceStarted(resource schema.GroupVersionResource, index, total int) {
+	r.total = total
+	if r.terminal {
+		r.render(resource)
+	}
+}
+
+func (r *progressReporter) ObjectsFetched(resource schema.GroupVersionResource, count int) {
+	r.objects += count
+}
+
+func (r *progressReporter) ResourceFinished(resource schema.GroupVersionResource, err error) {
+	r.finished++
+	done := r.finished == r.total
+	if r.terminal {
+		if done {
+			// the results are printed in place of the progress line
+			fmt.Fprint(r.out, "\r\033[K")
+		} else {
+			r.render(resource)
+		}
+		return
+	}
+	if done || time.Since(r.lastLog) >= progressLogInterval {
+		r.lastLog = time.Now()
+		fmt.Fprintf(r.out, "listed %d of %d resources, %d objects fetched\n", r.finished, r.total, r.objects)
+	}
+}
+
+func (r *progressReporter) render(resource schema.GroupVersionResource) {
+	fmt.Fprintf(r.out, "\r\033[K[%d/%d] %d objects fetched, listing %s", r.finished, r.total, r.objects, resource.String())
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2021 Devtron Labs
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *    http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ *
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/devtron-labs/silver-surfer/kubedd"
+	"github.com/devtron-labs/silver-surfer/pkg"
+	log2 "github.com/devtron-labs/silver-surfer/pkg/log"
+)
+
+// watchObjects keeps validating the objects created or updated in the cluster after it is scanned
+var watchObjects = false
+
+// watchContext flushes the results of the scan of cluster and then puts the results with findings of the objects
+// created or updated in it to outputManager as they come, until ctx is done. It returns false if any of them has
+// findings which fail the run
+func watchContext(ctx context.Context, cluster *pkg.Cluster, name string, conf *pkg.Config, outputManager pkg.OutputManager) (bool, error) {
+	if err := outputManager.Flush(); err != nil {
+		return false, err
+	}
+	fmt.Println("")
+	fmt.Printf("Watching cluster %s for created and updated objects, interrupt to stop\n", name)
+	fmt.Println("-------------------------------------------")
+	success := true
+	err := kubedd.WatchCluster(ctx, cluster, conf, func(result pkg.ValidationResult) {
+		if !pkg.HasFindings(result) {
+			return
+		}
+		results := []pkg.ValidationResult{result}
+		if err := outputManager.PutBulk(results); err != nil {
+			log2.Error(err)
+		} else if err := outputManager.Flush(); err != nil {
+			log2.Error(err)
+		}
+		success = success && !pkg.HasGatingFindings(results, conf)
+	})
+	if err != nil && ctx.Err() == nil {
+		return false, err
+	}
+	// watching ends when interrupted
+	return success, nil
+}